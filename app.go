@@ -42,6 +42,16 @@ type App struct {
 	aiTaggingService      *services.AITaggingService
 	shortFeedService      *services.ShortFeedService
 	shortFeedServer       *services.ShortFeedHTTPServer
+	scanWatcherService    *services.ScanWatcherService
+	scanJobService        *services.ScanJobService
+	thumbnailService      *services.ThumbnailService
+	transcodeService      *services.TranscodeService
+	playbackProgress      *services.PlaybackProgressService
+	playbackHistory       *services.PlaybackHistoryService
+	playerProfileService  *services.PlayerProfileService
+	playlistService       *services.PlaylistService
+	savedSearchService    *services.SavedSearchService
+	tagRuleService        *services.TagRuleService
 	shortFeedStartupError string
 	startupError          string
 	logFile               *os.File // 保持日志文件句柄引用，防止泄漏
@@ -53,6 +63,10 @@ func NewApp() *App {
 	homeDir, _ := os.UserHomeDir()
 	dataDir := filepath.Join(homeDir, ".video-master")
 	videoService := &services.VideoService{}
+	thumbnailService := services.NewThumbnailService(dataDir, services.ThumbnailConfig{})
+	videoService.SetThumbnailService(thumbnailService)
+	transcodeService := services.NewTranscodeService(services.TranscodeConfig{})
+	videoService.SetTranscodeService(transcodeService)
 
 	return &App{
 		videoService:          videoService,
@@ -62,8 +76,18 @@ func NewApp() *App {
 		subtitleService:       services.NewSubtitleService(dataDir),
 		cleanupService:        &services.CleanupService{},
 		subtitleSearchService: &services.SubtitleSearchService{},
-		aiTaggingService:      services.NewAITaggingService(),
+		aiTaggingService:      services.NewAITaggingService(dataDir),
 		shortFeedService:      services.NewShortFeedService(videoService),
+		scanWatcherService:    services.NewScanWatcherService(videoService, services.ScanWatcherConfig{}),
+		scanJobService:        services.NewScanJobService(videoService, services.ScanJobConfig{}),
+		thumbnailService:      thumbnailService,
+		transcodeService:      transcodeService,
+		playbackProgress:      &services.PlaybackProgressService{},
+		playbackHistory:       &services.PlaybackHistoryService{},
+		playerProfileService:  &services.PlayerProfileService{},
+		playlistService:       services.NewPlaylistService(videoService),
+		savedSearchService:    services.NewSavedSearchService(videoService),
+		tagRuleService:        &services.TagRuleService{},
 	}
 }
 
@@ -77,8 +101,17 @@ func (a *App) startup(ctx context.Context) {
 	}
 	a.subtitleService.SetContext(ctx) // Inject context
 	a.cleanupService.SetContext(ctx)
+	a.scanWatcherService.SetContext(ctx)
+	a.scanJobService.SetContext(ctx)
+	a.thumbnailService.SetContext(ctx)
+	a.thumbnailService.Start(ctx)
+	a.transcodeService.Start(ctx)
 	a.aiTaggingService.Start(ctx)
 	a.startShortFeedServer(ctx)
+	a.reloadScanWatcher()
+	if _, err := a.directoryService.RefreshDirectoryStates(); err != nil {
+		log.Printf("App startup directory state refresh failed err=%v", err)
+	}
 	if settings, err := a.settingsService.GetSettings(); err == nil {
 		log.Printf("App startup settings loaded %s", summarizeSettings(settings))
 		a.setLogEnabled(settings.LogEnabled)
@@ -91,6 +124,18 @@ func (a *App) shutdown(ctx context.Context) {
 	if a.aiTaggingService != nil {
 		a.aiTaggingService.Stop()
 	}
+	if a.scanWatcherService != nil {
+		a.scanWatcherService.Stop()
+	}
+	if a.scanJobService != nil {
+		a.scanJobService.Cancel()
+	}
+	if a.thumbnailService != nil {
+		a.thumbnailService.Stop()
+	}
+	if a.transcodeService != nil {
+		a.transcodeService.Stop()
+	}
 	if a.shortFeedServer != nil {
 		if err := a.shortFeedServer.Stop(ctx); err != nil {
 			log.Printf("Short feed server shutdown failed: %v", err)
@@ -240,6 +285,15 @@ func (a *App) SearchVideos(keyword string, cursorScore float64, cursorSize int64
 	return videos, err
 }
 
+// CheckSearchQuery 校验搜索框查询语法，错误带字符位置
+func (a *App) CheckSearchQuery(query string) services.SearchQueryParseResult {
+	result := services.CheckSearchQuery(query)
+	if !result.Valid {
+		log.Printf("API CheckSearchQuery query=%q error=%+v", query, result.Error)
+	}
+	return result
+}
+
 // SearchSubtitleMatches 按字幕内容搜索视频片段
 func (a *App) SearchSubtitleMatches(keyword string, limit int) ([]services.SubtitleSearchMatch, error) {
 	matches, err := a.subtitleSearchService.SearchSubtitleMatches(keyword, limit)
@@ -247,6 +301,24 @@ func (a *App) SearchSubtitleMatches(keyword string, limit int) ([]services.Subti
 	return matches, err
 }
 
+// SearchVideosRanked 按文件名全文检索，结果按相关度排序并带高亮片段
+func (a *App) SearchVideosRanked(keyword string, limit int) ([]services.RankedVideo, error) {
+	results, err := a.videoService.SearchVideosRanked(keyword, limit)
+	log.Printf("API SearchVideosRanked keyword=%q limit=%d result=%d err=%v", keyword, limit, len(results), err)
+	return results, err
+}
+
+// SearchSubtitleHits 按字幕内容搜索，返回每条命中及上下文，按视频游标分页
+func (a *App) SearchSubtitleHits(query services.SubtitleHitQuery) (*services.SubtitleHitPage, error) {
+	page, err := a.subtitleSearchService.SearchSubtitleHits(query)
+	videoCount := 0
+	if page != nil {
+		videoCount = len(page.Videos)
+	}
+	log.Printf("API SearchSubtitleHits keyword=%q context=%d/%d cursorVideoID=%d limit=%d videos=%d err=%v", query.Keyword, query.ContextBefore, query.ContextAfter, query.CursorVideoID, query.Limit, videoCount, err)
+	return page, err
+}
+
 // SearchVideosByTags 按标签搜索视频（多选 AND，支持分页）
 func (a *App) SearchVideosByTags(tagIDs []uint, cursorScore float64, cursorSize int64, cursorID uint, limit int) ([]models.Video, error) {
 	videos, err := a.videoService.SearchVideosByTags(tagIDs, cursorScore, cursorSize, cursorID, limit)
@@ -254,10 +326,17 @@ func (a *App) SearchVideosByTags(tagIDs []uint, cursorScore float64, cursorSize
 	return videos, err
 }
 
-// SearchVideosWithFilters 组合搜索视频（名称 + 标签 + 体积 + 分辨率，支持分页）
-func (a *App) SearchVideosWithFilters(keyword string, tagIDs []uint, minSize, maxSize int64, minHeight, maxHeight int, cursorScore float64, cursorSize int64, cursorID uint, limit int) ([]models.Video, error) {
-	videos, err := a.videoService.SearchVideosWithFilters(keyword, tagIDs, minSize, maxSize, minHeight, maxHeight, cursorScore, cursorSize, cursorID, limit)
-	log.Printf("API SearchVideosWithFilters keyword=%q tags=%v size=[%d,%d] height=[%d,%d] cursorScore=%.4f cursorSize=%d cursorID=%d limit=%d result=%d err=%v sample=%s", keyword, tagIDs, minSize, maxSize, minHeight, maxHeight, cursorScore, cursorSize, cursorID, limit, len(videos), err, summarizeVideos(videos, 3))
+// SearchVideosWithFilters 组合搜索视频（名称 + 标签 + 体积 + 分辨率 + 媒体信息，支持分页）
+func (a *App) SearchVideosWithFilters(keyword string, tagIDs []uint, minSize, maxSize int64, minHeight, maxHeight int, media services.VideoMediaFilter, cursorScore float64, cursorSize int64, cursorID uint, limit int) ([]models.Video, error) {
+	videos, err := a.videoService.SearchVideosWithFilters(keyword, tagIDs, minSize, maxSize, minHeight, maxHeight, media, cursorScore, cursorSize, cursorID, limit)
+	log.Printf("API SearchVideosWithFilters keyword=%q tags=%v size=[%d,%d] height=[%d,%d] media=%+v cursorScore=%.4f cursorSize=%d cursorID=%d limit=%d result=%d err=%v sample=%s", keyword, tagIDs, minSize, maxSize, minHeight, maxHeight, media, cursorScore, cursorSize, cursorID, limit, len(videos), err, summarizeVideos(videos, 3))
+	return videos, err
+}
+
+// SearchVideosByFilter 按完整组合条件搜索视频（含子标签展开等选项，支持分页）
+func (a *App) SearchVideosByFilter(filter services.VideoSearchFilter, cursorScore float64, cursorSize int64, cursorID uint, limit int) ([]models.Video, error) {
+	videos, err := a.videoService.SearchVideosByFilter(filter, cursorScore, cursorSize, cursorID, limit)
+	log.Printf("API SearchVideosByFilter filter=%+v cursorScore=%.4f cursorSize=%d cursorID=%d limit=%d result=%d err=%v sample=%s", filter, cursorScore, cursorSize, cursorID, limit, len(videos), err, summarizeVideos(videos, 3))
 	return videos, err
 }
 
@@ -384,6 +463,68 @@ func (a *App) PlayRandomVideo() (*services.PlaybackAttemptResult, error) {
 	return result, err
 }
 
+// PlayRandomVideoWithOptions 按策略在指定范围内随机发起正式播放
+func (a *App) PlayRandomVideoWithOptions(options services.RandomPlayOptions) (*services.PlaybackAttemptResult, error) {
+	result, err := a.videoService.PlayRandomVideoWithOptions(options)
+	if result != nil && result.Video != nil {
+		log.Printf("API PlayRandomVideoWithOptions strategy=%s scope=%+v id=%d dispatch=%v reason=%s err=%v", options.Strategy, options.Scope, result.Video.ID, result.DispatchSucceeded, result.ReasonCode, err)
+	} else {
+		log.Printf("API PlayRandomVideoWithOptions strategy=%s scope=%+v id=0 dispatch=false err=%v", options.Strategy, options.Scope, err)
+	}
+	return result, err
+}
+
+// GetRandomPlayStrategies 列出可选的随机播放策略
+func (a *App) GetRandomPlayStrategies() []services.RandomPlayStrategyInfo {
+	return services.RandomPlayStrategies()
+}
+
+// UpdatePlaybackProgress 记录应用内预览的播放位置
+func (a *App) UpdatePlaybackProgress(videoID uint, positionSeconds float64, durationSeconds float64) (*models.PlaybackProgress, error) {
+	progress, err := a.playbackProgress.UpdateProgress(videoID, positionSeconds, durationSeconds, services.PlaybackSourcePreview)
+	if err != nil {
+		log.Printf("API UpdatePlaybackProgress id=%d position=%.1f duration=%.1f err=%v", videoID, positionSeconds, durationSeconds, err)
+	}
+	return progress, err
+}
+
+// GetPlaybackProgress 获取视频的播放进度，从未播放过时返回 nil
+func (a *App) GetPlaybackProgress(videoID uint) (*models.PlaybackProgress, error) {
+	return a.playbackProgress.GetProgress(videoID)
+}
+
+// ClearPlaybackProgress 清除播放进度并从继续观看中移除
+func (a *App) ClearPlaybackProgress(videoID uint) error {
+	err := a.playbackProgress.ClearProgress(videoID)
+	log.Printf("API ClearPlaybackProgress id=%d err=%v", videoID, err)
+	return err
+}
+
+// GetContinueWatching 获取看了一部分、尚未看完的视频
+func (a *App) GetContinueWatching(limit int) ([]models.PlaybackProgress, error) {
+	rows, err := a.playbackProgress.ContinueWatching(limit)
+	log.Printf("API GetContinueWatching limit=%d result=%d err=%v", limit, len(rows), err)
+	return rows, err
+}
+
+// ListPlaybackHistory 按时间倒序浏览播放历史（支持来源、日期范围与分页）
+func (a *App) ListPlaybackHistory(filter services.PlaybackHistoryFilter) (*services.PlaybackHistoryPage, error) {
+	page, err := a.playbackHistory.ListHistory(filter)
+	if err != nil {
+		log.Printf("API ListPlaybackHistory filter=%+v err=%v", filter, err)
+		return nil, err
+	}
+	log.Printf("API ListPlaybackHistory filter=%+v result=%d total=%d", filter, len(page.Events), page.Total)
+	return page, nil
+}
+
+// DeletePlaybackEvents 删除播放历史条目并回算播放统计
+func (a *App) DeletePlaybackEvents(eventIDs []uint) (int64, error) {
+	deleted, err := a.playbackHistory.DeleteEvents(eventIDs)
+	log.Printf("API DeletePlaybackEvents requested=%d deleted=%d err=%v", len(eventIDs), deleted, err)
+	return deleted, err
+}
+
 // AddTagToVideo 为视频添加标签
 func (a *App) AddTagToVideo(videoID uint, tagID uint) error {
 	err := a.videoService.AddTagToVideo(videoID, tagID)
@@ -430,6 +571,42 @@ func (a *App) CreateTag(name, color string) (*models.Tag, error) {
 	return tag, err
 }
 
+// GetTagTree 以树形获取所有标签
+func (a *App) GetTagTree() ([]services.TagTreeNode, error) {
+	tree, err := a.tagService.GetTagTree()
+	log.Printf("API GetTagTree roots=%d err=%v", len(tree), err)
+	return tree, err
+}
+
+// GetTagStats 获取标签使用统计、标签共现与各扫描目录的未打标签数
+func (a *App) GetTagStats() (*services.TagStatsReport, error) {
+	report, err := a.tagService.GetTagStats()
+	if err != nil {
+		log.Printf("API GetTagStats err=%v", err)
+		return nil, err
+	}
+	log.Printf("API GetTagStats tags=%d pairs=%d dirs=%d", len(report.Tags), len(report.CoOccurrences), len(report.Directories))
+	return report, nil
+}
+
+// CreateChildTag 在父标签下创建标签，parentID 为 0 时创建顶层标签
+func (a *App) CreateChildTag(name, color string, parentID uint) (*models.Tag, error) {
+	tag, err := a.tagService.CreateChildTag(name, color, parentID)
+	var id uint
+	if tag != nil {
+		id = tag.ID
+	}
+	log.Printf("API CreateChildTag name=%s color=%s parentID=%d id=%d err=%v", name, color, parentID, id, err)
+	return tag, err
+}
+
+// MoveTag 把标签移到新的父标签下，parentID 为 0 时移到顶层
+func (a *App) MoveTag(id, parentID uint) error {
+	err := a.tagService.MoveTag(id, parentID)
+	log.Printf("API MoveTag id=%d parentID=%d err=%v", id, parentID, err)
+	return err
+}
+
 // UpdateTag 更新标签
 func (a *App) UpdateTag(id uint, name, color string) error {
 	err := a.tagService.UpdateTag(id, name, color)
@@ -444,6 +621,93 @@ func (a *App) DeleteTag(id uint) error {
 	return err
 }
 
+// AddTagAlias 为标签添加别名
+func (a *App) AddTagAlias(tagID uint, name string) (*models.TagAlias, error) {
+	alias, err := a.tagService.AddTagAlias(tagID, name)
+	log.Printf("API AddTagAlias tagID=%d name=%s err=%v", tagID, name, err)
+	return alias, err
+}
+
+// RemoveTagAlias 删除标签别名
+func (a *App) RemoveTagAlias(aliasID uint) error {
+	err := a.tagService.RemoveTagAlias(aliasID)
+	log.Printf("API RemoveTagAlias aliasID=%d err=%v", aliasID, err)
+	return err
+}
+
+// MergeTags 把来源标签合并到目标标签
+func (a *App) MergeTags(sourceIDs []uint, targetID uint) error {
+	err := a.tagService.MergeTags(sourceIDs, targetID)
+	log.Printf("API MergeTags sources=%v target=%d err=%v", sourceIDs, targetID, err)
+	return err
+}
+
+// SplitTag 把选中视频的来源标签改为新建的同级标签
+func (a *App) SplitTag(sourceID uint, newName string, videoIDs []uint) (*models.Tag, error) {
+	tag, err := a.tagService.SplitTag(sourceID, newName, videoIDs)
+	log.Printf("API SplitTag source=%d name=%s videos=%d err=%v", sourceID, newName, len(videoIDs), err)
+	return tag, err
+}
+
+// ===== Tag Rule Methods =====
+
+// GetTagRules 获取全部自动打标规则
+func (a *App) GetTagRules() ([]models.TagRule, error) {
+	rules, err := a.tagRuleService.GetTagRules()
+	log.Printf("API GetTagRules result=%d err=%v", len(rules), err)
+	return rules, err
+}
+
+// CreateTagRule 新建自动打标规则
+func (a *App) CreateTagRule(input services.TagRuleInput) (*models.TagRule, error) {
+	rule, err := a.tagRuleService.CreateTagRule(input)
+	log.Printf("API CreateTagRule input=%+v err=%v", input, err)
+	return rule, err
+}
+
+// UpdateTagRule 修改自动打标规则
+func (a *App) UpdateTagRule(id uint, input services.TagRuleInput) error {
+	err := a.tagRuleService.UpdateTagRule(id, input)
+	log.Printf("API UpdateTagRule id=%d input=%+v err=%v", id, input, err)
+	return err
+}
+
+// DeleteTagRule 删除自动打标规则，revert 为 true 时同时撤销其建立的标签
+func (a *App) DeleteTagRule(id uint, revert bool) error {
+	err := a.tagRuleService.DeleteTagRule(id, revert)
+	log.Printf("API DeleteTagRule id=%d revert=%v err=%v", id, revert, err)
+	return err
+}
+
+// PreviewTagRules 对整个库试运行规则，ruleID 为 0 时为全部已启用规则
+func (a *App) PreviewTagRules(ruleID uint) (*services.TagRuleRunResult, error) {
+	result, err := a.tagRuleService.PreviewTagRules(ruleID)
+	if result != nil {
+		log.Printf("API PreviewTagRules ruleID=%d scanned=%d linked=%d err=%v", ruleID, result.Scanned, result.Linked, err)
+	} else {
+		log.Printf("API PreviewTagRules ruleID=%d err=%v", ruleID, err)
+	}
+	return result, err
+}
+
+// RunTagRules 对整个库执行规则，ruleID 为 0 时为全部已启用规则
+func (a *App) RunTagRules(ruleID uint) (*services.TagRuleRunResult, error) {
+	result, err := a.tagRuleService.RunTagRules(ruleID)
+	if result != nil {
+		log.Printf("API RunTagRules ruleID=%d scanned=%d linked=%d err=%v", ruleID, result.Scanned, result.Linked, err)
+	} else {
+		log.Printf("API RunTagRules ruleID=%d err=%v", ruleID, err)
+	}
+	return result, err
+}
+
+// RevertTagRule 撤销规则建立的全部标签关联
+func (a *App) RevertTagRule(ruleID uint) (int, error) {
+	removed, err := a.tagRuleService.RevertTagRule(ruleID)
+	log.Printf("API RevertTagRule ruleID=%d removed=%d err=%v", ruleID, removed, err)
+	return removed, err
+}
+
 // ===== AI Tagging Methods =====
 
 func (a *App) ListAITagCandidates(videoID uint, confidence string, status string) ([]services.AITaggingReviewItem, error) {
@@ -482,6 +746,44 @@ func (a *App) GetAITaggingStatusSummary() (*services.AITaggingStatusSummary, err
 	return summary, err
 }
 
+// GetAITaggingQueueStatus 获取 AI 打标队列深度（可处理、退避中、死信、处理中）
+func (a *App) GetAITaggingQueueStatus() (*services.AITaggingQueueStatus, error) {
+	status, err := a.aiTaggingService.QueueStatus()
+	log.Printf("API GetAITaggingQueueStatus err=%v status=%+v", err, status)
+	return status, err
+}
+
+// PauseAITaggingQueue 暂停后台 AI 打标队列
+func (a *App) PauseAITaggingQueue() {
+	a.aiTaggingService.PauseQueue()
+	log.Printf("API PauseAITaggingQueue")
+}
+
+// ResumeAITaggingQueue 恢复后台 AI 打标队列
+func (a *App) ResumeAITaggingQueue() {
+	a.aiTaggingService.ResumeQueue()
+	log.Printf("API ResumeAITaggingQueue")
+}
+
+// RetryAITaggingDeadLetters 把重试次数用尽的视频重新排队
+func (a *App) RetryAITaggingDeadLetters() (int64, error) {
+	count, err := a.aiTaggingService.RetryDeadLetters()
+	log.Printf("API RetryAITaggingDeadLetters count=%d err=%v", count, err)
+	return count, err
+}
+
+// GetAITaggingCLIPRuntimeReady 本地 CLIP 运行时是否已准备好
+func (a *App) GetAITaggingCLIPRuntimeReady() bool {
+	return a.aiTaggingService.CLIPRuntimeReady()
+}
+
+// PrepareAITaggingCLIPRuntime 创建本地 CLIP 虚拟环境并安装依赖
+func (a *App) PrepareAITaggingCLIPRuntime() error {
+	err := a.aiTaggingService.PrepareCLIPRuntime()
+	log.Printf("API PrepareAITaggingCLIPRuntime err=%v", err)
+	return err
+}
+
 // ===== Settings Methods =====
 
 // GetSettings 获取设置
@@ -513,18 +815,66 @@ func (a *App) GetAllDirectories() ([]models.ScanDirectory, error) {
 }
 
 // AddDirectory 添加扫描目录
-func (a *App) AddDirectory(path, alias string) (*models.ScanDirectory, error) {
-	return a.directoryService.AddDirectory(path, alias)
+func (a *App) AddDirectory(path, alias string, rules models.ScanDirectoryRules) (*models.ScanDirectory, error) {
+	dir, err := a.directoryService.AddDirectory(path, alias, rules)
+	if err == nil {
+		a.reloadScanWatcher()
+	}
+	return dir, err
 }
 
 // UpdateDirectory 更新目录
-func (a *App) UpdateDirectory(id uint, path, alias string) error {
-	return a.directoryService.UpdateDirectory(id, path, alias)
+func (a *App) UpdateDirectory(id uint, path, alias string, rules models.ScanDirectoryRules) error {
+	err := a.directoryService.UpdateDirectory(id, path, alias, rules)
+	if err == nil {
+		a.reloadScanWatcher()
+	}
+	return err
+}
+
+// RefreshDirectoryStates 重新检测扫描目录在线状态
+func (a *App) RefreshDirectoryStates() ([]models.ScanDirectory, error) {
+	dirs, err := a.directoryService.RefreshDirectoryStates()
+	offline := 0
+	for _, dir := range dirs {
+		if dir.Offline {
+			offline++
+		}
+	}
+	log.Printf("API RefreshDirectoryStates result=%d offline=%d err=%v", len(dirs), offline, err)
+	return dirs, err
 }
 
 // DeleteDirectory 删除扫描目录
 func (a *App) DeleteDirectory(id uint) error {
-	return a.directoryService.DeleteDirectory(id)
+	err := a.directoryService.DeleteDirectory(id)
+	if err == nil {
+		a.reloadScanWatcher()
+	}
+	return err
+}
+
+// GetScanWatcherStatus 获取扫描目录实时监听状态
+func (a *App) GetScanWatcherStatus() *services.ScanWatcherStatus {
+	status := a.scanWatcherService.Status()
+	log.Printf("API GetScanWatcherStatus running=%v roots=%d dirs=%d pending=%d err=%q",
+		status.Running, len(status.WatchedRoots), status.WatchedDirs, len(status.PendingRoots), status.Error)
+	return status
+}
+
+// reloadScanWatcher 按当前扫描目录配置重建实时监听
+func (a *App) reloadScanWatcher() {
+	if a.scanWatcherService == nil || a.ctx == nil || a.startupError != "" {
+		return
+	}
+	dirs, err := a.directoryService.GetAllDirectories()
+	if err != nil {
+		log.Printf("Scan watcher reload skipped: load dirs err=%v", err)
+		return
+	}
+	if err := a.scanWatcherService.Reload(dirs); err != nil {
+		log.Printf("Scan watcher reload failed: %v", err)
+	}
 }
 
 func (a *App) SyncScanDirectories() (*services.ScanSyncResult, error) {
@@ -539,6 +889,221 @@ func (a *App) SyncScanDirectories() (*services.ScanSyncResult, error) {
 	return result, nil
 }
 
+// StartScanJob 在后台启动扫描目录增量同步，进度通过 scan-job-progress 事件推送
+func (a *App) StartScanJob() (*services.ScanJobStatus, error) {
+	dirs, err := a.directoryService.GetAllDirectories()
+	if err != nil {
+		log.Printf("API StartScanJob load dirs err=%v", err)
+		return nil, err
+	}
+	status, err := a.scanJobService.Start(dirs)
+	log.Printf("API StartScanJob dirs=%d running=%v err=%v", len(dirs), status != nil && status.Running, err)
+	return status, err
+}
+
+// CancelScanJob 取消正在运行的扫描任务
+func (a *App) CancelScanJob() *services.ScanJobStatus {
+	status := a.scanJobService.Cancel()
+	log.Printf("API CancelScanJob running=%v cancelled=%v", status.Running, status.Cancelled)
+	return status
+}
+
+// GetScanJobStatus 获取扫描任务状态
+func (a *App) GetScanJobStatus() *services.ScanJobStatus {
+	return a.scanJobService.Status()
+}
+
+// ===== Saved Search Methods =====
+
+// GetSavedSearches 获取全部保存的搜索（含实时匹配数）
+func (a *App) GetSavedSearches() ([]models.SavedSearch, error) {
+	searches, err := a.savedSearchService.GetSavedSearches()
+	log.Printf("API GetSavedSearches result=%d err=%v", len(searches), err)
+	return searches, err
+}
+
+// CreateSavedSearch 保存当前搜索条件
+func (a *App) CreateSavedSearch(input services.SavedSearchInput) (*models.SavedSearch, error) {
+	search, err := a.savedSearchService.CreateSavedSearch(input)
+	log.Printf("API CreateSavedSearch name=%q filter=%+v err=%v", input.Name, input.Filter, err)
+	return search, err
+}
+
+// UpdateSavedSearch 修改保存的搜索
+func (a *App) UpdateSavedSearch(id uint, input services.SavedSearchInput) error {
+	err := a.savedSearchService.UpdateSavedSearch(id, input)
+	log.Printf("API UpdateSavedSearch id=%d name=%q filter=%+v err=%v", id, input.Name, input.Filter, err)
+	return err
+}
+
+// DeleteSavedSearch 删除保存的搜索
+func (a *App) DeleteSavedSearch(id uint) error {
+	err := a.savedSearchService.DeleteSavedSearch(id)
+	log.Printf("API DeleteSavedSearch id=%d err=%v", id, err)
+	return err
+}
+
+// RunSavedSearch 执行保存的搜索（游标分页，附匹配总数）
+func (a *App) RunSavedSearch(id uint, cursorScore float64, cursorSize int64, cursorID uint, limit int) (*services.SavedSearchResult, error) {
+	result, err := a.savedSearchService.RunSavedSearch(id, cursorScore, cursorSize, cursorID, limit)
+	count, total := 0, int64(0)
+	if result != nil {
+		count, total = len(result.Videos), result.Total
+	}
+	log.Printf("API RunSavedSearch id=%d cursorScore=%.4f cursorSize=%d cursorID=%d limit=%d result=%d total=%d err=%v", id, cursorScore, cursorSize, cursorID, limit, count, total, err)
+	return result, err
+}
+
+// ===== Playlist Methods =====
+
+// GetPlaylists 获取全部播放列表
+func (a *App) GetPlaylists() ([]models.Playlist, error) {
+	playlists, err := a.playlistService.GetPlaylists()
+	log.Printf("API GetPlaylists result=%d err=%v", len(playlists), err)
+	return playlists, err
+}
+
+// GetPlaylist 获取播放列表详情（含有序条目）
+func (a *App) GetPlaylist(id uint) (*models.Playlist, error) {
+	playlist, err := a.playlistService.GetPlaylist(id)
+	log.Printf("API GetPlaylist id=%d err=%v", id, err)
+	return playlist, err
+}
+
+// ResolvePlaylistVideos 按播放顺序获取播放列表中的视频（智能列表实时筛选）
+func (a *App) ResolvePlaylistVideos(id uint) ([]models.Video, error) {
+	videos, err := a.playlistService.ResolvePlaylistVideos(id)
+	log.Printf("API ResolvePlaylistVideos id=%d result=%d err=%v", id, len(videos), err)
+	return videos, err
+}
+
+// CreatePlaylist 新建手动或智能播放列表
+func (a *App) CreatePlaylist(input services.PlaylistInput) (*models.Playlist, error) {
+	playlist, err := a.playlistService.CreatePlaylist(input)
+	log.Printf("API CreatePlaylist name=%q kind=%s err=%v", input.Name, input.Kind, err)
+	return playlist, err
+}
+
+// UpdatePlaylist 更新播放列表名称、筛选条件与播放模式
+func (a *App) UpdatePlaylist(id uint, input services.PlaylistInput) error {
+	err := a.playlistService.UpdatePlaylist(id, input)
+	log.Printf("API UpdatePlaylist id=%d name=%q kind=%s shuffle=%v repeat=%s err=%v", id, input.Name, input.Kind, input.Shuffle, input.RepeatMode, err)
+	return err
+}
+
+// DeletePlaylist 删除播放列表
+func (a *App) DeletePlaylist(id uint) error {
+	err := a.playlistService.DeletePlaylist(id)
+	log.Printf("API DeletePlaylist id=%d err=%v", id, err)
+	return err
+}
+
+// AddVideosToPlaylist 把视频追加到手动播放列表
+func (a *App) AddVideosToPlaylist(id uint, videoIDs []uint) (int, error) {
+	added, err := a.playlistService.AddVideosToPlaylist(id, videoIDs)
+	log.Printf("API AddVideosToPlaylist id=%d requested=%d added=%d err=%v", id, len(videoIDs), added, err)
+	return added, err
+}
+
+// RemovePlaylistItems 从手动播放列表移除条目
+func (a *App) RemovePlaylistItems(id uint, itemIDs []uint) error {
+	err := a.playlistService.RemovePlaylistItems(id, itemIDs)
+	log.Printf("API RemovePlaylistItems id=%d items=%v err=%v", id, itemIDs, err)
+	return err
+}
+
+// ReorderPlaylist 按条目 ID 顺序重排手动播放列表
+func (a *App) ReorderPlaylist(id uint, itemIDs []uint) error {
+	err := a.playlistService.ReorderPlaylist(id, itemIDs)
+	log.Printf("API ReorderPlaylist id=%d items=%d err=%v", id, len(itemIDs), err)
+	return err
+}
+
+// StartPlaylist 开始播放列表（target: external 外部播放器 / inline 内嵌预览）
+func (a *App) StartPlaylist(id uint, startVideoID uint, target string) (*services.PlaylistPlaybackResult, error) {
+	result, err := a.playlistService.StartPlaylist(id, startVideoID, target)
+	logPlaylistPlayback("StartPlaylist", id, target, result, err)
+	return result, err
+}
+
+// StepPlaylist 播放列表前进/后退（step: next / previous / ended）
+func (a *App) StepPlaylist(id uint, step string, target string) (*services.PlaylistPlaybackResult, error) {
+	result, err := a.playlistService.StepPlaylist(id, step, target)
+	logPlaylistPlayback("StepPlaylist step="+step, id, target, result, err)
+	return result, err
+}
+
+// RecordPlaylistInlinePlayback 内嵌播放列表的当前项真正开始播放后上报，计入正式播放统计
+func (a *App) RecordPlaylistInlinePlayback(id uint, videoID uint) (*services.PlaybackAttemptResult, error) {
+	result, err := a.playlistService.RecordInlinePlayback(id, videoID)
+	log.Printf("API RecordPlaylistInlinePlayback id=%d video_id=%d err=%v", id, videoID, err)
+	return result, err
+}
+
+func logPlaylistPlayback(api string, id uint, target string, result *services.PlaylistPlaybackResult, err error) {
+	if err != nil || result == nil {
+		log.Printf("API %s id=%d target=%s err=%v", api, id, target, err)
+		return
+	}
+	videoID := uint(0)
+	if result.Video != nil {
+		videoID = result.Video.ID
+	}
+	dispatched := result.Attempt != nil && result.Attempt.DispatchSucceeded
+	log.Printf("API %s id=%d target=%s position=%d/%d finished=%v videoID=%d dispatched=%v", api, id, target, result.Position, result.Total, result.Finished, videoID, dispatched)
+}
+
+// ===== Player Profile Methods =====
+
+// GetPlayerProfiles 获取外部播放器配置
+func (a *App) GetPlayerProfiles() ([]models.PlayerProfile, error) {
+	profiles, err := a.playerProfileService.GetAllPlayerProfiles()
+	log.Printf("API GetPlayerProfiles result=%d err=%v", len(profiles), err)
+	return profiles, err
+}
+
+// AddPlayerProfile 新增外部播放器配置
+func (a *App) AddPlayerProfile(input models.PlayerProfile) (*models.PlayerProfile, error) {
+	profile, err := a.playerProfileService.AddPlayerProfile(input)
+	log.Printf("API AddPlayerProfile name=%q exe=%s err=%v", input.Name, input.ExecutablePath, err)
+	return profile, err
+}
+
+// UpdatePlayerProfile 更新外部播放器配置
+func (a *App) UpdatePlayerProfile(input models.PlayerProfile) error {
+	err := a.playerProfileService.UpdatePlayerProfile(input)
+	log.Printf("API UpdatePlayerProfile id=%d name=%q err=%v", input.ID, input.Name, err)
+	return err
+}
+
+// DeletePlayerProfile 删除外部播放器配置
+func (a *App) DeletePlayerProfile(id uint) error {
+	err := a.playerProfileService.DeletePlayerProfile(id)
+	log.Printf("API DeletePlayerProfile id=%d err=%v", id, err)
+	return err
+}
+
+// SelectPlayerExecutable 选择外部播放器可执行文件
+func (a *App) SelectPlayerExecutable() (string, error) {
+	return runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
+		Title: "选择播放器程序",
+	})
+}
+
+// ===== Thumbnail Methods =====
+
+// RegenerateThumbnails 清除指定视频的封面与联系表缓存并重新排队生成
+func (a *App) RegenerateThumbnails(videoIDs []uint) int {
+	queued := a.thumbnailService.Regenerate(videoIDs...)
+	log.Printf("API RegenerateThumbnails requested=%d queued=%d", len(videoIDs), queued)
+	return queued
+}
+
+// GetThumbnailQueueStatus 获取缩略图后台队列状态
+func (a *App) GetThumbnailQueueStatus() services.ThumbnailQueueStatus {
+	return a.thumbnailService.Status()
+}
+
 // ===== Subtitle Methods =====
 
 // GetSubtitleEngineStatuses 获取字幕引擎可用性状态
@@ -626,14 +1191,15 @@ func (a *App) GetSubtitleSegments(videoID uint) ([]subtitleparser.Segment, error
 }
 
 // GetCleanupCandidates 获取清理候选（轻量规则）
-func (a *App) GetCleanupCandidates(minDurationSeconds int, minWidth int, minHeight int) (*services.CleanupAnalysis, error) {
+func (a *App) GetCleanupCandidates(minDurationSeconds int, minWidth int, minHeight int, minBitrateKbps int) (*services.CleanupAnalysis, error) {
 	criteria := services.CleanupCriteria{
 		MinDuration: time.Duration(minDurationSeconds) * time.Second,
 		MinWidth:    minWidth,
 		MinHeight:   minHeight,
+		MinBitrate:  int64(minBitrateKbps) * 1000,
 	}
 	startedAt := time.Now()
-	log.Printf("API GetCleanupCandidates begin duration=%d width=%d height=%d", minDurationSeconds, minWidth, minHeight)
+	log.Printf("API GetCleanupCandidates begin duration=%d width=%d height=%d bitrate=%dkbps", minDurationSeconds, minWidth, minHeight, minBitrateKbps)
 	analysis, err := a.cleanupService.AnalyzeCleanupCandidates(criteria)
 	if err != nil {
 		log.Printf("API GetCleanupCandidates duration=%d width=%d height=%d elapsed=%s err=%v",
@@ -641,23 +1207,24 @@ func (a *App) GetCleanupCandidates(minDurationSeconds int, minWidth int, minHeig
 		return nil, err
 	}
 
-	log.Printf("API GetCleanupCandidates duration=%d width=%d height=%d elapsed=%s duplicate_groups=%d low_duration=%d low_resolution=%d",
-		minDurationSeconds, minWidth, minHeight,
+	log.Printf("API GetCleanupCandidates duration=%d width=%d height=%d bitrate=%dkbps elapsed=%s duplicate_groups=%d low_duration=%d low_resolution=%d low_bitrate=%d",
+		minDurationSeconds, minWidth, minHeight, minBitrateKbps,
 		time.Since(startedAt).Round(time.Millisecond),
-		len(analysis.DuplicateGroups), len(analysis.LowDuration), len(analysis.LowResolution),
+		len(analysis.DuplicateGroups), len(analysis.LowDuration), len(analysis.LowResolution), len(analysis.LowBitrate),
 	)
 	return analysis, nil
 }
 
-func (a *App) StartCleanupAnalysis(minDurationSeconds int, minWidth int, minHeight int) (*services.CleanupStatus, error) {
+func (a *App) StartCleanupAnalysis(minDurationSeconds int, minWidth int, minHeight int, minBitrateKbps int) (*services.CleanupStatus, error) {
 	criteria := services.CleanupCriteria{
 		MinDuration: time.Duration(minDurationSeconds) * time.Second,
 		MinWidth:    minWidth,
 		MinHeight:   minHeight,
+		MinBitrate:  int64(minBitrateKbps) * 1000,
 	}
 	status, err := a.cleanupService.StartAnalysis(criteria)
-	log.Printf("API StartCleanupAnalysis duration=%d width=%d height=%d running=%v completed=%v err=%v",
-		minDurationSeconds, minWidth, minHeight, status != nil && status.Running, status != nil && status.Completed, err)
+	log.Printf("API StartCleanupAnalysis duration=%d width=%d height=%d bitrate=%dkbps running=%v completed=%v err=%v",
+		minDurationSeconds, minWidth, minHeight, minBitrateKbps, status != nil && status.Running, status != nil && status.Completed, err)
 	return status, err
 }
 
@@ -726,12 +1293,14 @@ func summarizeSettings(settings *models.Settings) string {
 	if settings == nil {
 		return "<nil>"
 	}
-	return fmt.Sprintf("{id:%d theme:%q log_enabled:%v auto_scan:%v play_weight:%.2f short_feed_max_minutes:%d bilingual:%v lang:%q}",
+	return fmt.Sprintf("{id:%d theme:%q log_enabled:%v auto_scan:%v play_weight:%.2f no_repeat:%d/%dh short_feed_max_minutes:%d bilingual:%v lang:%q}",
 		settings.ID,
 		settings.Theme,
 		settings.LogEnabled,
 		settings.AutoScanOnStartup,
 		settings.PlayWeight,
+		settings.RandomNoRepeatCount,
+		settings.RandomNoRepeatHours,
 		settings.ShortFeedMaxDurationMinutes,
 		settings.BilingualEnabled,
 		settings.BilingualLang,
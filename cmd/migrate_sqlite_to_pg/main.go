@@ -21,10 +21,20 @@ type videoTag struct {
 type sqliteSnapshot struct {
 	Videos                  []models.Video
 	Tags                    []models.Tag
+	TagAliases              []models.TagAlias
+	TagRules                []models.TagRule
+	TagRuleLinks            []models.TagRuleLink
 	Settings                []models.Settings
 	ScanDirectories         []models.ScanDirectory
 	ShortFeedInteractions   []models.ShortFeedInteraction
 	ShortFeedTagPreferences []models.ShortFeedTagPreference
+	PlaybackProgresses      []models.PlaybackProgress
+	PlaybackEvents          []models.PlaybackEvent
+	PlayerProfiles          []models.PlayerProfile
+	Playlists               []models.Playlist
+	PlaylistItems           []models.PlaylistItem
+	RandomPicks             []models.RandomPick
+	SavedSearches           []models.SavedSearch
 	VideoTags               []videoTag
 }
 
@@ -93,6 +103,56 @@ func loadSqliteData(db *gorm.DB) (sqliteSnapshot, error) {
 			return snapshot, err
 		}
 	}
+	if db.Migrator().HasTable(&models.PlaybackProgress{}) {
+		if err := db.Find(&snapshot.PlaybackProgresses).Error; err != nil {
+			return snapshot, err
+		}
+	}
+	if db.Migrator().HasTable(&models.PlaybackEvent{}) {
+		if err := db.Find(&snapshot.PlaybackEvents).Error; err != nil {
+			return snapshot, err
+		}
+	}
+	if db.Migrator().HasTable(&models.PlayerProfile{}) {
+		if err := db.Find(&snapshot.PlayerProfiles).Error; err != nil {
+			return snapshot, err
+		}
+	}
+	if db.Migrator().HasTable(&models.Playlist{}) {
+		if err := db.Find(&snapshot.Playlists).Error; err != nil {
+			return snapshot, err
+		}
+	}
+	if db.Migrator().HasTable(&models.PlaylistItem{}) {
+		if err := db.Find(&snapshot.PlaylistItems).Error; err != nil {
+			return snapshot, err
+		}
+	}
+	if db.Migrator().HasTable(&models.RandomPick{}) {
+		if err := db.Find(&snapshot.RandomPicks).Error; err != nil {
+			return snapshot, err
+		}
+	}
+	if db.Migrator().HasTable(&models.TagAlias{}) {
+		if err := db.Find(&snapshot.TagAliases).Error; err != nil {
+			return snapshot, err
+		}
+	}
+	if db.Migrator().HasTable(&models.TagRule{}) {
+		if err := db.Find(&snapshot.TagRules).Error; err != nil {
+			return snapshot, err
+		}
+	}
+	if db.Migrator().HasTable(&models.TagRuleLink{}) {
+		if err := db.Find(&snapshot.TagRuleLinks).Error; err != nil {
+			return snapshot, err
+		}
+	}
+	if db.Migrator().HasTable(&models.SavedSearch{}) {
+		if err := db.Find(&snapshot.SavedSearches).Error; err != nil {
+			return snapshot, err
+		}
+	}
 	if err := db.Table("video_tags").Find(&snapshot.VideoTags).Error; err != nil {
 		return snapshot, err
 	}
@@ -174,6 +234,56 @@ func migrateSqliteToPostgres(sqlitePath string) error {
 			return err
 		}
 	}
+	if len(snapshot.PlaybackProgresses) > 0 {
+		if err := pgDB.Omit("Video").CreateInBatches(&snapshot.PlaybackProgresses, 200).Error; err != nil {
+			return err
+		}
+	}
+	if len(snapshot.PlaybackEvents) > 0 {
+		if err := pgDB.Omit("Video").CreateInBatches(&snapshot.PlaybackEvents, 500).Error; err != nil {
+			return err
+		}
+	}
+	if len(snapshot.PlayerProfiles) > 0 {
+		if err := pgDB.CreateInBatches(&snapshot.PlayerProfiles, 100).Error; err != nil {
+			return err
+		}
+	}
+	if len(snapshot.Playlists) > 0 {
+		if err := pgDB.Omit("Items").CreateInBatches(&snapshot.Playlists, 100).Error; err != nil {
+			return err
+		}
+	}
+	if len(snapshot.PlaylistItems) > 0 {
+		if err := pgDB.Omit("Video").CreateInBatches(&snapshot.PlaylistItems, 500).Error; err != nil {
+			return err
+		}
+	}
+	if len(snapshot.RandomPicks) > 0 {
+		if err := pgDB.CreateInBatches(&snapshot.RandomPicks, 500).Error; err != nil {
+			return err
+		}
+	}
+	if len(snapshot.SavedSearches) > 0 {
+		if err := pgDB.CreateInBatches(&snapshot.SavedSearches, 100).Error; err != nil {
+			return err
+		}
+	}
+	if len(snapshot.TagAliases) > 0 {
+		if err := pgDB.Omit("Tag").CreateInBatches(&snapshot.TagAliases, 200).Error; err != nil {
+			return err
+		}
+	}
+	if len(snapshot.TagRules) > 0 {
+		if err := pgDB.Omit("Tag").CreateInBatches(&snapshot.TagRules, 100).Error; err != nil {
+			return err
+		}
+	}
+	if len(snapshot.TagRuleLinks) > 0 {
+		if err := pgDB.Omit("Rule", "Video").CreateInBatches(&snapshot.TagRuleLinks, 500).Error; err != nil {
+			return err
+		}
+	}
 	if len(snapshot.VideoTags) > 0 {
 		if err := pgDB.Table("video_tags").CreateInBatches(&snapshot.VideoTags, 500).Error; err != nil {
 			return err
@@ -198,6 +308,36 @@ func migrateSqliteToPostgres(sqlitePath string) error {
 	if err := resetSequence(pgDB, "short_feed_tag_preferences"); err != nil {
 		return err
 	}
+	if err := resetSequence(pgDB, "playback_progresses"); err != nil {
+		return err
+	}
+	if err := resetSequence(pgDB, "playback_events"); err != nil {
+		return err
+	}
+	if err := resetSequence(pgDB, "player_profiles"); err != nil {
+		return err
+	}
+	if err := resetSequence(pgDB, "playlists"); err != nil {
+		return err
+	}
+	if err := resetSequence(pgDB, "playlist_items"); err != nil {
+		return err
+	}
+	if err := resetSequence(pgDB, "random_picks"); err != nil {
+		return err
+	}
+	if err := resetSequence(pgDB, "saved_searches"); err != nil {
+		return err
+	}
+	if err := resetSequence(pgDB, "tag_aliases"); err != nil {
+		return err
+	}
+	if err := resetSequence(pgDB, "tag_rules"); err != nil {
+		return err
+	}
+	if err := resetSequence(pgDB, "tag_rule_links"); err != nil {
+		return err
+	}
 
 	return nil
 }
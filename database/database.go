@@ -111,6 +111,11 @@ func Init() error {
 		}
 	}
 
+	// 标签名改为同一父级下唯一，先去掉旧的全局唯一约束
+	if db.Migrator().HasTable(&models.Tag{}) {
+		dropLegacyTagNameUnique(db)
+	}
+
 	// 自动迁移数据表
 	if err := db.AutoMigrate(models.AllModels()...); err != nil {
 		return fmt.Errorf("数据库迁移失败: %w", err)
@@ -134,6 +139,7 @@ func Init() error {
 			VideoExtensions:             defaultExts,
 			PlayWeight:                  2.0, // 默认 1次播放 = 2次随机播放
 			AutoScanOnStartup:           false,
+			RandomNoRepeatCount:         1,
 			ShortFeedMaxDurationMinutes: 5,
 			LogEnabled:                  false,
 			AITaggingFrameCount:         2,
@@ -195,6 +201,15 @@ func cleanupDuplicateVideos(db *gorm.DB) error {
 	return nil
 }
 
+// dropLegacyTagNameUnique 删除 tags.name 上的旧全局唯一约束；新旧版本 GORM 生成的约束名不同，两个都尝试
+func dropLegacyTagNameUnique(db *gorm.DB) {
+	for _, name := range []string{"uni_tags_name", "tags_name_key"} {
+		if err := db.Exec(`ALTER TABLE tags DROP CONSTRAINT IF EXISTS ` + name).Error; err != nil {
+			log.Printf("删除旧标签名唯一约束失败: %v constraint=%s", err, name)
+		}
+	}
+}
+
 func ensureVideoPathUniqueIndex(db *gorm.DB) error {
 	return db.Exec(`
 		CREATE UNIQUE INDEX IF NOT EXISTS idx_videos_path_active
@@ -212,6 +227,10 @@ func ensureCoreQueryIndexes(db *gorm.DB) {
 		`CREATE INDEX IF NOT EXISTS idx_videos_score_inputs_active ON videos(play_count, random_play_count, size, id) WHERE deleted_at IS NULL`,
 		`CREATE INDEX IF NOT EXISTS idx_video_tags_tag_video ON video_tags(tag_id, video_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_video_tags_video_tag ON video_tags(video_id, tag_id)`,
+		// 文件名全文检索：标点统一替换为空格后按 'simple' 分词，与 services.splitFullTextTerms 的切词规则一致
+		`ALTER TABLE videos ADD COLUMN IF NOT EXISTS name_tsv tsvector
+			GENERATED ALWAYS AS (to_tsvector('simple', regexp_replace(COALESCE(name, ''), '[^[:alnum:]]+', ' ', 'g'))) STORED`,
+		`CREATE INDEX IF NOT EXISTS idx_videos_name_tsv ON videos USING GIN (name_tsv)`,
 	}
 	for _, statement := range statements {
 		if err := db.Exec(statement).Error; err != nil {
@@ -252,16 +271,29 @@ func ensureShortFeedIndexes(db *gorm.DB) {
 }
 
 func ensureSubtitleSearchIndexes(db *gorm.DB) {
+	if err := db.Exec(`
+		ALTER TABLE subtitle_segments ADD COLUMN IF NOT EXISTS text_tsv tsvector
+		GENERATED ALWAYS AS (to_tsvector('simple', regexp_replace(COALESCE(text, ''), '[^[:alnum:]]+', ' ', 'g'))) STORED
+	`).Error; err != nil {
+		log.Printf("创建字幕全文检索列失败: %v", err)
+	} else if err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_subtitle_segments_text_tsv ON subtitle_segments USING GIN (text_tsv)`).Error; err != nil {
+		log.Printf("创建字幕全文检索索引失败: %v", err)
+	}
+
+	// CJK 词无法被 'simple' 分词切开，检索时回退为子串匹配，由下面的三元组索引加速；
+	// 三元组索引只对不少于 3 个字的词生效，1~2 个字的 CJK 词仍需逐行匹配
 	if err := db.Exec(`CREATE EXTENSION IF NOT EXISTS pg_trgm`).Error; err != nil {
 		log.Printf("创建 pg_trgm 扩展失败，字幕搜索仍可用但模糊搜索索引不可用: %v", err)
 		return
 	}
-	if err := db.Exec(`
-		CREATE INDEX IF NOT EXISTS idx_subtitle_segments_text_trgm
-		ON subtitle_segments
-		USING GIN (LOWER(text) gin_trgm_ops)
-	`).Error; err != nil {
-		log.Printf("创建字幕模糊搜索索引失败，字幕搜索仍可用但可能较慢: %v", err)
+	statements := []string{
+		`CREATE INDEX IF NOT EXISTS idx_subtitle_segments_text_trgm ON subtitle_segments USING GIN (LOWER(text) gin_trgm_ops)`,
+		`CREATE INDEX IF NOT EXISTS idx_videos_name_trgm ON videos USING GIN (LOWER(name) gin_trgm_ops) WHERE deleted_at IS NULL`,
+	}
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			log.Printf("创建模糊搜索索引失败，搜索仍可用但可能较慢: %v sql=%s", err, statement)
+		}
 	}
 }
 
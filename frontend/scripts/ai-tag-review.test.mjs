@@ -1,10 +1,13 @@
 import assert from 'node:assert/strict';
 import { readFileSync } from 'node:fs';
 import {
+  AI_TAGGING_PROVIDERS,
   confidenceMeta,
   createRejectVideoConfirm,
+  describeAITaggingQueue,
   filterCandidatesForReview,
   groupCandidatesByVideo,
+  normalizeAITaggingProvider,
   removeCandidateById
 } from '../src/utils/aiTagReview.js';
 
@@ -51,6 +54,21 @@ assert.deepEqual(confirmState, {
 assert.equal(createRejectVideoConfirm({ videoId: 42, candidates: [] }), null);
 assert.equal(createRejectVideoConfirm(null), null);
 
+assert.equal(describeAITaggingQueue(null), '');
+assert.equal(
+  describeAITaggingQueue({ paused: false, queued: 5, running: 2, waiting_retry: 1, dead_letter: 0, concurrency: 3 }),
+  '运行中：可处理 5 · 处理中 2 · 退避中 1 · 死信 0 · 并发 3'
+);
+assert.equal(
+  describeAITaggingQueue({ paused: true, queued: 1, concurrency: 1, requests_per_minute: 20, tokens_per_minute: 50000 }),
+  '已暂停：可处理 1 · 处理中 0 · 退避中 0 · 死信 0 · 并发 1 · 20 次/分钟 · 50000 tokens/分钟'
+);
+
+assert.deepEqual(AI_TAGGING_PROVIDERS.map(item => item.value), ['openai', 'ollama', 'anthropic', 'clip']);
+assert.equal(normalizeAITaggingProvider(' Ollama '), 'ollama');
+assert.equal(normalizeAITaggingProvider(''), '');
+assert.equal(normalizeAITaggingProvider('unknown'), '');
+
 const componentSource = readFileSync(new URL('../src/components/AITagReviewDialog.vue', import.meta.url), 'utf8');
 assert.match(componentSource, /data-confidence/);
 assert.match(componentSource, /ai-confidence--high/);
@@ -60,5 +78,7 @@ assert.match(componentSource, /RejectAITagCandidatesByVideo/);
 assert.match(componentSource, /reviewSearch/);
 assert.match(componentSource, /RenameVideo/);
 assert.match(componentSource, /renameConfirm/);
+assert.match(componentSource, /PauseAITaggingQueue/);
+assert.match(componentSource, /RetryAITaggingDeadLetters/);
 
 console.log('ai-tag-review tests passed');
@@ -0,0 +1,23 @@
+import assert from 'node:assert/strict';
+import { HLS_MIME, canPlayNativeHLS, resolveStreamSource, withStreamStart } from '../src/utils/mediaSource.js';
+
+assert.equal(canPlayNativeHLS({ canPlayType: type => (type === HLS_MIME ? 'maybe' : '') }), true);
+assert.equal(canPlayNativeHLS({ canPlayType: () => '' }), false);
+assert.equal(canPlayNativeHLS(null), false);
+
+assert.equal(withStreamStart('/short-media/3/stream.mp4', 0), '/short-media/3/stream.mp4');
+assert.equal(withStreamStart('/short-media/3/stream.mp4', 12.5), '/short-media/3/stream.mp4?start=12.500');
+assert.equal(withStreamStart('/a?x=1', 2), '/a?x=1&start=2.000');
+
+const direct = resolveStreamSource({ url: '/short-media/1', mime: 'video/mp4', transcoded: false }, { start: 30 });
+assert.deepEqual(direct, { url: '/short-media/1', mime: 'video/mp4', offset: 0, reloadOnSeek: false });
+
+const transcoded = { url: '/short-media/2/stream.mp4', hlsUrl: '/short-media/2/hls/index.m3u8', mime: 'video/mp4', transcoded: true };
+assert.deepEqual(resolveStreamSource(transcoded, { nativeHLS: true, start: 30 }), {
+  url: '/short-media/2/hls/index.m3u8', mime: HLS_MIME, offset: 0, reloadOnSeek: false
+});
+assert.deepEqual(resolveStreamSource(transcoded, { start: 30 }), {
+  url: '/short-media/2/stream.mp4?start=30.000', mime: 'video/mp4', offset: 30, reloadOnSeek: true
+});
+
+console.log('media-source tests passed');
@@ -0,0 +1,19 @@
+import assert from 'node:assert/strict';
+import { describeSearchQueryError, hasSearchQuerySyntax } from '../src/utils/searchQuery.js';
+
+assert.equal(hasSearchQuerySyntax('holiday beach'), false);
+assert.equal(hasSearchQuerySyntax('12:30 a-b'), false);
+assert.equal(hasSearchQuerySyntax('tag:舞蹈'), true);
+assert.equal(hasSearchQuerySyntax('cat duration>120'), true);
+assert.equal(hasSearchQuerySyntax('"exact phrase"'), true);
+assert.equal(hasSearchQuerySyntax('cat -dog'), true);
+
+assert.equal(describeSearchQueryError('x', null), null);
+const described = describeSearchQueryError('猫 height>=abc', { position: 10, message: 'height 需要数字' });
+assert.equal(described.message, '第 11 个字符：height 需要数字');
+assert.equal(described.before, '猫 height>=');
+assert.equal(described.at, 'a');
+assert.equal(described.after, 'bc');
+assert.equal(describeSearchQueryError('tag:', { position: 4, message: '缺少取值' }).at, '');
+
+console.log('search-query tests passed');
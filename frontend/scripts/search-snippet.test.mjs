@@ -0,0 +1,25 @@
+import assert from 'node:assert/strict';
+import { splitSearchSnippet } from '../src/utils/searchSnippet.js';
+
+assert.deepEqual(splitSearchSnippet(null), []);
+assert.deepEqual(splitSearchSnippet({ text: 'plain', highlights: [] }), [{ text: 'plain', hit: false }]);
+assert.deepEqual(
+  splitSearchSnippet({ text: '我们去跳舞 Dance', highlights: [{ start: 6, end: 11 }, { start: 3, end: 5 }] }),
+  [
+    { text: '我们去', hit: false },
+    { text: '跳舞', hit: true },
+    { text: ' ', hit: false },
+    { text: 'Dance', hit: true }
+  ]
+);
+assert.deepEqual(
+  splitSearchSnippet({ text: 'ab cd', highlights: [{ start: 3, end: 99 }], prefix: true, suffix: true }),
+  [
+    { text: '…', hit: false },
+    { text: 'ab ', hit: false },
+    { text: 'cd', hit: true },
+    { text: '…', hit: false }
+  ]
+);
+
+console.log('search-snippet tests passed');
@@ -0,0 +1,41 @@
+import assert from 'node:assert/strict';
+import { describeTagRule, emptyTagRuleForm, tagRuleFormToInput, tagRuleToForm } from '../src/utils/tagRuleForm.js';
+
+const input = tagRuleFormToInput({
+  ...emptyTagRuleForm(),
+  name: ' 教程 ',
+  tagId: '3',
+  pathGlob: ' /Courses/** ',
+  minMinutes: '1.5',
+  maxSizeMB: '2',
+  minHeight: 'abc'
+});
+assert.deepEqual(input, {
+  name: '教程',
+  tag_id: 3,
+  enabled: true,
+  path_glob: '/Courses/**',
+  name_regex: '',
+  min_duration: 90,
+  max_duration: 0,
+  min_height: 0,
+  max_height: 0,
+  min_size: 0,
+  max_size: 2 * 1024 * 1024
+});
+
+const form = tagRuleToForm({ id: 7, name: '4K', tag_id: 2, enabled: false, min_height: 2160, min_duration: 90, max_size: 2 * 1024 * 1024 });
+assert.equal(form.enabled, false);
+assert.equal(form.minHeight, '2160');
+assert.equal(form.minMinutes, '1.5');
+assert.equal(form.maxSizeMB, '2');
+assert.equal(form.maxHeight, '');
+assert.deepEqual(tagRuleFormToInput(form), { ...input, name: '4K', tag_id: 2, enabled: false, path_glob: '', min_height: 2160 });
+
+assert.equal(
+  describeTagRule({ path_glob: '/Courses/**', name_regex: 'ep\\d+', min_height: 2160, min_duration: 600, max_duration: 1200 }),
+  '路径 /Courses/**，文件名 /ep\\d+/，时长 10–20 分钟，高度 ≥2160p'
+);
+assert.equal(describeTagRule({ max_size: 512 * 1024 * 1024 }), '体积 <512 MB');
+
+console.log('tag-rule-form tests passed');
@@ -0,0 +1,36 @@
+import assert from 'node:assert/strict';
+import { describeTagUsage, formatBytes, formatHours, tagStatsById, topCoOccurrences } from '../src/utils/tagStats.js';
+
+assert.equal(formatBytes(0), '0 B');
+assert.equal(formatBytes(1536), '1.5 KB');
+assert.equal(formatBytes(20 * 1024 * 1024), '20 MB');
+assert.equal(formatHours(90), '2 分钟');
+assert.equal(formatHours(5400), '1.5 小时');
+
+assert.equal(describeTagUsage(undefined), '未使用');
+assert.equal(describeTagUsage({ video_count: 0 }), '未使用');
+assert.equal(
+  describeTagUsage({ video_count: 2, total_duration: 7200, total_size: 2 * 1024 * 1024 * 1024, play_count: 3, random_play_count: 1 }),
+  '2 个视频 · 2.0 小时 · 2.0 GB · 播放 4 次'
+);
+assert.equal(describeTagUsage({ video_count: 1, total_duration: 60, total_size: 1024 }), '1 个视频 · 1 分钟 · 1.0 KB');
+
+const report = {
+  tags: [
+    { tag_id: 1, path: '运动/足球', video_count: 3 },
+    { tag_id: 2, path: '4K', video_count: 2 }
+  ],
+  co_occurrences: [
+    { tag_id: 1, other_tag_id: 2, count: 2 },
+    { tag_id: 2, other_tag_id: 9, count: 1 }
+  ]
+};
+assert.equal(tagStatsById(report).get(2).path, '4K');
+assert.deepEqual(topCoOccurrences(report, 5), [
+  { key: '1-2', left: '运动/足球', right: '4K', count: 2 },
+  { key: '2-9', left: '4K', right: '#9', count: 1 }
+]);
+assert.equal(topCoOccurrences(report, 1).length, 1);
+assert.deepEqual(topCoOccurrences(null), []);
+
+console.log('tag-stats tests passed');
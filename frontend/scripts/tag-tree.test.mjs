@@ -0,0 +1,33 @@
+import assert from 'node:assert/strict';
+import { descendantTagIds, groupTagsByCategory, tagPath, videoMatchesTagIds } from '../src/utils/tagTree.js';
+
+const tags = [
+  { id: 1, name: '运动', parent_id: 0 },
+  { id: 2, name: '足球', parent_id: 1 },
+  { id: 3, name: '篮球', parent_id: 1 },
+  { id: 4, name: 'NBA', parent_id: 3 },
+  { id: 5, name: '4K', parent_id: 0 },
+  { id: 6, name: '孤儿', parent_id: 99 }
+];
+
+assert.equal(tagPath(tags, 4), '运动/篮球/NBA');
+assert.equal(tagPath(tags, 6), '孤儿');
+assert.equal(tagPath(tags, 404), '');
+assert.deepEqual(descendantTagIds(tags, 1), [1, 3, 2, 4]);
+assert.deepEqual(descendantTagIds(tags, 5), [5]);
+
+const groups = groupTagsByCategory(tags);
+assert.deepEqual(groups.map(group => group.name), ['运动', '未分组']);
+assert.deepEqual(
+  groups[0].items.map(item => [item.tag.id, item.depth, item.path]),
+  [[1, 0, '运动'], [3, 1, '运动/篮球'], [4, 2, '运动/篮球/NBA'], [2, 1, '运动/足球']]
+);
+assert.deepEqual(groups[1].items.map(item => item.tag.id), [5, 6]);
+
+const video = { tags: [{ id: 4 }, { id: 5 }] };
+assert.equal(videoMatchesTagIds(video, [1], tags), false);
+assert.equal(videoMatchesTagIds(video, [1, 5], tags, true), true);
+assert.equal(videoMatchesTagIds(video, [2], tags, true), false);
+assert.equal(videoMatchesTagIds(video, [], tags), true);
+
+console.log('tag-tree tests passed');
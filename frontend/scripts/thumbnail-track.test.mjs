@@ -0,0 +1,38 @@
+import assert from 'node:assert/strict';
+import { findThumbnailCue, parseThumbnailTrack, thumbnailCueStyle } from '../src/utils/thumbnailTrack.js';
+
+const track = `WEBVTT
+
+00:00:00.000 --> 00:00:10.000
+7.jpg#xywh=0,0,160,90
+
+00:00:10.000 --> 00:00:20.000
+7.jpg#xywh=160,0,160,90
+
+00:20.000 --> 00:25.000
+7.jpg#xywh=0,90,160,90
+
+broken --> cue
+7.jpg
+`;
+
+const cues = parseThumbnailTrack(track, 'http://127.0.0.1:34115/short-sprite/7.vtt');
+assert.equal(cues.length, 3);
+assert.equal(cues[0].url, 'http://127.0.0.1:34115/short-sprite/7.jpg');
+assert.deepEqual([cues[2].start, cues[2].end, cues[2].x, cues[2].y], [20, 25, 0, 90]);
+
+assert.equal(findThumbnailCue(cues, 0), cues[0]);
+assert.equal(findThumbnailCue(cues, 12.5), cues[1]);
+assert.equal(findThumbnailCue(cues, 20), cues[2]);
+assert.equal(findThumbnailCue(cues, 99), cues[2]);
+assert.equal(findThumbnailCue([], 3), null);
+
+assert.deepEqual(thumbnailCueStyle(cues[1]), {
+  width: '160px',
+  height: '90px',
+  backgroundImage: 'url("http://127.0.0.1:34115/short-sprite/7.jpg")',
+  backgroundPosition: '-160px 0px'
+});
+assert.deepEqual(thumbnailCueStyle(null), {});
+
+console.log('thumbnail-track tests passed');
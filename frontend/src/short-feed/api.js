@@ -23,15 +23,29 @@ function postJSON(path, body) {
   });
 }
 
-export function getNextVideo(excludeIDs = []) {
-  const query = excludeIDs.length > 0 ? `?exclude=${excludeIDs.join(',')}` : '';
-  return requestJSON(`/short-api/feed/next${query}`);
+export function getNextVideo(excludeIDs = [], savedSearchID = 0) {
+  const params = new URLSearchParams();
+  if (excludeIDs.length > 0) params.set('exclude', excludeIDs.join(','));
+  if (savedSearchID > 0) params.set('saved_search', String(savedSearchID));
+  const query = params.toString();
+  return requestJSON(`/short-api/feed/next${query ? `?${query}` : ''}`);
+}
+
+export function getSavedSearches() {
+  return requestJSON('/short-api/saved-searches');
 }
 
 export function recordPlay(videoID) {
   return postJSON(`/short-api/videos/${videoID}/play`, { source: 'short_feed' });
 }
 
+export function recordProgress(videoID, positionSeconds, durationSeconds) {
+  return postJSON(`/short-api/videos/${videoID}/progress`, {
+    position_seconds: positionSeconds,
+    duration_seconds: durationSeconds
+  });
+}
+
 export function setLiked(videoID, liked) {
   return postJSON(`/short-api/videos/${videoID}/like`, { liked });
 }
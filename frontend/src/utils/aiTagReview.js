@@ -75,3 +75,32 @@ export function createRejectVideoConfirm(group) {
     candidateIds: candidates.map(candidate => Number(candidate.id)),
   };
 }
+
+// 队列深度摘要，如 "可处理 5 · 处理中 2 · 退避中 1 · 死信 0 · 并发 3"
+export function describeAITaggingQueue(status) {
+  if (!status) return '';
+  const parts = [
+    `可处理 ${status.queued || 0}`,
+    `处理中 ${status.running || 0}`,
+    `退避中 ${status.waiting_retry || 0}`,
+    `死信 ${status.dead_letter || 0}`,
+    `并发 ${status.concurrency || 1}`,
+  ];
+  if (status.requests_per_minute > 0) parts.push(`${status.requests_per_minute} 次/分钟`);
+  if (status.tokens_per_minute > 0) parts.push(`${status.tokens_per_minute} tokens/分钟`);
+  return `${status.paused ? '已暂停' : '运行中'}：${parts.join(' · ')}`;
+}
+
+// AI 标签提供方；与 services.AITaggingProvider* 保持一致
+export const AI_TAGGING_PROVIDERS = [
+  { value: 'openai', label: 'OpenAI 兼容接口（LM Studio、vLLM 等）' },
+  { value: 'ollama', label: 'Ollama' },
+  { value: 'anthropic', label: 'Anthropic Messages' },
+  { value: 'clip', label: '本地 CLIP 零样本分类（仅从现有标签中选择）' },
+];
+
+// 空字符串表示沿用环境变量 AI_TAGGING_PROVIDER，未知取值同样回退为空
+export function normalizeAITaggingProvider(value) {
+  const provider = String(value || '').trim().toLowerCase();
+  return AI_TAGGING_PROVIDERS.some(item => item.value === provider) ? provider : '';
+}
@@ -0,0 +1,23 @@
+// 选择视频地址：直接播放的文件原样返回；实时转码的视频优先使用原生 HLS，
+// 否则使用分片 MP4 流，此时流不可随意跳转，需要带 ?start= 重新请求并记录偏移
+export const HLS_MIME = 'application/vnd.apple.mpegurl';
+
+export function canPlayNativeHLS(videoElement) {
+  return !!videoElement?.canPlayType?.(HLS_MIME);
+}
+
+export function withStreamStart(url, seconds) {
+  if (!url || !(seconds > 0)) return url;
+  return `${url}${url.includes('?') ? '&' : '?'}start=${seconds.toFixed(3)}`;
+}
+
+export function resolveStreamSource({ url, hlsUrl, mime, transcoded }, { nativeHLS = false, start = 0 } = {}) {
+  if (!transcoded) {
+    return { url, mime, offset: 0, reloadOnSeek: false };
+  }
+  if (hlsUrl && nativeHLS) {
+    return { url: hlsUrl, mime: HLS_MIME, offset: 0, reloadOnSeek: false };
+  }
+  const offset = start > 0 ? start : 0;
+  return { url: withStreamStart(url, offset), mime: mime || 'video/mp4', offset, reloadOnSeek: true };
+}
@@ -0,0 +1,23 @@
+// 搜索框查询语法的前端辅助：语法解析与执行都在后端（services/search_query.go）
+
+const FIELD_PATTERN = /(^|\s)-?[a-z]+(:|>=|<=|>|<|=)/i;
+
+// 是否使用了字段、引号或取反等语法；普通关键词可在前端直接按名称/路径匹配
+export function hasSearchQuerySyntax(keyword) {
+  const text = String(keyword || '');
+  return text.includes('"') || FIELD_PATTERN.test(text) || /(^|\s)-\S/.test(text);
+}
+
+// 把错误位置格式化为 “第 N 个字符” 提示，并返回出错处前后的片段用于高亮
+export function describeSearchQueryError(query, error) {
+  if (!error) return null;
+  const chars = Array.from(String(query || ''));
+  const position = Math.min(Math.max(Number(error.position) || 0, 0), chars.length);
+  return {
+    position,
+    message: `第 ${position + 1} 个字符：${error.message}`,
+    before: chars.slice(0, position).join(''),
+    at: chars[position] ?? '',
+    after: chars.slice(position + 1).join('')
+  };
+}
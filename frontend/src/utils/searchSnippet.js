@@ -0,0 +1,19 @@
+// 把后端返回的高亮片段（rune 偏移）拆成可直接渲染的文本段，避免用 v-html 拼接
+export function splitSearchSnippet(snippet) {
+  if (!snippet || !snippet.text) return [];
+  const chars = Array.from(snippet.text);
+  const parts = [];
+  let cursor = 0;
+  const highlights = [...(snippet.highlights || [])].sort((a, b) => a.start - b.start);
+  for (const highlight of highlights) {
+    const start = Math.max(cursor, Math.min(highlight.start, chars.length));
+    const end = Math.min(Math.max(highlight.end, start), chars.length);
+    if (start > cursor) parts.push({ text: chars.slice(cursor, start).join(''), hit: false });
+    if (end > start) parts.push({ text: chars.slice(start, end).join(''), hit: true });
+    cursor = Math.max(cursor, end);
+  }
+  if (cursor < chars.length) parts.push({ text: chars.slice(cursor).join(''), hit: false });
+  if (snippet.prefix) parts.unshift({ text: '…', hit: false });
+  if (snippet.suffix) parts.push({ text: '…', hit: false });
+  return parts;
+}
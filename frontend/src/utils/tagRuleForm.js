@@ -0,0 +1,81 @@
+const MB = 1024 * 1024;
+
+function toNumber(value) {
+  const number = Number(value);
+  return Number.isFinite(number) && number > 0 ? number : 0;
+}
+
+export function emptyTagRuleForm() {
+  return {
+    id: 0,
+    name: '',
+    tagId: 0,
+    enabled: true,
+    pathGlob: '',
+    nameRegex: '',
+    minMinutes: '',
+    maxMinutes: '',
+    minHeight: '',
+    maxHeight: '',
+    minSizeMB: '',
+    maxSizeMB: ''
+  };
+}
+
+// 表单里时长用分钟、体积用 MB，接口里分别是秒与字节
+export function tagRuleToForm(rule) {
+  const blank = value => (value ? String(value) : '');
+  return {
+    id: rule?.id || 0,
+    name: rule?.name || '',
+    tagId: rule?.tag_id || 0,
+    enabled: rule?.enabled !== false,
+    pathGlob: rule?.path_glob || '',
+    nameRegex: rule?.name_regex || '',
+    minMinutes: blank(rule?.min_duration ? +(rule.min_duration / 60).toFixed(2) : 0),
+    maxMinutes: blank(rule?.max_duration ? +(rule.max_duration / 60).toFixed(2) : 0),
+    minHeight: blank(rule?.min_height),
+    maxHeight: blank(rule?.max_height),
+    minSizeMB: blank(rule?.min_size ? +(rule.min_size / MB).toFixed(2) : 0),
+    maxSizeMB: blank(rule?.max_size ? +(rule.max_size / MB).toFixed(2) : 0)
+  };
+}
+
+export function tagRuleFormToInput(form) {
+  return {
+    name: String(form.name || '').trim(),
+    tag_id: Number(form.tagId) || 0,
+    enabled: !!form.enabled,
+    path_glob: String(form.pathGlob || '').trim(),
+    name_regex: String(form.nameRegex || '').trim(),
+    min_duration: Math.round(toNumber(form.minMinutes) * 60),
+    max_duration: Math.round(toNumber(form.maxMinutes) * 60),
+    min_height: Math.round(toNumber(form.minHeight)),
+    max_height: Math.round(toNumber(form.maxHeight)),
+    min_size: Math.round(toNumber(form.minSizeMB) * MB),
+    max_size: Math.round(toNumber(form.maxSizeMB) * MB)
+  };
+}
+
+function describeRange(min, max, unit) {
+  if (min && max) return `${min}–${max}${unit}`;
+  if (min) return `≥${min}${unit}`;
+  return `<${max}${unit}`;
+}
+
+// 规则条件的简短说明，用于列表展示
+export function describeTagRule(rule) {
+  const parts = [];
+  if (rule?.path_glob) parts.push(`路径 ${rule.path_glob}`);
+  if (rule?.name_regex) parts.push(`文件名 /${rule.name_regex}/`);
+  if (rule?.min_duration || rule?.max_duration) {
+    parts.push(`时长 ${describeRange(+(rule.min_duration / 60).toFixed(1), +(rule.max_duration / 60).toFixed(1), ' 分钟')}`);
+  }
+  if (rule?.min_height || rule?.max_height) {
+    parts.push(`高度 ${rule.min_height && rule.max_height ? `${rule.min_height}–${rule.max_height}p` : rule.min_height ? `≥${rule.min_height}p` : `≤${rule.max_height}p`}`);
+  }
+  if (rule?.min_size || rule?.max_size) {
+    parts.push(`体积 ${describeRange(+(rule.min_size / MB).toFixed(1), +(rule.max_size / MB).toFixed(1), ' MB')}`);
+  }
+  return parts.join('，');
+}
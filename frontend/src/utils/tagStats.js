@@ -0,0 +1,40 @@
+// 标签统计的展示换算：体积、时长与共现排行
+
+export function formatBytes(bytes) {
+  if (!bytes) return '0 B';
+  const units = ['B', 'KB', 'MB', 'GB', 'TB'];
+  const i = Math.min(Math.floor(Math.log(bytes) / Math.log(1024)), units.length - 1);
+  const value = bytes / Math.pow(1024, i);
+  return `${value.toFixed(value >= 10 || i === 0 ? 0 : 1)} ${units[i]}`;
+}
+
+export function formatHours(seconds) {
+  if (!seconds) return '0 分钟';
+  if (seconds < 3600) return `${Math.round(seconds / 60)} 分钟`;
+  return `${(seconds / 3600).toFixed(1)} 小时`;
+}
+
+// 单个标签的一行摘要，如 "12 个视频 · 3.5 小时 · 2.1 GB · 播放 8 次"
+export function describeTagUsage(stat) {
+  if (!stat || !stat.video_count) return '未使用';
+  const plays = (stat.play_count || 0) + (stat.random_play_count || 0);
+  const parts = [`${stat.video_count} 个视频`, formatHours(stat.total_duration), formatBytes(stat.total_size)];
+  if (plays > 0) parts.push(`播放 ${plays} 次`);
+  return parts.join(' · ');
+}
+
+// 按 tag_id 索引统计，便于列表逐行查找
+export function tagStatsById(report) {
+  return new Map((report?.tags || []).map(stat => [stat.tag_id, stat]));
+}
+
+// 共现次数最多的标签对，名称用统计中的路径；后端已按次数降序
+export function topCoOccurrences(report, limit = 10) {
+  const byId = tagStatsById(report);
+  return (report?.co_occurrences || []).slice(0, limit).map(pair => ({
+    key: `${pair.tag_id}-${pair.other_tag_id}`,
+    left: byId.get(pair.tag_id)?.path || `#${pair.tag_id}`,
+    right: byId.get(pair.other_tag_id)?.path || `#${pair.other_tag_id}`,
+    count: pair.count
+  }));
+}
@@ -0,0 +1,94 @@
+function normalizeTags(tags) {
+  const seen = new Set();
+  const result = [];
+  for (const tag of Array.isArray(tags) ? tags : []) {
+    const id = Number(tag?.id);
+    if (!Number.isFinite(id) || id <= 0 || seen.has(id)) continue;
+    seen.add(id);
+    result.push(tag);
+  }
+  return result;
+}
+
+function byName(a, b) {
+  return String(a.name || '').localeCompare(String(b.name || ''));
+}
+
+// 父标签不存在时当作顶层
+function childrenByParent(tags) {
+  const list = normalizeTags(tags);
+  const ids = new Set(list.map(tag => Number(tag.id)));
+  const children = new Map();
+  for (const tag of list) {
+    let parentId = Number(tag.parent_id) || 0;
+    if (!ids.has(parentId)) parentId = 0;
+    if (!children.has(parentId)) children.set(parentId, []);
+    children.get(parentId).push(tag);
+  }
+  for (const items of children.values()) items.sort(byName);
+  return children;
+}
+
+export function tagPath(tags, tagId) {
+  const byId = new Map(normalizeTags(tags).map(tag => [Number(tag.id), tag]));
+  const names = [];
+  const visited = new Set();
+  let current = byId.get(Number(tagId));
+  while (current && !visited.has(Number(current.id))) {
+    visited.add(Number(current.id));
+    names.unshift(current.name);
+    current = byId.get(Number(current.parent_id) || 0);
+  }
+  return names.join('/');
+}
+
+export function descendantTagIds(tags, tagId) {
+  const children = childrenByParent(tags);
+  const result = [];
+  const seen = new Set();
+  const queue = [Number(tagId)];
+  while (queue.length > 0) {
+    const id = queue.shift();
+    if (!id || seen.has(id)) continue;
+    seen.add(id);
+    result.push(id);
+    for (const child of children.get(id) || []) queue.push(Number(child.id));
+  }
+  return result;
+}
+
+// 按顶层类别分组：有子标签的顶层标签各成一组（含自身与全部子孙，depth 为层级），其余顶层标签归入 id 为 0 的“未分组”
+export function groupTagsByCategory(tags) {
+  const children = childrenByParent(tags);
+  const groups = [];
+  const ungrouped = { id: 0, name: '未分组', items: [] };
+  for (const root of children.get(0) || []) {
+    const rootId = Number(root.id);
+    if (!(children.get(rootId) || []).length) {
+      ungrouped.items.push({ tag: root, depth: 0, path: root.name });
+      continue;
+    }
+    const group = { id: rootId, name: root.name, items: [] };
+    const visited = new Set();
+    const walk = (tag, depth, path) => {
+      const id = Number(tag.id);
+      if (visited.has(id)) return;
+      visited.add(id);
+      group.items.push({ tag, depth, path });
+      for (const child of children.get(id) || []) walk(child, depth + 1, `${path}/${child.name}`);
+    };
+    walk(root, 0, root.name);
+    groups.push(group);
+  }
+  if (ungrouped.items.length > 0) groups.push(ungrouped);
+  return groups;
+}
+
+// 视频需命中每个选中标签；includeDescendants 时命中其任一子孙标签也算
+export function videoMatchesTagIds(video, selectedTagIds, tags, includeDescendants = false) {
+  const own = new Set((video?.tags || []).map(tag => Number(tag.id)));
+  return (Array.isArray(selectedTagIds) ? selectedTagIds : []).every(selected => {
+    if (!includeDescendants) return own.has(Number(selected));
+    return descendantTagIds(tags, selected).some(id => own.has(id));
+  });
+}
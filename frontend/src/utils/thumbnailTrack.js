@@ -0,0 +1,79 @@
+// 解析后端生成的 WebVTT 缩略图轨道：每条 cue 指向雪碧图中的一格（image.jpg#xywh=x,y,w,h）
+
+function parseTimestamp(value) {
+  const parts = String(value || '').trim().split(':');
+  if (parts.length < 2 || parts.length > 3) return NaN;
+  const seconds = Number(parts.pop());
+  const minutes = Number(parts.pop());
+  const hours = parts.length ? Number(parts.pop()) : 0;
+  return hours * 3600 + minutes * 60 + seconds;
+}
+
+export function parseThumbnailTrack(text, trackUrl) {
+  const cues = [];
+  const blocks = String(text || '').replace(/\r\n/g, '\n').split(/\n{2,}/);
+  for (const block of blocks) {
+    const lines = block.split('\n').map(line => line.trim()).filter(Boolean);
+    const timingIndex = lines.findIndex(line => line.includes('-->'));
+    if (timingIndex === -1 || !lines[timingIndex + 1]) continue;
+
+    const [startText, endText] = lines[timingIndex].split('-->');
+    const start = parseTimestamp(startText);
+    const end = parseTimestamp(endText.trim().split(/\s+/)[0]);
+    const [ref, fragment = ''] = lines[timingIndex + 1].split('#');
+    const match = /^xywh=(\d+),(\d+),(\d+),(\d+)$/.exec(fragment);
+    if (!Number.isFinite(start) || !Number.isFinite(end) || !match) continue;
+
+    cues.push({
+      start,
+      end,
+      url: trackUrl ? new URL(ref, trackUrl).href : ref,
+      x: Number(match[1]),
+      y: Number(match[2]),
+      w: Number(match[3]),
+      h: Number(match[4])
+    });
+  }
+  return cues;
+}
+
+export function findThumbnailCue(cues, time) {
+  if (!Array.isArray(cues) || cues.length === 0 || !Number.isFinite(time)) return null;
+  let low = 0;
+  let high = cues.length - 1;
+  while (low <= high) {
+    const mid = (low + high) >> 1;
+    if (time < cues[mid].start) {
+      high = mid - 1;
+    } else if (time >= cues[mid].end) {
+      low = mid + 1;
+    } else {
+      return cues[mid];
+    }
+  }
+  // 超出最后一格时仍显示最后一帧
+  return time >= cues[cues.length - 1].end ? cues[cues.length - 1] : null;
+}
+
+export function thumbnailCueStyle(cue) {
+  if (!cue) return {};
+  return {
+    width: `${cue.w}px`,
+    height: `${cue.h}px`,
+    backgroundImage: `url("${cue.url}")`,
+    backgroundPosition: `${-cue.x}px ${-cue.y}px`
+  };
+}
+
+// 轨道尚未生成时后端返回 404，此时返回空数组，等 thumbnail-ready 事件后再重新加载
+export async function loadThumbnailTrack(url) {
+  if (!url) return [];
+  const trackUrl = new URL(url, window.location.href).href;
+  try {
+    const response = await fetch(trackUrl);
+    if (!response.ok) return [];
+    return parseThumbnailTrack(await response.text(), trackUrl);
+  } catch (err) {
+    return [];
+  }
+}
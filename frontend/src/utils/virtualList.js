@@ -9,7 +9,7 @@ export function estimateVideoRowHeight(video, widthBucket, subtitleMode) {
   const extraTagRows = Math.max(0, Math.ceil(Math.max(tags, 1) / tagsPerRow) - 1);
   const tagExtra = extraTagRows * 28;
   const subtitleExtra = subtitleMode && video?._subtitleMatchText ? 42 : 0;
-  const staleExtra = video?.is_stale ? 18 : 0;
+  const staleExtra = video?.is_stale || video?.is_offline ? 18 : 0;
   return baseHeight + tagExtra + subtitleExtra + staleExtra;
 }
 
@@ -4,12 +4,18 @@ import {models} from '../models';
 import {services} from '../models';
 import {subtitleparser} from '../models';
 
-export function AddDirectory(arg1:string,arg2:string):Promise<models.ScanDirectory>;
+export function AddDirectory(arg1:string,arg2:string,arg3:models.ScanDirectoryRules):Promise<models.ScanDirectory>;
+
+export function AddPlayerProfile(arg1:models.PlayerProfile):Promise<models.PlayerProfile>;
+
+export function AddTagAlias(arg1:number,arg2:string):Promise<models.TagAlias>;
 
 export function AddTagToVideo(arg1:number,arg2:number):Promise<void>;
 
 export function AddVideo(arg1:string):Promise<models.Video>;
 
+export function AddVideosToPlaylist(arg1:number,arg2:Array<number>):Promise<number>;
+
 export function ApproveAITagCandidate(arg1:number):Promise<services.AITaggingReviewItem>;
 
 export function BatchAddTagToVideos(arg1:Array<number>,arg2:number):Promise<services.BatchVideoOperationResult>;
@@ -20,16 +26,40 @@ export function BatchRefreshVideoMetadata(arg1:Array<number>):Promise<services.B
 
 export function BatchRemoveTagFromVideos(arg1:Array<number>,arg2:number):Promise<services.BatchVideoOperationResult>;
 
+export function CancelScanJob():Promise<services.ScanJobStatus>;
+
 export function CancelSubtitle():Promise<void>;
 
+export function CheckSearchQuery(arg1:string):Promise<services.SearchQueryParseResult>;
+
 export function CheckSubtitleDependencies():Promise<Record<string, boolean>>;
 
+export function ClearPlaybackProgress(arg1:number):Promise<void>;
+
+export function CreateChildTag(arg1:string,arg2:string,arg3:number):Promise<models.Tag>;
+
+export function CreatePlaylist(arg1:services.PlaylistInput):Promise<models.Playlist>;
+
+export function CreateSavedSearch(arg1:services.SavedSearchInput):Promise<models.SavedSearch>;
+
 export function CreateTag(arg1:string,arg2:string):Promise<models.Tag>;
 
+export function CreateTagRule(arg1:services.TagRuleInput):Promise<models.TagRule>;
+
 export function DeleteDirectory(arg1:number):Promise<void>;
 
+export function DeletePlaybackEvents(arg1:Array<number>):Promise<number>;
+
+export function DeletePlayerProfile(arg1:number):Promise<void>;
+
+export function DeletePlaylist(arg1:number):Promise<void>;
+
+export function DeleteSavedSearch(arg1:number):Promise<void>;
+
 export function DeleteTag(arg1:number):Promise<void>;
 
+export function DeleteTagRule(arg1:number,arg2:boolean):Promise<void>;
+
 export function DeleteVideo(arg1:number,arg2:boolean):Promise<void>;
 
 export function DownloadSubtitleDependencies():Promise<void>;
@@ -38,6 +68,10 @@ export function ForceGenerateSubtitle(arg1:services.SubtitleGenerateRequest):Pro
 
 export function GenerateSubtitle(arg1:services.SubtitleGenerateRequest):Promise<services.SubtitleGenerateResult>;
 
+export function GetAITaggingCLIPRuntimeReady():Promise<boolean>;
+
+export function GetAITaggingQueueStatus():Promise<services.AITaggingQueueStatus>;
+
 export function GetAITaggingStatusSummary():Promise<services.AITaggingStatusSummary>;
 
 export function GetAllDirectories():Promise<Array<models.ScanDirectory>>;
@@ -46,12 +80,30 @@ export function GetAllTags():Promise<Array<models.Tag>>;
 
 export function GetAllVideos():Promise<Array<models.Video>>;
 
-export function GetCleanupCandidates(arg1:number,arg2:number,arg3:number):Promise<services.CleanupAnalysis>;
+export function GetCleanupCandidates(arg1:number,arg2:number,arg3:number,arg4:number):Promise<services.CleanupAnalysis>;
 
 export function GetCleanupStatus():Promise<services.CleanupStatus>;
 
+export function GetContinueWatching(arg1:number):Promise<Array<models.PlaybackProgress>>;
+
+export function GetPlaybackProgress(arg1:number):Promise<models.PlaybackProgress>;
+
+export function GetPlayerProfiles():Promise<Array<models.PlayerProfile>>;
+
+export function GetPlaylist(arg1:number):Promise<models.Playlist>;
+
+export function GetPlaylists():Promise<Array<models.Playlist>>;
+
 export function GetPreviewSession(arg1:number):Promise<services.PreviewSession>;
 
+export function GetRandomPlayStrategies():Promise<Array<services.RandomPlayStrategyInfo>>;
+
+export function GetSavedSearches():Promise<Array<models.SavedSearch>>;
+
+export function GetScanJobStatus():Promise<services.ScanJobStatus>;
+
+export function GetScanWatcherStatus():Promise<services.ScanWatcherStatus>;
+
 export function GetSettings():Promise<models.Settings>;
 
 export function GetShortFeedServerStatus():Promise<services.ShortFeedServerStatus>;
@@ -62,58 +114,130 @@ export function GetSubtitleEngineStatuses():Promise<Array<services.SubtitleEngin
 
 export function GetSubtitleSegments(arg1:number):Promise<Array<subtitleparser.Segment>>;
 
+export function GetTagRules():Promise<Array<models.TagRule>>;
+
+export function GetTagStats():Promise<services.TagStatsReport>;
+
+export function GetTagTree():Promise<Array<services.TagTreeNode>>;
+
+export function GetThumbnailQueueStatus():Promise<services.ThumbnailQueueStatus>;
+
 export function GetVideosByDirectory(arg1:string):Promise<Array<models.Video>>;
 
 export function GetVideosPaginated(arg1:number,arg2:number,arg3:number,arg4:number):Promise<Array<models.Video>>;
 
 export function ListAITagCandidates(arg1:number,arg2:string,arg3:string):Promise<Array<services.AITaggingReviewItem>>;
 
+export function ListPlaybackHistory(arg1:services.PlaybackHistoryFilter):Promise<services.PlaybackHistoryPage>;
+
 export function LogFrontend(arg1:string,arg2:string,arg3:string):Promise<void>;
 
+export function MergeTags(arg1:Array<number>,arg2:number):Promise<void>;
+
+export function MoveTag(arg1:number,arg2:number):Promise<void>;
+
 export function OpenDirectory(arg1:number):Promise<void>;
 
+export function PauseAITaggingQueue():Promise<void>;
+
 export function PlayRandomVideo():Promise<services.PlaybackAttemptResult>;
 
+export function PlayRandomVideoWithOptions(arg1:services.RandomPlayOptions):Promise<services.PlaybackAttemptResult>;
+
 export function PlayVideo(arg1:number):Promise<services.PlaybackAttemptResult>;
 
+export function PrepareAITaggingCLIPRuntime():Promise<void>;
+
 export function PrepareSubtitleEngine(arg1:services.SubtitleEngine):Promise<void>;
 
 export function PreviewExternally(arg1:number):Promise<void>;
 
+export function PreviewTagRules(arg1:number):Promise<services.TagRuleRunResult>;
+
+export function RecordPlaylistInlinePlayback(arg1:number,arg2:number):Promise<services.PlaybackAttemptResult>;
+
+export function RefreshDirectoryStates():Promise<Array<models.ScanDirectory>>;
+
 export function RefreshVideoMetadata(arg1:number):Promise<void>;
 
+export function RegenerateThumbnails(arg1:Array<number>):Promise<number>;
+
 export function RejectAITagCandidate(arg1:number):Promise<void>;
 
 export function RejectAITagCandidatesByVideo(arg1:number):Promise<number>;
 
 export function RelocateVideo(arg1:number,arg2:string):Promise<void>;
 
+export function RemovePlaylistItems(arg1:number,arg2:Array<number>):Promise<void>;
+
+export function RemoveTagAlias(arg1:number):Promise<void>;
+
 export function RemoveTagFromVideo(arg1:number,arg2:number):Promise<void>;
 
 export function RenameVideo(arg1:number,arg2:string):Promise<void>;
 
+export function ReorderPlaylist(arg1:number,arg2:Array<number>):Promise<void>;
+
+export function ResolvePlaylistVideos(arg1:number):Promise<Array<models.Video>>;
+
+export function ResumeAITaggingQueue():Promise<void>;
+
 export function RetryAITagging(arg1:number):Promise<void>;
 
+export function RetryAITaggingDeadLetters():Promise<number>;
+
+export function RevertTagRule(arg1:number):Promise<number>;
+
+export function RunSavedSearch(arg1:number,arg2:number,arg3:number,arg4:number,arg5:number):Promise<services.SavedSearchResult>;
+
+export function RunTagRules(arg1:number):Promise<services.TagRuleRunResult>;
+
 export function ScanDirectory(arg1:string):Promise<Array<string>>;
 
 export function ScanDirectoryWithInfo(arg1:string):Promise<Array<services.ScannedFile>>;
 
+export function SearchSubtitleHits(arg1:services.SubtitleHitQuery):Promise<services.SubtitleHitPage>;
+
 export function SearchSubtitleMatches(arg1:string,arg2:number):Promise<Array<services.SubtitleSearchMatch>>;
 
 export function SearchVideos(arg1:string,arg2:number,arg3:number,arg4:number,arg5:number):Promise<Array<models.Video>>;
 
+export function SearchVideosByFilter(arg1:services.VideoSearchFilter,arg2:number,arg3:number,arg4:number,arg5:number):Promise<Array<models.Video>>;
+
 export function SearchVideosByTags(arg1:Array<number>,arg2:number,arg3:number,arg4:number,arg5:number):Promise<Array<models.Video>>;
 
-export function SearchVideosWithFilters(arg1:string,arg2:Array<number>,arg3:number,arg4:number,arg5:number,arg6:number,arg7:number,arg8:number,arg9:number,arg10:number):Promise<Array<models.Video>>;
+export function SearchVideosRanked(arg1:string,arg2:number):Promise<Array<services.RankedVideo>>;
+
+export function SearchVideosWithFilters(arg1:string,arg2:Array<number>,arg3:number,arg4:number,arg5:number,arg6:number,arg7:services.VideoMediaFilter,arg8:number,arg9:number,arg10:number,arg11:number):Promise<Array<models.Video>>;
 
 export function SelectDirectory():Promise<string>;
 
-export function StartCleanupAnalysis(arg1:number,arg2:number,arg3:number):Promise<services.CleanupStatus>;
+export function SelectPlayerExecutable():Promise<string>;
+
+export function SplitTag(arg1:number,arg2:string,arg3:Array<number>):Promise<models.Tag>;
+
+export function StartCleanupAnalysis(arg1:number,arg2:number,arg3:number,arg4:number):Promise<services.CleanupStatus>;
+
+export function StartPlaylist(arg1:number,arg2:number,arg3:string):Promise<services.PlaylistPlaybackResult>;
+
+export function StartScanJob():Promise<services.ScanJobStatus>;
+
+export function StepPlaylist(arg1:number,arg2:string,arg3:string):Promise<services.PlaylistPlaybackResult>;
 
 export function SyncScanDirectories():Promise<services.ScanSyncResult>;
 
-export function UpdateDirectory(arg1:number,arg2:string,arg3:string):Promise<void>;
+export function UpdateDirectory(arg1:number,arg2:string,arg3:string,arg4:models.ScanDirectoryRules):Promise<void>;
+
+export function UpdatePlaybackProgress(arg1:number,arg2:number,arg3:number):Promise<models.PlaybackProgress>;
+
+export function UpdatePlayerProfile(arg1:models.PlayerProfile):Promise<void>;
+
+export function UpdatePlaylist(arg1:number,arg2:services.PlaylistInput):Promise<void>;
+
+export function UpdateSavedSearch(arg1:number,arg2:services.SavedSearchInput):Promise<void>;
 
 export function UpdateSettings(arg1:models.Settings):Promise<void>;
 
 export function UpdateTag(arg1:number,arg2:string,arg3:string):Promise<void>;
+
+export function UpdateTagRule(arg1:number,arg2:services.TagRuleInput):Promise<void>;
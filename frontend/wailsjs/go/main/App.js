@@ -2,8 +2,16 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
-export function AddDirectory(arg1, arg2) {
-  return window['go']['main']['App']['AddDirectory'](arg1, arg2);
+export function AddDirectory(arg1, arg2, arg3) {
+  return window['go']['main']['App']['AddDirectory'](arg1, arg2, arg3);
+}
+
+export function AddPlayerProfile(arg1) {
+  return window['go']['main']['App']['AddPlayerProfile'](arg1);
+}
+
+export function AddTagAlias(arg1, arg2) {
+  return window['go']['main']['App']['AddTagAlias'](arg1, arg2);
 }
 
 export function AddTagToVideo(arg1, arg2) {
@@ -14,6 +22,10 @@ export function AddVideo(arg1) {
   return window['go']['main']['App']['AddVideo'](arg1);
 }
 
+export function AddVideosToPlaylist(arg1, arg2) {
+  return window['go']['main']['App']['AddVideosToPlaylist'](arg1, arg2);
+}
+
 export function ApproveAITagCandidate(arg1) {
   return window['go']['main']['App']['ApproveAITagCandidate'](arg1);
 }
@@ -34,26 +46,74 @@ export function BatchRemoveTagFromVideos(arg1, arg2) {
   return window['go']['main']['App']['BatchRemoveTagFromVideos'](arg1, arg2);
 }
 
+export function CancelScanJob() {
+  return window['go']['main']['App']['CancelScanJob']();
+}
+
 export function CancelSubtitle() {
   return window['go']['main']['App']['CancelSubtitle']();
 }
 
+export function CheckSearchQuery(arg1) {
+  return window['go']['main']['App']['CheckSearchQuery'](arg1);
+}
+
 export function CheckSubtitleDependencies() {
   return window['go']['main']['App']['CheckSubtitleDependencies']();
 }
 
+export function ClearPlaybackProgress(arg1) {
+  return window['go']['main']['App']['ClearPlaybackProgress'](arg1);
+}
+
+export function CreateChildTag(arg1, arg2, arg3) {
+  return window['go']['main']['App']['CreateChildTag'](arg1, arg2, arg3);
+}
+
+export function CreatePlaylist(arg1) {
+  return window['go']['main']['App']['CreatePlaylist'](arg1);
+}
+
+export function CreateSavedSearch(arg1) {
+  return window['go']['main']['App']['CreateSavedSearch'](arg1);
+}
+
 export function CreateTag(arg1, arg2) {
   return window['go']['main']['App']['CreateTag'](arg1, arg2);
 }
 
+export function CreateTagRule(arg1) {
+  return window['go']['main']['App']['CreateTagRule'](arg1);
+}
+
 export function DeleteDirectory(arg1) {
   return window['go']['main']['App']['DeleteDirectory'](arg1);
 }
 
+export function DeletePlaybackEvents(arg1) {
+  return window['go']['main']['App']['DeletePlaybackEvents'](arg1);
+}
+
+export function DeletePlayerProfile(arg1) {
+  return window['go']['main']['App']['DeletePlayerProfile'](arg1);
+}
+
+export function DeletePlaylist(arg1) {
+  return window['go']['main']['App']['DeletePlaylist'](arg1);
+}
+
+export function DeleteSavedSearch(arg1) {
+  return window['go']['main']['App']['DeleteSavedSearch'](arg1);
+}
+
 export function DeleteTag(arg1) {
   return window['go']['main']['App']['DeleteTag'](arg1);
 }
 
+export function DeleteTagRule(arg1, arg2) {
+  return window['go']['main']['App']['DeleteTagRule'](arg1, arg2);
+}
+
 export function DeleteVideo(arg1, arg2) {
   return window['go']['main']['App']['DeleteVideo'](arg1, arg2);
 }
@@ -70,6 +130,14 @@ export function GenerateSubtitle(arg1) {
   return window['go']['main']['App']['GenerateSubtitle'](arg1);
 }
 
+export function GetAITaggingCLIPRuntimeReady() {
+  return window['go']['main']['App']['GetAITaggingCLIPRuntimeReady']();
+}
+
+export function GetAITaggingQueueStatus() {
+  return window['go']['main']['App']['GetAITaggingQueueStatus']();
+}
+
 export function GetAITaggingStatusSummary() {
   return window['go']['main']['App']['GetAITaggingStatusSummary']();
 }
@@ -86,18 +154,54 @@ export function GetAllVideos() {
   return window['go']['main']['App']['GetAllVideos']();
 }
 
-export function GetCleanupCandidates(arg1, arg2, arg3) {
-  return window['go']['main']['App']['GetCleanupCandidates'](arg1, arg2, arg3);
+export function GetCleanupCandidates(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['GetCleanupCandidates'](arg1, arg2, arg3, arg4);
 }
 
 export function GetCleanupStatus() {
   return window['go']['main']['App']['GetCleanupStatus']();
 }
 
+export function GetContinueWatching(arg1) {
+  return window['go']['main']['App']['GetContinueWatching'](arg1);
+}
+
+export function GetPlaybackProgress(arg1) {
+  return window['go']['main']['App']['GetPlaybackProgress'](arg1);
+}
+
+export function GetPlayerProfiles() {
+  return window['go']['main']['App']['GetPlayerProfiles']();
+}
+
+export function GetPlaylist(arg1) {
+  return window['go']['main']['App']['GetPlaylist'](arg1);
+}
+
+export function GetPlaylists() {
+  return window['go']['main']['App']['GetPlaylists']();
+}
+
 export function GetPreviewSession(arg1) {
   return window['go']['main']['App']['GetPreviewSession'](arg1);
 }
 
+export function GetRandomPlayStrategies() {
+  return window['go']['main']['App']['GetRandomPlayStrategies']();
+}
+
+export function GetSavedSearches() {
+  return window['go']['main']['App']['GetSavedSearches']();
+}
+
+export function GetScanJobStatus() {
+  return window['go']['main']['App']['GetScanJobStatus']();
+}
+
+export function GetScanWatcherStatus() {
+  return window['go']['main']['App']['GetScanWatcherStatus']();
+}
+
 export function GetSettings() {
   return window['go']['main']['App']['GetSettings']();
 }
@@ -118,6 +222,22 @@ export function GetSubtitleSegments(arg1) {
   return window['go']['main']['App']['GetSubtitleSegments'](arg1);
 }
 
+export function GetTagRules() {
+  return window['go']['main']['App']['GetTagRules']();
+}
+
+export function GetTagStats() {
+  return window['go']['main']['App']['GetTagStats']();
+}
+
+export function GetTagTree() {
+  return window['go']['main']['App']['GetTagTree']();
+}
+
+export function GetThumbnailQueueStatus() {
+  return window['go']['main']['App']['GetThumbnailQueueStatus']();
+}
+
 export function GetVideosByDirectory(arg1) {
   return window['go']['main']['App']['GetVideosByDirectory'](arg1);
 }
@@ -130,22 +250,46 @@ export function ListAITagCandidates(arg1, arg2, arg3) {
   return window['go']['main']['App']['ListAITagCandidates'](arg1, arg2, arg3);
 }
 
+export function ListPlaybackHistory(arg1) {
+  return window['go']['main']['App']['ListPlaybackHistory'](arg1);
+}
+
 export function LogFrontend(arg1, arg2, arg3) {
   return window['go']['main']['App']['LogFrontend'](arg1, arg2, arg3);
 }
 
+export function MergeTags(arg1, arg2) {
+  return window['go']['main']['App']['MergeTags'](arg1, arg2);
+}
+
+export function MoveTag(arg1, arg2) {
+  return window['go']['main']['App']['MoveTag'](arg1, arg2);
+}
+
 export function OpenDirectory(arg1) {
   return window['go']['main']['App']['OpenDirectory'](arg1);
 }
 
+export function PauseAITaggingQueue() {
+  return window['go']['main']['App']['PauseAITaggingQueue']();
+}
+
 export function PlayRandomVideo() {
   return window['go']['main']['App']['PlayRandomVideo']();
 }
 
+export function PlayRandomVideoWithOptions(arg1) {
+  return window['go']['main']['App']['PlayRandomVideoWithOptions'](arg1);
+}
+
 export function PlayVideo(arg1) {
   return window['go']['main']['App']['PlayVideo'](arg1);
 }
 
+export function PrepareAITaggingCLIPRuntime() {
+  return window['go']['main']['App']['PrepareAITaggingCLIPRuntime']();
+}
+
 export function PrepareSubtitleEngine(arg1) {
   return window['go']['main']['App']['PrepareSubtitleEngine'](arg1);
 }
@@ -154,10 +298,26 @@ export function PreviewExternally(arg1) {
   return window['go']['main']['App']['PreviewExternally'](arg1);
 }
 
+export function PreviewTagRules(arg1) {
+  return window['go']['main']['App']['PreviewTagRules'](arg1);
+}
+
+export function RecordPlaylistInlinePlayback(arg1, arg2) {
+  return window['go']['main']['App']['RecordPlaylistInlinePlayback'](arg1, arg2);
+}
+
+export function RefreshDirectoryStates() {
+  return window['go']['main']['App']['RefreshDirectoryStates']();
+}
+
 export function RefreshVideoMetadata(arg1) {
   return window['go']['main']['App']['RefreshVideoMetadata'](arg1);
 }
 
+export function RegenerateThumbnails(arg1) {
+  return window['go']['main']['App']['RegenerateThumbnails'](arg1);
+}
+
 export function RejectAITagCandidate(arg1) {
   return window['go']['main']['App']['RejectAITagCandidate'](arg1);
 }
@@ -170,6 +330,14 @@ export function RelocateVideo(arg1, arg2) {
   return window['go']['main']['App']['RelocateVideo'](arg1, arg2);
 }
 
+export function RemovePlaylistItems(arg1, arg2) {
+  return window['go']['main']['App']['RemovePlaylistItems'](arg1, arg2);
+}
+
+export function RemoveTagAlias(arg1) {
+  return window['go']['main']['App']['RemoveTagAlias'](arg1);
+}
+
 export function RemoveTagFromVideo(arg1, arg2) {
   return window['go']['main']['App']['RemoveTagFromVideo'](arg1, arg2);
 }
@@ -178,10 +346,38 @@ export function RenameVideo(arg1, arg2) {
   return window['go']['main']['App']['RenameVideo'](arg1, arg2);
 }
 
+export function ReorderPlaylist(arg1, arg2) {
+  return window['go']['main']['App']['ReorderPlaylist'](arg1, arg2);
+}
+
+export function ResolvePlaylistVideos(arg1) {
+  return window['go']['main']['App']['ResolvePlaylistVideos'](arg1);
+}
+
+export function ResumeAITaggingQueue() {
+  return window['go']['main']['App']['ResumeAITaggingQueue']();
+}
+
 export function RetryAITagging(arg1) {
   return window['go']['main']['App']['RetryAITagging'](arg1);
 }
 
+export function RetryAITaggingDeadLetters() {
+  return window['go']['main']['App']['RetryAITaggingDeadLetters']();
+}
+
+export function RevertTagRule(arg1) {
+  return window['go']['main']['App']['RevertTagRule'](arg1);
+}
+
+export function RunSavedSearch(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['RunSavedSearch'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function RunTagRules(arg1) {
+  return window['go']['main']['App']['RunTagRules'](arg1);
+}
+
 export function ScanDirectory(arg1) {
   return window['go']['main']['App']['ScanDirectory'](arg1);
 }
@@ -190,6 +386,10 @@ export function ScanDirectoryWithInfo(arg1) {
   return window['go']['main']['App']['ScanDirectoryWithInfo'](arg1);
 }
 
+export function SearchSubtitleHits(arg1) {
+  return window['go']['main']['App']['SearchSubtitleHits'](arg1);
+}
+
 export function SearchSubtitleMatches(arg1, arg2) {
   return window['go']['main']['App']['SearchSubtitleMatches'](arg1, arg2);
 }
@@ -198,28 +398,72 @@ export function SearchVideos(arg1, arg2, arg3, arg4, arg5) {
   return window['go']['main']['App']['SearchVideos'](arg1, arg2, arg3, arg4, arg5);
 }
 
+export function SearchVideosByFilter(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['SearchVideosByFilter'](arg1, arg2, arg3, arg4, arg5);
+}
+
 export function SearchVideosByTags(arg1, arg2, arg3, arg4, arg5) {
   return window['go']['main']['App']['SearchVideosByTags'](arg1, arg2, arg3, arg4, arg5);
 }
 
-export function SearchVideosWithFilters(arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9, arg10) {
-  return window['go']['main']['App']['SearchVideosWithFilters'](arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9, arg10);
+export function SearchVideosRanked(arg1, arg2) {
+  return window['go']['main']['App']['SearchVideosRanked'](arg1, arg2);
+}
+
+export function SearchVideosWithFilters(arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9, arg10, arg11) {
+  return window['go']['main']['App']['SearchVideosWithFilters'](arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9, arg10, arg11);
 }
 
 export function SelectDirectory() {
   return window['go']['main']['App']['SelectDirectory']();
 }
 
-export function StartCleanupAnalysis(arg1, arg2, arg3) {
-  return window['go']['main']['App']['StartCleanupAnalysis'](arg1, arg2, arg3);
+export function SelectPlayerExecutable() {
+  return window['go']['main']['App']['SelectPlayerExecutable']();
+}
+
+export function SplitTag(arg1, arg2, arg3) {
+  return window['go']['main']['App']['SplitTag'](arg1, arg2, arg3);
+}
+
+export function StartCleanupAnalysis(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['StartCleanupAnalysis'](arg1, arg2, arg3, arg4);
+}
+
+export function StartPlaylist(arg1, arg2, arg3) {
+  return window['go']['main']['App']['StartPlaylist'](arg1, arg2, arg3);
+}
+
+export function StartScanJob() {
+  return window['go']['main']['App']['StartScanJob']();
+}
+
+export function StepPlaylist(arg1, arg2, arg3) {
+  return window['go']['main']['App']['StepPlaylist'](arg1, arg2, arg3);
 }
 
 export function SyncScanDirectories() {
   return window['go']['main']['App']['SyncScanDirectories']();
 }
 
-export function UpdateDirectory(arg1, arg2, arg3) {
-  return window['go']['main']['App']['UpdateDirectory'](arg1, arg2, arg3);
+export function UpdateDirectory(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['UpdateDirectory'](arg1, arg2, arg3, arg4);
+}
+
+export function UpdatePlaybackProgress(arg1, arg2, arg3) {
+  return window['go']['main']['App']['UpdatePlaybackProgress'](arg1, arg2, arg3);
+}
+
+export function UpdatePlayerProfile(arg1) {
+  return window['go']['main']['App']['UpdatePlayerProfile'](arg1);
+}
+
+export function UpdatePlaylist(arg1, arg2) {
+  return window['go']['main']['App']['UpdatePlaylist'](arg1, arg2);
+}
+
+export function UpdateSavedSearch(arg1, arg2) {
+  return window['go']['main']['App']['UpdateSavedSearch'](arg1, arg2);
 }
 
 export function UpdateSettings(arg1) {
@@ -229,3 +473,7 @@ export function UpdateSettings(arg1) {
 export function UpdateTag(arg1, arg2, arg3) {
   return window['go']['main']['App']['UpdateTag'](arg1, arg2, arg3);
 }
+
+export function UpdateTagRule(arg1, arg2) {
+  return window['go']['main']['App']['UpdateTagRule'](arg1, arg2);
+}
@@ -1,9 +1,58 @@
 export namespace models {
 	
+	export class ScanDirectoryRules {
+	    include_patterns: string;
+	    exclude_patterns: string;
+	    max_depth: number;
+	    follow_symlinks: boolean;
+	    video_extensions: string;
+	    volume_marker: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ScanDirectoryRules(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.include_patterns = source["include_patterns"];
+	        this.exclude_patterns = source["exclude_patterns"];
+	        this.max_depth = source["max_depth"];
+	        this.follow_symlinks = source["follow_symlinks"];
+	        this.video_extensions = source["video_extensions"];
+	        this.volume_marker = source["volume_marker"];
+	    }
+	}
+	export class SavedSearch {
+	    id: number;
+	    name: string;
+	    filter: string;
+	    match_count: number;
+	    created_at: string;
+	    updated_at: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SavedSearch(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.filter = source["filter"];
+	        this.match_count = source["match_count"];
+	        this.created_at = source["created_at"];
+	        this.updated_at = source["updated_at"];
+	    }
+	}
 	export class ScanDirectory {
 	    id: number;
 	    path: string;
 	    alias: string;
+	    rules: ScanDirectoryRules;
+	    offline: boolean;
+	    offline_reason: string;
+	    offline_since?: string;
+	    last_checked_at?: string;
 	    created_at: string;
 	    updated_at: string;
 	
@@ -16,6 +65,58 @@ export namespace models {
 	        this.id = source["id"];
 	        this.path = source["path"];
 	        this.alias = source["alias"];
+	        this.rules = this.convertValues(source["rules"], ScanDirectoryRules);
+	        this.offline = source["offline"];
+	        this.offline_reason = source["offline_reason"];
+	        this.offline_since = source["offline_since"];
+	        this.last_checked_at = source["last_checked_at"];
+	        this.created_at = source["created_at"];
+	        this.updated_at = source["updated_at"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class PlayerProfile {
+	    id: number;
+	    name: string;
+	    executable_path: string;
+	    args_template: string;
+	    extensions: string;
+	    priority: number;
+	    enabled: boolean;
+	    created_at: string;
+	    updated_at: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new PlayerProfile(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.executable_path = source["executable_path"];
+	        this.args_template = source["args_template"];
+	        this.extensions = source["extensions"];
+	        this.priority = source["priority"];
+	        this.enabled = source["enabled"];
 	        this.created_at = source["created_at"];
 	        this.updated_at = source["updated_at"];
 	    }
@@ -39,6 +140,21 @@ export namespace models {
 	    ai_tagging_frame_count: number;
 	    ai_tagging_subtitle_char_limit: number;
 	    ai_tagging_startup_batch_size: number;
+	    ai_tagging_concurrency: number;
+	    ai_tagging_requests_per_minute: number;
+	    ai_tagging_tokens_per_minute: number;
+	    ai_tagging_max_attempts: number;
+	    ai_tagging_provider: string;
+	    ai_tagging_ollama_base_url: string;
+	    ai_tagging_ollama_model: string;
+	    ai_tagging_anthropic_base_url: string;
+	    ai_tagging_anthropic_api_key: string;
+	    ai_tagging_anthropic_model: string;
+	    ai_tagging_clip_base_url: string;
+	    ai_tagging_clip_model: string;
+	    ai_tagging_clip_min_score: number;
+	    random_no_repeat_count: number;
+	    random_no_repeat_hours: number;
 	    updated_at: string;
 	
 	    static createFrom(source: any = {}) {
@@ -65,13 +181,48 @@ export namespace models {
 	        this.ai_tagging_frame_count = source["ai_tagging_frame_count"];
 	        this.ai_tagging_subtitle_char_limit = source["ai_tagging_subtitle_char_limit"];
 	        this.ai_tagging_startup_batch_size = source["ai_tagging_startup_batch_size"];
+	        this.ai_tagging_concurrency = source["ai_tagging_concurrency"];
+	        this.ai_tagging_requests_per_minute = source["ai_tagging_requests_per_minute"];
+	        this.ai_tagging_tokens_per_minute = source["ai_tagging_tokens_per_minute"];
+	        this.ai_tagging_max_attempts = source["ai_tagging_max_attempts"];
+	        this.ai_tagging_provider = source["ai_tagging_provider"];
+	        this.ai_tagging_ollama_base_url = source["ai_tagging_ollama_base_url"];
+	        this.ai_tagging_ollama_model = source["ai_tagging_ollama_model"];
+	        this.ai_tagging_anthropic_base_url = source["ai_tagging_anthropic_base_url"];
+	        this.ai_tagging_anthropic_api_key = source["ai_tagging_anthropic_api_key"];
+	        this.ai_tagging_anthropic_model = source["ai_tagging_anthropic_model"];
+	        this.ai_tagging_clip_base_url = source["ai_tagging_clip_base_url"];
+	        this.ai_tagging_clip_model = source["ai_tagging_clip_model"];
+	        this.ai_tagging_clip_min_score = source["ai_tagging_clip_min_score"];
+	        this.random_no_repeat_count = source["random_no_repeat_count"];
+	        this.random_no_repeat_hours = source["random_no_repeat_hours"];
 	        this.updated_at = source["updated_at"];
 	    }
 	}
+	export class TagAlias {
+	    id: number;
+	    tag_id: number;
+	    name: string;
+	    created_at: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new TagAlias(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.tag_id = source["tag_id"];
+	        this.name = source["name"];
+	        this.created_at = source["created_at"];
+	    }
+	}
 	export class Tag {
 	    id: number;
 	    name: string;
+	    parent_id: number;
 	    color: string;
+	    aliases?: TagAlias[];
 	    created_at: string;
 	    updated_at: string;
 	
@@ -83,10 +234,66 @@ export namespace models {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.id = source["id"];
 	        this.name = source["name"];
+	        this.parent_id = source["parent_id"];
 	        this.color = source["color"];
+	        this.aliases = this.convertValues(source["aliases"], TagAlias);
 	        this.created_at = source["created_at"];
 	        this.updated_at = source["updated_at"];
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class VideoMediaInfo {
+	    video_codec: string;
+	    audio_codecs: string;
+	    bitrate: number;
+	    frame_rate: number;
+	    color_transfer: string;
+	    is_hdr: boolean;
+	    rotation: number;
+	    container_format: string;
+	    audio_languages: string;
+	    subtitle_languages: string;
+	    subtitle_stream_count: number;
+	    media_created_at?: string;
+	    media_probed_at?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new VideoMediaInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.video_codec = source["video_codec"];
+	        this.audio_codecs = source["audio_codecs"];
+	        this.bitrate = source["bitrate"];
+	        this.frame_rate = source["frame_rate"];
+	        this.color_transfer = source["color_transfer"];
+	        this.is_hdr = source["is_hdr"];
+	        this.rotation = source["rotation"];
+	        this.container_format = source["container_format"];
+	        this.audio_languages = source["audio_languages"];
+	        this.subtitle_languages = source["subtitle_languages"];
+	        this.subtitle_stream_count = source["subtitle_stream_count"];
+	        this.media_created_at = source["media_created_at"];
+	        this.media_probed_at = source["media_probed_at"];
+	    }
 	}
 	export class Video {
 	    id: number;
@@ -98,7 +305,10 @@ export namespace models {
 	    resolution: string;
 	    width: number;
 	    height: number;
+	    content_hash: string;
 	    is_stale: boolean;
+	    is_offline: boolean;
+	    media_info: VideoMediaInfo;
 	    play_count: number;
 	    random_play_count: number;
 	    last_played_at?: string;
@@ -121,7 +331,10 @@ export namespace models {
 	        this.resolution = source["resolution"];
 	        this.width = source["width"];
 	        this.height = source["height"];
+	        this.content_hash = source["content_hash"];
 	        this.is_stale = source["is_stale"];
+	        this.is_offline = source["is_offline"];
+	        this.media_info = this.convertValues(source["media_info"], VideoMediaInfo);
 	        this.play_count = source["play_count"];
 	        this.random_play_count = source["random_play_count"];
 	        this.last_played_at = source["last_played_at"];
@@ -148,43 +361,34 @@ export namespace models {
 		    return a;
 		}
 	}
-
-}
-
-export namespace services {
-	
-	export class AITaggingReviewItem {
+	export class PlaybackProgress {
 	    id: number;
 	    video_id: number;
-	    video?: models.Video;
-	    suggested_name: string;
-	    normalized_name: string;
-	    matched_tag_id?: number;
-	    matched_tag?: models.Tag;
-	    confidence: string;
-	    reasoning: string;
-	    source_summary: string;
-	    status: string;
+	    video: Video;
+	    position_seconds: number;
+	    duration_seconds: number;
+	    percent: number;
+	    completed: boolean;
+	    source: string;
+	    last_watched_at: string;
 	    created_at: string;
 	    updated_at: string;
 	
 	    static createFrom(source: any = {}) {
-	        return new AITaggingReviewItem(source);
+	        return new PlaybackProgress(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.id = source["id"];
 	        this.video_id = source["video_id"];
-	        this.video = this.convertValues(source["video"], models.Video);
-	        this.suggested_name = source["suggested_name"];
-	        this.normalized_name = source["normalized_name"];
-	        this.matched_tag_id = source["matched_tag_id"];
-	        this.matched_tag = this.convertValues(source["matched_tag"], models.Tag);
-	        this.confidence = source["confidence"];
-	        this.reasoning = source["reasoning"];
-	        this.source_summary = source["source_summary"];
-	        this.status = source["status"];
+	        this.video = this.convertValues(source["video"], Video);
+	        this.position_seconds = source["position_seconds"];
+	        this.duration_seconds = source["duration_seconds"];
+	        this.percent = source["percent"];
+	        this.completed = source["completed"];
+	        this.source = source["source"];
+	        this.last_watched_at = source["last_watched_at"];
 	        this.created_at = source["created_at"];
 	        this.updated_at = source["updated_at"];
 	    }
@@ -207,58 +411,30 @@ export namespace services {
 		    return a;
 		}
 	}
-	export class AITaggingStatusSummary {
-	    config_available: boolean;
-	    pending: number;
-	    processing: number;
-	    completed: number;
-	    skipped: number;
-	    failed: number;
-	
-	    static createFrom(source: any = {}) {
-	        return new AITaggingStatusSummary(source);
-	    }
-	
-	    constructor(source: any = {}) {
-	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.config_available = source["config_available"];
-	        this.pending = source["pending"];
-	        this.processing = source["processing"];
-	        this.completed = source["completed"];
-	        this.skipped = source["skipped"];
-	        this.failed = source["failed"];
-	    }
-	}
-	export class BatchVideoOperationError {
+	export class PlaybackEvent {
+	    id: number;
 	    video_id: number;
-	    error: string;
+	    video: Video;
+	    source: string;
+	    outcome?: string;
+	    succeeded: boolean;
+	    played_at: string;
+	    created_at: string;
 	
 	    static createFrom(source: any = {}) {
-	        return new BatchVideoOperationError(source);
+	        return new PlaybackEvent(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
 	        this.video_id = source["video_id"];
-	        this.error = source["error"];
-	    }
-	}
-	export class BatchVideoOperationResult {
-	    requested: number;
-	    succeeded: number;
-	    failed: number;
-	    errors: BatchVideoOperationError[];
-	
-	    static createFrom(source: any = {}) {
-	        return new BatchVideoOperationResult(source);
-	    }
-	
-	    constructor(source: any = {}) {
-	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.requested = source["requested"];
+	        this.video = this.convertValues(source["video"], Video);
+	        this.source = source["source"];
+	        this.outcome = source["outcome"];
 	        this.succeeded = source["succeeded"];
-	        this.failed = source["failed"];
-	        this.errors = this.convertValues(source["errors"], BatchVideoOperationError);
+	        this.played_at = source["played_at"];
+	        this.created_at = source["created_at"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -279,20 +455,26 @@ export namespace services {
 		    return a;
 		}
 	}
-	export class CleanupDuplicateGroup {
-	    original: models.Video;
-	    candidates: models.Video[];
-	    reason: string;
+	export class PlaylistItem {
+	    id: number;
+	    playlist_id: number;
+	    video_id: number;
+	    video: Video;
+	    position: number;
+	    created_at: string;
 	
 	    static createFrom(source: any = {}) {
-	        return new CleanupDuplicateGroup(source);
+	        return new PlaylistItem(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.original = this.convertValues(source["original"], models.Video);
-	        this.candidates = this.convertValues(source["candidates"], models.Video);
-	        this.reason = source["reason"];
+	        this.id = source["id"];
+	        this.playlist_id = source["playlist_id"];
+	        this.video_id = source["video_id"];
+	        this.video = this.convertValues(source["video"], Video);
+	        this.position = source["position"];
+	        this.created_at = source["created_at"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -313,20 +495,38 @@ export namespace services {
 		    return a;
 		}
 	}
-	export class CleanupAnalysis {
-	    duplicate_groups: CleanupDuplicateGroup[];
-	    low_duration: models.Video[];
-	    low_resolution: models.Video[];
+	export class Playlist {
+	    id: number;
+	    name: string;
+	    kind: string;
+	    smart_filter: string;
+	    shuffle: boolean;
+	    repeat_mode: string;
+	    queue_video_ids: string;
+	    queue_position: number;
+	    items: PlaylistItem[];
+	    item_count: number;
+	    created_at: string;
+	    updated_at: string;
 	
 	    static createFrom(source: any = {}) {
-	        return new CleanupAnalysis(source);
+	        return new Playlist(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.duplicate_groups = this.convertValues(source["duplicate_groups"], CleanupDuplicateGroup);
-	        this.low_duration = this.convertValues(source["low_duration"], models.Video);
-	        this.low_resolution = this.convertValues(source["low_resolution"], models.Video);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.kind = source["kind"];
+	        this.smart_filter = source["smart_filter"];
+	        this.shuffle = source["shuffle"];
+	        this.repeat_mode = source["repeat_mode"];
+	        this.queue_video_ids = source["queue_video_ids"];
+	        this.queue_position = source["queue_position"];
+	        this.items = this.convertValues(source["items"], PlaylistItem);
+	        this.item_count = source["item_count"];
+	        this.created_at = source["created_at"];
+	        this.updated_at = source["updated_at"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -347,48 +547,43 @@ export namespace services {
 		    return a;
 		}
 	}
-	
-	export class CleanupProgress {
-	    stage: string;
-	    message: string;
-	    current: number;
-	    total: number;
-	    path: string;
-	
-	    static createFrom(source: any = {}) {
-	        return new CleanupProgress(source);
-	    }
-	
-	    constructor(source: any = {}) {
-	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.stage = source["stage"];
-	        this.message = source["message"];
-	        this.current = source["current"];
-	        this.total = source["total"];
-	        this.path = source["path"];
-	    }
-	}
-	export class CleanupStatus {
-	    running: boolean;
-	    completed: boolean;
-	    error: string;
-	    progress: CleanupProgress;
-	    analysis?: CleanupAnalysis;
-	    started_at?: string;
-	    updated_at?: string;
+	export class TagRule {
+	    id: number;
+	    name: string;
+	    tag_id: number;
+	    tag: Tag;
+	    enabled: boolean;
+	    path_glob: string;
+	    name_regex: string;
+	    min_duration: number;
+	    max_duration: number;
+	    min_height: number;
+	    max_height: number;
+	    min_size: number;
+	    max_size: number;
+	    created_at: string;
+	    updated_at: string;
 	
 	    static createFrom(source: any = {}) {
-	        return new CleanupStatus(source);
+	        return new TagRule(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.running = source["running"];
-	        this.completed = source["completed"];
-	        this.error = source["error"];
-	        this.progress = this.convertValues(source["progress"], CleanupProgress);
-	        this.analysis = this.convertValues(source["analysis"], CleanupAnalysis);
-	        this.started_at = source["started_at"];
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.tag_id = source["tag_id"];
+	        this.tag = this.convertValues(source["tag"], Tag);
+	        this.enabled = source["enabled"];
+	        this.path_glob = source["path_glob"];
+	        this.name_regex = source["name_regex"];
+	        this.min_duration = source["min_duration"];
+	        this.max_duration = source["max_duration"];
+	        this.min_height = source["min_height"];
+	        this.max_height = source["max_height"];
+	        this.min_size = source["min_size"];
+	        this.max_size = source["max_size"];
+	        this.created_at = source["created_at"];
 	        this.updated_at = source["updated_at"];
 	    }
 	
@@ -410,7 +605,359 @@ export namespace services {
 		    return a;
 		}
 	}
-	export class PlaybackReconcileResult {
+
+}
+
+export namespace services {
+	
+	export class AITaggingReviewItem {
+	    id: number;
+	    video_id: number;
+	    video?: models.Video;
+	    suggested_name: string;
+	    normalized_name: string;
+	    matched_tag_id?: number;
+	    matched_tag?: models.Tag;
+	    confidence: string;
+	    reasoning: string;
+	    source_summary: string;
+	    status: string;
+	    created_at: string;
+	    updated_at: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new AITaggingReviewItem(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.video_id = source["video_id"];
+	        this.video = this.convertValues(source["video"], models.Video);
+	        this.suggested_name = source["suggested_name"];
+	        this.normalized_name = source["normalized_name"];
+	        this.matched_tag_id = source["matched_tag_id"];
+	        this.matched_tag = this.convertValues(source["matched_tag"], models.Tag);
+	        this.confidence = source["confidence"];
+	        this.reasoning = source["reasoning"];
+	        this.source_summary = source["source_summary"];
+	        this.status = source["status"];
+	        this.created_at = source["created_at"];
+	        this.updated_at = source["updated_at"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class AITaggingQueueStatus {
+	    paused: boolean;
+	    running: number;
+	    queued: number;
+	    waiting_retry: number;
+	    dead_letter: number;
+	    next_retry_at?: string;
+	    concurrency: number;
+	    requests_per_minute: number;
+	    tokens_per_minute: number;
+	    max_attempts: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new AITaggingQueueStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.paused = source["paused"];
+	        this.running = source["running"];
+	        this.queued = source["queued"];
+	        this.waiting_retry = source["waiting_retry"];
+	        this.dead_letter = source["dead_letter"];
+	        this.next_retry_at = source["next_retry_at"];
+	        this.concurrency = source["concurrency"];
+	        this.requests_per_minute = source["requests_per_minute"];
+	        this.tokens_per_minute = source["tokens_per_minute"];
+	        this.max_attempts = source["max_attempts"];
+	    }
+	}
+	export class AITaggingStatusSummary {
+	    config_available: boolean;
+	    pending: number;
+	    processing: number;
+	    completed: number;
+	    skipped: number;
+	    failed: number;
+	    dead_letter: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new AITaggingStatusSummary(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.config_available = source["config_available"];
+	        this.pending = source["pending"];
+	        this.processing = source["processing"];
+	        this.completed = source["completed"];
+	        this.skipped = source["skipped"];
+	        this.failed = source["failed"];
+	        this.dead_letter = source["dead_letter"];
+	    }
+	}
+	export class BatchVideoOperationError {
+	    video_id: number;
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new BatchVideoOperationError(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.video_id = source["video_id"];
+	        this.error = source["error"];
+	    }
+	}
+	export class BatchVideoOperationResult {
+	    requested: number;
+	    succeeded: number;
+	    failed: number;
+	    errors: BatchVideoOperationError[];
+	
+	    static createFrom(source: any = {}) {
+	        return new BatchVideoOperationResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.requested = source["requested"];
+	        this.succeeded = source["succeeded"];
+	        this.failed = source["failed"];
+	        this.errors = this.convertValues(source["errors"], BatchVideoOperationError);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class CleanupDuplicateGroup {
+	    original: models.Video;
+	    candidates: models.Video[];
+	    reason: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new CleanupDuplicateGroup(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.original = this.convertValues(source["original"], models.Video);
+	        this.candidates = this.convertValues(source["candidates"], models.Video);
+	        this.reason = source["reason"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class CleanupAnalysis {
+	    duplicate_groups: CleanupDuplicateGroup[];
+	    low_duration: models.Video[];
+	    low_resolution: models.Video[];
+	    low_bitrate: models.Video[];
+	
+	    static createFrom(source: any = {}) {
+	        return new CleanupAnalysis(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.duplicate_groups = this.convertValues(source["duplicate_groups"], CleanupDuplicateGroup);
+	        this.low_duration = this.convertValues(source["low_duration"], models.Video);
+	        this.low_resolution = this.convertValues(source["low_resolution"], models.Video);
+	        this.low_bitrate = this.convertValues(source["low_bitrate"], models.Video);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class CleanupProgress {
+	    stage: string;
+	    message: string;
+	    current: number;
+	    total: number;
+	    path: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new CleanupProgress(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.stage = source["stage"];
+	        this.message = source["message"];
+	        this.current = source["current"];
+	        this.total = source["total"];
+	        this.path = source["path"];
+	    }
+	}
+	export class CleanupStatus {
+	    running: boolean;
+	    completed: boolean;
+	    error: string;
+	    progress: CleanupProgress;
+	    analysis?: CleanupAnalysis;
+	    started_at?: string;
+	    updated_at?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new CleanupStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.running = source["running"];
+	        this.completed = source["completed"];
+	        this.error = source["error"];
+	        this.progress = this.convertValues(source["progress"], CleanupProgress);
+	        this.analysis = this.convertValues(source["analysis"], CleanupAnalysis);
+	        this.started_at = source["started_at"];
+	        this.updated_at = source["updated_at"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class PlaybackHistoryFilter {
+	    video_id: number;
+	    sources: string[];
+	    from: string;
+	    to: string;
+	    succeeded_only: boolean;
+	    limit: number;
+	    offset: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new PlaybackHistoryFilter(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.video_id = source["video_id"];
+	        this.sources = source["sources"];
+	        this.from = source["from"];
+	        this.to = source["to"];
+	        this.succeeded_only = source["succeeded_only"];
+	        this.limit = source["limit"];
+	        this.offset = source["offset"];
+	    }
+	}
+	export class PlaybackHistoryPage {
+	    events: models.PlaybackEvent[];
+	    total: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new PlaybackHistoryPage(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.events = this.convertValues(source["events"], models.PlaybackEvent);
+	        this.total = source["total"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class PlaybackReconcileResult {
 	    video_id: number;
 	    did_mark_stale: boolean;
 	    did_relocate: boolean;
@@ -420,18 +967,1023 @@ export namespace services {
 	    reason_code?: string;
 	
 	    static createFrom(source: any = {}) {
-	        return new PlaybackReconcileResult(source);
+	        return new PlaybackReconcileResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.video_id = source["video_id"];
+	        this.did_mark_stale = source["did_mark_stale"];
+	        this.did_relocate = source["did_relocate"];
+	        this.did_refresh_metadata = source["did_refresh_metadata"];
+	        this.needs_reload = source["needs_reload"];
+	        this.updated_video = this.convertValues(source["updated_video"], models.Video);
+	        this.reason_code = source["reason_code"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class PlaybackAttemptResult {
+	    video?: models.Video;
+	    dispatch_succeeded: boolean;
+	    user_message?: string;
+	    reason_code?: string;
+	    reconcile_result?: PlaybackReconcileResult;
+	
+	    static createFrom(source: any = {}) {
+	        return new PlaybackAttemptResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.video = this.convertValues(source["video"], models.Video);
+	        this.dispatch_succeeded = source["dispatch_succeeded"];
+	        this.user_message = source["user_message"];
+	        this.reason_code = source["reason_code"];
+	        this.reconcile_result = this.convertValues(source["reconcile_result"], PlaybackReconcileResult);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class PlaylistSmartFilter {
+	    keyword: string;
+	    tag_ids: number[];
+	    min_size: number;
+	    max_size: number;
+	    min_height: number;
+	    max_height: number;
+	    min_duration: number;
+	    max_duration: number;
+	    media: VideoMediaFilter;
+	    include_tag_descendants: boolean;
+	    limit: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new PlaylistSmartFilter(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.keyword = source["keyword"];
+	        this.tag_ids = source["tag_ids"];
+	        this.min_size = source["min_size"];
+	        this.max_size = source["max_size"];
+	        this.min_height = source["min_height"];
+	        this.max_height = source["max_height"];
+	        this.min_duration = source["min_duration"];
+	        this.max_duration = source["max_duration"];
+	        this.media = this.convertValues(source["media"], VideoMediaFilter);
+	        this.include_tag_descendants = source["include_tag_descendants"];
+	        this.limit = source["limit"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class PlaylistInput {
+	    name: string;
+	    kind: string;
+	    smart_filter?: PlaylistSmartFilter;
+	    shuffle: boolean;
+	    repeat_mode: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new PlaylistInput(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.kind = source["kind"];
+	        this.smart_filter = this.convertValues(source["smart_filter"], PlaylistSmartFilter);
+	        this.shuffle = source["shuffle"];
+	        this.repeat_mode = source["repeat_mode"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class PreviewExternalAction {
+	    action_id: string;
+	    button_label: string;
+	    hint: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new PreviewExternalAction(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.action_id = source["action_id"];
+	        this.button_label = source["button_label"];
+	        this.hint = source["hint"];
+	    }
+	}
+	export class PreviewSourceDescriptor {
+	    locator_strategy: string;
+	    locator_value: string;
+	    mime: string;
+	    thumbnail_track?: string;
+	    transcode_mode?: string;
+	    hls_playlist?: string;
+	    duration?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new PreviewSourceDescriptor(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.locator_strategy = source["locator_strategy"];
+	        this.locator_value = source["locator_value"];
+	        this.mime = source["mime"];
+	        this.thumbnail_track = source["thumbnail_track"];
+	        this.transcode_mode = source["transcode_mode"];
+	        this.hls_playlist = source["hls_playlist"];
+	        this.duration = source["duration"];
+	    }
+	}
+	export class PreviewSession {
+	    video_id: number;
+	    mode: string;
+	    display_name: string;
+	    inline_source?: PreviewSourceDescriptor;
+	    external_action?: PreviewExternalAction;
+	    reason_code?: string;
+	    reason_message?: string;
+	    resume_position?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new PreviewSession(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.video_id = source["video_id"];
+	        this.mode = source["mode"];
+	        this.display_name = source["display_name"];
+	        this.inline_source = this.convertValues(source["inline_source"], PreviewSourceDescriptor);
+	        this.external_action = this.convertValues(source["external_action"], PreviewExternalAction);
+	        this.reason_code = source["reason_code"];
+	        this.reason_message = source["reason_message"];
+	        this.resume_position = source["resume_position"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class PlaylistPlaybackResult {
+	    playlist_id: number;
+	    position: number;
+	    total: number;
+	    finished: boolean;
+	    video?: models.Video;
+	    attempt?: PlaybackAttemptResult;
+	    preview_session?: PreviewSession;
+	
+	    static createFrom(source: any = {}) {
+	        return new PlaylistPlaybackResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.playlist_id = source["playlist_id"];
+	        this.position = source["position"];
+	        this.total = source["total"];
+	        this.finished = source["finished"];
+	        this.video = this.convertValues(source["video"], models.Video);
+	        this.attempt = this.convertValues(source["attempt"], PlaybackAttemptResult);
+	        this.preview_session = this.convertValues(source["preview_session"], PreviewSession);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class RandomPlayScope {
+	    keyword: string;
+	    tag_ids: number[];
+	    directory: string;
+	    min_size: number;
+	    max_size: number;
+	    min_height: number;
+	    max_height: number;
+	    saved_search_id: number;
+	    include_tag_descendants: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new RandomPlayScope(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.keyword = source["keyword"];
+	        this.tag_ids = source["tag_ids"];
+	        this.directory = source["directory"];
+	        this.min_size = source["min_size"];
+	        this.max_size = source["max_size"];
+	        this.min_height = source["min_height"];
+	        this.max_height = source["max_height"];
+	        this.saved_search_id = source["saved_search_id"];
+	        this.include_tag_descendants = source["include_tag_descendants"];
+	    }
+	}
+	export class RandomPlayOptions {
+	    strategy: string;
+	    scope: RandomPlayScope;
+	
+	    static createFrom(source: any = {}) {
+	        return new RandomPlayOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.strategy = source["strategy"];
+	        this.scope = this.convertValues(source["scope"], RandomPlayScope);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class RandomPlayStrategyInfo {
+	    name: string;
+	    label: string;
+	    description: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new RandomPlayStrategyInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.label = source["label"];
+	        this.description = source["description"];
+	    }
+	}
+	export class SearchHighlight {
+	    start: number;
+	    end: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SearchHighlight(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.start = source["start"];
+	        this.end = source["end"];
+	    }
+	}
+	export class SearchSnippet {
+	    text: string;
+	    highlights: SearchHighlight[];
+	    prefix: boolean;
+	    suffix: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new SearchSnippet(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.text = source["text"];
+	        this.highlights = this.convertValues(source["highlights"], SearchHighlight);
+	        this.prefix = source["prefix"];
+	        this.suffix = source["suffix"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class RankedVideo {
+	    video: models.Video;
+	    rank: number;
+	    snippet: SearchSnippet;
+	
+	    static createFrom(source: any = {}) {
+	        return new RankedVideo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.video = this.convertValues(source["video"], models.Video);
+	        this.rank = source["rank"];
+	        this.snippet = this.convertValues(source["snippet"], SearchSnippet);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ScanJobProgress {
+	    stage: string;
+	    message: string;
+	    current: number;
+	    total: number;
+	    path: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ScanJobProgress(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.stage = source["stage"];
+	        this.message = source["message"];
+	        this.current = source["current"];
+	        this.total = source["total"];
+	        this.path = source["path"];
+	    }
+	}
+	export class ScanSyncError {
+	    operation: string;
+	    directory?: string;
+	    path?: string;
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ScanSyncError(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.operation = source["operation"];
+	        this.directory = source["directory"];
+	        this.path = source["path"];
+	        this.error = source["error"];
+	    }
+	}
+	export class ScanSyncAmbiguousMatch {
+	    strategy: string;
+	    video_ids: number[];
+	    video_paths: string[];
+	    candidate_paths: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ScanSyncAmbiguousMatch(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.strategy = source["strategy"];
+	        this.video_ids = source["video_ids"];
+	        this.video_paths = source["video_paths"];
+	        this.candidate_paths = source["candidate_paths"];
+	    }
+	}
+	export class ScanSyncResult {
+	    directories: number;
+	    scanned: number;
+	    added: number;
+	    deleted: number;
+	    relocated: number;
+	    metadata_refreshed: number;
+	    hashes_computed: number;
+	    rule_tagged: number;
+	    skipped: number;
+	    cancelled: boolean;
+	    offline: string[];
+	    ambiguous: ScanSyncAmbiguousMatch[];
+	    errors: ScanSyncError[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ScanSyncResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.directories = source["directories"];
+	        this.scanned = source["scanned"];
+	        this.added = source["added"];
+	        this.deleted = source["deleted"];
+	        this.relocated = source["relocated"];
+	        this.metadata_refreshed = source["metadata_refreshed"];
+	        this.hashes_computed = source["hashes_computed"];
+	        this.rule_tagged = source["rule_tagged"];
+	        this.skipped = source["skipped"];
+	        this.cancelled = source["cancelled"];
+	        this.offline = source["offline"];
+	        this.ambiguous = this.convertValues(source["ambiguous"], ScanSyncAmbiguousMatch);
+	        this.errors = this.convertValues(source["errors"], ScanSyncError);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ScanJobStatus {
+	    running: boolean;
+	    completed: boolean;
+	    cancelled: boolean;
+	    error: string;
+	    progress: ScanJobProgress;
+	    result?: ScanSyncResult;
+	    started_at?: string;
+	    updated_at?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ScanJobStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.running = source["running"];
+	        this.completed = source["completed"];
+	        this.cancelled = source["cancelled"];
+	        this.error = source["error"];
+	        this.progress = this.convertValues(source["progress"], ScanJobProgress);
+	        this.result = this.convertValues(source["result"], ScanSyncResult);
+	        this.started_at = source["started_at"];
+	        this.updated_at = source["updated_at"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	
+	
+	export class ScanWatcherStatus {
+	    running: boolean;
+	    watched_roots: string[];
+	    watched_dirs: number;
+	    pending_roots: string[];
+	    error: string;
+	    last_result?: ScanSyncResult;
+	    last_sync_at?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ScanWatcherStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.running = source["running"];
+	        this.watched_roots = source["watched_roots"];
+	        this.watched_dirs = source["watched_dirs"];
+	        this.pending_roots = source["pending_roots"];
+	        this.error = source["error"];
+	        this.last_result = this.convertValues(source["last_result"], ScanSyncResult);
+	        this.last_sync_at = source["last_sync_at"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ScannedFile {
+	    path: string;
+	    size: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ScannedFile(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.size = source["size"];
+	    }
+	}
+	export class ShortFeedServerStatus {
+	    running: boolean;
+	    bind_address: string;
+	    port: number;
+	    url: string;
+	    lan_urls: string[];
+	    startup_error: string;
+	    fallback_used: boolean;
+	    allowed_access: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ShortFeedServerStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.running = source["running"];
+	        this.bind_address = source["bind_address"];
+	        this.port = source["port"];
+	        this.url = source["url"];
+	        this.lan_urls = source["lan_urls"];
+	        this.startup_error = source["startup_error"];
+	        this.fallback_used = source["fallback_used"];
+	        this.allowed_access = source["allowed_access"];
+	    }
+	}
+	export class SubtitleEngineStatus {
+	    engine: string;
+	    display_name: string;
+	    supported: boolean;
+	    available: boolean;
+	    needs_prepare: boolean;
+	    prepare_mode: string;
+	    reason_code: string;
+	    source_lang_mode: string;
+	    reason_message: string;
+	    prepare_hint: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SubtitleEngineStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.engine = source["engine"];
+	        this.display_name = source["display_name"];
+	        this.supported = source["supported"];
+	        this.available = source["available"];
+	        this.needs_prepare = source["needs_prepare"];
+	        this.prepare_mode = source["prepare_mode"];
+	        this.reason_code = source["reason_code"];
+	        this.source_lang_mode = source["source_lang_mode"];
+	        this.reason_message = source["reason_message"];
+	        this.prepare_hint = source["prepare_hint"];
+	    }
+	}
+	export class SubtitleGenerateRequest {
+	    video_id: number;
+	    engine: string;
+	    source_lang: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SubtitleGenerateRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.video_id = source["video_id"];
+	        this.engine = source["engine"];
+	        this.source_lang = source["source_lang"];
+	    }
+	}
+	export class SubtitleGenerateResult {
+	    status: string;
+	    video_id: number;
+	    path?: string;
+	    message?: string;
+	    validation_code?: string;
+	    force_eligible?: boolean;
+	    engine?: string;
+	    source_lang?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SubtitleGenerateResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.status = source["status"];
+	        this.video_id = source["video_id"];
+	        this.path = source["path"];
+	        this.message = source["message"];
+	        this.validation_code = source["validation_code"];
+	        this.force_eligible = source["force_eligible"];
+	        this.engine = source["engine"];
+	        this.source_lang = source["source_lang"];
+	    }
+	}
+	export class SubtitleHitQuery {
+	    keyword: string;
+	    context_before: number;
+	    context_after: number;
+	    max_hits_per_video: number;
+	    cursor_rank: number;
+	    cursor_hit_count: number;
+	    cursor_video_id: number;
+	    limit: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SubtitleHitQuery(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.keyword = source["keyword"];
+	        this.context_before = source["context_before"];
+	        this.context_after = source["context_after"];
+	        this.max_hits_per_video = source["max_hits_per_video"];
+	        this.cursor_rank = source["cursor_rank"];
+	        this.cursor_hit_count = source["cursor_hit_count"];
+	        this.cursor_video_id = source["cursor_video_id"];
+	        this.limit = source["limit"];
+	    }
+	}
+	export class SubtitleHit {
+	    segment: subtitleparser.Segment;
+	    snippet: SearchSnippet;
+	    before: subtitleparser.Segment[];
+	    after: subtitleparser.Segment[];
+	
+	    static createFrom(source: any = {}) {
+	        return new SubtitleHit(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.segment = this.convertValues(source["segment"], subtitleparser.Segment);
+	        this.snippet = this.convertValues(source["snippet"], SearchSnippet);
+	        this.before = this.convertValues(source["before"], subtitleparser.Segment);
+	        this.after = this.convertValues(source["after"], subtitleparser.Segment);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SubtitleVideoHits {
+	    video: models.Video;
+	    rank: number;
+	    hit_count: number;
+	    hits: SubtitleHit[];
+	
+	    static createFrom(source: any = {}) {
+	        return new SubtitleVideoHits(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.video = this.convertValues(source["video"], models.Video);
+	        this.rank = source["rank"];
+	        this.hit_count = source["hit_count"];
+	        this.hits = this.convertValues(source["hits"], SubtitleHit);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SubtitleHitPage {
+	    videos: SubtitleVideoHits[];
+	    has_more: boolean;
+	    next_cursor_rank: number;
+	    next_cursor_hit_count: number;
+	    next_cursor_video_id: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SubtitleHitPage(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.videos = this.convertValues(source["videos"], SubtitleVideoHits);
+	        this.has_more = source["has_more"];
+	        this.next_cursor_rank = source["next_cursor_rank"];
+	        this.next_cursor_hit_count = source["next_cursor_hit_count"];
+	        this.next_cursor_video_id = source["next_cursor_video_id"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SubtitleSearchMatch {
+	    video: models.Video;
+	    segment: subtitleparser.Segment;
+	    rank: number;
+	    snippet: SearchSnippet;
+	
+	    static createFrom(source: any = {}) {
+	        return new SubtitleSearchMatch(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.video = this.convertValues(source["video"], models.Video);
+	        this.segment = this.convertValues(source["segment"], subtitleparser.Segment);
+	        this.rank = source["rank"];
+	        this.snippet = this.convertValues(source["snippet"], SearchSnippet);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ThumbnailQueueStatus {
+	    running: boolean;
+	    pending: number;
+	    active: number;
+	    generated: number;
+	    failed: number;
+	    last_error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ThumbnailQueueStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.running = source["running"];
+	        this.pending = source["pending"];
+	        this.active = source["active"];
+	        this.generated = source["generated"];
+	        this.failed = source["failed"];
+	        this.last_error = source["last_error"];
+	    }
+	}
+	export class VideoMediaFilter {
+	    video_codecs: string[];
+	    audio_codecs: string[];
+	    container_formats: string[];
+	    color_transfers: string[];
+	    audio_languages: string[];
+	    subtitle_languages: string[];
+	    min_bitrate: number;
+	    max_bitrate: number;
+	    min_frame_rate: number;
+	    max_frame_rate: number;
+	    rotations: number[];
+	    dynamic_range: string;
+	    embedded_subtitles: string;
+	    created_after: string;
+	    created_before: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new VideoMediaFilter(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.video_codecs = source["video_codecs"];
+	        this.audio_codecs = source["audio_codecs"];
+	        this.container_formats = source["container_formats"];
+	        this.color_transfers = source["color_transfers"];
+	        this.audio_languages = source["audio_languages"];
+	        this.subtitle_languages = source["subtitle_languages"];
+	        this.min_bitrate = source["min_bitrate"];
+	        this.max_bitrate = source["max_bitrate"];
+	        this.min_frame_rate = source["min_frame_rate"];
+	        this.max_frame_rate = source["max_frame_rate"];
+	        this.rotations = source["rotations"];
+	        this.dynamic_range = source["dynamic_range"];
+	        this.embedded_subtitles = source["embedded_subtitles"];
+	        this.created_after = source["created_after"];
+	        this.created_before = source["created_before"];
+	    }
+	}
+	export class SearchQueryError {
+	    position: number;
+	    message: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SearchQueryError(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.video_id = source["video_id"];
-	        this.did_mark_stale = source["did_mark_stale"];
-	        this.did_relocate = source["did_relocate"];
-	        this.did_refresh_metadata = source["did_refresh_metadata"];
-	        this.needs_reload = source["needs_reload"];
-	        this.updated_video = this.convertValues(source["updated_video"], models.Video);
-	        this.reason_code = source["reason_code"];
+	        this.position = source["position"];
+	        this.message = source["message"];
+	    }
+	}
+	export class SearchQueryParseResult {
+	    valid: boolean;
+	    error?: SearchQueryError;
+	
+	    static createFrom(source: any = {}) {
+	        return new SearchQueryParseResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.valid = source["valid"];
+	        this.error = this.convertValues(source["error"], SearchQueryError);
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -452,24 +2004,34 @@ export namespace services {
 		    return a;
 		}
 	}
-	export class PlaybackAttemptResult {
-	    video?: models.Video;
-	    dispatch_succeeded: boolean;
-	    user_message?: string;
-	    reason_code?: string;
-	    reconcile_result?: PlaybackReconcileResult;
+	export class VideoSearchFilter {
+	    keyword: string;
+	    tag_ids: number[];
+	    min_size: number;
+	    max_size: number;
+	    min_height: number;
+	    max_height: number;
+	    min_duration: number;
+	    max_duration: number;
+	    media: VideoMediaFilter;
+	    include_tag_descendants: boolean;
 	
 	    static createFrom(source: any = {}) {
-	        return new PlaybackAttemptResult(source);
+	        return new VideoSearchFilter(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.video = this.convertValues(source["video"], models.Video);
-	        this.dispatch_succeeded = source["dispatch_succeeded"];
-	        this.user_message = source["user_message"];
-	        this.reason_code = source["reason_code"];
-	        this.reconcile_result = this.convertValues(source["reconcile_result"], PlaybackReconcileResult);
+	        this.keyword = source["keyword"];
+	        this.tag_ids = source["tag_ids"];
+	        this.min_size = source["min_size"];
+	        this.max_size = source["max_size"];
+	        this.min_height = source["min_height"];
+	        this.max_height = source["max_height"];
+	        this.min_duration = source["min_duration"];
+	        this.max_duration = source["max_duration"];
+	        this.media = this.convertValues(source["media"], VideoMediaFilter);
+	        this.include_tag_descendants = source["include_tag_descendants"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -490,61 +2052,52 @@ export namespace services {
 		    return a;
 		}
 	}
-	
-	export class PreviewExternalAction {
-	    action_id: string;
-	    button_label: string;
-	    hint: string;
+	export class SavedSearchInput {
+	    name: string;
+	    filter: VideoSearchFilter;
 	
 	    static createFrom(source: any = {}) {
-	        return new PreviewExternalAction(source);
+	        return new SavedSearchInput(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.action_id = source["action_id"];
-	        this.button_label = source["button_label"];
-	        this.hint = source["hint"];
-	    }
-	}
-	export class PreviewSourceDescriptor {
-	    locator_strategy: string;
-	    locator_value: string;
-	    mime: string;
-	
-	    static createFrom(source: any = {}) {
-	        return new PreviewSourceDescriptor(source);
+	        this.name = source["name"];
+	        this.filter = this.convertValues(source["filter"], VideoSearchFilter);
 	    }
 	
-	    constructor(source: any = {}) {
-	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.locator_strategy = source["locator_strategy"];
-	        this.locator_value = source["locator_value"];
-	        this.mime = source["mime"];
-	    }
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
-	export class PreviewSession {
-	    video_id: number;
-	    mode: string;
-	    display_name: string;
-	    inline_source?: PreviewSourceDescriptor;
-	    external_action?: PreviewExternalAction;
-	    reason_code?: string;
-	    reason_message?: string;
+	export class SavedSearchResult {
+	    saved_search: models.SavedSearch;
+	    videos: models.Video[];
+	    total: number;
 	
 	    static createFrom(source: any = {}) {
-	        return new PreviewSession(source);
+	        return new SavedSearchResult(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.video_id = source["video_id"];
-	        this.mode = source["mode"];
-	        this.display_name = source["display_name"];
-	        this.inline_source = this.convertValues(source["inline_source"], PreviewSourceDescriptor);
-	        this.external_action = this.convertValues(source["external_action"], PreviewExternalAction);
-	        this.reason_code = source["reason_code"];
-	        this.reason_message = source["reason_message"];
+	        this.saved_search = this.convertValues(source["saved_search"], models.SavedSearch);
+	        this.videos = this.convertValues(source["videos"], models.Video);
+	        this.total = source["total"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -565,49 +2118,90 @@ export namespace services {
 		    return a;
 		}
 	}
+	export class DirectoryUntaggedStat {
+	    directory_id: number;
+	    path: string;
+	    alias: string;
+	    video_count: number;
+	    untagged_count: number;
 	
-	export class ScanSyncError {
-	    operation: string;
-	    directory?: string;
-	    path?: string;
-	    error: string;
+	    static createFrom(source: any = {}) {
+	        return new DirectoryUntaggedStat(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.directory_id = source["directory_id"];
+	        this.path = source["path"];
+	        this.alias = source["alias"];
+	        this.video_count = source["video_count"];
+	        this.untagged_count = source["untagged_count"];
+	    }
+	}
+	export class TagCoOccurrence {
+	    tag_id: number;
+	    other_tag_id: number;
+	    count: number;
 	
 	    static createFrom(source: any = {}) {
-	        return new ScanSyncError(source);
+	        return new TagCoOccurrence(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.operation = source["operation"];
-	        this.directory = source["directory"];
+	        this.tag_id = source["tag_id"];
+	        this.other_tag_id = source["other_tag_id"];
+	        this.count = source["count"];
+	    }
+	}
+	export class TagUsageStat {
+	    tag_id: number;
+	    name: string;
+	    path: string;
+	    parent_id: number;
+	    color: string;
+	    video_count: number;
+	    total_duration: number;
+	    total_size: number;
+	    play_count: number;
+	    random_play_count: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new TagUsageStat(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.tag_id = source["tag_id"];
+	        this.name = source["name"];
 	        this.path = source["path"];
-	        this.error = source["error"];
+	        this.parent_id = source["parent_id"];
+	        this.color = source["color"];
+	        this.video_count = source["video_count"];
+	        this.total_duration = source["total_duration"];
+	        this.total_size = source["total_size"];
+	        this.play_count = source["play_count"];
+	        this.random_play_count = source["random_play_count"];
 	    }
 	}
-	export class ScanSyncResult {
-	    directories: number;
-	    scanned: number;
-	    added: number;
-	    deleted: number;
-	    relocated: number;
-	    metadata_refreshed: number;
-	    skipped: number;
-	    errors: ScanSyncError[];
+	export class TagStatsReport {
+	    total_videos: number;
+	    tagged_videos: number;
+	    tags: TagUsageStat[];
+	    co_occurrences: TagCoOccurrence[];
+	    directories: DirectoryUntaggedStat[];
 	
 	    static createFrom(source: any = {}) {
-	        return new ScanSyncResult(source);
+	        return new TagStatsReport(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.directories = source["directories"];
-	        this.scanned = source["scanned"];
-	        this.added = source["added"];
-	        this.deleted = source["deleted"];
-	        this.relocated = source["relocated"];
-	        this.metadata_refreshed = source["metadata_refreshed"];
-	        this.skipped = source["skipped"];
-	        this.errors = this.convertValues(source["errors"], ScanSyncError);
+	        this.total_videos = source["total_videos"];
+	        this.tagged_videos = source["tagged_videos"];
+	        this.tags = this.convertValues(source["tags"], TagUsageStat);
+	        this.co_occurrences = this.convertValues(source["co_occurrences"], TagCoOccurrence);
+	        this.directories = this.convertValues(source["directories"], DirectoryUntaggedStat);
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -628,130 +2222,114 @@ export namespace services {
 		    return a;
 		}
 	}
-	export class ScannedFile {
+	export class TagTreeNode {
+	    tag: models.Tag;
 	    path: string;
-	    size: number;
+	    children: TagTreeNode[];
 	
 	    static createFrom(source: any = {}) {
-	        return new ScannedFile(source);
+	        return new TagTreeNode(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.tag = this.convertValues(source["tag"], models.Tag);
 	        this.path = source["path"];
-	        this.size = source["size"];
-	    }
-	}
-	export class ShortFeedServerStatus {
-	    running: boolean;
-	    bind_address: string;
-	    port: number;
-	    url: string;
-	    lan_urls: string[];
-	    startup_error: string;
-	    fallback_used: boolean;
-	    allowed_access: string;
-	
-	    static createFrom(source: any = {}) {
-	        return new ShortFeedServerStatus(source);
-	    }
-	
-	    constructor(source: any = {}) {
-	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.running = source["running"];
-	        this.bind_address = source["bind_address"];
-	        this.port = source["port"];
-	        this.url = source["url"];
-	        this.lan_urls = source["lan_urls"];
-	        this.startup_error = source["startup_error"];
-	        this.fallback_used = source["fallback_used"];
-	        this.allowed_access = source["allowed_access"];
-	    }
-	}
-	export class SubtitleEngineStatus {
-	    engine: string;
-	    display_name: string;
-	    supported: boolean;
-	    available: boolean;
-	    needs_prepare: boolean;
-	    prepare_mode: string;
-	    reason_code: string;
-	    source_lang_mode: string;
-	    reason_message: string;
-	    prepare_hint: string;
-	
-	    static createFrom(source: any = {}) {
-	        return new SubtitleEngineStatus(source);
+	        this.children = this.convertValues(source["children"], TagTreeNode);
 	    }
 	
-	    constructor(source: any = {}) {
-	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.engine = source["engine"];
-	        this.display_name = source["display_name"];
-	        this.supported = source["supported"];
-	        this.available = source["available"];
-	        this.needs_prepare = source["needs_prepare"];
-	        this.prepare_mode = source["prepare_mode"];
-	        this.reason_code = source["reason_code"];
-	        this.source_lang_mode = source["source_lang_mode"];
-	        this.reason_message = source["reason_message"];
-	        this.prepare_hint = source["prepare_hint"];
-	    }
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
-	export class SubtitleGenerateRequest {
-	    video_id: number;
-	    engine: string;
-	    source_lang: string;
+	export class TagRuleInput {
+	    name: string;
+	    tag_id: number;
+	    enabled: boolean;
+	    path_glob: string;
+	    name_regex: string;
+	    min_duration: number;
+	    max_duration: number;
+	    min_height: number;
+	    max_height: number;
+	    min_size: number;
+	    max_size: number;
 	
 	    static createFrom(source: any = {}) {
-	        return new SubtitleGenerateRequest(source);
+	        return new TagRuleInput(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.video_id = source["video_id"];
-	        this.engine = source["engine"];
-	        this.source_lang = source["source_lang"];
+	        this.name = source["name"];
+	        this.tag_id = source["tag_id"];
+	        this.enabled = source["enabled"];
+	        this.path_glob = source["path_glob"];
+	        this.name_regex = source["name_regex"];
+	        this.min_duration = source["min_duration"];
+	        this.max_duration = source["max_duration"];
+	        this.min_height = source["min_height"];
+	        this.max_height = source["max_height"];
+	        this.min_size = source["min_size"];
+	        this.max_size = source["max_size"];
 	    }
 	}
-	export class SubtitleGenerateResult {
-	    status: string;
+	export class TagRuleChange {
+	    rule_id: number;
+	    rule_name: string;
+	    tag_id: number;
+	    tag_name: string;
 	    video_id: number;
-	    path?: string;
-	    message?: string;
-	    validation_code?: string;
-	    force_eligible?: boolean;
-	    engine?: string;
-	    source_lang?: string;
+	    video_name: string;
+	    video_path: string;
 	
 	    static createFrom(source: any = {}) {
-	        return new SubtitleGenerateResult(source);
+	        return new TagRuleChange(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.status = source["status"];
+	        this.rule_id = source["rule_id"];
+	        this.rule_name = source["rule_name"];
+	        this.tag_id = source["tag_id"];
+	        this.tag_name = source["tag_name"];
 	        this.video_id = source["video_id"];
-	        this.path = source["path"];
-	        this.message = source["message"];
-	        this.validation_code = source["validation_code"];
-	        this.force_eligible = source["force_eligible"];
-	        this.engine = source["engine"];
-	        this.source_lang = source["source_lang"];
+	        this.video_name = source["video_name"];
+	        this.video_path = source["video_path"];
 	    }
 	}
-	export class SubtitleSearchMatch {
-	    video: models.Video;
-	    segment: subtitleparser.Segment;
+	export class TagRuleRunResult {
+	    dry_run: boolean;
+	    rules: number;
+	    scanned: number;
+	    linked: number;
+	    changes: TagRuleChange[];
 	
 	    static createFrom(source: any = {}) {
-	        return new SubtitleSearchMatch(source);
+	        return new TagRuleRunResult(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.video = this.convertValues(source["video"], models.Video);
-	        this.segment = this.convertValues(source["segment"], subtitleparser.Segment);
+	        this.dry_run = source["dry_run"];
+	        this.rules = source["rules"];
+	        this.scanned = source["scanned"];
+	        this.linked = source["linked"];
+	        this.changes = this.convertValues(source["changes"], TagRuleChange);
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
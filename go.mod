@@ -3,6 +3,7 @@ module video-master
 go 1.23
 
 require (
+	github.com/fsnotify/fsnotify v1.9.0
 	github.com/jinzhu/now v1.1.5
 	github.com/joho/godotenv v1.5.1
 	github.com/wailsapp/wails/v2 v2.11.0
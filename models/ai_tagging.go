@@ -17,6 +17,8 @@ const (
 	AITaggingStateStatusCompleted  = "completed"
 	AITaggingStateStatusSkipped    = "skipped"
 	AITaggingStateStatusFailed     = "failed"
+	// AITaggingStateStatusDeadLetter 重试次数用尽，不再自动处理，只能手动重试
+	AITaggingStateStatusDeadLetter = "dead_letter"
 )
 
 // AITagCandidate stores unconfirmed AI suggestions outside the canonical tag tables.
@@ -61,6 +63,7 @@ type AITaggingState struct {
 	AttemptCount        int        `gorm:"default:0" json:"attempt_count"`
 	LastError           string     `gorm:"type:text" json:"last_error"`
 	LastProcessedAt     *time.Time `gorm:"index:idx_ai_tagging_states_status_processed,priority:2" json:"last_processed_at,omitempty" ts_type:"string"`
+	NextAttemptAt       *time.Time `gorm:"index" json:"next_attempt_at,omitempty" ts_type:"string"` // 失败后按指数退避计算的下次重试时间
 	CreatedAt           time.Time  `json:"created_at" ts_type:"string"`
 	UpdatedAt           time.Time  `json:"updated_at" ts_type:"string"`
 }
@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// PlaybackProgress 每个视频的续播进度，由内嵌预览与短视频客户端上报
+type PlaybackProgress struct {
+	ID              uint      `gorm:"primarykey" json:"id"`
+	VideoID         uint      `gorm:"uniqueIndex;not null" json:"video_id"`
+	Video           Video     `gorm:"constraint:OnDelete:CASCADE;" json:"video"`
+	PositionSeconds float64   `gorm:"default:0" json:"position_seconds"`    // 最后播放位置（秒）
+	DurationSeconds float64   `gorm:"default:0" json:"duration_seconds"`    // 上报时的视频时长（秒）
+	Percent         float64   `gorm:"default:0" json:"percent"`             // 已观看百分比 (0-100)
+	Completed       bool      `gorm:"default:false;index" json:"completed"` // 是否已看完
+	Source          string    `json:"source"`                               // 最近一次上报来源 (preview/short_feed)
+	LastWatchedAt   time.Time `gorm:"index" json:"last_watched_at" ts_type:"string"`
+	CreatedAt       time.Time `json:"created_at" ts_type:"string"`
+	UpdatedAt       time.Time `json:"updated_at" ts_type:"string"`
+}
+
+// PlaybackEvent 播放历史，每次播放/预览记录一条；Outcome 为空表示成功，否则为失败原因码
+type PlaybackEvent struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	VideoID   uint      `gorm:"index;not null" json:"video_id"`
+	Video     Video     `gorm:"constraint:OnDelete:CASCADE;" json:"video"`
+	Source    string    `gorm:"index;not null" json:"source"` // formal/random/preview/external_preview/short_feed
+	Outcome   string    `json:"outcome,omitempty"`            // 取自 PlaybackAttemptResult.ReasonCode
+	Succeeded bool      `json:"succeeded"`
+	PlayedAt  time.Time `gorm:"index" json:"played_at" ts_type:"string"`
+	CreatedAt time.Time `json:"created_at" ts_type:"string"`
+}
+
+// PlayerProfile 外部播放器配置；ArgsTemplate 支持 {path}/{start}/{subtitle} 占位符
+type PlayerProfile struct {
+	ID             uint      `gorm:"primarykey" json:"id"`
+	Name           string    `json:"name"`                           // 显示名称，如 mpv、VLC
+	ExecutablePath string    `json:"executable_path"`                // 可执行文件路径
+	ArgsTemplate   string    `gorm:"type:text" json:"args_template"` // 参数模板，按空白切分，支持引号
+	Extensions     string    `json:"extensions"`                     // 适用的视频格式（逗号分隔），为空表示全部
+	Priority       int       `gorm:"default:0" json:"priority"`      // 同时匹配多个配置时数值小者优先
+	Enabled        bool      `json:"enabled"`
+	CreatedAt      time.Time `json:"created_at" ts_type:"string"`
+	UpdatedAt      time.Time `json:"updated_at" ts_type:"string"`
+}
+
+// RandomPick 随机选片记录（桌面随机播放与短视频共用），用于防重复窗口，只保留最近若干条
+type RandomPick struct {
+	ID       uint      `gorm:"primarykey" json:"id"`
+	VideoID  uint      `gorm:"index;not null" json:"video_id"`
+	Source   string    `json:"source"` // desktop/short_feed
+	PickedAt time.Time `gorm:"index" json:"picked_at" ts_type:"string"`
+}
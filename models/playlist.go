@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Playlist 播放列表；Kind 为 manual 时播放 Items，为 smart 时按 SmartFilter（JSON）实时查询
+type Playlist struct {
+	ID            uint           `gorm:"primarykey" json:"id"`
+	Name          string         `gorm:"not null" json:"name"`
+	Kind          string         `gorm:"not null" json:"kind"`             // manual / smart
+	SmartFilter   string         `gorm:"type:text" json:"smart_filter"`    // 智能列表的筛选条件 JSON
+	Shuffle       bool           `json:"shuffle"`                          // 开始播放时打乱顺序
+	RepeatMode    string         `json:"repeat_mode"`                      // off / all / one
+	QueueVideoIDs string         `gorm:"type:text" json:"queue_video_ids"` // 当前播放队列（逗号分隔的视频 ID）
+	QueuePosition int            `gorm:"default:0" json:"queue_position"`  // 当前播放到的队列下标
+	Items         []PlaylistItem `gorm:"constraint:OnDelete:CASCADE;" json:"items"`
+	ItemCount     int            `gorm:"-" json:"item_count"` // 手动列表的条目数，仅用于列表展示
+	CreatedAt     time.Time      `json:"created_at" ts_type:"string"`
+	UpdatedAt     time.Time      `json:"updated_at" ts_type:"string"`
+}
+
+// PlaylistItem 手动播放列表中的一项，Position 越小越靠前
+type PlaylistItem struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	PlaylistID uint      `gorm:"index;not null" json:"playlist_id"`
+	VideoID    uint      `gorm:"index;not null" json:"video_id"`
+	Video      Video     `gorm:"constraint:OnDelete:CASCADE;" json:"video"`
+	Position   int       `gorm:"not null" json:"position"`
+	CreatedAt  time.Time `json:"created_at" ts_type:"string"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// SavedSearch 保存的搜索（智能合集），Filter 为组合搜索条件 JSON，执行时实时查询
+type SavedSearch struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	Name       string    `gorm:"not null;uniqueIndex" json:"name"`
+	Filter     string    `gorm:"type:text" json:"filter"`
+	MatchCount int64     `gorm:"-" json:"match_count"` // 当前匹配的视频数，列表接口实时统计
+	CreatedAt  time.Time `json:"created_at" ts_type:"string"`
+	UpdatedAt  time.Time `json:"updated_at" ts_type:"string"`
+}
@@ -6,11 +6,21 @@ func AllModels() []interface{} {
 		&SubtitleSegment{},
 		&SubtitleIndexState{},
 		&Tag{},
+		&TagAlias{},
 		&AITagCandidate{},
 		&AITagApprovalRecord{},
 		&AITaggingState{},
 		&ShortFeedInteraction{},
 		&ShortFeedTagPreference{},
+		&PlaybackProgress{},
+		&PlaybackEvent{},
+		&PlayerProfile{},
+		&Playlist{},
+		&PlaylistItem{},
+		&RandomPick{},
+		&SavedSearch{},
+		&TagRule{},
+		&TagRuleLink{},
 		&Settings{},
 		&ScanDirectory{},
 	}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// TagRule 自动打标规则：全部非零条件同时满足时给视频打上 TagID；区间条件与组合搜索一致（体积、时长左闭右开，高度闭区间）
+type TagRule struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	Name        string    `gorm:"not null;uniqueIndex" json:"name"`
+	TagID       uint      `gorm:"index;not null" json:"tag_id"`
+	Tag         Tag       `gorm:"constraint:OnDelete:CASCADE;" json:"tag"`
+	Enabled     bool      `gorm:"not null" json:"enabled"`
+	PathGlob    string    `json:"path_glob"`  // 匹配完整路径，分隔符统一为 /，** 可跨目录，不区分大小写
+	NameRegex   string    `json:"name_regex"` // 匹配文件名
+	MinDuration float64   `json:"min_duration"`
+	MaxDuration float64   `json:"max_duration"`
+	MinHeight   int       `json:"min_height"`
+	MaxHeight   int       `json:"max_height"`
+	MinSize     int64     `json:"min_size"`
+	MaxSize     int64     `json:"max_size"`
+	CreatedAt   time.Time `json:"created_at" ts_type:"string"`
+	UpdatedAt   time.Time `json:"updated_at" ts_type:"string"`
+}
+
+// TagRuleLink 记录由规则新建的视频标签关联，撤销规则时只删除这些关联
+type TagRuleLink struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	RuleID    uint      `gorm:"index;not null" json:"rule_id"`
+	Rule      TagRule   `gorm:"constraint:OnDelete:CASCADE;" json:"-"`
+	VideoID   uint      `gorm:"uniqueIndex:idx_tag_rule_links_video_tag,priority:1;not null" json:"video_id"`
+	Video     Video     `gorm:"constraint:OnDelete:CASCADE;" json:"-"`
+	TagID     uint      `gorm:"uniqueIndex:idx_tag_rule_links_video_tag,priority:2;index;not null" json:"tag_id"`
+	CreatedAt time.Time `json:"created_at" ts_type:"string"`
+}
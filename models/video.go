@@ -15,7 +15,10 @@ type Video struct {
 	Resolution      string         `json:"resolution"`                                                              // 分辨率 (如 1920x1080)
 	Width           int            `json:"width"`                                                                   // 宽度
 	Height          int            `json:"height"`                                                                  // 高度
+	ContentHash     string         `gorm:"index" json:"content_hash"`                                               // 采样内容哈希（用于迁移/改名识别）
 	IsStale         bool           `gorm:"default:false" json:"is_stale"`                                           // 当前路径是否失效/待纠偏
+	IsOffline       bool           `gorm:"default:false" json:"is_offline"`                                         // 所在扫描根目录（卷）是否离线
+	MediaInfo       VideoMediaInfo `gorm:"embedded" json:"media_info"`                                              // ffprobe 解析的编码、码率等媒体信息
 	PlayCount       int            `gorm:"default:0" json:"play_count"`                                             // 播放次数
 	RandomPlayCount int            `gorm:"default:0" json:"random_play_count"`                                      // 随机播放次数
 	LastPlayedAt    *time.Time     `json:"last_played_at" ts_type:"string"`                                         // 最后播放时间
@@ -25,6 +28,23 @@ type Video struct {
 	DeletedAt       SoftDeleteTime `gorm:"index" json:"-"`
 }
 
+// VideoMediaInfo ffprobe 解析出的媒体信息，多值字段以逗号分隔（按流顺序，去重）
+type VideoMediaInfo struct {
+	VideoCodec          string     `gorm:"index" json:"video_codec"`               // 视频编码 (如 h264, hevc)
+	AudioCodecs         string     `json:"audio_codecs"`                           // 音频编码 (如 aac,ac3)
+	Bitrate             int64      `gorm:"default:0" json:"bitrate"`               // 总码率 (bit/s)
+	FrameRate           float64    `gorm:"default:0" json:"frame_rate"`            // 帧率 (fps)
+	ColorTransfer       string     `json:"color_transfer"`                         // 色彩传输特性 (如 bt709, smpte2084)
+	IsHDR               bool       `gorm:"default:false" json:"is_hdr"`            // 是否为 HDR（PQ/HLG/杜比视界）
+	Rotation            int        `gorm:"default:0" json:"rotation"`              // 顺时针旋转角度 (0/90/180/270)
+	ContainerFormat     string     `json:"container_format"`                       // 容器格式 (ffprobe format_name)
+	AudioLanguages      string     `json:"audio_languages"`                        // 音轨语言 (如 jpn,eng)
+	SubtitleLanguages   string     `json:"subtitle_languages"`                     // 内嵌字幕语言
+	SubtitleStreamCount int        `gorm:"default:0" json:"subtitle_stream_count"` // 内嵌字幕流数量
+	MediaCreatedAt      *time.Time `json:"media_created_at" ts_type:"string"`      // 文件内记录的创建时间 (creation_time)
+	MediaProbedAt       *time.Time `json:"media_probed_at" ts_type:"string"`       // 最近一次成功解析媒体信息的时间，为空表示待补齐
+}
+
 // SubtitleSegment stores searchable SRT segments for fast subtitle lookup.
 type SubtitleSegment struct {
 	ID              uint      `gorm:"primarykey" json:"id"`
@@ -57,14 +77,25 @@ type SubtitleIndexState struct {
 // Tag 标签模型
 type Tag struct {
 	ID        uint           `gorm:"primarykey" json:"id"`
-	Name      string         `gorm:"unique" json:"name"` // 标签名称
-	Color     string         `json:"color"`              // 标签颜色
+	Name      string         `gorm:"uniqueIndex:idx_tags_parent_name" json:"name"`                               // 标签名称，同一父级下唯一
+	ParentID  uint           `gorm:"not null;default:0;index;uniqueIndex:idx_tags_parent_name" json:"parent_id"` // 父标签（类别），0 为顶层
+	Color     string         `json:"color"`                                                                      // 标签颜色
 	Videos    []Video        `gorm:"many2many:video_tags;" json:"-"`
+	Aliases   []TagAlias     `gorm:"foreignKey:TagID" json:"aliases,omitempty"` // 别名，仅 GetAllTags 预加载
 	CreatedAt time.Time      `json:"created_at" ts_type:"string"`
 	UpdatedAt time.Time      `json:"updated_at" ts_type:"string"`
 	DeletedAt SoftDeleteTime `gorm:"index" json:"-"`
 }
 
+// TagAlias 标签别名，搜索语法 tag: 与 AI 候选匹配时视同其所属标签；别名全局唯一
+type TagAlias struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	TagID     uint      `gorm:"index;not null" json:"tag_id"`
+	Tag       Tag       `gorm:"constraint:OnDelete:CASCADE;" json:"-"`
+	Name      string    `gorm:"uniqueIndex;not null" json:"name"`
+	CreatedAt time.Time `json:"created_at" ts_type:"string"`
+}
+
 // Settings 应用设置
 type Settings struct {
 	ID                          uint      `gorm:"primarykey" json:"id"`
@@ -85,15 +116,45 @@ type Settings struct {
 	AITaggingFrameCount         int       `gorm:"default:5" json:"ai_tagging_frame_count"`
 	AITaggingSubtitleCharLimit  int       `gorm:"default:4000" json:"ai_tagging_subtitle_char_limit"`
 	AITaggingStartupBatchSize   int       `gorm:"default:10" json:"ai_tagging_startup_batch_size"`
+	AITaggingConcurrency        int       `gorm:"default:1" json:"ai_tagging_concurrency"`  // 同时处理的视频数
+	AITaggingRequestsPerMinute  int       `json:"ai_tagging_requests_per_minute"`           // 每分钟请求上限（0 为不限）
+	AITaggingTokensPerMinute    int       `json:"ai_tagging_tokens_per_minute"`             // 每分钟预估 token 上限（0 为不限）
+	AITaggingMaxAttempts        int       `gorm:"default:5" json:"ai_tagging_max_attempts"` // 连续失败达到该次数后进入死信
+	AITaggingProvider           string    `json:"ai_tagging_provider"`                      // AI 标签提供方: openai, ollama, anthropic, clip；为空时沿用环境变量
+	AITaggingOllamaBaseURL      string    `json:"ai_tagging_ollama_base_url"`               // Ollama 服务地址
+	AITaggingOllamaModel        string    `json:"ai_tagging_ollama_model"`                  // Ollama 视觉模型
+	AITaggingAnthropicBaseURL   string    `json:"ai_tagging_anthropic_base_url"`            // Anthropic Messages 接口地址
+	AITaggingAnthropicAPIKey    string    `json:"ai_tagging_anthropic_api_key"`             // Anthropic API Key
+	AITaggingAnthropicModel     string    `json:"ai_tagging_anthropic_model"`               // Anthropic 模型
+	AITaggingClipBaseURL        string    `json:"ai_tagging_clip_base_url"`                 // CLIP 服务地址（留空使用本地 Python 运行时）
+	AITaggingClipModel          string    `json:"ai_tagging_clip_model"`                    // CLIP 模型
+	AITaggingClipMinScore       float64   `json:"ai_tagging_clip_min_score"`                // CLIP 候选的最低平均概率（0 使用默认值）
+	RandomNoRepeatCount         int       `gorm:"default:1" json:"random_no_repeat_count"`  // 随机选片不重复最近 N 次（0 为关闭）
+	RandomNoRepeatHours         int       `json:"random_no_repeat_hours"`                   // 随机选片不重复最近 T 小时（0 为关闭）
 	UpdatedAt                   time.Time `json:"updated_at" ts_type:"string"`
 }
 
 // ScanDirectory 扫描目录配置
 type ScanDirectory struct {
-	ID        uint           `gorm:"primarykey" json:"id"`
-	Path      string         `json:"path"`  // 目录路径
-	Alias     string         `json:"alias"` // 目录别名
-	CreatedAt time.Time      `json:"created_at" ts_type:"string"`
-	UpdatedAt time.Time      `json:"updated_at" ts_type:"string"`
-	DeletedAt SoftDeleteTime `gorm:"index" json:"-"`
+	ID            uint               `gorm:"primarykey" json:"id"`
+	Path          string             `json:"path"`                             // 目录路径
+	Alias         string             `json:"alias"`                            // 目录别名
+	Rules         ScanDirectoryRules `gorm:"embedded" json:"rules"`            // 目录级扫描规则
+	Offline       bool               `gorm:"default:false" json:"offline"`     // 根目录或卷标识不可访问（外接盘/NAS 未挂载）
+	OfflineReason string             `json:"offline_reason"`                   // 最近一次离线检测的原因
+	OfflineSince  *time.Time         `json:"offline_since" ts_type:"string"`   // 进入离线状态的时间
+	LastCheckedAt *time.Time         `json:"last_checked_at" ts_type:"string"` // 最近一次在线检测时间
+	CreatedAt     time.Time          `json:"created_at" ts_type:"string"`
+	UpdatedAt     time.Time          `json:"updated_at" ts_type:"string"`
+	DeletedAt     SoftDeleteTime     `gorm:"index" json:"-"`
+}
+
+// ScanDirectoryRules 目录级扫描规则，模式相对扫描根目录，多个模式以逗号或换行分隔
+type ScanDirectoryRules struct {
+	IncludePatterns string `gorm:"type:text" json:"include_patterns"`    // 仅扫描匹配的文件（为空表示全部）
+	ExcludePatterns string `gorm:"type:text" json:"exclude_patterns"`    // 排除匹配的文件或目录
+	MaxDepth        int    `gorm:"default:0" json:"max_depth"`           // 最大递归深度，0 表示不限，1 表示仅根目录本层
+	FollowSymlinks  bool   `gorm:"default:false" json:"follow_symlinks"` // 是否进入符号链接指向的目录
+	VideoExtensions string `json:"video_extensions"`                     // 覆盖全局视频格式（为空则使用设置）
+	VolumeMarker    string `json:"volume_marker"`                        // 卷标识文件（相对根目录），设置后仅当该文件存在时视为在线
 }
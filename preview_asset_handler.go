@@ -5,8 +5,12 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"video-master/services"
+
+	"gorm.io/gorm"
 )
 
 func newAssetHandler(app *App) http.Handler {
@@ -16,6 +20,18 @@ func newAssetHandler(app *App) http.Handler {
 				app.servePreviewMedia(w, r)
 				return
 			}
+			if strings.HasPrefix(r.URL.Path, "/preview/thumbnail/") {
+				app.serveThumbnail(w, r, "/preview/thumbnail/", services.ThumbnailKindPoster)
+				return
+			}
+			if strings.HasPrefix(r.URL.Path, "/preview/contact-sheet/") {
+				app.serveThumbnail(w, r, "/preview/contact-sheet/", services.ThumbnailKindContactSheet)
+				return
+			}
+			if strings.HasPrefix(r.URL.Path, "/preview/sprite/") {
+				app.serveSprite(w, r)
+				return
+			}
 		}
 
 		http.NotFound(w, r)
@@ -23,6 +39,11 @@ func newAssetHandler(app *App) http.Handler {
 }
 
 func (a *App) servePreviewMedia(w http.ResponseWriter, r *http.Request) {
+	if rest := strings.TrimPrefix(r.URL.Path, "/preview/media/"); strings.Contains(rest, "/") {
+		a.serveTranscode(w, r, rest)
+		return
+	}
+
 	videoID, err := previewVideoIDFromPath(r.URL.Path)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -57,8 +78,84 @@ func (a *App) servePreviewMedia(w http.ResponseWriter, r *http.Request) {
 	http.ServeContent(w, r, media.DisplayName, media.ModTime, file)
 }
 
+// serveTranscode 输出 /preview/media/<id>/stream.mp4 或 /preview/media/<id>/hls/<文件> 的实时转码内容
+func (a *App) serveTranscode(w http.ResponseWriter, r *http.Request, rest string) {
+	videoID, resource, ok := services.ParseTranscodeAssetPath(rest)
+	if !ok {
+		http.Error(w, "invalid preview media path", http.StatusBadRequest)
+		return
+	}
+	if err := a.videoService.ServeTranscode(w, r, videoID, resource); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "preview media not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, fmt.Sprintf("transcode unavailable: %v", err), services.TranscodeHTTPStatus(err))
+	}
+}
+
+// serveThumbnail 返回缓存的封面或联系表；尚未生成时已加入后台队列，返回 404 由前端在 thumbnail-ready 事件后重试
+func (a *App) serveThumbnail(w http.ResponseWriter, r *http.Request, prefix string, kind string) {
+	videoID, err := videoIDFromAssetPath(r.URL.Path, prefix)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	a.serveThumbnailAsset(w, r, videoID, kind)
+}
+
+// serveSprite 返回拖动预览雪碧图（<id>.jpg）或其 WebVTT 轨道（<id>.vtt）
+func (a *App) serveSprite(w http.ResponseWriter, r *http.Request) {
+	kind := services.ThumbnailKindSprite
+	path := r.URL.Path
+	switch filepath.Ext(path) {
+	case ".jpg":
+	case ".vtt":
+		kind = services.ThumbnailKindSpriteTrack
+	default:
+		http.Error(w, "invalid sprite path", http.StatusBadRequest)
+		return
+	}
+	videoID, err := videoIDFromAssetPath(strings.TrimSuffix(path, filepath.Ext(path)), "/preview/sprite/")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	a.serveThumbnailAsset(w, r, videoID, kind)
+}
+
+func (a *App) serveThumbnailAsset(w http.ResponseWriter, r *http.Request, videoID uint, kind string) {
+	asset, err := a.thumbnailService.ResolveThumbnail(videoID, kind)
+	if err != nil {
+		if errors.Is(err, services.ErrThumbnailPending) {
+			http.Error(w, "thumbnail pending", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, os.ErrNotExist) || errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "thumbnail not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, fmt.Sprintf("thumbnail unavailable: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	file, err := os.Open(asset.Path)
+	if err != nil {
+		http.Error(w, "thumbnail not found", http.StatusNotFound)
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", asset.MIME)
+	http.ServeContent(w, r, filepath.Base(asset.Path), asset.ModTime, file)
+}
+
 func previewVideoIDFromPath(path string) (uint, error) {
-	videoIDText := strings.TrimPrefix(path, "/preview/media/")
+	return videoIDFromAssetPath(path, "/preview/media/")
+}
+
+func videoIDFromAssetPath(path string, prefix string) (uint, error) {
+	videoIDText := strings.TrimPrefix(path, prefix)
 	if videoIDText == "" || videoIDText == path {
 		return 0, fmt.Errorf("invalid preview media path")
 	}
@@ -9,8 +9,10 @@ import (
 	"log"
 	"net/http"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
+	"video-master/models"
 )
 
 type AITaggingAIClient interface {
@@ -22,9 +24,18 @@ type OpenAICompatibleAITaggingClient struct {
 	client *http.Client
 }
 
-const aiTaggingRequestTimeout = 5 * time.Minute
+const (
+	aiTaggingRequestTimeout = 5 * time.Minute
+	// 提示词中 "常用标签" 最多列出的标签数
+	aiTaggingUsageSummaryLimit = 20
+	aiTaggingSystemPrompt      = "你是视频库标签审核助手。你只能输出 JSON，不要输出 Markdown。"
+)
 
-var aiTaggingDataURLPattern = regexp.MustCompile(`"url":"data:image/[^"]+"`)
+var (
+	aiTaggingDataURLPattern = regexp.MustCompile(`"url":"data:image/[^"]+"`)
+	// Ollama 与 Anthropic 直接携带裸 base64 图片
+	aiTaggingBase64Pattern = regexp.MustCompile(`"[A-Za-z0-9+/]{256,}={0,2}"`)
+)
 
 func NewOpenAICompatibleAITaggingClient(config AITaggingConfig) AITaggingAIClient {
 	return &OpenAICompatibleAITaggingClient{
@@ -34,42 +45,10 @@ func NewOpenAICompatibleAITaggingClient(config AITaggingConfig) AITaggingAIClien
 }
 
 func (c *OpenAICompatibleAITaggingClient) AnalyzeTags(ctx context.Context, req AITaggingRequest) ([]AITagSuggestion, error) {
-	body := c.buildRequest(req)
-	payload, err := json.Marshal(body)
+	respBody, err := postAITaggingJSON(ctx, c.client, openAIChatCompletionsURL(c.config.BaseURL), c.authHeaders(), c.buildRequest(req), req.Video.ID, c.config.Model)
 	if err != nil {
 		return nil, err
 	}
-	log.Printf("[AITagging] request video_id=%d model=%q base_url=%q payload_bytes=%d payload=%s",
-		req.Video.ID,
-		c.config.Model,
-		openAIChatCompletionsURL(c.config.BaseURL),
-		len(payload),
-		redactAITaggingPayload(payload),
-	)
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, openAIChatCompletionsURL(c.config.BaseURL), bytes.NewReader(payload))
-	if err != nil {
-		return nil, err
-	}
-	httpReq.Header.Set("Content-Type", "application/json")
-	if strings.TrimSpace(c.config.APIKey) != "" {
-		httpReq.Header.Set("Authorization", "Bearer "+c.config.APIKey)
-	}
-	resp, err := c.client.Do(httpReq)
-	if err != nil {
-		log.Printf("[AITagging] request failed video_id=%d err=%v", req.Video.ID, err)
-		return nil, err
-	}
-	defer resp.Body.Close()
-	respBody, _ := io.ReadAll(resp.Body)
-	log.Printf("[AITagging] response video_id=%d status=%d bytes=%d body=%s",
-		req.Video.ID,
-		resp.StatusCode,
-		len(respBody),
-		truncateLogSnippet(string(respBody), 4000),
-	)
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("AI tagging API returned %d: %s", resp.StatusCode, truncateLogSnippet(string(respBody), 300))
-	}
 	var parsed struct {
 		Choices []struct {
 			Message struct {
@@ -81,32 +60,50 @@ func (c *OpenAICompatibleAITaggingClient) AnalyzeTags(ctx context.Context, req A
 		log.Printf("[AITagging] response parse failed video_id=%d err=%v body=%s", req.Video.ID, err, truncateLogSnippet(string(respBody), 4000))
 		return nil, err
 	}
-	if len(parsed.Choices) == 0 || strings.TrimSpace(parsed.Choices[0].Message.Content) == "" {
-		log.Printf("[AITagging] response empty content video_id=%d body=%s", req.Video.ID, truncateLogSnippet(string(respBody), 4000))
-		return nil, fmt.Errorf("AI tagging API returned empty content")
+	content := ""
+	if len(parsed.Choices) > 0 {
+		content = parsed.Choices[0].Message.Content
 	}
-	content := parsed.Choices[0].Message.Content
-	suggestions, err := parseAITagSuggestions(content)
-	if err != nil {
-		log.Printf("[AITagging] response content parse failed video_id=%d err=%v content=%s", req.Video.ID, err, truncateLogSnippet(content, 4000))
-		return nil, err
+	return parseAITaggingResponseContent(req.Video.ID, content, respBody)
+}
+
+func (c *OpenAICompatibleAITaggingClient) authHeaders() map[string]string {
+	if strings.TrimSpace(c.config.APIKey) == "" {
+		return nil
 	}
-	log.Printf("[AITagging] parsed suggestions video_id=%d count=%d suggestions=%s",
-		req.Video.ID,
-		len(suggestions),
-		summarizeAITagSuggestions(suggestions),
-	)
-	return suggestions, nil
+	return map[string]string{"Authorization": "Bearer " + c.config.APIKey}
 }
 
 func (c *OpenAICompatibleAITaggingClient) buildRequest(req AITaggingRequest) map[string]interface{} {
-	existingTagNames := make([]string, 0, len(req.ExistingTags))
-	for _, tag := range req.ExistingTags {
-		existingTagNames = append(existingTagNames, tag.Name)
+	evidence := req.Evidence
+	frameContents := make([]map[string]interface{}, 0, len(evidence.Frames)*2+1)
+	frameContents = append(frameContents, map[string]interface{}{"type": "text", "text": buildAITaggingPrompt(req, c.config.SubtitleCharLimit)})
+	for _, frame := range evidence.Frames {
+		frameContents = append(frameContents, map[string]interface{}{
+			"type": "text",
+			"text": aiTaggingFrameNote(frame, len(evidence.Frames)),
+		})
+		frameContents = append(frameContents, map[string]interface{}{
+			"type": "image_url",
+			"image_url": map[string]string{
+				"url": frame.DataURL,
+			},
+		})
+	}
+	return map[string]interface{}{
+		"model": c.config.Model,
+		"messages": []map[string]interface{}{
+			{"role": "system", "content": aiTaggingSystemPrompt},
+			{"role": "user", "content": frameContents},
+		},
+		"temperature": 0.1,
 	}
+}
+
+// buildAITaggingPrompt 生成各对话式提供方共用的标签提示词
+func buildAITaggingPrompt(req AITaggingRequest, subtitleCharLimit int) string {
 	evidence := req.Evidence
-	frameContents := make([]map[string]interface{}, 0, len(evidence.Frames)+1)
-	text := fmt.Sprintf(`请为本地视频生成标签候选。当前请求包含 %d 张视频抽帧；如果抽帧可用，必须优先根据画面内容判断，文件名和路径只能作为辅助证据。必须优先从现有标签库中选择，只有画面证据非常明确且现有标签库没有合适标签时，才提出新标签。
+	return fmt.Sprintf(`请为本地视频生成标签候选。当前请求包含 %d 张视频抽帧；如果抽帧可用，必须优先根据画面内容判断，文件名和路径只能作为辅助证据。必须优先从现有标签库中选择，只有画面证据非常明确且现有标签库没有合适标签时，才提出新标签。
 
 输出 JSON，格式为 {"suggestions":[{"label":"标签名","confidence":"high|medium|low","match_type":"existing_exact|existing_semantic|new_candidate","matched_existing_name":"若匹配已有标签则填写","reasoning":"简短理由"}]}。
 
@@ -116,6 +113,8 @@ func (c *OpenAICompatibleAITaggingClient) buildRequest(req AITaggingRequest) map
 3. 文件名、路径、字幕只能用于补充画面判断；不得只因为标题包含某个词就给 high。
 4. 如果画面不可用，再退化为文件名、路径、字幕和已有标签库判断，并在 reasoning 里说明依据不足。
 5. 同义词不要新增标签。例如已有 "4K" 时，不要输出 "4K超清"；已有 "舞蹈" 时，不要输出 "舞蹈表演"。
+6. 标签库按 "类别: 子标签" 分组时，优先选择最具体的子标签，label 只填子标签名称，不要带类别前缀。
+7. 多个已有标签都合适时，优先选择 "常用标签" 中使用较多的标签。
 
 置信度规则：
 - high: 多帧画面证据明确，且能匹配已有标签，或文件名和画面共同强确认。
@@ -125,29 +124,72 @@ func (c *OpenAICompatibleAITaggingClient) buildRequest(req AITaggingRequest) map
 视频文件名：%s
 视频路径：%s
 现有标签库：%s
+常用标签：%s
 字幕摘要：%s
-采样警告：%s`, len(evidence.Frames), req.Video.Name, req.Video.Path, strings.Join(existingTagNames, ", "), truncateLogSnippet(evidence.SubtitleText, c.config.SubtitleCharLimit), strings.Join(evidence.Warnings, "; "))
-	frameContents = append(frameContents, map[string]interface{}{"type": "text", "text": text})
-	for _, frame := range evidence.Frames {
-		frameContents = append(frameContents, map[string]interface{}{
-			"type": "text",
-			"text": fmt.Sprintf("视频抽帧 %d/%d，约 %.1f 秒。请把这张图与其他抽帧综合比较，不要单独依赖文件名。", frame.Index, len(evidence.Frames), frame.Position),
-		})
-		frameContents = append(frameContents, map[string]interface{}{
-			"type": "image_url",
-			"image_url": map[string]string{
-				"url": frame.DataURL,
-			},
-		})
+采样警告：%s`, len(evidence.Frames), req.Video.Name, req.Video.Path, formatExistingTagLibrary(req.ExistingTags), formatTagUsageSummary(req.ExistingTags, req.TagUsage), truncateLogSnippet(evidence.SubtitleText, subtitleCharLimit), strings.Join(evidence.Warnings, "; "))
+}
+
+func aiTaggingFrameNote(frame AITaggingFrame, total int) string {
+	return fmt.Sprintf("视频抽帧 %d/%d，约 %.1f 秒。请把这张图与其他抽帧综合比较，不要单独依赖文件名。", frame.Index, total, frame.Position)
+}
+
+// postAITaggingJSON 发送 JSON 请求并返回 2xx 响应体，请求与响应都会记录日志（图片数据已脱敏）
+func postAITaggingJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, body interface{}, videoID uint, model string) ([]byte, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
 	}
-	return map[string]interface{}{
-		"model": c.config.Model,
-		"messages": []map[string]interface{}{
-			{"role": "system", "content": "你是视频库标签审核助手。你只能输出 JSON，不要输出 Markdown。"},
-			{"role": "user", "content": frameContents},
-		},
-		"temperature": 0.1,
+	log.Printf("[AITagging] request video_id=%d model=%q base_url=%q payload_bytes=%d payload=%s",
+		videoID,
+		model,
+		url,
+		len(payload),
+		redactAITaggingPayload(payload),
+	)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
 	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		httpReq.Header.Set(key, value)
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		log.Printf("[AITagging] request failed video_id=%d err=%v", videoID, err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(resp.Body)
+	log.Printf("[AITagging] response video_id=%d status=%d bytes=%d body=%s",
+		videoID,
+		resp.StatusCode,
+		len(respBody),
+		truncateLogSnippet(string(respBody), 4000),
+	)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("AI tagging API returned %d: %s", resp.StatusCode, truncateLogSnippet(string(respBody), 300))
+	}
+	return respBody, nil
+}
+
+// parseAITaggingResponseContent 解析模型返回的文本内容为标签建议
+func parseAITaggingResponseContent(videoID uint, content string, respBody []byte) ([]AITagSuggestion, error) {
+	if strings.TrimSpace(content) == "" {
+		log.Printf("[AITagging] response empty content video_id=%d body=%s", videoID, truncateLogSnippet(string(respBody), 4000))
+		return nil, fmt.Errorf("AI tagging API returned empty content")
+	}
+	suggestions, err := parseAITagSuggestions(content)
+	if err != nil {
+		log.Printf("[AITagging] response content parse failed video_id=%d err=%v content=%s", videoID, err, truncateLogSnippet(content, 4000))
+		return nil, err
+	}
+	log.Printf("[AITagging] parsed suggestions video_id=%d count=%d suggestions=%s",
+		videoID,
+		len(suggestions),
+		summarizeAITagSuggestions(suggestions),
+	)
+	return suggestions, nil
 }
 
 func openAIChatCompletionsURL(baseURL string) string {
@@ -163,6 +205,7 @@ func openAIChatCompletionsURL(baseURL string) string {
 
 func redactAITaggingPayload(payload []byte) string {
 	redacted := aiTaggingDataURLPattern.ReplaceAllString(string(payload), `"url":"<data_url_redacted>"`)
+	redacted = aiTaggingBase64Pattern.ReplaceAllString(redacted, `"<base64_redacted>"`)
 	return truncateLogSnippet(redacted, 4000)
 }
 
@@ -278,3 +321,74 @@ func findJSONValueEnd(content string, open rune, close rune) (int, bool) {
 	}
 	return 0, false
 }
+
+// formatTagUsageSummary 列出使用最多的标签及其视频数，如 "足球(12), 4K(8)"；没有使用记录时为 "无"
+func formatTagUsageSummary(tags []models.Tag, usage map[uint]int64) string {
+	used := make([]models.Tag, 0, len(tags))
+	for _, tag := range tags {
+		if usage[tag.ID] > 0 {
+			used = append(used, tag)
+		}
+	}
+	if len(used) == 0 {
+		return "无"
+	}
+	sort.SliceStable(used, func(i, j int) bool {
+		return usage[used[i].ID] > usage[used[j].ID]
+	})
+	if len(used) > aiTaggingUsageSummaryLimit {
+		used = used[:aiTaggingUsageSummaryLimit]
+	}
+	parts := make([]string, 0, len(used))
+	for _, tag := range used {
+		parts = append(parts, fmt.Sprintf("%s(%d)", tag.Name, usage[tag.ID]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatExistingTagLibrary 生成提示词中的标签库；没有层级时为逗号分隔列表，
+// 有层级时每个类别一行 "类别路径: 子标签, ..."，无子标签的顶层标签归入 "未分组"
+func formatExistingTagLibrary(tags []models.Tag) string {
+	tree := buildTagTree(tags)
+	hierarchical := false
+	for _, node := range tree {
+		if len(node.Children) > 0 {
+			hierarchical = true
+			break
+		}
+	}
+	if !hierarchical {
+		names := make([]string, 0, len(tags))
+		for _, tag := range tags {
+			names = append(names, tag.Name)
+		}
+		return strings.Join(names, ", ")
+	}
+
+	var lines []string
+	var ungrouped []string
+	var walk func(node TagTreeNode)
+	walk = func(node TagTreeNode) {
+		children := make([]string, 0, len(node.Children))
+		for _, child := range node.Children {
+			children = append(children, child.Tag.Name)
+		}
+		lines = append(lines, node.Path+": "+strings.Join(children, ", "))
+		for _, child := range node.Children {
+			if len(child.Children) > 0 {
+				walk(child)
+			}
+		}
+	}
+	for _, node := range tree {
+		if len(node.Children) == 0 {
+			ungrouped = append(ungrouped, node.Tag.Name)
+			continue
+		}
+		walk(node)
+	}
+	if len(ungrouped) > 0 {
+		lines = append(lines, "未分组: "+strings.Join(ungrouped, ", "))
+	}
+	return "\n" + strings.Join(lines, "\n")
+}
@@ -0,0 +1,352 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"video-master/models"
+)
+
+// CLIP 零样本分类：把现有标签作为候选文本，对每张抽帧计算 softmax 概率后跨帧取平均。
+// 只能从现有标签中选择，不会提出新标签。配置了 BaseURL 时调用常驻 CLIP 服务
+// （clip_tagging_worker.py --serve），否则每个视频启动一次本地 Python worker。
+
+const (
+	clipRuntimeDirName  = "clip_tagging_sidecar"
+	clipVenvDirName     = "venv"
+	clipWorkerFileName  = "clip_tagging_worker.py"
+	clipReadyMarkerName = ".ready"
+	// 每个视频最多返回的 CLIP 候选数
+	clipMaxSuggestions = 5
+)
+
+//go:embed clip_tagging_worker.py
+var clipWorkerScript string
+
+type clipClassifyRequest struct {
+	Model  string   `json:"model"`
+	Texts  []string `json:"texts"`
+	Images []string `json:"images"`
+}
+
+type clipClassifyResponse struct {
+	Scores [][]float64 `json:"scores"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// clipTagLabel 候选标签：Label 为 "类别/子标签" 路径，用于回填；Text 为送入 CLIP 的描述
+type clipTagLabel struct {
+	Label string
+	Text  string
+}
+
+type CLIPAITaggingClient struct {
+	config  AITaggingConfig
+	client  *http.Client
+	runtime clipTaggingRuntime
+}
+
+func NewCLIPAITaggingClient(config AITaggingConfig) AITaggingAIClient {
+	return &CLIPAITaggingClient{
+		config:  config,
+		client:  &http.Client{Timeout: aiTaggingRequestTimeout},
+		runtime: clipTaggingRuntime{dir: config.RuntimeDir},
+	}
+}
+
+func (c *CLIPAITaggingClient) AnalyzeTags(ctx context.Context, req AITaggingRequest) ([]AITagSuggestion, error) {
+	labels := clipTagLabels(req.ExistingTags)
+	if len(labels) == 0 {
+		return nil, nil
+	}
+	frames := req.Evidence.Frames
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("CLIP 零样本分类需要视频抽帧")
+	}
+	payload := clipClassifyRequest{Model: c.config.Model, Texts: make([]string, len(labels)), Images: make([]string, len(frames))}
+	for i, label := range labels {
+		payload.Texts[i] = label.Text
+	}
+	for i, frame := range frames {
+		payload.Images[i] = dataURLBase64(frame.DataURL)
+	}
+
+	var result clipClassifyResponse
+	var err error
+	if strings.TrimSpace(c.config.BaseURL) != "" {
+		result, err = c.classifyRemote(ctx, req.Video.ID, payload)
+	} else {
+		result, err = c.runtime.classify(ctx, payload)
+	}
+	if err != nil {
+		log.Printf("[AITagging][CLIP] classify failed video_id=%d err=%v", req.Video.ID, err)
+		return nil, err
+	}
+	suggestions, err := clipSuggestions(labels, result.Scores, c.config.CLIPMinScore)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[AITagging][CLIP] suggestions video_id=%d count=%d suggestions=%s", req.Video.ID, len(suggestions), summarizeAITagSuggestions(suggestions))
+	return suggestions, nil
+}
+
+func (c *CLIPAITaggingClient) classifyRemote(ctx context.Context, videoID uint, payload clipClassifyRequest) (clipClassifyResponse, error) {
+	var result clipClassifyResponse
+	respBody, err := postAITaggingJSON(ctx, c.client, clipClassifyURL(c.config.BaseURL), nil, payload, videoID, c.config.Model)
+	if err != nil {
+		return result, err
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return result, err
+	}
+	if result.Error != "" {
+		return result, fmt.Errorf("CLIP 服务返回错误: %s", result.Error)
+	}
+	return result, nil
+}
+
+func clipClassifyURL(baseURL string) string {
+	base := strings.TrimRight(strings.TrimSpace(baseURL), "/")
+	if strings.HasSuffix(base, "/classify") {
+		return base
+	}
+	return base + "/classify"
+}
+
+// clipTagLabels 以标签路径作为候选，描述文本只用最末一级名称
+func clipTagLabels(tags []models.Tag) []clipTagLabel {
+	paths := tagPathsByID(tags)
+	labels := make([]clipTagLabel, 0, len(tags))
+	for _, tag := range tags {
+		name := strings.TrimSpace(tag.Name)
+		if name == "" {
+			continue
+		}
+		label := paths[tag.ID]
+		if label == "" {
+			label = name
+		}
+		labels = append(labels, clipTagLabel{Label: label, Text: fmt.Sprintf("一张关于「%s」的视频画面", name)})
+	}
+	return labels
+}
+
+// clipSuggestions 按跨帧平均概率排序，平均概率不低于 minScore 的标签成为候选；
+// 平均概率达到 2×minScore 且在至少一半抽帧中排名第一时为 high，其余为 medium
+func clipSuggestions(labels []clipTagLabel, scores [][]float64, minScore float64) ([]AITagSuggestion, error) {
+	if len(scores) == 0 {
+		return nil, fmt.Errorf("CLIP 未返回任何抽帧评分")
+	}
+	type ranked struct {
+		index int
+		mean  float64
+		top   int
+	}
+	items := make([]ranked, len(labels))
+	for i := range items {
+		items[i].index = i
+	}
+	for _, row := range scores {
+		if len(row) != len(labels) {
+			return nil, fmt.Errorf("CLIP 评分数量 %d 与候选标签数量 %d 不一致", len(row), len(labels))
+		}
+		best := 0
+		for i, score := range row {
+			items[i].mean += score / float64(len(scores))
+			if score > row[best] {
+				best = i
+			}
+		}
+		items[best].top++
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].mean > items[j].mean })
+
+	suggestions := make([]AITagSuggestion, 0, clipMaxSuggestions)
+	for _, item := range items {
+		if len(suggestions) >= clipMaxSuggestions || item.mean < minScore {
+			break
+		}
+		confidence := "medium"
+		if item.mean >= 2*minScore && item.top*2 >= len(scores) {
+			confidence = "high"
+		}
+		label := labels[item.index].Label
+		suggestions = append(suggestions, AITagSuggestion{
+			Label:               label,
+			Confidence:          confidence,
+			MatchType:           "existing_exact",
+			MatchedExistingName: label,
+			Reasoning:           fmt.Sprintf("CLIP 零样本平均概率 %.2f，%d/%d 帧排名第一", item.mean, item.top, len(scores)),
+		})
+	}
+	return suggestions, nil
+}
+
+// clipTaggingRuntime 本地 CLIP 运行时，目录结构与 Qwen sidecar 相同：独立 venv + 内嵌 worker 脚本 + HF 缓存
+type clipTaggingRuntime struct {
+	dir string
+}
+
+func clipTaggingRuntimeDir(baseDir string) string {
+	return filepath.Join(baseDir, clipRuntimeDirName)
+}
+
+// clipTaggingRuntimeReady 只检查安装完成标记与 venv python，不启动 Python，可在每轮调度时调用
+func clipTaggingRuntimeReady(dir string) bool {
+	if dir == "" {
+		return false
+	}
+	r := clipTaggingRuntime{dir: dir}
+	if _, err := os.Stat(filepath.Join(dir, clipReadyMarkerName)); err != nil {
+		return false
+	}
+	_, err := os.Stat(r.venvPython())
+	return err == nil
+}
+
+func (r clipTaggingRuntime) workerPath() string {
+	return filepath.Join(r.dir, clipWorkerFileName)
+}
+
+func (r clipTaggingRuntime) venvDir() string {
+	return filepath.Join(r.dir, clipVenvDirName)
+}
+
+func (r clipTaggingRuntime) venvPython() string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(r.venvDir(), "Scripts", "python.exe")
+	}
+	return filepath.Join(r.venvDir(), "bin", "python3")
+}
+
+func (r clipTaggingRuntime) hfHomeDir() string {
+	return filepath.Join(r.dir, "hf")
+}
+
+func (r clipTaggingRuntime) modelCached(modelName string) bool {
+	sanitized := strings.ReplaceAll(modelName, "/", "--")
+	_, err := os.Stat(filepath.Join(r.hfHomeDir(), "hub", "models--"+sanitized))
+	return err == nil
+}
+
+func (r clipTaggingRuntime) ensureWorkerScript() error {
+	if err := os.MkdirAll(r.dir, 0755); err != nil {
+		return err
+	}
+	path := r.workerPath()
+	if data, err := os.ReadFile(path); err == nil && string(data) == clipWorkerScript {
+		return nil
+	}
+	return os.WriteFile(path, []byte(clipWorkerScript), 0644)
+}
+
+func (r clipTaggingRuntime) environment() ([]string, error) {
+	if err := os.MkdirAll(r.hfHomeDir(), 0755); err != nil {
+		return nil, err
+	}
+	return append(os.Environ(),
+		"PIP_DISABLE_PIP_VERSION_CHECK=1",
+		"PIP_PROGRESS_BAR=off",
+		"PYTHONUNBUFFERED=1",
+		"TOKENIZERS_PARALLELISM=false",
+		"HF_HUB_DISABLE_TELEMETRY=1",
+		"HF_HOME="+r.hfHomeDir(),
+	), nil
+}
+
+// install 创建 venv 并安装 torch/transformers/pillow，成功后写入完成标记
+func (r clipTaggingRuntime) install() error {
+	if r.dir == "" {
+		return fmt.Errorf("CLIP 运行时目录未配置")
+	}
+	if err := r.ensureWorkerScript(); err != nil {
+		return err
+	}
+	_ = os.Remove(filepath.Join(r.dir, clipReadyMarkerName))
+	// 复用字幕组件的 Python 查找逻辑（包括托管 Python）
+	pythonFinder := &SubtitleService{BaseDir: filepath.Dir(r.dir)}
+	venvPython := r.venvPython()
+	if !pythonFinder.pythonMeetsMinimumVersion(venvPython) {
+		basePython := pythonFinder.findBasePython()
+		if basePython == "" {
+			return fmt.Errorf("CLIP 运行时需要 Python 3.10+，当前环境未找到可用 Python")
+		}
+		_ = os.RemoveAll(r.venvDir())
+		if output, err := exec.Command(basePython, "-m", "venv", r.venvDir()).CombinedOutput(); err != nil {
+			return fmt.Errorf("创建 CLIP 虚拟环境失败: %s", strings.TrimSpace(string(output)))
+		}
+	}
+	env, err := r.environment()
+	if err != nil {
+		return err
+	}
+	install := exec.Command(venvPython, "-m", "pip", "install", "-U", "torch", "transformers", "pillow")
+	install.Env = env
+	if output, err := install.CombinedOutput(); err != nil {
+		return fmt.Errorf("安装 CLIP 依赖失败: %s", truncateLogSnippet(strings.TrimSpace(string(output)), 2000))
+	}
+	return os.WriteFile(filepath.Join(r.dir, clipReadyMarkerName), []byte("ok\n"), 0644)
+}
+
+// classify 启动一次性 worker，请求经 stdin 传入，结果从 stdout 读取
+func (r clipTaggingRuntime) classify(ctx context.Context, payload clipClassifyRequest) (clipClassifyResponse, error) {
+	var result clipClassifyResponse
+	if !clipTaggingRuntimeReady(r.dir) {
+		return result, fmt.Errorf("缺少 CLIP 运行时，请先在设置中准备")
+	}
+	if err := r.ensureWorkerScript(); err != nil {
+		return result, err
+	}
+	env, err := r.environment()
+	if err != nil {
+		return result, err
+	}
+	if r.modelCached(payload.Model) {
+		env = append(env, "HF_HUB_OFFLINE=1", "TRANSFORMERS_OFFLINE=1")
+	}
+	input, err := json.Marshal(payload)
+	if err != nil {
+		return result, err
+	}
+	cmd := exec.CommandContext(ctx, r.venvPython(), r.workerPath())
+	cmd.Env = env
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		detail := strings.TrimSpace(stderr.String())
+		if detail == "" {
+			detail = err.Error()
+		}
+		return result, fmt.Errorf("CLIP worker 失败: %s", truncateLogSnippet(detail, 2000))
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		return result, fmt.Errorf("CLIP worker 输出解析失败")
+	}
+	return result, nil
+}
+
+// CLIPRuntimeReady 本地 CLIP 运行时是否已准备好
+func (s *AITaggingService) CLIPRuntimeReady() bool {
+	return clipTaggingRuntimeReady(s.clipRuntimeDir)
+}
+
+// PrepareCLIPRuntime 创建本地 CLIP 虚拟环境并安装依赖；模型在首次分类时下载到运行时目录
+func (s *AITaggingService) PrepareCLIPRuntime() error {
+	if err := (clipTaggingRuntime{dir: s.clipRuntimeDir}).install(); err != nil {
+		return err
+	}
+	s.wakeWorker()
+	return nil
+}
@@ -9,27 +9,54 @@ import (
 	"video-master/models"
 )
 
+// AI 标签提供方
 const (
-	envAITaggingBaseURL = "AI_TAGGING_BASE_URL"
-	envAITaggingAPIKey  = "AI_TAGGING_API_KEY"
-	envAITaggingModel   = "AI_TAGGING_MODEL"
+	AITaggingProviderOpenAI    = "openai"    // OpenAI 兼容的 chat completions（LM Studio、vLLM 等）
+	AITaggingProviderOllama    = "ollama"    // Ollama 原生 /api/chat
+	AITaggingProviderAnthropic = "anthropic" // Anthropic Messages 接口
+	AITaggingProviderCLIP      = "clip"      // 本地 CLIP 零样本分类，只能从现有标签中选择
+)
+
+const (
+	envAITaggingProvider = "AI_TAGGING_PROVIDER"
+	envAITaggingBaseURL  = "AI_TAGGING_BASE_URL"
+	envAITaggingAPIKey   = "AI_TAGGING_API_KEY"
+	envAITaggingModel    = "AI_TAGGING_MODEL"
 
 	envAITaggingFrameCount        = "AI_TAGGING_FRAME_COUNT"
 	envAITaggingSubtitleCharLimit = "AI_TAGGING_SUBTITLE_CHAR_LIMIT"
 	envAITaggingStartupBatchSize  = "AI_TAGGING_STARTUP_BATCH_SIZE"
+	envAITaggingConcurrency       = "AI_TAGGING_CONCURRENCY"
+	envAITaggingRequestsPerMinute = "AI_TAGGING_REQUESTS_PER_MINUTE"
+	envAITaggingTokensPerMinute   = "AI_TAGGING_TOKENS_PER_MINUTE"
+	envAITaggingMaxAttempts       = "AI_TAGGING_MAX_ATTEMPTS"
 
 	defaultAITaggingFrameCount        = 5
 	defaultAITaggingSubtitleCharLimit = 4000
 	defaultAITaggingStartupBatchSize  = 10
+	defaultAITaggingConcurrency       = 1
+	defaultAITaggingMaxAttempts       = 5
+
+	defaultOllamaBaseURL    = "http://127.0.0.1:11434"
+	defaultAnthropicBaseURL = "https://api.anthropic.com"
+	defaultCLIPModel        = "OFA-Sys/chinese-clip-vit-base-patch16"
+	defaultCLIPMinScore     = 0.2
 )
 
 type AITaggingConfig struct {
+	Provider          string // 为空按 openai 处理
 	BaseURL           string
 	APIKey            string
 	Model             string
 	FrameCount        int
 	SubtitleCharLimit int
 	StartupBatchSize  int
+	Concurrency       int     // 同时处理的视频数
+	RequestsPerMinute int     // 每分钟请求上限，0 为不限
+	TokensPerMinute   int     // 每分钟预估 token 上限，0 为不限
+	MaxAttempts       int     // 连续失败达到该次数后进入死信
+	CLIPMinScore      float64 // CLIP 候选的最低平均概率
+	RuntimeDir        string  // 本地 CLIP 运行时目录；为空时 CLIP 只能使用 BaseURL 指向的服务
 }
 
 type AITaggingConfigProvider interface {
@@ -39,61 +66,151 @@ type AITaggingConfigProvider interface {
 type EnvAITaggingConfigProvider struct{}
 
 func (EnvAITaggingConfigProvider) Load() (AITaggingConfig, error) {
-	config := AITaggingConfig{
-		BaseURL:           strings.TrimSpace(os.Getenv(envAITaggingBaseURL)),
-		APIKey:            strings.TrimSpace(os.Getenv(envAITaggingAPIKey)),
-		Model:             strings.TrimSpace(os.Getenv(envAITaggingModel)),
-		FrameCount:        envInt(envAITaggingFrameCount, defaultAITaggingFrameCount),
-		SubtitleCharLimit: envInt(envAITaggingSubtitleCharLimit, defaultAITaggingSubtitleCharLimit),
-		StartupBatchSize:  envInt(envAITaggingStartupBatchSize, defaultAITaggingStartupBatchSize),
+	config := envAITaggingConfig()
+	return config, validateAITaggingConfig(config)
+}
+
+// SettingsAITaggingConfigProvider 以环境变量为默认值，数据库设置优先；
+// 设置中选择的提供方与环境变量不同时，不沿用环境变量里的地址、Key 与模型
+type SettingsAITaggingConfigProvider struct {
+	BaseDir string // 数据目录，用于定位本地 CLIP 运行时
+}
+
+func (p SettingsAITaggingConfigProvider) Load() (AITaggingConfig, error) {
+	config := envAITaggingConfig()
+	if p.BaseDir != "" {
+		config.RuntimeDir = clipTaggingRuntimeDir(p.BaseDir)
 	}
-	if config.BaseURL == "" || config.Model == "" {
-		return config, fmt.Errorf("AI tagging config unavailable")
+	if database.DB != nil {
+		var settings models.Settings
+		if err := database.DB.First(&settings).Error; err == nil {
+			applyAITaggingSettings(&config, settings)
+		}
 	}
-	return config, nil
+	applyAITaggingProviderDefaults(&config)
+	return config, validateAITaggingConfig(config)
 }
 
-type SettingsAITaggingConfigProvider struct{}
-
-func (SettingsAITaggingConfigProvider) Load() (AITaggingConfig, error) {
-	envConfig := AITaggingConfig{
+func envAITaggingConfig() AITaggingConfig {
+	config := AITaggingConfig{
+		Provider:          normalizeAITaggingProvider(os.Getenv(envAITaggingProvider)),
 		BaseURL:           strings.TrimSpace(os.Getenv(envAITaggingBaseURL)),
 		APIKey:            strings.TrimSpace(os.Getenv(envAITaggingAPIKey)),
 		Model:             strings.TrimSpace(os.Getenv(envAITaggingModel)),
 		FrameCount:        envInt(envAITaggingFrameCount, defaultAITaggingFrameCount),
 		SubtitleCharLimit: envInt(envAITaggingSubtitleCharLimit, defaultAITaggingSubtitleCharLimit),
 		StartupBatchSize:  envInt(envAITaggingStartupBatchSize, defaultAITaggingStartupBatchSize),
+		Concurrency:       envInt(envAITaggingConcurrency, defaultAITaggingConcurrency),
+		RequestsPerMinute: envInt(envAITaggingRequestsPerMinute, 0),
+		TokensPerMinute:   envInt(envAITaggingTokensPerMinute, 0),
+		MaxAttempts:       envInt(envAITaggingMaxAttempts, defaultAITaggingMaxAttempts),
 	}
+	applyAITaggingProviderDefaults(&config)
+	return config
+}
 
-	config := envConfig
-	if database.DB != nil {
-		var settings models.Settings
-		if err := database.DB.First(&settings).Error; err == nil {
-			if value := strings.TrimSpace(settings.AITaggingBaseURL); value != "" {
-				config.BaseURL = value
-			}
-			if value := strings.TrimSpace(settings.AITaggingAPIKey); value != "" {
-				config.APIKey = value
-			}
-			if value := strings.TrimSpace(settings.AITaggingModel); value != "" {
-				config.Model = value
-			}
-			if settings.AITaggingFrameCount > 0 {
-				config.FrameCount = settings.AITaggingFrameCount
-			}
-			if settings.AITaggingSubtitleCharLimit > 0 {
-				config.SubtitleCharLimit = settings.AITaggingSubtitleCharLimit
-			}
-			if settings.AITaggingStartupBatchSize > 0 {
-				config.StartupBatchSize = settings.AITaggingStartupBatchSize
-			}
+func applyAITaggingSettings(config *AITaggingConfig, settings models.Settings) {
+	if provider := strings.TrimSpace(settings.AITaggingProvider); provider != "" {
+		provider = normalizeAITaggingProvider(provider)
+		if provider != config.Provider {
+			config.Provider = provider
+			config.BaseURL, config.APIKey, config.Model = "", "", ""
 		}
 	}
+	var baseURL, apiKey, model string
+	switch config.Provider {
+	case AITaggingProviderOllama:
+		baseURL, model = settings.AITaggingOllamaBaseURL, settings.AITaggingOllamaModel
+	case AITaggingProviderAnthropic:
+		baseURL, apiKey, model = settings.AITaggingAnthropicBaseURL, settings.AITaggingAnthropicAPIKey, settings.AITaggingAnthropicModel
+	case AITaggingProviderCLIP:
+		baseURL, model = settings.AITaggingClipBaseURL, settings.AITaggingClipModel
+		if settings.AITaggingClipMinScore > 0 {
+			config.CLIPMinScore = settings.AITaggingClipMinScore
+		}
+	default:
+		baseURL, apiKey, model = settings.AITaggingBaseURL, settings.AITaggingAPIKey, settings.AITaggingModel
+	}
+	if value := strings.TrimSpace(baseURL); value != "" {
+		config.BaseURL = value
+	}
+	if value := strings.TrimSpace(apiKey); value != "" {
+		config.APIKey = value
+	}
+	if value := strings.TrimSpace(model); value != "" {
+		config.Model = value
+	}
+	if settings.AITaggingFrameCount > 0 {
+		config.FrameCount = settings.AITaggingFrameCount
+	}
+	if settings.AITaggingSubtitleCharLimit > 0 {
+		config.SubtitleCharLimit = settings.AITaggingSubtitleCharLimit
+	}
+	if settings.AITaggingStartupBatchSize > 0 {
+		config.StartupBatchSize = settings.AITaggingStartupBatchSize
+	}
+	if settings.AITaggingConcurrency > 0 {
+		config.Concurrency = settings.AITaggingConcurrency
+	}
+	if settings.AITaggingRequestsPerMinute > 0 {
+		config.RequestsPerMinute = settings.AITaggingRequestsPerMinute
+	}
+	if settings.AITaggingTokensPerMinute > 0 {
+		config.TokensPerMinute = settings.AITaggingTokensPerMinute
+	}
+	if settings.AITaggingMaxAttempts > 0 {
+		config.MaxAttempts = settings.AITaggingMaxAttempts
+	}
+}
 
-	if config.BaseURL == "" || config.Model == "" {
-		return config, fmt.Errorf("AI tagging config unavailable")
+// applyAITaggingProviderDefaults 补全各提供方的默认地址与模型
+func applyAITaggingProviderDefaults(config *AITaggingConfig) {
+	switch config.Provider {
+	case AITaggingProviderOllama:
+		if config.BaseURL == "" {
+			config.BaseURL = defaultOllamaBaseURL
+		}
+	case AITaggingProviderAnthropic:
+		if config.BaseURL == "" {
+			config.BaseURL = defaultAnthropicBaseURL
+		}
+	case AITaggingProviderCLIP:
+		if config.Model == "" {
+			config.Model = defaultCLIPModel
+		}
+		if config.CLIPMinScore <= 0 {
+			config.CLIPMinScore = defaultCLIPMinScore
+		}
+	}
+}
+
+// validateAITaggingConfig 检查当前提供方的必填项；CLIP 未配置服务地址时要求本地运行时已准备好
+func validateAITaggingConfig(config AITaggingConfig) error {
+	var ok bool
+	switch config.Provider {
+	case AITaggingProviderOpenAI:
+		ok = config.BaseURL != "" && config.Model != ""
+	case AITaggingProviderOllama:
+		ok = config.BaseURL != "" && config.Model != ""
+	case AITaggingProviderAnthropic:
+		ok = config.BaseURL != "" && config.APIKey != "" && config.Model != ""
+	case AITaggingProviderCLIP:
+		ok = config.Model != "" && (config.BaseURL != "" || clipTaggingRuntimeReady(config.RuntimeDir))
+	default:
+		return fmt.Errorf("unknown AI tagging provider %q", config.Provider)
+	}
+	if !ok {
+		return fmt.Errorf("AI tagging config unavailable")
+	}
+	return nil
+}
+
+func normalizeAITaggingProvider(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return AITaggingProviderOpenAI
 	}
-	return config, nil
+	return value
 }
 
 func envInt(key string, fallback int) int {
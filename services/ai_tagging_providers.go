@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+const (
+	anthropicAPIVersion = "2023-06-01"
+	// Anthropic Messages 接口要求显式给出输出上限，标签 JSON 通常远小于该值
+	anthropicMaxTokens = 2048
+)
+
+// NewAITaggingClient 按 config.Provider 创建对应的客户端，未知提供方回退为 OpenAI 兼容接口
+func NewAITaggingClient(config AITaggingConfig) AITaggingAIClient {
+	switch config.Provider {
+	case AITaggingProviderOllama:
+		return NewOllamaAITaggingClient(config)
+	case AITaggingProviderAnthropic:
+		return NewAnthropicAITaggingClient(config)
+	case AITaggingProviderCLIP:
+		return NewCLIPAITaggingClient(config)
+	default:
+		return NewOpenAICompatibleAITaggingClient(config)
+	}
+}
+
+// OllamaAITaggingClient 调用 Ollama 原生 /api/chat，图片以裸 base64 放在消息的 images 字段
+type OllamaAITaggingClient struct {
+	config AITaggingConfig
+	client *http.Client
+}
+
+func NewOllamaAITaggingClient(config AITaggingConfig) AITaggingAIClient {
+	return &OllamaAITaggingClient{
+		config: config,
+		client: &http.Client{Timeout: aiTaggingRequestTimeout},
+	}
+}
+
+func (c *OllamaAITaggingClient) AnalyzeTags(ctx context.Context, req AITaggingRequest) ([]AITagSuggestion, error) {
+	respBody, err := postAITaggingJSON(ctx, c.client, ollamaChatURL(c.config.BaseURL), nil, c.buildRequest(req), req.Video.ID, c.config.Model)
+	if err != nil {
+		return nil, err
+	}
+	var parsed struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(respBody, &parsed); err != nil {
+		log.Printf("[AITagging] response parse failed video_id=%d err=%v body=%s", req.Video.ID, err, truncateLogSnippet(string(respBody), 4000))
+		return nil, err
+	}
+	if parsed.Error != "" {
+		return nil, fmt.Errorf("Ollama returned error: %s", parsed.Error)
+	}
+	return parseAITaggingResponseContent(req.Video.ID, parsed.Message.Content, respBody)
+}
+
+func (c *OllamaAITaggingClient) buildRequest(req AITaggingRequest) map[string]interface{} {
+	frames := req.Evidence.Frames
+	text := buildAITaggingPrompt(req, c.config.SubtitleCharLimit)
+	images := make([]string, 0, len(frames))
+	for _, frame := range frames {
+		text += "\n" + aiTaggingFrameNote(frame, len(frames))
+		images = append(images, dataURLBase64(frame.DataURL))
+	}
+	user := map[string]interface{}{"role": "user", "content": text}
+	if len(images) > 0 {
+		user["images"] = images
+	}
+	return map[string]interface{}{
+		"model": c.config.Model,
+		"messages": []map[string]interface{}{
+			{"role": "system", "content": aiTaggingSystemPrompt},
+			user,
+		},
+		"stream":  false,
+		"format":  "json",
+		"options": map[string]interface{}{"temperature": 0.1},
+	}
+}
+
+func ollamaChatURL(baseURL string) string {
+	base := strings.TrimRight(strings.TrimSpace(baseURL), "/")
+	if strings.HasSuffix(base, "/api/chat") {
+		return base
+	}
+	return strings.TrimSuffix(base, "/api") + "/api/chat"
+}
+
+// AnthropicAITaggingClient 调用 Anthropic Messages 接口，图片以 base64 image 块发送
+type AnthropicAITaggingClient struct {
+	config AITaggingConfig
+	client *http.Client
+}
+
+func NewAnthropicAITaggingClient(config AITaggingConfig) AITaggingAIClient {
+	return &AnthropicAITaggingClient{
+		config: config,
+		client: &http.Client{Timeout: aiTaggingRequestTimeout},
+	}
+}
+
+func (c *AnthropicAITaggingClient) AnalyzeTags(ctx context.Context, req AITaggingRequest) ([]AITagSuggestion, error) {
+	headers := map[string]string{
+		"x-api-key":         c.config.APIKey,
+		"anthropic-version": anthropicAPIVersion,
+	}
+	respBody, err := postAITaggingJSON(ctx, c.client, anthropicMessagesURL(c.config.BaseURL), headers, c.buildRequest(req), req.Video.ID, c.config.Model)
+	if err != nil {
+		return nil, err
+	}
+	var parsed struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+	}
+	if err := json.Unmarshal(respBody, &parsed); err != nil {
+		log.Printf("[AITagging] response parse failed video_id=%d err=%v body=%s", req.Video.ID, err, truncateLogSnippet(string(respBody), 4000))
+		return nil, err
+	}
+	var content strings.Builder
+	for _, block := range parsed.Content {
+		if block.Type == "text" {
+			content.WriteString(block.Text)
+		}
+	}
+	return parseAITaggingResponseContent(req.Video.ID, content.String(), respBody)
+}
+
+func (c *AnthropicAITaggingClient) buildRequest(req AITaggingRequest) map[string]interface{} {
+	frames := req.Evidence.Frames
+	blocks := make([]map[string]interface{}, 0, len(frames)*2+1)
+	blocks = append(blocks, map[string]interface{}{"type": "text", "text": buildAITaggingPrompt(req, c.config.SubtitleCharLimit)})
+	for _, frame := range frames {
+		blocks = append(blocks, map[string]interface{}{"type": "text", "text": aiTaggingFrameNote(frame, len(frames))})
+		blocks = append(blocks, map[string]interface{}{
+			"type": "image",
+			"source": map[string]string{
+				"type":       "base64",
+				"media_type": dataURLMimeType(frame.DataURL, frame.MimeType),
+				"data":       dataURLBase64(frame.DataURL),
+			},
+		})
+	}
+	return map[string]interface{}{
+		"model":       c.config.Model,
+		"max_tokens":  anthropicMaxTokens,
+		"system":      aiTaggingSystemPrompt,
+		"messages":    []map[string]interface{}{{"role": "user", "content": blocks}},
+		"temperature": 0.1,
+	}
+}
+
+func anthropicMessagesURL(baseURL string) string {
+	base := strings.TrimRight(strings.TrimSpace(baseURL), "/")
+	if strings.HasSuffix(base, "/messages") {
+		return base
+	}
+	if strings.HasSuffix(base, "/v1") {
+		return base + "/messages"
+	}
+	return base + "/v1/messages"
+}
+
+// dataURLBase64 返回 data URL 中的 base64 数据部分；不是 data URL 时原样返回
+func dataURLBase64(dataURL string) string {
+	if !strings.HasPrefix(dataURL, "data:") {
+		return dataURL
+	}
+	if idx := strings.Index(dataURL, ","); idx >= 0 {
+		return dataURL[idx+1:]
+	}
+	return dataURL
+}
+
+// dataURLMimeType 优先取 data URL 声明的类型，其次为 fallback，默认 image/jpeg
+func dataURLMimeType(dataURL, fallback string) string {
+	if rest, ok := strings.CutPrefix(dataURL, "data:"); ok {
+		if idx := strings.IndexAny(rest, ";,"); idx > 0 {
+			return rest[:idx]
+		}
+	}
+	if fallback != "" {
+		return fallback
+	}
+	return "image/jpeg"
+}
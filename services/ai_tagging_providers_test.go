@@ -0,0 +1,259 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"video-master/database"
+	"video-master/models"
+)
+
+func testAITaggingRequest(tags ...models.Tag) AITaggingRequest {
+	return AITaggingRequest{
+		Video:        models.Video{ID: 7, Name: "match.mp4", Path: "/tmp/match.mp4"},
+		ExistingTags: tags,
+		Evidence: AITaggingEvidence{Frames: []AITaggingFrame{
+			{MimeType: "image/jpeg", DataURL: "data:image/jpeg;base64,AAAA", Index: 1, Position: 1.5},
+			{MimeType: "image/jpeg", DataURL: "data:image/jpeg;base64,BBBB", Index: 2, Position: 9},
+		}},
+	}
+}
+
+func TestOllamaClientUsesNativeChatAPI(t *testing.T) {
+	var body map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("请求路径应为 /api/chat，实际 %s", r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("解析请求体失败: %v", err)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": map[string]string{"role": "assistant", "content": `{"suggestions":[{"label":"足球","confidence":"high","match_type":"existing_exact"}]}`},
+			"done":    true,
+		})
+	}))
+	defer srv.Close()
+
+	client := NewAITaggingClient(AITaggingConfig{Provider: AITaggingProviderOllama, BaseURL: srv.URL, Model: "llava"})
+	suggestions, err := client.AnalyzeTags(context.Background(), testAITaggingRequest(models.Tag{ID: 1, Name: "足球"}))
+	if err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	if len(suggestions) != 1 || suggestions[0].Label != "足球" {
+		t.Fatalf("解析结果不正确: %+v", suggestions)
+	}
+	if body["model"] != "llava" || body["stream"] != false || body["format"] != "json" {
+		t.Fatalf("Ollama 请求参数不正确: %+v", body)
+	}
+	messages := body["messages"].([]interface{})
+	user := messages[1].(map[string]interface{})
+	images := user["images"].([]interface{})
+	if len(images) != 2 || images[0] != "AAAA" {
+		t.Fatalf("图片应以去掉 data URL 前缀的 base64 发送: %v", images)
+	}
+	if !strings.Contains(user["content"].(string), "视频抽帧 2/2") {
+		t.Fatalf("提示词应包含抽帧说明: %s", user["content"])
+	}
+}
+
+func TestAnthropicClientUsesMessagesAPI(t *testing.T) {
+	var body map[string]interface{}
+	var apiKey, version string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			t.Errorf("请求路径应为 /v1/messages，实际 %s", r.URL.Path)
+		}
+		apiKey = r.Header.Get("x-api-key")
+		version = r.Header.Get("anthropic-version")
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("解析请求体失败: %v", err)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"content": []map[string]string{
+				{"type": "text", "text": "```json\n{\"suggestions\":[{\"label\":\"篮球\",\"confidence\":\"medium\",\"match_type\":\"existing_semantic\"}]}\n```"},
+			},
+		})
+	}))
+	defer srv.Close()
+
+	client := NewAITaggingClient(AITaggingConfig{Provider: AITaggingProviderAnthropic, BaseURL: srv.URL, APIKey: "sk-test", Model: "vision-model"})
+	suggestions, err := client.AnalyzeTags(context.Background(), testAITaggingRequest(models.Tag{ID: 1, Name: "篮球"}))
+	if err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	if len(suggestions) != 1 || suggestions[0].Label != "篮球" || suggestions[0].Confidence != "medium" {
+		t.Fatalf("解析结果不正确: %+v", suggestions)
+	}
+	if apiKey != "sk-test" || version != anthropicAPIVersion {
+		t.Fatalf("请求头不正确 api_key=%q version=%q", apiKey, version)
+	}
+	if body["system"] != aiTaggingSystemPrompt || body["max_tokens"] == nil {
+		t.Fatalf("应发送 system 与 max_tokens: %+v", body)
+	}
+	blocks := body["messages"].([]interface{})[0].(map[string]interface{})["content"].([]interface{})
+	if len(blocks) != 5 {
+		t.Fatalf("期望提示词 + 2×(帧说明、图片) 共 5 块，实际 %d", len(blocks))
+	}
+	source := blocks[2].(map[string]interface{})["source"].(map[string]interface{})
+	if source["type"] != "base64" || source["media_type"] != "image/jpeg" || source["data"] != "AAAA" {
+		t.Fatalf("图片块格式不正确: %+v", source)
+	}
+}
+
+func TestCLIPClientScoresExistingTagsViaService(t *testing.T) {
+	var payload clipClassifyRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/classify" {
+			t.Errorf("请求路径应为 /classify，实际 %s", r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("解析请求体失败: %v", err)
+		}
+		_ = json.NewEncoder(w).Encode(clipClassifyResponse{Scores: [][]float64{
+			{0.7, 0.2, 0.1},
+			{0.5, 0.4, 0.1},
+		}})
+	}))
+	defer srv.Close()
+
+	tags := []models.Tag{{ID: 1, Name: "运动"}, {ID: 2, Name: "足球", ParentID: 1}, {ID: 3, Name: "风景"}}
+	client := NewAITaggingClient(AITaggingConfig{Provider: AITaggingProviderCLIP, BaseURL: srv.URL, Model: "clip-test", CLIPMinScore: 0.2})
+	suggestions, err := client.AnalyzeTags(context.Background(), testAITaggingRequest(tags...))
+	if err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	if payload.Model != "clip-test" || len(payload.Texts) != 3 || len(payload.Images) != 2 || payload.Images[1] != "BBBB" {
+		t.Fatalf("CLIP 请求不正确: %+v", payload)
+	}
+	if !strings.Contains(payload.Texts[1], "足球") || strings.Contains(payload.Texts[1], "运动") {
+		t.Fatalf("描述文本应只使用标签名称: %q", payload.Texts[1])
+	}
+	if len(suggestions) != 2 {
+		t.Fatalf("平均概率低于阈值的标签不应成为候选: %+v", suggestions)
+	}
+	if suggestions[0].Label != "运动" || suggestions[0].Confidence != "high" {
+		t.Fatalf("多帧排名第一且概率高的标签应为 high: %+v", suggestions[0])
+	}
+	if suggestions[1].MatchedExistingName != "运动/足球" || suggestions[1].Confidence != "medium" {
+		t.Fatalf("子标签应以路径回填且为 medium: %+v", suggestions[1])
+	}
+
+	if _, err := client.AnalyzeTags(context.Background(), AITaggingRequest{ExistingTags: tags}); err == nil {
+		t.Fatalf("没有抽帧时 CLIP 应返回错误")
+	}
+}
+
+func TestCLIPSuggestionsPersistAsExistingTagCandidates(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	parent := createShortFeedTag(t, "运动")
+	child, err := (&TagService{}).CreateChildTag("足球", "#00aa00", parent.ID)
+	if err != nil {
+		t.Fatalf("创建子标签失败: %v", err)
+	}
+	video := models.Video{Name: "match.mp4", Path: "/tmp/match.mp4", Directory: "/tmp"}
+	if err := database.DB.Create(&video).Error; err != nil {
+		t.Fatalf("创建视频失败: %v", err)
+	}
+	tags := []models.Tag{parent, *child}
+	suggestions, err := clipSuggestions(clipTagLabels(tags), [][]float64{{0.1, 0.9}}, defaultCLIPMinScore)
+	if err != nil {
+		t.Fatalf("计算候选失败: %v", err)
+	}
+	svc := newTestAITaggingService(&fakeAITaggingClient{}, nil)
+	created, err := svc.persistSuggestions(video, tags, AITaggingEvidence{}, suggestions)
+	if err != nil || created != 1 {
+		t.Fatalf("保存候选失败 created=%d err=%v", created, err)
+	}
+	var candidate models.AITagCandidate
+	if err := database.DB.First(&candidate).Error; err != nil {
+		t.Fatalf("读取候选失败: %v", err)
+	}
+	if candidate.MatchedTagID == nil || *candidate.MatchedTagID != child.ID || candidate.SuggestedName != "足球" {
+		t.Fatalf("CLIP 候选应匹配到子标签: %+v", candidate)
+	}
+}
+
+func TestSettingsAITaggingConfigProviderSelectsProvider(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	t.Setenv(envAITaggingBaseURL, "http://env.example/v1")
+	t.Setenv(envAITaggingAPIKey, "env-key")
+	t.Setenv(envAITaggingModel, "env-model")
+	update := func(values map[string]interface{}) {
+		t.Helper()
+		if err := database.DB.Model(&models.Settings{}).Where("1 = 1").Updates(values).Error; err != nil {
+			t.Fatalf("更新设置失败: %v", err)
+		}
+	}
+
+	update(map[string]interface{}{"ai_tagging_provider": AITaggingProviderOllama, "ai_tagging_ollama_model": "llava"})
+	config, err := SettingsAITaggingConfigProvider{}.Load()
+	if err != nil || config.Provider != AITaggingProviderOllama || config.BaseURL != defaultOllamaBaseURL || config.Model != "llava" || config.APIKey != "" {
+		t.Fatalf("切换到 Ollama 后不应沿用环境变量的地址与 Key config=%+v err=%v", config, err)
+	}
+
+	update(map[string]interface{}{"ai_tagging_provider": AITaggingProviderAnthropic, "ai_tagging_anthropic_model": "vision-model"})
+	if _, err := (SettingsAITaggingConfigProvider{}).Load(); err == nil {
+		t.Fatalf("Anthropic 缺少 API Key 时配置应不可用")
+	}
+	update(map[string]interface{}{"ai_tagging_anthropic_api_key": "sk-db"})
+	config, err = SettingsAITaggingConfigProvider{}.Load()
+	if err != nil || config.BaseURL != defaultAnthropicBaseURL || config.APIKey != "sk-db" {
+		t.Fatalf("Anthropic 配置读取错误 config=%+v err=%v", config, err)
+	}
+
+	baseDir := t.TempDir()
+	update(map[string]interface{}{"ai_tagging_provider": AITaggingProviderCLIP})
+	if _, err := (SettingsAITaggingConfigProvider{BaseDir: baseDir}).Load(); err == nil {
+		t.Fatalf("本地 CLIP 运行时未准备时配置应不可用")
+	}
+	runtime := clipTaggingRuntime{dir: clipTaggingRuntimeDir(baseDir)}
+	mustCreateFile(t, runtime.venvPython())
+	mustCreateFile(t, filepath.Join(runtime.dir, clipReadyMarkerName))
+	config, err = SettingsAITaggingConfigProvider{BaseDir: baseDir}.Load()
+	if err != nil || config.Model != defaultCLIPModel || config.CLIPMinScore != defaultCLIPMinScore || config.RuntimeDir != runtime.dir {
+		t.Fatalf("CLIP 应使用默认模型与本地运行时 config=%+v err=%v", config, err)
+	}
+	if _, ok := NewAITaggingClient(config).(*CLIPAITaggingClient); !ok {
+		t.Fatalf("应按提供方创建 CLIP 客户端")
+	}
+
+	if err := os.RemoveAll(runtime.dir); err != nil {
+		t.Fatalf("删除运行时失败: %v", err)
+	}
+	update(map[string]interface{}{"ai_tagging_clip_base_url": "http://127.0.0.1:8765"})
+	if _, err := (SettingsAITaggingConfigProvider{BaseDir: baseDir}).Load(); err != nil {
+		t.Fatalf("配置了 CLIP 服务地址时不需要本地运行时: %v", err)
+	}
+
+	if err := (&SettingsService{}).UpdateSettings(models.Settings{AITaggingProvider: "unknown"}); err == nil {
+		t.Fatalf("未知提供方应被拒绝")
+	}
+}
+
+func TestEmptyAITaggingProviderSettingFollowsEnv(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	t.Setenv(envAITaggingProvider, AITaggingProviderOllama)
+	t.Setenv(envAITaggingModel, "env-llava")
+
+	var settings models.Settings
+	if err := database.DB.First(&settings).Error; err != nil || settings.AITaggingProvider != "" {
+		t.Fatalf("新建设置的提供方应为空以沿用环境变量 provider=%q err=%v", settings.AITaggingProvider, err)
+	}
+	config, err := SettingsAITaggingConfigProvider{}.Load()
+	if err != nil || config.Provider != AITaggingProviderOllama || config.Model != "env-llava" {
+		t.Fatalf("提供方为空时应使用环境变量 config=%+v err=%v", config, err)
+	}
+
+	if err := (&SettingsService{}).UpdateSettings(models.Settings{AITaggingProvider: " "}); err != nil {
+		t.Fatalf("空提供方应允许保存: %v", err)
+	}
+	if err := database.DB.First(&settings).Error; err != nil || settings.AITaggingProvider != "" {
+		t.Fatalf("空提供方应原样保存 provider=%q err=%v", settings.AITaggingProvider, err)
+	}
+}
@@ -0,0 +1,334 @@
+package services
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+	"video-master/database"
+	"video-master/models"
+)
+
+// AI 打标任务队列：后台按配置的并发数处理待打标视频，请求受每分钟请求数与预估 token 预算限制；
+// 失败后按 AttemptCount 指数退避重试，连续失败达到 MaxAttempts 次进入死信（dead_letter），只能手动重试。
+// 暂停只影响后台队列，手动触发的 ProcessVideo 不受影响；暂停状态不持久化，重启后恢复运行。
+
+const (
+	maxAITaggingConcurrency = 8
+	// 一批处理完仍有积压时，到下一批之前的间隔
+	aiTaggingQueueBusyInterval = 2 * time.Second
+	aiTaggingRetryBaseDelay    = time.Minute
+	aiTaggingRetryMaxDelay     = 6 * time.Hour
+	aiTaggingRateWindow        = time.Minute
+	// 预估 token：提示词固定部分 + 每张抽帧 + 字幕与标签库字符数
+	aiTaggingPromptBaseTokens = 1200
+	aiTaggingFrameTokens      = 800
+)
+
+// AITaggingQueueStatus 队列深度与当前生效的队列配置
+type AITaggingQueueStatus struct {
+	Paused            bool       `json:"paused"`
+	Running           int        `json:"running"`       // 正在请求模型的视频数
+	Queued            int64      `json:"queued"`        // 现在即可处理的视频数
+	WaitingRetry      int64      `json:"waiting_retry"` // 失败后处于退避等待的视频数
+	DeadLetter        int64      `json:"dead_letter"`   // 重试次数用尽的视频数
+	NextRetryAt       *time.Time `json:"next_retry_at,omitempty" ts_type:"string"`
+	Concurrency       int        `json:"concurrency"`
+	RequestsPerMinute int        `json:"requests_per_minute"`
+	TokensPerMinute   int        `json:"tokens_per_minute"`
+	MaxAttempts       int        `json:"max_attempts"`
+}
+
+// runWorkerOnce 取一批可处理的视频并发处理，返回到下一轮的等待时间
+func (s *AITaggingService) runWorkerOnce(ctx context.Context) time.Duration {
+	if s.paused.Load() {
+		return aiTaggingWorkerInterval
+	}
+	config, err := s.configProvider.Load()
+	if err != nil {
+		log.Printf("[AITagging] config unavailable; background worker idle err=%v", err)
+		return aiTaggingWorkerInterval
+	}
+	concurrency := min(positiveOrDefault(config.Concurrency, defaultAITaggingConcurrency), maxAITaggingConcurrency)
+	batchSize := max(positiveOrDefault(config.StartupBatchSize, defaultAITaggingStartupBatchSize), concurrency)
+	log.Printf("[AITagging] worker config base_url=%q model=%q frame_count=%d subtitle_char_limit=%d batch_size=%d concurrency=%d rpm=%d tpm=%d max_attempts=%d api_key_empty=%v",
+		config.BaseURL,
+		config.Model,
+		config.FrameCount,
+		config.SubtitleCharLimit,
+		batchSize,
+		concurrency,
+		config.RequestsPerMinute,
+		config.TokensPerMinute,
+		config.MaxAttempts,
+		strings.TrimSpace(config.APIKey) == "",
+	)
+	videos, err := s.findUntaggedVideos(batchSize)
+	if err != nil {
+		log.Printf("[AITagging] find untagged videos failed: %v", err)
+		return aiTaggingWorkerInterval
+	}
+
+	jobs := make(chan models.Video)
+	var wg sync.WaitGroup
+	for i := 0; i < min(concurrency, len(videos)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for video := range jobs {
+				s.running.Add(1)
+				if err := s.processVideoWithConfig(ctx, video, config); err != nil {
+					log.Printf("[AITagging] process video id=%d failed: %v", video.ID, err)
+				}
+				s.running.Add(-1)
+			}
+		}()
+	}
+	dispatched := 0
+	for _, video := range videos {
+		if ctx.Err() != nil || s.paused.Load() {
+			break
+		}
+		jobs <- video
+		dispatched++
+	}
+	close(jobs)
+	wg.Wait()
+
+	if ctx.Err() != nil || s.paused.Load() {
+		return aiTaggingWorkerInterval
+	}
+	if len(videos) == batchSize && dispatched == len(videos) {
+		return aiTaggingQueueBusyInterval
+	}
+	return s.nextRetryDelay()
+}
+
+// nextRetryDelay 到最早一个退避中的视频可重试的时间，最长 aiTaggingWorkerInterval
+func (s *AITaggingService) nextRetryDelay() time.Duration {
+	next, err := s.nextRetryAt()
+	if err != nil || next == nil {
+		return aiTaggingWorkerInterval
+	}
+	return min(max(next.Sub(s.now()), aiTaggingQueueBusyInterval), aiTaggingWorkerInterval)
+}
+
+func (s *AITaggingService) nextRetryAt() (*time.Time, error) {
+	var states []models.AITaggingState
+	if err := database.DB.Where("status = ? AND next_attempt_at > ?", models.AITaggingStateStatusFailed, s.now()).
+		Order("next_attempt_at").Limit(1).Find(&states).Error; err != nil {
+		return nil, err
+	}
+	if len(states) == 0 {
+		return nil, nil
+	}
+	return states[0].NextAttemptAt, nil
+}
+
+// recoverInterruptedJobs 上次退出时仍在处理中的视频重新排队
+func (s *AITaggingService) recoverInterruptedJobs() {
+	result := database.DB.Model(&models.AITaggingState{}).
+		Where("status = ?", models.AITaggingStateStatusProcessing).
+		Update("status", models.AITaggingStateStatusPending)
+	if result.Error != nil {
+		log.Printf("[AITagging] recover interrupted jobs failed: %v", result.Error)
+		return
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("[AITagging] requeued interrupted jobs count=%d", result.RowsAffected)
+	}
+}
+
+// markFailed 记录失败：未达到最大尝试次数时按指数退避安排下次重试，否则进入死信
+func (s *AITaggingService) markFailed(videoID uint, fingerprint string, cause error, config AITaggingConfig) error {
+	var state models.AITaggingState
+	if err := database.DB.Where("video_id = ?", videoID).First(&state).Error; err != nil {
+		return err
+	}
+	now := s.now()
+	updates := map[string]interface{}{
+		"skip_reason":       "",
+		"last_error":        cause.Error(),
+		"last_processed_at": &now,
+	}
+	if fingerprint != "" {
+		updates["evidence_fingerprint"] = fingerprint
+	}
+	if maxAttempts := positiveOrDefault(config.MaxAttempts, defaultAITaggingMaxAttempts); state.AttemptCount >= maxAttempts {
+		updates["status"] = models.AITaggingStateStatusDeadLetter
+		updates["next_attempt_at"] = nil
+		log.Printf("[AITagging] dead letter video_id=%d attempts=%d err=%v", videoID, state.AttemptCount, cause)
+	} else {
+		next := now.Add(aiTaggingRetryDelay(state.AttemptCount))
+		updates["status"] = models.AITaggingStateStatusFailed
+		updates["next_attempt_at"] = &next
+		log.Printf("[AITagging] retry scheduled video_id=%d attempts=%d next_attempt_at=%s", videoID, state.AttemptCount, next.Format(time.RFC3339))
+	}
+	return database.DB.Model(&state).Updates(updates).Error
+}
+
+// aiTaggingRetryDelay 第 attempt 次失败后的退避时间：1 分钟起每次翻倍，最长 6 小时
+func aiTaggingRetryDelay(attempt int) time.Duration {
+	delay := aiTaggingRetryBaseDelay
+	for i := 1; i < attempt && delay < aiTaggingRetryMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, aiTaggingRetryMaxDelay)
+}
+
+// PauseQueue 暂停后台队列；正在处理的视频会继续完成
+func (s *AITaggingService) PauseQueue() {
+	s.paused.Store(true)
+	log.Printf("[AITagging] queue paused")
+}
+
+// ResumeQueue 恢复后台队列并立即开始下一轮
+func (s *AITaggingService) ResumeQueue() {
+	s.paused.Store(false)
+	log.Printf("[AITagging] queue resumed")
+	s.wakeWorker()
+}
+
+func (s *AITaggingService) wakeWorker() {
+	s.workerMu.Lock()
+	wake := s.workerWake
+	s.workerMu.Unlock()
+	if wake == nil {
+		return
+	}
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
+
+// RetryDeadLetters 把全部死信视频重新排队，重试次数清零
+func (s *AITaggingService) RetryDeadLetters() (int64, error) {
+	result := database.DB.Model(&models.AITaggingState{}).
+		Where("status = ?", models.AITaggingStateStatusDeadLetter).
+		Updates(map[string]interface{}{
+			"status":               models.AITaggingStateStatusPending,
+			"evidence_fingerprint": "",
+			"last_error":           "",
+			"attempt_count":        0,
+			"next_attempt_at":      nil,
+		})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected > 0 {
+		s.wakeWorker()
+	}
+	return result.RowsAffected, nil
+}
+
+// QueueStatus 返回队列深度：可立即处理、退避等待、死信与正在处理的数量
+func (s *AITaggingService) QueueStatus() (*AITaggingQueueStatus, error) {
+	config, _ := s.configProvider.Load()
+	status := &AITaggingQueueStatus{
+		Paused:            s.paused.Load(),
+		Running:           int(s.running.Load()),
+		Concurrency:       min(positiveOrDefault(config.Concurrency, defaultAITaggingConcurrency), maxAITaggingConcurrency),
+		RequestsPerMinute: config.RequestsPerMinute,
+		TokensPerMinute:   config.TokensPerMinute,
+		MaxAttempts:       positiveOrDefault(config.MaxAttempts, defaultAITaggingMaxAttempts),
+	}
+	if err := s.queuedVideosQuery().Count(&status.Queued).Error; err != nil {
+		return nil, err
+	}
+	if err := database.DB.Model(&models.AITaggingState{}).
+		Where("status = ? AND next_attempt_at > ?", models.AITaggingStateStatusFailed, s.now()).
+		Count(&status.WaitingRetry).Error; err != nil {
+		return nil, err
+	}
+	if err := database.DB.Model(&models.AITaggingState{}).
+		Where("status = ?", models.AITaggingStateStatusDeadLetter).
+		Count(&status.DeadLetter).Error; err != nil {
+		return nil, err
+	}
+	next, err := s.nextRetryAt()
+	if err != nil {
+		return nil, err
+	}
+	status.NextRetryAt = next
+	return status, nil
+}
+
+// estimateAITaggingTokens 粗略估算一次请求消耗的 token，用于每分钟 token 预算
+func estimateAITaggingTokens(req AITaggingRequest) int {
+	tokens := aiTaggingPromptBaseTokens + len(req.Evidence.Frames)*aiTaggingFrameTokens
+	tokens += utf8.RuneCountInString(req.Evidence.SubtitleText)
+	tokens += utf8.RuneCountInString(formatExistingTagLibrary(req.ExistingTags))
+	return tokens
+}
+
+// aiTaggingRateLimiter 一分钟滑动窗口内的请求数与 token 预算
+type aiTaggingRateLimiter struct {
+	mu      sync.Mutex
+	entries []aiTaggingRateEntry
+}
+
+type aiTaggingRateEntry struct {
+	at     time.Time
+	tokens int
+}
+
+// reserve 预算允许时登记本次请求并返回 0，否则返回需要等待的时长（不登记）。
+// 单次请求超过整个 token 预算时只要求窗口内没有其他请求，避免永远等待
+func (l *aiTaggingRateLimiter) reserve(now time.Time, tokens, rpm, tpm int) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	cutoff := now.Add(-aiTaggingRateWindow)
+	kept := l.entries[:0]
+	used := 0
+	for _, entry := range l.entries {
+		if entry.at.After(cutoff) {
+			kept = append(kept, entry)
+			used += entry.tokens
+		}
+	}
+	l.entries = kept
+
+	var wait time.Duration
+	if rpm > 0 && len(l.entries) >= rpm {
+		wait = l.entries[len(l.entries)-rpm].at.Add(aiTaggingRateWindow).Sub(now)
+	}
+	if tpm > 0 {
+		need := min(tokens, tpm)
+		remaining := used
+		for _, entry := range l.entries {
+			if remaining+need <= tpm {
+				break
+			}
+			remaining -= entry.tokens
+			wait = max(wait, entry.at.Add(aiTaggingRateWindow).Sub(now))
+		}
+	}
+	if wait > 0 {
+		return wait
+	}
+	l.entries = append(l.entries, aiTaggingRateEntry{at: now, tokens: tokens})
+	return 0
+}
+
+// wait 阻塞直到预算允许本次请求或 ctx 结束；rpm 与 tpm 都为 0 时不限制
+func (l *aiTaggingRateLimiter) wait(ctx context.Context, now func() time.Time, tokens, rpm, tpm int) error {
+	if rpm <= 0 && tpm <= 0 {
+		return nil
+	}
+	for {
+		delay := l.reserve(now(), tokens, rpm, tpm)
+		if delay <= 0 {
+			return nil
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
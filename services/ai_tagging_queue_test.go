@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+	"video-master/database"
+	"video-master/models"
+)
+
+type concurrentFakeAITaggingClient struct {
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+	calls       int
+	delay       time.Duration
+}
+
+func (c *concurrentFakeAITaggingClient) AnalyzeTags(ctx context.Context, req AITaggingRequest) ([]AITagSuggestion, error) {
+	c.mu.Lock()
+	c.calls++
+	c.inFlight++
+	c.maxInFlight = max(c.maxInFlight, c.inFlight)
+	c.mu.Unlock()
+	time.Sleep(c.delay)
+	c.mu.Lock()
+	c.inFlight--
+	c.mu.Unlock()
+	return nil, nil
+}
+
+func TestAITaggingRetryDelayBackoff(t *testing.T) {
+	cases := map[int]time.Duration{
+		0:  time.Minute,
+		1:  time.Minute,
+		2:  2 * time.Minute,
+		4:  8 * time.Minute,
+		30: aiTaggingRetryMaxDelay,
+	}
+	for attempt, want := range cases {
+		if got := aiTaggingRetryDelay(attempt); got != want {
+			t.Fatalf("attempt=%d 退避时间应为 %s，实际 %s", attempt, want, got)
+		}
+	}
+}
+
+func TestAITaggingRateLimiterBudgets(t *testing.T) {
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	var rpm aiTaggingRateLimiter
+	if rpm.reserve(start, 0, 2, 0) != 0 || rpm.reserve(start, 0, 2, 0) != 0 {
+		t.Fatalf("预算内的请求应立即放行")
+	}
+	if wait := rpm.reserve(start.Add(10*time.Second), 0, 2, 0); wait != 50*time.Second {
+		t.Fatalf("超过每分钟请求数应等待最早请求滑出窗口，实际 %s", wait)
+	}
+	if wait := rpm.reserve(start.Add(61*time.Second), 0, 2, 0); wait != 0 {
+		t.Fatalf("窗口滑过后应放行，实际 %s", wait)
+	}
+
+	var tpm aiTaggingRateLimiter
+	if tpm.reserve(start, 600, 0, 1000) != 0 {
+		t.Fatalf("token 预算内应立即放行")
+	}
+	if wait := tpm.reserve(start.Add(time.Second), 600, 0, 1000); wait != 59*time.Second {
+		t.Fatalf("超过 token 预算应等待，实际 %s", wait)
+	}
+	var large aiTaggingRateLimiter
+	if large.reserve(start, 5000, 0, 1000) != 0 {
+		t.Fatalf("窗口为空时超大请求也应放行，避免永远等待")
+	}
+}
+
+func TestAITaggingFailureBackoffAndDeadLetter(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	video := models.Video{Name: "flaky.mp4", Path: "/tmp/flaky.mp4", Directory: "/tmp"}
+	if err := database.DB.Create(&video).Error; err != nil {
+		t.Fatalf("创建视频失败: %v", err)
+	}
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	svc := newTestAITaggingService(&fakeAITaggingClient{err: errors.New("503 unavailable")}, fakeAITaggingConfigProvider{config: AITaggingConfig{
+		BaseURL:          "http://127.0.0.1:9999/v1",
+		Model:            "test-model",
+		StartupBatchSize: 10,
+		MaxAttempts:      2,
+	}})
+	svc.now = func() time.Time { return now }
+	queued := func() int {
+		t.Helper()
+		videos, err := svc.findUntaggedVideos(10)
+		if err != nil {
+			t.Fatalf("查询队列失败: %v", err)
+		}
+		return len(videos)
+	}
+
+	if err := svc.ProcessVideo(context.Background(), video.ID); err != nil {
+		t.Fatalf("处理视频失败: %v", err)
+	}
+	loadState := func() models.AITaggingState {
+		t.Helper()
+		var state models.AITaggingState
+		if err := database.DB.Where("video_id = ?", video.ID).First(&state).Error; err != nil {
+			t.Fatalf("读取状态失败: %v", err)
+		}
+		return state
+	}
+	state := loadState()
+	if state.Status != models.AITaggingStateStatusFailed || state.AttemptCount != 1 || state.NextAttemptAt == nil || !state.NextAttemptAt.Equal(now.Add(time.Minute)) {
+		t.Fatalf("首次失败应在 1 分钟后重试: %+v", state)
+	}
+	if queued() != 0 {
+		t.Fatalf("退避期间不应重新入队")
+	}
+	status, err := svc.QueueStatus()
+	if err != nil || status.WaitingRetry != 1 || status.Queued != 0 || status.NextRetryAt == nil {
+		t.Fatalf("队列状态应显示退避中的视频 status=%+v err=%v", status, err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if queued() != 1 {
+		t.Fatalf("退避结束后应重新入队")
+	}
+	if err := svc.ProcessVideo(context.Background(), video.ID); err != nil {
+		t.Fatalf("处理视频失败: %v", err)
+	}
+	state = loadState()
+	if state.Status != models.AITaggingStateStatusDeadLetter || state.AttemptCount != 2 || state.NextAttemptAt != nil {
+		t.Fatalf("达到最大尝试次数应进入死信: %+v", state)
+	}
+	now = now.Add(24 * time.Hour)
+	if queued() != 0 {
+		t.Fatalf("死信不应自动重试")
+	}
+	if summary, _ := svc.StatusSummary(); summary.DeadLetter != 1 {
+		t.Fatalf("状态汇总应统计死信: %+v", summary)
+	}
+
+	retried, err := svc.RetryDeadLetters()
+	if err != nil || retried != 1 {
+		t.Fatalf("重试死信失败 retried=%d err=%v", retried, err)
+	}
+	state = loadState()
+	if state.Status != models.AITaggingStateStatusPending || state.AttemptCount != 0 || queued() != 1 {
+		t.Fatalf("重试死信后应清零并重新入队: %+v", state)
+	}
+}
+
+func TestAITaggingQueueRunsConcurrentlyAndPauses(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	sqlDB, err := database.DB.DB()
+	if err != nil {
+		t.Fatalf("获取连接池失败: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	for _, name := range []string{"a.mp4", "b.mp4", "c.mp4", "d.mp4", "e.mp4", "f.mp4"} {
+		if err := database.DB.Create(&models.Video{Name: name, Path: "/tmp/" + name, Directory: "/tmp"}).Error; err != nil {
+			t.Fatalf("创建视频失败: %v", err)
+		}
+	}
+	client := &concurrentFakeAITaggingClient{delay: 100 * time.Millisecond}
+	svc := newTestAITaggingService(nil, fakeAITaggingConfigProvider{config: AITaggingConfig{
+		BaseURL:          "http://127.0.0.1:9999/v1",
+		Model:            "test-model",
+		StartupBatchSize: 10,
+		Concurrency:      3,
+	}})
+	svc.clientFactory = func(AITaggingConfig) AITaggingAIClient { return client }
+
+	svc.PauseQueue()
+	if delay := svc.runWorkerOnce(context.Background()); delay != aiTaggingWorkerInterval || client.calls != 0 {
+		t.Fatalf("暂停时不应处理视频 delay=%s calls=%d", delay, client.calls)
+	}
+	status, err := svc.QueueStatus()
+	if err != nil || !status.Paused || status.Queued != 6 || status.Concurrency != 3 {
+		t.Fatalf("暂停时的队列状态错误 status=%+v err=%v", status, err)
+	}
+
+	svc.ResumeQueue()
+	svc.runWorkerOnce(context.Background())
+	if client.calls != 6 || client.maxInFlight != 3 {
+		t.Fatalf("应以 3 个并发处理全部视频 calls=%d max_in_flight=%d", client.calls, client.maxInFlight)
+	}
+	if status, _ := svc.QueueStatus(); status.Paused || status.Queued != 0 || status.Running != 0 {
+		t.Fatalf("处理完成后队列应为空: %+v", status)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"log"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 	"video-master/database"
 	"video-master/models"
@@ -24,12 +25,19 @@ type AITaggingService struct {
 	now            func() time.Time
 	workerMu       sync.Mutex
 	workerCancel   context.CancelFunc
+	workerWake     chan struct{}
+	paused         atomic.Bool
+	running        atomic.Int32
+	limiter        aiTaggingRateLimiter
+	clipRuntimeDir string
 }
 
-func NewAITaggingService() *AITaggingService {
+// NewAITaggingService 创建 AI 标签服务；dataDir 用于存放本地 CLIP 运行时
+func NewAITaggingService(dataDir string) *AITaggingService {
 	return &AITaggingService{
-		configProvider: SettingsAITaggingConfigProvider{},
-		clientFactory:  NewOpenAICompatibleAITaggingClient,
+		configProvider: SettingsAITaggingConfigProvider{BaseDir: dataDir},
+		clientFactory:  NewAITaggingClient,
+		clipRuntimeDir: clipTaggingRuntimeDir(dataDir),
 		extractor:      NewAITaggingExtractor(),
 		now:            time.Now,
 	}
@@ -46,7 +54,8 @@ func (s *AITaggingService) Start(ctx context.Context) {
 	}
 	workerCtx, cancel := context.WithCancel(ctx)
 	s.workerCancel = cancel
-	go s.workerLoop(workerCtx)
+	s.workerWake = make(chan struct{}, 1)
+	go s.workerLoop(workerCtx, s.workerWake)
 }
 
 func (s *AITaggingService) Stop() {
@@ -58,52 +67,21 @@ func (s *AITaggingService) Stop() {
 	if s.workerCancel != nil {
 		s.workerCancel()
 		s.workerCancel = nil
+		s.workerWake = nil
 	}
 }
 
-func (s *AITaggingService) workerLoop(ctx context.Context) {
-	s.runWorkerOnce(ctx)
-	ticker := time.NewTicker(aiTaggingWorkerInterval)
-	defer ticker.Stop()
+func (s *AITaggingService) workerLoop(ctx context.Context, wake <-chan struct{}) {
+	s.recoverInterruptedJobs()
 	for {
+		timer := time.NewTimer(s.runWorkerOnce(ctx))
 		select {
 		case <-ctx.Done():
+			timer.Stop()
 			return
-		case <-ticker.C:
-			s.runWorkerOnce(ctx)
-		}
-	}
-}
-
-func (s *AITaggingService) runWorkerOnce(ctx context.Context) {
-	config, err := s.configProvider.Load()
-	if err != nil {
-		log.Printf("[AITagging] config unavailable; background worker idle err=%v", err)
-		return
-	}
-	log.Printf("[AITagging] worker config base_url=%q model=%q frame_count=%d subtitle_char_limit=%d startup_batch_size=%d api_key_empty=%v",
-		config.BaseURL,
-		config.Model,
-		config.FrameCount,
-		config.SubtitleCharLimit,
-		config.StartupBatchSize,
-		strings.TrimSpace(config.APIKey) == "",
-	)
-	batchSize := config.StartupBatchSize
-	if batchSize <= 0 {
-		batchSize = defaultAITaggingStartupBatchSize
-	}
-	videos, err := s.findUntaggedVideos(batchSize)
-	if err != nil {
-		log.Printf("[AITagging] find untagged videos failed: %v", err)
-		return
-	}
-	for _, video := range videos {
-		if ctx.Err() != nil {
-			return
-		}
-		if err := s.processVideoWithConfig(ctx, video, config); err != nil {
-			log.Printf("[AITagging] process video id=%d failed: %v", video.ID, err)
+		case <-wake:
+			timer.Stop()
+		case <-timer.C:
 		}
 	}
 }
@@ -132,7 +110,10 @@ func (s *AITaggingService) processVideoWithConfig(ctx context.Context, video mod
 		config.SubtitleCharLimit,
 		strings.TrimSpace(config.APIKey) == "",
 	)
-	if len(video.Tags) > 0 {
+	// 规则自动建立的标签不算已打标签，视频仍交给 AI 分析
+	if tagged, err := hasTagsBesidesRuleLinks(video.ID); err != nil {
+		return err
+	} else if tagged {
 		log.Printf("[AITagging] skip already tagged video_id=%d", video.ID)
 		return s.markState(video.ID, models.AITaggingStateStatusSkipped, "already_tagged", "", "")
 	}
@@ -140,6 +121,10 @@ func (s *AITaggingService) processVideoWithConfig(ctx context.Context, video mod
 	if err != nil {
 		return err
 	}
+	tagUsage, err := tagVideoCounts()
+	if err != nil {
+		return err
+	}
 	evidence := s.extractor.Collect(ctx, video, config)
 	log.Printf("[AITagging] evidence video_id=%d subtitle_len=%d frames=%d warnings=%q",
 		video.ID,
@@ -153,24 +138,30 @@ func (s *AITaggingService) processVideoWithConfig(ctx context.Context, video mod
 	} else if skip {
 		return nil
 	}
+	request := AITaggingRequest{
+		Video:        video,
+		ExistingTags: existingTags,
+		TagUsage:     tagUsage,
+		Evidence:     evidence,
+	}
+	// 先占用请求预算再标记处理中，等待期间被取消不计入重试次数
+	if err := s.limiter.wait(ctx, s.now, estimateAITaggingTokens(request), config.RequestsPerMinute, config.TokensPerMinute); err != nil {
+		return err
+	}
 	if err := s.setProcessing(video.ID, fingerprint); err != nil {
 		return err
 	}
 	client := s.clientFactory(config)
-	suggestions, err := client.AnalyzeTags(ctx, AITaggingRequest{
-		Video:        video,
-		ExistingTags: existingTags,
-		Evidence:     evidence,
-	})
+	suggestions, err := client.AnalyzeTags(ctx, request)
 	if err != nil {
 		log.Printf("[AITagging] analyze failed video_id=%d err=%v", video.ID, err)
-		return s.markState(video.ID, models.AITaggingStateStatusFailed, "", fingerprint, err.Error())
+		return s.markFailed(video.ID, fingerprint, err, config)
 	}
 	log.Printf("[AITagging] analyze succeeded video_id=%d suggestions=%d", video.ID, len(suggestions))
 	created, err := s.persistSuggestions(video, existingTags, evidence, suggestions)
 	if err != nil {
 		log.Printf("[AITagging] persist failed video_id=%d err=%v", video.ID, err)
-		return s.markState(video.ID, models.AITaggingStateStatusFailed, "", fingerprint, err.Error())
+		return s.markFailed(video.ID, fingerprint, err, config)
 	}
 	if created == 0 {
 		log.Printf("[AITagging] skipped no high/medium confidence video_id=%d", video.ID)
@@ -182,29 +173,43 @@ func (s *AITaggingService) processVideoWithConfig(ctx context.Context, video mod
 
 func (s *AITaggingService) findUntaggedVideos(limit int) ([]models.Video, error) {
 	var videos []models.Video
-	err := database.DB.Model(&models.Video{}).
+	err := s.queuedVideosQuery().
 		Preload("Tags").
-		Where("is_stale = ?", false).
-		Where("NOT EXISTS (SELECT 1 FROM video_tags WHERE video_tags.video_id = videos.id)").
+		Order("id").
+		Limit(limit).
+		Find(&videos).Error
+	return videos, err
+}
+
+// queuedVideosQuery 可立即处理的视频：未打标签（规则自动建立的关联不算）、在线、没有待审候选，
+// 且没有处理中/已完成/已跳过/死信状态，失败的视频需等到退避时间 next_attempt_at 之后
+func (s *AITaggingService) queuedVideosQuery() *gorm.DB {
+	return database.DB.Model(&models.Video{}).
+		Where("is_stale = ? AND is_offline = ?", false, false).
+		Where(`NOT EXISTS (
+			SELECT 1 FROM video_tags
+			WHERE video_tags.video_id = videos.id
+				AND NOT EXISTS (
+					SELECT 1 FROM tag_rule_links
+					WHERE tag_rule_links.video_id = video_tags.video_id AND tag_rule_links.tag_id = video_tags.tag_id
+				)
+		)`).
 		Where("NOT EXISTS (SELECT 1 FROM ai_tag_candidates WHERE ai_tag_candidates.video_id = videos.id AND ai_tag_candidates.status = ?)", models.AITagCandidateStatusPending).
 		Where(`NOT EXISTS (
 			SELECT 1 FROM ai_tagging_states
 			WHERE ai_tagging_states.video_id = videos.id
-				AND ai_tagging_states.status IN ?
+				AND (ai_tagging_states.status IN ? OR ai_tagging_states.next_attempt_at > ?)
 		)`, []string{
 			models.AITaggingStateStatusProcessing,
 			models.AITaggingStateStatusCompleted,
 			models.AITaggingStateStatusSkipped,
-		}).
-		Order("id").
-		Limit(limit).
-		Find(&videos).Error
-	return videos, err
+			models.AITaggingStateStatusDeadLetter,
+		}, s.now())
 }
 
 func (s *AITaggingService) loadActiveTags() ([]models.Tag, error) {
 	var tags []models.Tag
-	if err := database.DB.Order("id").Find(&tags).Error; err != nil {
+	if err := database.DB.Preload("Aliases").Order("id").Find(&tags).Error; err != nil {
 		return nil, err
 	}
 	return tags, nil
@@ -264,6 +269,7 @@ func (s *AITaggingService) setProcessing(videoID uint, fingerprint string) error
 			"attempt_count":        state.AttemptCount + 1,
 			"last_error":           "",
 			"last_processed_at":    &now,
+			"next_attempt_at":      nil,
 		}).Error
 	})
 }
@@ -299,10 +305,7 @@ func (s *AITaggingService) markState(videoID uint, status, skipReason, fingerpri
 }
 
 func (s *AITaggingService) persistSuggestions(video models.Video, tags []models.Tag, evidence AITaggingEvidence, suggestions []AITagSuggestion) (int, error) {
-	tagsByName := make(map[string]models.Tag, len(tags))
-	for _, tag := range tags {
-		tagsByName[normalizeAITagName(tag.Name)] = tag
-	}
+	tagsByName := buildAITagLookup(tags)
 	created := 0
 	for _, suggestion := range suggestions {
 		confidence := normalizeAIConfidence(suggestion.Confidence)
@@ -474,22 +477,29 @@ func (s *AITaggingService) ApproveCandidate(candidateID uint) (*AITaggingReviewI
 	return &item, nil
 }
 
-func (s *AITaggingService) hasManualOfficialTagsInTx(tx *gorm.DB, videoID uint) (bool, error) {
-	var officialCount int64
-	if err := tx.Table("video_tags").Where("video_id = ?", videoID).Count(&officialCount).Error; err != nil {
+// hasTagsBesidesRuleLinks 视频是否有规则自动建立之外的标签（手动或 AI 审批）
+func hasTagsBesidesRuleLinks(videoID uint) (bool, error) {
+	var count int64
+	if err := database.DB.Table("video_tags AS vt").
+		Where("vt.video_id = ?", videoID).
+		Where("NOT EXISTS (SELECT 1 FROM tag_rule_links AS rl WHERE rl.video_id = vt.video_id AND rl.tag_id = vt.tag_id)").
+		Count(&count).Error; err != nil {
 		return false, err
 	}
-	if officialCount == 0 {
-		return false, nil
-	}
-	var aiApprovedCount int64
+	return count > 0, nil
+}
+
+// hasManualOfficialTagsInTx 视频是否有手动添加的标签；AI 审批与规则自动建立的关联不算
+func (s *AITaggingService) hasManualOfficialTagsInTx(tx *gorm.DB, videoID uint) (bool, error) {
+	var manualCount int64
 	if err := tx.Table("video_tags AS vt").
-		Joins("INNER JOIN ai_tag_approval_records AS ar ON ar.video_id = vt.video_id AND ar.tag_id = vt.tag_id").
 		Where("vt.video_id = ?", videoID).
-		Count(&aiApprovedCount).Error; err != nil {
+		Where("NOT EXISTS (SELECT 1 FROM ai_tag_approval_records AS ar WHERE ar.video_id = vt.video_id AND ar.tag_id = vt.tag_id)").
+		Where("NOT EXISTS (SELECT 1 FROM tag_rule_links AS rl WHERE rl.video_id = vt.video_id AND rl.tag_id = vt.tag_id)").
+		Count(&manualCount).Error; err != nil {
 		return false, err
 	}
-	return officialCount > aiApprovedCount, nil
+	return manualCount > 0, nil
 }
 
 func (s *AITaggingService) resolveOfficialTagInTx(tx *gorm.DB, candidate models.AITagCandidate) (uint, error) {
@@ -504,12 +514,14 @@ func (s *AITaggingService) resolveOfficialTagInTx(tx *gorm.DB, candidate models.
 	if name == "" {
 		return 0, fmt.Errorf("empty suggested tag name")
 	}
-	var existing models.Tag
-	if err := tx.Where("name = ?", name).First(&existing).Error; err == nil {
-		return existing.ID, nil
-	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+	// 与候选回填相同的匹配规则：先按 “类别/子标签” 路径，再按名称（不同类别下重名时优先叶子标签），最后按别名
+	var tags []models.Tag
+	if err := tx.Preload("Aliases").Find(&tags).Error; err != nil {
 		return 0, err
 	}
+	if existing, ok := buildAITagLookup(tags)[normalizeAITagName(name)]; ok {
+		return existing.ID, nil
+	}
 	var deleted models.Tag
 	if err := tx.Unscoped().Where("name = ? AND deleted_at IS NOT NULL", name).First(&deleted).Error; err == nil {
 		deleted.DeletedAt.Clear()
@@ -563,7 +575,7 @@ func (s *AITaggingService) RejectPendingCandidatesByVideo(videoID uint) (int64,
 }
 
 func (s *AITaggingService) RetryVideo(videoID uint) error {
-	return database.DB.Transaction(func(tx *gorm.DB) error {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
 		if err := tx.Model(&models.AITagCandidate{}).
 			Where("video_id = ? AND status = ?", videoID, models.AITagCandidateStatusPending).
 			Update("status", models.AITagCandidateStatusSuperseded).Error; err != nil {
@@ -583,8 +595,14 @@ func (s *AITaggingService) RetryVideo(videoID uint) error {
 			"skip_reason":          "",
 			"evidence_fingerprint": "",
 			"last_error":           "",
+			"attempt_count":        0,
+			"next_attempt_at":      nil,
 		}).Error
 	})
+	if err == nil {
+		s.wakeWorker()
+	}
+	return err
 }
 
 func (s *AITaggingService) StatusSummary() (*AITaggingStatusSummary, error) {
@@ -608,6 +626,9 @@ func (s *AITaggingService) StatusSummary() (*AITaggingStatusSummary, error) {
 	if err := countState(models.AITaggingStateStatusFailed, &summary.Failed); err != nil {
 		return nil, err
 	}
+	if err := countState(models.AITaggingStateStatusDeadLetter, &summary.DeadLetter); err != nil {
+		return nil, err
+	}
 	return summary, nil
 }
 
@@ -624,6 +645,43 @@ func normalizeAIConfidence(confidence string) string {
 	}
 }
 
+// buildAITagLookup 按名称、别名与 “类别/子标签” 路径索引标签；不同类别下重名时优先叶子标签
+func buildAITagLookup(tags []models.Tag) map[string]models.Tag {
+	lookup := make(map[string]models.Tag, len(tags)*2)
+	var walk func(nodes []TagTreeNode)
+	walk = func(nodes []TagTreeNode) {
+		for _, node := range nodes {
+			lookup[normalizeAITagName(node.Path)] = node.Tag
+			name := normalizeAITagName(node.Tag.Name)
+			if existing, ok := lookup[name]; !ok || (len(node.Children) == 0 && existing.ID != node.Tag.ID && hasTagChildren(tags, existing.ID)) {
+				lookup[name] = node.Tag
+			}
+			walk(node.Children)
+		}
+	}
+	walk(buildTagTree(tags))
+	// 别名只补充未被标签名或路径占用的键
+	for _, tag := range tags {
+		for _, alias := range tag.Aliases {
+			if key := normalizeAITagName(alias.Name); key != "" {
+				if _, ok := lookup[key]; !ok {
+					lookup[key] = tag
+				}
+			}
+		}
+	}
+	return lookup
+}
+
+func hasTagChildren(tags []models.Tag, id uint) bool {
+	for _, tag := range tags {
+		if tag.ParentID == id {
+			return true
+		}
+	}
+	return false
+}
+
 func normalizeAITagName(name string) string {
 	return strings.ToLower(strings.TrimSpace(name))
 }
@@ -538,6 +538,53 @@ func TestApproveAITagCandidateSupersedesWhenVideoWasManuallyTagged(t *testing.T)
 	}
 }
 
+func TestRuleTaggedVideoStillQueuedAndApprovedForAITagging(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	ruleTag := models.Tag{Name: "4K", Color: "#fff"}
+	aiTag := models.Tag{Name: "风景", Color: "#000"}
+	for _, tag := range []*models.Tag{&ruleTag, &aiTag} {
+		if err := database.DB.Create(tag).Error; err != nil {
+			t.Fatalf("创建标签失败: %v", err)
+		}
+	}
+	video := models.Video{Name: "mountain.mp4", Path: "/tmp/mountain.mp4", Directory: "/tmp", Height: 2160}
+	if err := database.DB.Create(&video).Error; err != nil {
+		t.Fatalf("创建视频失败: %v", err)
+	}
+	rules := &TagRuleService{}
+	rule, err := rules.CreateTagRule(TagRuleInput{Name: "4K", TagID: ruleTag.ID, Enabled: true, MinHeight: 2160})
+	if err != nil {
+		t.Fatalf("创建规则失败: %v", err)
+	}
+	if result, err := rules.RunTagRules(rule.ID); err != nil || result.Linked != 1 {
+		t.Fatalf("执行规则失败: result=%#v err=%v", result, err)
+	}
+
+	client := &fakeAITaggingClient{suggestions: []AITagSuggestion{{Label: "风景", Confidence: "high", MatchedExistingName: "风景"}}}
+	svc := newTestAITaggingService(client, nil)
+	status, err := svc.QueueStatus()
+	if err != nil || status.Queued != 1 {
+		t.Fatalf("仅有规则标签的视频仍应排队等待 AI 打标: status=%#v err=%v", status, err)
+	}
+	if err := svc.ProcessVideo(context.Background(), video.ID); err != nil {
+		t.Fatalf("处理视频失败: %v", err)
+	}
+	var candidate models.AITagCandidate
+	if err := database.DB.First(&candidate).Error; err != nil {
+		t.Fatalf("读取候选失败: %v", err)
+	}
+	item, err := svc.ApproveCandidate(candidate.ID)
+	if err != nil {
+		t.Fatalf("审批候选失败: %v", err)
+	}
+	if item.Status != models.AITagCandidateStatusApproved {
+		t.Fatalf("规则标签不应视为手动标签，候选应被批准，实际 %s", item.Status)
+	}
+	if got := countRows(t, "video_tags"); got != 2 {
+		t.Fatalf("审批后应同时保留规则标签与 AI 标签，实际 %d", got)
+	}
+}
+
 func TestApproveAITagCandidateSupersedesAfterManualTagAddedFollowingAIApproval(t *testing.T) {
 	setupVideoServiceTestDB(t)
 	firstTag := models.Tag{Name: "动作", Color: "#fff"}
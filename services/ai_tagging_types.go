@@ -27,6 +27,7 @@ type AITaggingStatusSummary struct {
 	Completed       int64 `json:"completed"`
 	Skipped         int64 `json:"skipped"`
 	Failed          int64 `json:"failed"`
+	DeadLetter      int64 `json:"dead_letter"`
 }
 
 type AITagSuggestion struct {
@@ -40,5 +41,6 @@ type AITagSuggestion struct {
 type AITaggingRequest struct {
 	Video        models.Video
 	ExistingTags []models.Tag
+	TagUsage     map[uint]int64 // 标签 ID -> 已关联的视频数，用于提示模型哪些标签常用
 	Evidence     AITaggingEvidence
 }
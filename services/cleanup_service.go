@@ -23,6 +23,7 @@ type CleanupCriteria struct {
 	MinDuration time.Duration `json:"min_duration"`
 	MinWidth    int           `json:"min_width"`
 	MinHeight   int           `json:"min_height"`
+	MinBitrate  int64         `json:"min_bitrate"` // bit/s，码率未知的视频不参与判断
 }
 
 type CleanupDuplicateGroup struct {
@@ -35,6 +36,7 @@ type CleanupAnalysis struct {
 	DuplicateGroups []CleanupDuplicateGroup `json:"duplicate_groups"`
 	LowDuration     []models.Video          `json:"low_duration"`
 	LowResolution   []models.Video          `json:"low_resolution"`
+	LowBitrate      []models.Video          `json:"low_bitrate"`
 }
 
 type CleanupProgress struct {
@@ -127,13 +129,14 @@ func (s *CleanupService) statusSnapshotLocked() CleanupStatus {
 func (s *CleanupService) AnalyzeCleanupCandidates(criteria CleanupCriteria) (*CleanupAnalysis, error) {
 	startedAt := time.Now()
 	var videos []models.Video
-	if err := database.DB.Order("id asc").Find(&videos).Error; err != nil {
+	// 离线卷上的文件无法访问，不参与本次分析，避免被当作缺失文件
+	if err := database.DB.Where("is_offline = ?", false).Order("id asc").Find(&videos).Error; err != nil {
 		return nil, err
 	}
 	videoService := &VideoService{}
 
-	log.Printf("[Cleanup] analysis started total_videos=%d criteria={min_duration=%s min_width=%d min_height=%d}",
-		len(videos), criteria.MinDuration, criteria.MinWidth, criteria.MinHeight,
+	log.Printf("[Cleanup] analysis started total_videos=%d criteria={min_duration=%s min_width=%d min_height=%d min_bitrate=%d}",
+		len(videos), criteria.MinDuration, criteria.MinWidth, criteria.MinHeight, criteria.MinBitrate,
 	)
 	s.emitProgress("load", 0, len(videos), "", fmt.Sprintf("已读取 %d 条视频记录，正在整理候选…", len(videos)))
 
@@ -156,13 +159,14 @@ func (s *CleanupService) AnalyzeCleanupCandidates(criteria CleanupCriteria) (*Cl
 		}
 
 		workingVideo := video
-		freshDuration, freshResolution, freshWidth, freshHeight := videoService.getVideoMetadata(video.Path)
-		hasFreshMetadata := freshDuration > 0 && freshResolution != "" && freshWidth > 0 && freshHeight > 0
+		fresh := videoService.probeVideoMetadata(context.Background(), video.Path)
+		hasFreshMetadata := fresh.Duration > 0 && fresh.Resolution != "" && fresh.Width > 0 && fresh.Height > 0
 		if hasFreshMetadata {
-			workingVideo.Duration = freshDuration
-			workingVideo.Resolution = freshResolution
-			workingVideo.Width = freshWidth
-			workingVideo.Height = freshHeight
+			workingVideo.Duration = fresh.Duration
+			workingVideo.Resolution = fresh.Resolution
+			workingVideo.Width = fresh.Width
+			workingVideo.Height = fresh.Height
+			workingVideo.MediaInfo = fresh.Media
 		} else {
 			log.Printf("[Cleanup] metadata unavailable for candidate id=%d path=%s", video.ID, video.Path)
 			continue
@@ -174,6 +178,9 @@ func (s *CleanupService) AnalyzeCleanupCandidates(criteria CleanupCriteria) (*Cl
 		if hasFreshMetadata && criteria.MinWidth > 0 && criteria.MinHeight > 0 && (workingVideo.Width < criteria.MinWidth || workingVideo.Height < criteria.MinHeight) {
 			result.LowResolution = append(result.LowResolution, workingVideo)
 		}
+		if criteria.MinBitrate > 0 && workingVideo.MediaInfo.Bitrate > 0 && workingVideo.MediaInfo.Bitrate < criteria.MinBitrate {
+			result.LowBitrate = append(result.LowBitrate, workingVideo)
+		}
 		sizeBuckets[workingVideo.Size] = append(sizeBuckets[workingVideo.Size], workingVideo)
 
 		if shouldEmitCleanupProgress(idx+1, len(videos), 400) {
@@ -226,13 +233,13 @@ func (s *CleanupService) AnalyzeCleanupCandidates(criteria CleanupCriteria) (*Cl
 		return result.DuplicateGroups[i].Original.ID < result.DuplicateGroups[j].Original.ID
 	})
 
-	log.Printf("[Cleanup] analysis completed elapsed=%s duplicate_groups=%d low_duration=%d low_resolution=%d hash_candidates=%d",
+	log.Printf("[Cleanup] analysis completed elapsed=%s duplicate_groups=%d low_duration=%d low_resolution=%d low_bitrate=%d hash_candidates=%d",
 		time.Since(startedAt).Round(time.Millisecond),
-		len(result.DuplicateGroups), len(result.LowDuration), len(result.LowResolution), len(hashCandidates),
+		len(result.DuplicateGroups), len(result.LowDuration), len(result.LowResolution), len(result.LowBitrate), len(hashCandidates),
 	)
 	s.emitProgress("done", len(hashCandidates), len(hashCandidates), "", fmt.Sprintf(
-		"分析完成：重复组 %d，短视频 %d，低清视频 %d。",
-		len(result.DuplicateGroups), len(result.LowDuration), len(result.LowResolution),
+		"分析完成：重复组 %d，短视频 %d，低清视频 %d，低码率 %d。",
+		len(result.DuplicateGroups), len(result.LowDuration), len(result.LowResolution), len(result.LowBitrate),
 	))
 
 	return result, nil
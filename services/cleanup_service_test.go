@@ -191,6 +191,7 @@ func TestAnalyzeCleanupCandidatesFindsLowQualityVideos(t *testing.T) {
 		MinDuration: 5 * time.Second,
 		MinWidth:    480,
 		MinHeight:   320,
+		MinBitrate:  500 * 1000,
 	})
 	if err != nil {
 		t.Fatalf("分析清理候选失败: %v", err)
@@ -202,6 +203,9 @@ func TestAnalyzeCleanupCandidatesFindsLowQualityVideos(t *testing.T) {
 	if len(result.LowResolution) != 1 || result.LowResolution[0].ID != small.ID {
 		t.Fatalf("低分辨率候选错误: %+v", result.LowResolution)
 	}
+	if len(result.LowBitrate) != 1 || result.LowBitrate[0].ID != small.ID || result.LowBitrate[0].MediaInfo.Bitrate != 300000 {
+		t.Fatalf("低码率候选错误: %+v", result.LowBitrate)
+	}
 }
 
 func mockFFProbe(t *testing.T, root string) {
@@ -219,20 +223,23 @@ case "$name" in
     duration="2.0"
     width=1280
     height=720
+    bitrate=2000000
     ;;
   small.mp4)
     duration="30.0"
     width=320
     height=240
+    bitrate=300000
     ;;
   *)
     duration="12.0"
     width=1920
     height=1080
+    bitrate=8000000
     ;;
 esac
 cat <<JSON
-{"streams":[{"width":${width},"height":${height},"duration":"${duration}"}],"format":{"duration":"${duration}"}}
+{"streams":[{"width":${width},"height":${height},"duration":"${duration}"}],"format":{"duration":"${duration}","bit_rate":"${bitrate}"}}
 JSON
 `
 	if err := os.WriteFile(ffprobePath, []byte(script), 0755); err != nil {
@@ -0,0 +1,118 @@
+#!/usr/bin/env python3
+"""CLIP 零样本标签分类 worker。
+
+一次性模式：从 stdin 读取请求 JSON，向 stdout 输出结果 JSON。
+服务模式（--serve）：在 POST /classify 上提供同样的协议，模型只加载一次。
+
+请求：{"model": "...", "texts": ["..."], "images": ["<base64 或 data URL>"]}
+响应：{"scores": [[每个文本的概率, ...], ...]}，每张图片一行，行内为 softmax 概率。
+"""
+import argparse
+import base64
+import io
+import json
+import os
+import sys
+from http.server import BaseHTTPRequestHandler, HTTPServer
+
+os.environ.setdefault("TOKENIZERS_PARALLELISM", "false")
+
+_MODELS = {}
+
+
+def pick_device():
+    import torch
+
+    if torch.cuda.is_available():
+        return "cuda"
+    if getattr(torch.backends, "mps", None) is not None and torch.backends.mps.is_available():
+        return "mps"
+    return "cpu"
+
+
+def load_model(name):
+    if name in _MODELS:
+        return _MODELS[name]
+    from transformers import AutoModel, AutoProcessor
+
+    device = pick_device()
+    model = AutoModel.from_pretrained(name).to(device)
+    model.eval()
+    processor = AutoProcessor.from_pretrained(name)
+    _MODELS[name] = (model, processor, device)
+    return _MODELS[name]
+
+
+def decode_image(value):
+    from PIL import Image
+
+    if value.startswith("data:") and "," in value:
+        value = value.split(",", 1)[1]
+    return Image.open(io.BytesIO(base64.b64decode(value))).convert("RGB")
+
+
+def classify(request):
+    import torch
+
+    texts = request.get("texts") or []
+    images = request.get("images") or []
+    if not texts or not images:
+        return {"scores": []}
+    model, processor, device = load_model(request["model"])
+    inputs = processor(
+        text=texts,
+        images=[decode_image(item) for item in images],
+        return_tensors="pt",
+        padding=True,
+    )
+    inputs = {key: value.to(device) for key, value in inputs.items()}
+    with torch.no_grad():
+        outputs = model(**inputs)
+    probs = outputs.logits_per_image.softmax(dim=-1)
+    return {"scores": probs.cpu().tolist()}
+
+
+class Handler(BaseHTTPRequestHandler):
+    def do_POST(self):
+        if self.path.rstrip("/") != "/classify":
+            self.send_error(404)
+            return
+        try:
+            length = int(self.headers.get("Content-Length") or 0)
+            result = classify(json.loads(self.rfile.read(length)))
+            status = 200
+        except Exception as exc:  # noqa: BLE001
+            result = {"error": str(exc)}
+            status = 500
+        body = json.dumps(result).encode("utf-8")
+        self.send_response(status)
+        self.send_header("Content-Type", "application/json")
+        self.send_header("Content-Length", str(len(body)))
+        self.end_headers()
+        self.wfile.write(body)
+
+    def log_message(self, fmt, *args):
+        sys.stderr.write(fmt % args + "\n")
+
+
+def main():
+    parser = argparse.ArgumentParser()
+    parser.add_argument("--serve", action="store_true")
+    parser.add_argument("--host", default="127.0.0.1")
+    parser.add_argument("--port", type=int, default=8765)
+    args = parser.parse_args()
+
+    if args.serve:
+        HTTPServer((args.host, args.port), Handler).serve_forever()
+        return
+
+    try:
+        result = classify(json.load(sys.stdin))
+    except Exception as exc:  # noqa: BLE001
+        sys.stderr.write(str(exc) + "\n")
+        sys.exit(1)
+    json.dump(result, sys.stdout)
+
+
+if __name__ == "__main__":
+    main()
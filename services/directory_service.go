@@ -14,24 +14,46 @@ func (s *DirectoryService) GetAllDirectories() ([]models.ScanDirectory, error) {
 	return dirs, err
 }
 
-// AddDirectory 添加扫描目录
-func (s *DirectoryService) AddDirectory(path, alias string) (*models.ScanDirectory, error) {
+// AddDirectory 添加扫描目录（附带扫描规则）
+func (s *DirectoryService) AddDirectory(path, alias string, rules models.ScanDirectoryRules) (*models.ScanDirectory, error) {
+	if err := validateScanDirectoryRules(rules); err != nil {
+		return nil, err
+	}
 	dir := &models.ScanDirectory{
 		Path:  path,
 		Alias: alias,
+		Rules: rules,
 	}
 	err := database.DB.Create(dir).Error
 	return dir, err
 }
 
-// UpdateDirectory 更新目录别名
-func (s *DirectoryService) UpdateDirectory(id uint, path, alias string) error {
+// UpdateDirectory 更新目录路径、别名与扫描规则
+func (s *DirectoryService) UpdateDirectory(id uint, path, alias string, rules models.ScanDirectoryRules) error {
+	if err := validateScanDirectoryRules(rules); err != nil {
+		return err
+	}
 	return database.DB.Model(&models.ScanDirectory{}).Where("id = ?", id).Updates(map[string]interface{}{
-		"path":  path,
-		"alias": alias,
+		"path":             path,
+		"alias":            alias,
+		"include_patterns": rules.IncludePatterns,
+		"exclude_patterns": rules.ExcludePatterns,
+		"max_depth":        rules.MaxDepth,
+		"follow_symlinks":  rules.FollowSymlinks,
+		"video_extensions": rules.VideoExtensions,
+		"volume_marker":    rules.VolumeMarker,
 	}).Error
 }
 
+// RefreshDirectoryStates 重新检测所有扫描目录的在线状态（外接盘/NAS 是否挂载），并同步其下视频的离线标记
+func (s *DirectoryService) RefreshDirectoryStates() ([]models.ScanDirectory, error) {
+	dirs, err := s.GetAllDirectories()
+	if err != nil {
+		return nil, err
+	}
+	return refreshScanDirectoryStates(dirs), nil
+}
+
 // DeleteDirectory 删除扫描目录
 func (s *DirectoryService) DeleteDirectory(id uint) error {
 	return database.DB.Delete(&models.ScanDirectory{}, id).Error
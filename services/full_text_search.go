@@ -0,0 +1,296 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"video-master/database"
+	"video-master/models"
+)
+
+// 全文检索：Postgres 下拉丁词走 tsvector（'simple' 配置，前缀匹配，按词边界命中），
+// CJK 词 'simple' 分词无法切开，回退为子串匹配：不少于 3 个字的词由 pg_trgm 索引加速，
+// 1~2 个字的词抽不出三元组，只能在其他条件筛出的行上逐行匹配（单独出现时为全表扫描）；
+// SQLite（测试环境）全部走 LIKE。
+// 排序分 = ts_rank_cd（仅 Postgres）+ 各词出现次数，两种方言下都能按相关度排序。
+
+const (
+	fullTextSnippetRunes = 80
+	maxFullTextTerms     = 8
+	// minTrigramTermRunes pg_trgm 能从 LIKE 模式中抽出三元组的最短词长
+	minTrigramTermRunes = 3
+)
+
+// SearchHighlight 片段内的高亮区间，按 rune 计的 [Start, End)
+type SearchHighlight struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// SearchSnippet 命中片段；Prefix/Suffix 表示两端是否被截断
+type SearchSnippet struct {
+	Text       string            `json:"text"`
+	Highlights []SearchHighlight `json:"highlights"`
+	Prefix     bool              `json:"prefix"`
+	Suffix     bool              `json:"suffix"`
+}
+
+// RankedVideo 按文件名相关度排序的搜索结果，Snippet 为高亮后的文件名
+type RankedVideo struct {
+	Video   models.Video  `json:"video"`
+	Rank    float64       `json:"rank"`
+	Snippet SearchSnippet `json:"snippet"`
+}
+
+// SearchVideosRanked 对文件名做全文检索，按相关度降序返回前 limit 个（不分页）
+func (s *VideoService) SearchVideosRanked(keyword string, limit int) ([]RankedVideo, error) {
+	terms := splitFullTextTerms(keyword)
+	if len(terms) == 0 {
+		return []RankedVideo{}, nil
+	}
+	if limit <= 0 {
+		limit = 50
+	}
+	type rankedHit struct {
+		ID      uint
+		HitRank float64
+	}
+
+	condSQL, condArgs := fullTextCondition("videos.name_tsv", "videos.name", terms)
+	rankSQL, rankArgs := fullTextRankExpr("videos.name_tsv", "videos.name", terms)
+	var hits []rankedHit
+	err := database.DB.Model(&models.Video{}).
+		Select("videos.id, "+rankSQL+" AS hit_rank", rankArgs...).
+		Where(condSQL, condArgs...).
+		Order("hit_rank desc, videos.id desc").
+		Limit(limit).
+		Scan(&hits).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(hits) == 0 {
+		return []RankedVideo{}, nil
+	}
+
+	ids := make([]uint, 0, len(hits))
+	for _, hit := range hits {
+		ids = append(ids, hit.ID)
+	}
+	var videos []models.Video
+	if err := database.DB.Preload("Tags").Where("id IN ?", ids).Find(&videos).Error; err != nil {
+		return nil, err
+	}
+	videosByID := make(map[uint]models.Video, len(videos))
+	for _, video := range videos {
+		videosByID[video.ID] = video
+	}
+
+	results := make([]RankedVideo, 0, len(hits))
+	for _, hit := range hits {
+		video, ok := videosByID[hit.ID]
+		if !ok {
+			continue
+		}
+		results = append(results, RankedVideo{
+			Video:   video,
+			Rank:    hit.HitRank,
+			Snippet: buildSearchSnippet(video.Name, terms, 0),
+		})
+	}
+	return results, nil
+}
+
+func fullTextSearchEnabled() bool {
+	return database.DB != nil && database.DB.Dialector.Name() == "postgres"
+}
+
+func isCJKRune(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+func isCJKTerm(term string) bool {
+	for _, r := range term {
+		if !isCJKRune(r) {
+			return false
+		}
+	}
+	return term != ""
+}
+
+// splitFullTextTerms 按非字母数字切词并转小写去重；CJK 与其他文字的交界处也会切开
+func splitFullTextTerms(keyword string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	var current []rune
+	currentCJK := false
+	flush := func() {
+		if len(current) == 0 {
+			return
+		}
+		term := strings.ToLower(string(current))
+		current = current[:0]
+		if seen[term] || len(terms) >= maxFullTextTerms {
+			return
+		}
+		seen[term] = true
+		terms = append(terms, term)
+	}
+	for _, r := range keyword {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush()
+			continue
+		}
+		cjk := isCJKRune(r)
+		if len(current) > 0 && cjk != currentCJK {
+			flush()
+		}
+		currentCJK = cjk
+		current = append(current, r)
+	}
+	flush()
+	return terms
+}
+
+// buildPrefixTSQuery 把非 CJK 词拼成 to_tsquery 文本（term:* 用 & 连接）；词已只含字母数字，无需转义
+func buildPrefixTSQuery(terms []string) string {
+	parts := make([]string, 0, len(terms))
+	for _, term := range terms {
+		if isCJKTerm(term) {
+			continue
+		}
+		parts = append(parts, term+":*")
+	}
+	return strings.Join(parts, " & ")
+}
+
+// fullTextCondition 生成“所有词都命中”的 WHERE 片段
+func fullTextCondition(vectorColumn, textColumn string, terms []string) (string, []interface{}) {
+	return buildFullTextCondition(fullTextSearchEnabled(), vectorColumn, textColumn, terms)
+}
+
+// buildFullTextCondition 按方言生成条件；可走索引的条件（tsquery、不少于 3 个字的子串）排在前面，
+// 过短的 CJK 子串只作为剩余行上的过滤
+func buildFullTextCondition(postgres bool, vectorColumn, textColumn string, terms []string) (string, []interface{}) {
+	var clauses, shortClauses []string
+	var args, shortArgs []interface{}
+	if postgres {
+		if tsquery := buildPrefixTSQuery(terms); tsquery != "" {
+			clauses = append(clauses, vectorColumn+" @@ to_tsquery('simple', ?)")
+			args = append(args, tsquery)
+		}
+	}
+	likeClause := "LOWER(" + textColumn + ") LIKE ? ESCAPE '\\'"
+	for _, term := range terms {
+		if postgres && !isCJKTerm(term) {
+			continue
+		}
+		pattern := "%" + escapeSQLLike(term) + "%"
+		if postgres && len([]rune(term)) < minTrigramTermRunes {
+			shortClauses = append(shortClauses, likeClause)
+			shortArgs = append(shortArgs, pattern)
+			continue
+		}
+		clauses = append(clauses, likeClause)
+		args = append(args, pattern)
+	}
+	clauses = append(clauses, shortClauses...)
+	args = append(args, shortArgs...)
+	if len(clauses) == 0 {
+		return "1 = 0", nil
+	}
+	return "(" + strings.Join(clauses, " AND ") + ")", args
+}
+
+// fullTextRankExpr 生成相关度表达式：ts_rank_cd 加上各词的出现次数（LENGTH/REPLACE 两种方言通用）
+func fullTextRankExpr(vectorColumn, textColumn string, terms []string) (string, []interface{}) {
+	var parts []string
+	var args []interface{}
+	if fullTextSearchEnabled() {
+		if tsquery := buildPrefixTSQuery(terms); tsquery != "" {
+			parts = append(parts, "ts_rank_cd("+vectorColumn+", to_tsquery('simple', ?))")
+			args = append(args, tsquery)
+		}
+	}
+	lowered := "LOWER(" + textColumn + ")"
+	for _, term := range terms {
+		parts = append(parts, fmt.Sprintf("(LENGTH(%s) - LENGTH(REPLACE(%s, ?, ''))) * 1.0 / %d", lowered, lowered, len([]rune(term))))
+		args = append(args, term)
+	}
+	if len(parts) == 0 {
+		return "0", nil
+	}
+	return "(" + strings.Join(parts, " + ") + ")", args
+}
+
+// buildSearchSnippet 截取首个命中附近的文本并标出窗口内所有命中（不区分大小写）
+func buildSearchSnippet(text string, terms []string, maxRunes int) SearchSnippet {
+	runes := []rune(text)
+	lower := []rune(strings.ToLower(text))
+	if len(lower) != len(runes) {
+		// 个别字符小写后 rune 数会变化，此时退回逐字符比较
+		lower = make([]rune, len(runes))
+		for i, r := range runes {
+			lower[i] = unicode.ToLower(r)
+		}
+	}
+
+	var hits []SearchHighlight
+	for _, term := range terms {
+		needle := []rune(term)
+		if len(needle) == 0 {
+			continue
+		}
+		for i := 0; i+len(needle) <= len(lower); i++ {
+			if string(lower[i:i+len(needle)]) == term {
+				hits = append(hits, SearchHighlight{Start: i, End: i + len(needle)})
+				i += len(needle) - 1
+			}
+		}
+	}
+	hits = mergeSearchHighlights(hits)
+
+	if maxRunes <= 0 || len(runes) <= maxRunes {
+		return SearchSnippet{Text: text, Highlights: hits}
+	}
+	start := 0
+	if len(hits) > 0 {
+		start = hits[0].Start - maxRunes/4
+		if start < 0 {
+			start = 0
+		}
+	}
+	end := start + maxRunes
+	if end > len(runes) {
+		end = len(runes)
+		start = end - maxRunes
+	}
+	snippet := SearchSnippet{
+		Text:       string(runes[start:end]),
+		Highlights: []SearchHighlight{},
+		Prefix:     start > 0,
+		Suffix:     end < len(runes),
+	}
+	for _, hit := range hits {
+		if hit.Start >= start && hit.End <= end {
+			snippet.Highlights = append(snippet.Highlights, SearchHighlight{Start: hit.Start - start, End: hit.End - start})
+		}
+	}
+	return snippet
+}
+
+// mergeSearchHighlights 排序并合并重叠区间
+func mergeSearchHighlights(hits []SearchHighlight) []SearchHighlight {
+	sort.Slice(hits, func(i, j int) bool { return hits[i].Start < hits[j].Start })
+	merged := []SearchHighlight{}
+	for _, hit := range hits {
+		if n := len(merged); n > 0 && hit.Start <= merged[n-1].End {
+			if hit.End > merged[n-1].End {
+				merged[n-1].End = hit.End
+			}
+			continue
+		}
+		merged = append(merged, hit)
+	}
+	return merged
+}
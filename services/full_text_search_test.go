@@ -0,0 +1,122 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"video-master/database"
+	"video-master/models"
+)
+
+func TestSplitFullTextTermsSeparatesCJKAndPunctuation(t *testing.T) {
+	got := splitFullTextTerms(" Hello_World 跳舞abc 2024.mkv hello ")
+	want := []string{"hello", "world", "跳舞", "abc", "2024", "mkv"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("切词结果不符合预期 got=%v want=%v", got, want)
+	}
+	if query := buildPrefixTSQuery(got); query != "hello:* & world:* & abc:* & 2024:* & mkv:*" {
+		t.Fatalf("tsquery 应跳过 CJK 词，got=%q", query)
+	}
+	if terms := splitFullTextTerms(" !!! "); len(terms) != 0 {
+		t.Fatalf("纯标点不应产生检索词，got=%v", terms)
+	}
+}
+
+func TestBuildFullTextConditionPutsIndexableClausesFirst(t *testing.T) {
+	terms := splitFullTextTerms("猫 dance 跳舞视频")
+	sql, args := buildFullTextCondition(true, "name_tsv", "name", terms)
+	wantSQL := "(name_tsv @@ to_tsquery('simple', ?) AND LOWER(name) LIKE ? ESCAPE '\\' AND LOWER(name) LIKE ? ESCAPE '\\')"
+	wantArgs := []interface{}{"dance:*", "%跳舞视频%", "%猫%"}
+	if sql != wantSQL || !reflect.DeepEqual(args, wantArgs) {
+		t.Fatalf("短 CJK 词应排在可走索引的条件之后 sql=%s args=%v", sql, args)
+	}
+
+	sql, args = buildFullTextCondition(false, "name_tsv", "name", []string{"猫", "dance"})
+	if sql != "(LOWER(name) LIKE ? ESCAPE '\\' AND LOWER(name) LIKE ? ESCAPE '\\')" || !reflect.DeepEqual(args, []interface{}{"%猫%", "%dance%"}) {
+		t.Fatalf("SQLite 下所有词都应走 LIKE sql=%s args=%v", sql, args)
+	}
+}
+
+func TestBuildSearchSnippetHighlightsAroundFirstHit(t *testing.T) {
+	snippet := buildSearchSnippet("我们一起去跳舞吧 Dance dance", []string{"跳舞", "dance"}, 0)
+	want := []SearchHighlight{{Start: 5, End: 7}, {Start: 9, End: 14}, {Start: 15, End: 20}}
+	if !reflect.DeepEqual(snippet.Highlights, want) || snippet.Prefix || snippet.Suffix {
+		t.Fatalf("高亮区间不符合预期 got=%+v", snippet)
+	}
+
+	long := "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaa keyword bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+	snippet = buildSearchSnippet(long, []string{"keyword"}, 20)
+	if !snippet.Prefix || !snippet.Suffix || len([]rune(snippet.Text)) != 20 {
+		t.Fatalf("长文本应截断为命中附近的窗口 got=%+v", snippet)
+	}
+	if len(snippet.Highlights) != 1 {
+		t.Fatalf("窗口内应保留命中高亮 got=%+v", snippet)
+	}
+	hit := snippet.Highlights[0]
+	if got := string([]rune(snippet.Text)[hit.Start:hit.End]); got != "keyword" {
+		t.Fatalf("高亮区间应指向命中词 got=%q", got)
+	}
+}
+
+func TestSearchVideosRankedOrdersByRelevance(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	root := t.TempDir()
+	once := createShortFeedVideo(t, root, "cat video.mp4", 60, false)
+	twice := createShortFeedVideo(t, root, "cat and cat.mp4", 60, false)
+	createShortFeedVideo(t, root, "dog.mp4", 60, false)
+	cjk := createShortFeedVideo(t, root, "猫咪跳舞.mp4", 60, false)
+
+	svc := &VideoService{}
+	results, err := svc.SearchVideosRanked("CAT", 10)
+	if err != nil {
+		t.Fatalf("相关度搜索失败: %v", err)
+	}
+	if len(results) != 2 || results[0].Video.ID != twice.ID || results[1].Video.ID != once.ID {
+		t.Fatalf("命中次数多的应排在前面 results=%+v", results)
+	}
+	if results[0].Rank <= results[1].Rank || len(results[0].Snippet.Highlights) != 2 {
+		t.Fatalf("应返回相关度与高亮片段 results=%+v", results)
+	}
+
+	results, err = svc.SearchVideosRanked("跳舞", 10)
+	if err != nil || len(results) != 1 || results[0].Video.ID != cjk.ID {
+		t.Fatalf("CJK 词应回退为子串匹配 results=%+v err=%v", results, err)
+	}
+	if results, err := svc.SearchVideosRanked("cat dog", 10); err != nil || len(results) != 0 {
+		t.Fatalf("多个词应同时命中 results=%+v err=%v", results, err)
+	}
+}
+
+func TestSearchSubtitleMatchesRanksBestSegmentWithSnippet(t *testing.T) {
+	setupSubtitleSearchTestDB(t)
+	root := t.TempDir()
+	write := func(name, srt string) models.Video {
+		videoPath := filepath.Join(root, name+".mp4")
+		if err := os.WriteFile(videoPath, []byte("fake-video"), 0644); err != nil {
+			t.Fatalf("写入视频文件失败: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(root, name+".srt"), []byte(srt), 0644); err != nil {
+			t.Fatalf("写入字幕文件失败: %v", err)
+		}
+		video := models.Video{Name: name + ".mp4", Path: videoPath, Directory: root, Size: 10}
+		if err := database.DB.Create(&video).Error; err != nil {
+			t.Fatalf("创建视频失败: %v", err)
+		}
+		return video
+	}
+	weak := write("weak", "1\n00:00:01,000 --> 00:00:02,000\nrain today\n")
+	strong := write("strong", "1\n00:00:01,000 --> 00:00:02,000\nsunny\n\n2\n00:00:03,000 --> 00:00:04,000\nrain, rain, go away\n")
+
+	svc := &SubtitleSearchService{}
+	matches, err := svc.SearchSubtitleMatches("Rain", 10)
+	if err != nil {
+		t.Fatalf("搜索字幕失败: %v", err)
+	}
+	if len(matches) != 2 || matches[0].Video.ID != strong.ID || matches[1].Video.ID != weak.ID {
+		t.Fatalf("应按相关度排序 matches=%+v", matches)
+	}
+	if matches[0].Segment.Index != 2 || len(matches[0].Snippet.Highlights) != 2 {
+		t.Fatalf("应选出视频内相关度最高的字幕并高亮 match=%+v", matches[0])
+	}
+}
@@ -0,0 +1,418 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+	"video-master/models"
+
+	"gorm.io/gorm"
+)
+
+type ffprobeStream struct {
+	CodecType     string            `json:"codec_type"`
+	CodecName     string            `json:"codec_name"`
+	Width         int               `json:"width"`
+	Height        int               `json:"height"`
+	Duration      string            `json:"duration"`
+	BitRate       string            `json:"bit_rate"`
+	AvgFrameRate  string            `json:"avg_frame_rate"`
+	RFrameRate    string            `json:"r_frame_rate"`
+	ColorTransfer string            `json:"color_transfer"`
+	Tags          map[string]string `json:"tags"`
+	Disposition   map[string]int    `json:"disposition"`
+	SideDataList  []struct {
+		SideDataType string  `json:"side_data_type"`
+		Rotation     float64 `json:"rotation"`
+	} `json:"side_data_list"`
+}
+
+type ffprobePayload struct {
+	Streams []ffprobeStream `json:"streams"`
+	Format  struct {
+		FormatName string            `json:"format_name"`
+		Duration   string            `json:"duration"`
+		BitRate    string            `json:"bit_rate"`
+		Tags       map[string]string `json:"tags"`
+	} `json:"format"`
+}
+
+// videoMetadata 一次 ffprobe 得到的全部元数据
+type videoMetadata struct {
+	Duration   float64
+	Resolution string
+	Width      int
+	Height     int
+	Media      models.VideoMediaInfo
+}
+
+// valid 与历史判断保持一致：时长和分辨率都没有时视为提取失败
+func (m videoMetadata) valid() bool {
+	return m.Duration != 0 || m.Resolution != ""
+}
+
+// columns 返回写回 videos 表的字段；时间为空时显式写 NULL，避免保留旧值
+func (m videoMetadata) columns() map[string]interface{} {
+	media := m.Media
+	columns := map[string]interface{}{
+		"duration":              m.Duration,
+		"resolution":            m.Resolution,
+		"width":                 m.Width,
+		"height":                m.Height,
+		"video_codec":           media.VideoCodec,
+		"audio_codecs":          media.AudioCodecs,
+		"bitrate":               media.Bitrate,
+		"frame_rate":            media.FrameRate,
+		"color_transfer":        media.ColorTransfer,
+		"is_hdr":                media.IsHDR,
+		"rotation":              media.Rotation,
+		"container_format":      media.ContainerFormat,
+		"audio_languages":       media.AudioLanguages,
+		"subtitle_languages":    media.SubtitleLanguages,
+		"subtitle_stream_count": media.SubtitleStreamCount,
+		"media_created_at":      nil,
+		"media_probed_at":       nil,
+	}
+	if media.MediaCreatedAt != nil {
+		columns["media_created_at"] = *media.MediaCreatedAt
+	}
+	if media.MediaProbedAt != nil {
+		columns["media_probed_at"] = *media.MediaProbedAt
+	}
+	return columns
+}
+
+// parseFFProbeDetails 解析 ffprobe -show_streams -show_format 的 JSON 输出。
+// 视频流取第一条非封面的 video 流；输出中没有 codec_type 时（仅请求了部分字段）取第一条流。
+func parseFFProbeDetails(output []byte) (videoMetadata, error) {
+	var meta videoMetadata
+	trimmed := bytes.TrimSpace(output)
+	if len(trimmed) == 0 {
+		return meta, errors.New("empty ffprobe output")
+	}
+
+	var data ffprobePayload
+	if err := json.Unmarshal(trimmed, &data); err != nil {
+		return meta, err
+	}
+	if len(data.Streams) == 0 {
+		return meta, errors.New("ffprobe returned no video stream")
+	}
+
+	videoIdx := -1
+	typed := false
+	for idx, stream := range data.Streams {
+		if stream.CodecType != "" {
+			typed = true
+		}
+		if stream.CodecType == "video" && stream.Disposition["attached_pic"] == 0 {
+			videoIdx = idx
+			break
+		}
+	}
+	if videoIdx < 0 {
+		if typed {
+			return meta, errors.New("ffprobe returned no video stream")
+		}
+		videoIdx = 0
+	}
+
+	stream := data.Streams[videoIdx]
+	meta.Width = stream.Width
+	meta.Height = stream.Height
+	if meta.Width > 0 && meta.Height > 0 {
+		meta.Resolution = fmt.Sprintf("%dx%d", meta.Width, meta.Height)
+	}
+
+	durationText := strings.TrimSpace(stream.Duration)
+	if durationText == "" {
+		durationText = strings.TrimSpace(data.Format.Duration)
+	}
+	if durationText != "" {
+		if _, scanErr := fmt.Sscanf(durationText, "%f", &meta.Duration); scanErr != nil {
+			return videoMetadata{}, fmt.Errorf("invalid duration %q: %w", durationText, scanErr)
+		}
+	}
+
+	media := &meta.Media
+	media.VideoCodec = strings.ToLower(stream.CodecName)
+	media.FrameRate = parseFFProbeFrameRate(stream.AvgFrameRate)
+	if media.FrameRate == 0 {
+		media.FrameRate = parseFFProbeFrameRate(stream.RFrameRate)
+	}
+	media.ColorTransfer = strings.ToLower(stream.ColorTransfer)
+	media.IsHDR = media.ColorTransfer == "smpte2084" || media.ColorTransfer == "arib-std-b67"
+	media.Rotation = parseFFProbeRotation(stream)
+	for _, side := range stream.SideDataList {
+		if strings.HasPrefix(strings.ToLower(side.SideDataType), "dovi") {
+			media.IsHDR = true
+		}
+	}
+	media.ContainerFormat = strings.ToLower(data.Format.FormatName)
+
+	media.Bitrate, _ = strconv.ParseInt(strings.TrimSpace(data.Format.BitRate), 10, 64)
+	if media.Bitrate <= 0 {
+		// 部分容器不给总码率，退回各流码率之和
+		media.Bitrate = 0
+		for _, s := range data.Streams {
+			if rate, err := strconv.ParseInt(strings.TrimSpace(s.BitRate), 10, 64); err == nil && rate > 0 {
+				media.Bitrate += rate
+			}
+		}
+	}
+
+	var audioCodecs, audioLangs, subtitleLangs []string
+	for _, s := range data.Streams {
+		switch s.CodecType {
+		case "audio":
+			audioCodecs = appendUniqueMediaValue(audioCodecs, s.CodecName)
+			audioLangs = appendUniqueMediaValue(audioLangs, ffprobeStreamLanguage(s))
+		case "subtitle":
+			media.SubtitleStreamCount++
+			subtitleLangs = appendUniqueMediaValue(subtitleLangs, ffprobeStreamLanguage(s))
+		}
+	}
+	media.AudioCodecs = strings.Join(audioCodecs, ",")
+	media.AudioLanguages = strings.Join(audioLangs, ",")
+	media.SubtitleLanguages = strings.Join(subtitleLangs, ",")
+
+	media.MediaCreatedAt = parseFFProbeCreationTime(data.Format.Tags["creation_time"])
+	if media.MediaCreatedAt == nil {
+		media.MediaCreatedAt = parseFFProbeCreationTime(stream.Tags["creation_time"])
+	}
+	return meta, nil
+}
+
+// parseFFProbeFrameRate 解析 "30000/1001" 或 "25" 形式的帧率，保留两位小数
+func parseFFProbeFrameRate(text string) float64 {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return 0
+	}
+	num, den, hasDen := strings.Cut(text, "/")
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil || n <= 0 {
+		return 0
+	}
+	if hasDen {
+		d, err := strconv.ParseFloat(den, 64)
+		if err != nil || d <= 0 {
+			return 0
+		}
+		n /= d
+	}
+	return math.Round(n*100) / 100
+}
+
+// parseFFProbeRotation 返回顺时针旋转角度（0-359）。旧版 ffprobe 写在 tags.rotate（顺时针），
+// 新版写在 Display Matrix side data（逆时针）。
+func parseFFProbeRotation(stream ffprobeStream) int {
+	degrees := 0
+	if rotate, err := strconv.Atoi(strings.TrimSpace(stream.Tags["rotate"])); err == nil {
+		degrees = rotate
+	} else {
+		for _, side := range stream.SideDataList {
+			if strings.EqualFold(side.SideDataType, "Display Matrix") {
+				degrees = -int(math.Round(side.Rotation))
+				break
+			}
+		}
+	}
+	return ((degrees % 360) + 360) % 360
+}
+
+func parseFFProbeCreationTime(text string) *time.Time {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02T15:04:05"} {
+		if parsed, err := time.Parse(layout, text); err == nil {
+			if parsed.IsZero() || parsed.Year() <= 1970 {
+				// 部分编码器写入 1970/1904 等占位时间，视为未知
+				return nil
+			}
+			return &parsed
+		}
+	}
+	return nil
+}
+
+func ffprobeStreamLanguage(stream ffprobeStream) string {
+	lang := strings.ToLower(strings.TrimSpace(stream.Tags["language"]))
+	if lang == "und" {
+		return ""
+	}
+	return lang
+}
+
+func appendUniqueMediaValue(values []string, value string) []string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return values
+	}
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+// probeVideoMetadata 使用 ffprobe 读取时长、分辨率与媒体信息；失败时返回零值，ctx 取消时终止子进程
+func (s *VideoService) probeVideoMetadata(ctx context.Context, path string) videoMetadata {
+	ffprobeBin := findMediaBinary("ffprobe")
+	if ffprobeBin == "" {
+		log.Printf("[VideoService] ffprobe not found, skipping metadata extraction")
+		return videoMetadata{}
+	}
+
+	cmd := exec.CommandContext(ctx, ffprobeBin, "-v", "error", "-show_streams", "-show_format", "-of", "json", path)
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		log.Printf("[VideoService] ffprobe failed for %s: %v stderr=%s", path, err, truncateLogSnippet(stderr.String(), 400))
+		return videoMetadata{}
+	}
+
+	meta, err := parseFFProbeDetails(stdout.Bytes())
+	if err != nil {
+		log.Printf("[VideoService] failed to parse ffprobe output for %s: %v stdout=%s stderr=%s",
+			path,
+			err,
+			truncateLogSnippet(stdout.String(), 400),
+			truncateLogSnippet(stderr.String(), 400),
+		)
+		return videoMetadata{}
+	}
+	probedAt := time.Now()
+	meta.Media.MediaProbedAt = &probedAt
+	return meta
+}
+
+// VideoMediaFilter 媒体信息过滤条件，零值字段不参与过滤；列表字段内为 OR，字段之间为 AND
+type VideoMediaFilter struct {
+	VideoCodecs       []string `json:"video_codecs"`       // 视频编码，如 ["hevc","av1"]
+	AudioCodecs       []string `json:"audio_codecs"`       // 任一音轨使用其中之一
+	ContainerFormats  []string `json:"container_formats"`  // 容器格式，匹配 format_name 中的任一名称（如 mp4、matroska）
+	ColorTransfers    []string `json:"color_transfers"`    // 色彩传输特性
+	AudioLanguages    []string `json:"audio_languages"`    // 任一音轨语言
+	SubtitleLanguages []string `json:"subtitle_languages"` // 任一内嵌字幕语言
+	MinBitrate        int64    `json:"min_bitrate"`        // 码率下限 (bit/s，含)
+	MaxBitrate        int64    `json:"max_bitrate"`        // 码率上限 (bit/s，不含)
+	MinFrameRate      float64  `json:"min_frame_rate"`     // 帧率下限（含）
+	MaxFrameRate      float64  `json:"max_frame_rate"`     // 帧率上限（不含）
+	Rotations         []int    `json:"rotations"`          // 旋转角度
+	DynamicRange      string   `json:"dynamic_range"`      // "hdr" / "sdr"，为空不限
+	EmbeddedSubtitles string   `json:"embedded_subtitles"` // "with" / "without"，为空不限
+	CreatedAfter      string   `json:"created_after"`      // 媒体创建时间下限（含），YYYY-MM-DD 或 RFC3339
+	CreatedBefore     string   `json:"created_before"`     // 媒体创建时间上限（不含）
+}
+
+// applyVideoMediaFilter 把媒体过滤条件追加到 videos 查询；需要“未知即排除”的条件只匹配已解析过的记录
+func applyVideoMediaFilter(query *gorm.DB, filter VideoMediaFilter) (*gorm.DB, error) {
+	if values := normalizeMediaFilterValues(filter.VideoCodecs); len(values) > 0 {
+		query = query.Where("LOWER(videos.video_codec) IN ?", values)
+	}
+	if values := normalizeMediaFilterValues(filter.ColorTransfers); len(values) > 0 {
+		query = query.Where("LOWER(videos.color_transfer) IN ?", values)
+	}
+	query = whereMediaListContainsAny(query, "videos.audio_codecs", filter.AudioCodecs)
+	query = whereMediaListContainsAny(query, "videos.container_format", filter.ContainerFormats)
+	query = whereMediaListContainsAny(query, "videos.audio_languages", filter.AudioLanguages)
+	query = whereMediaListContainsAny(query, "videos.subtitle_languages", filter.SubtitleLanguages)
+
+	if filter.MinBitrate > 0 {
+		query = query.Where("videos.bitrate >= ?", filter.MinBitrate)
+	}
+	if filter.MaxBitrate > 0 {
+		query = query.Where("videos.bitrate > 0 AND videos.bitrate < ?", filter.MaxBitrate)
+	}
+	if filter.MinFrameRate > 0 {
+		query = query.Where("videos.frame_rate >= ?", filter.MinFrameRate)
+	}
+	if filter.MaxFrameRate > 0 {
+		query = query.Where("videos.frame_rate > 0 AND videos.frame_rate < ?", filter.MaxFrameRate)
+	}
+	if len(filter.Rotations) > 0 {
+		query = query.Where("videos.rotation IN ?", filter.Rotations).Where("videos.media_probed_at IS NOT NULL")
+	}
+
+	switch strings.ToLower(strings.TrimSpace(filter.DynamicRange)) {
+	case "":
+	case "hdr":
+		query = query.Where("videos.is_hdr = ?", true)
+	case "sdr":
+		query = query.Where("videos.is_hdr = ? AND videos.media_probed_at IS NOT NULL", false)
+	default:
+		return nil, fmt.Errorf("无效的动态范围过滤: %s", filter.DynamicRange)
+	}
+	switch strings.ToLower(strings.TrimSpace(filter.EmbeddedSubtitles)) {
+	case "":
+	case "with":
+		query = query.Where("videos.subtitle_stream_count > 0")
+	case "without":
+		query = query.Where("videos.subtitle_stream_count = 0 AND videos.media_probed_at IS NOT NULL")
+	default:
+		return nil, fmt.Errorf("无效的内嵌字幕过滤: %s", filter.EmbeddedSubtitles)
+	}
+
+	if after, err := parseMediaFilterTime(filter.CreatedAfter); err != nil {
+		return nil, err
+	} else if after != nil {
+		query = query.Where("videos.media_created_at >= ?", *after)
+	}
+	if before, err := parseMediaFilterTime(filter.CreatedBefore); err != nil {
+		return nil, err
+	} else if before != nil {
+		query = query.Where("videos.media_created_at < ?", *before)
+	}
+	return query, nil
+}
+
+// whereMediaListContainsAny 匹配逗号分隔列中的完整元素，避免 "ac3" 误中 "eac3"
+func whereMediaListContainsAny(query *gorm.DB, column string, values []string) *gorm.DB {
+	values = normalizeMediaFilterValues(values)
+	if len(values) == 0 {
+		return query
+	}
+	conditions := make([]string, 0, len(values))
+	args := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		conditions = append(conditions, "(',' || LOWER("+column+") || ',') LIKE ? ESCAPE '\\'")
+		args = append(args, "%,"+escapeSQLLike(value)+",%")
+	}
+	return query.Where("("+strings.Join(conditions, " OR ")+")", args...)
+}
+
+func normalizeMediaFilterValues(values []string) []string {
+	normalized := make([]string, 0, len(values))
+	for _, value := range values {
+		normalized = appendUniqueMediaValue(normalized, value)
+	}
+	return normalized
+}
+
+func parseMediaFilterTime(text string) (*time.Time, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if parsed, err := time.ParseInLocation(layout, text, time.Local); err == nil {
+			return &parsed, nil
+		}
+	}
+	return nil, fmt.Errorf("无效的日期: %s", text)
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"testing"
+	"time"
+	"video-master/database"
+	"video-master/models"
+)
+
+func TestParseFFProbeDetailsExtractsMediaInfo(t *testing.T) {
+	output := []byte(`{
+		"streams": [
+			{"codec_type": "video", "codec_name": "mjpeg", "width": 320, "height": 320, "disposition": {"attached_pic": 1}},
+			{"codec_type": "video", "codec_name": "HEVC", "width": 3840, "height": 2160,
+			 "avg_frame_rate": "24000/1001", "color_transfer": "smpte2084",
+			 "side_data_list": [{"side_data_type": "Display Matrix", "rotation": -90}]},
+			{"codec_type": "audio", "codec_name": "eac3", "tags": {"language": "jpn"}},
+			{"codec_type": "audio", "codec_name": "aac", "tags": {"language": "eng"}},
+			{"codec_type": "audio", "codec_name": "aac", "tags": {"language": "und"}},
+			{"codec_type": "subtitle", "codec_name": "subrip", "tags": {"language": "chi"}},
+			{"codec_type": "subtitle", "codec_name": "ass", "tags": {"language": "eng"}}
+		],
+		"format": {
+			"format_name": "matroska,webm", "duration": "5400.5", "bit_rate": "15000000",
+			"tags": {"creation_time": "2023-06-01T08:30:00.000000Z"}
+		}
+	}`)
+
+	meta, err := parseFFProbeDetails(output)
+	if err != nil {
+		t.Fatalf("解析 ffprobe 输出失败: %v", err)
+	}
+	if meta.Resolution != "3840x2160" || meta.Duration != 5400.5 {
+		t.Fatalf("应跳过封面图取主视频流: %+v", meta)
+	}
+	media := meta.Media
+	if media.VideoCodec != "hevc" || media.AudioCodecs != "eac3,aac" || media.ContainerFormat != "matroska,webm" {
+		t.Fatalf("编码或容器解析错误: %+v", media)
+	}
+	if media.Bitrate != 15000000 || media.FrameRate != 23.98 {
+		t.Fatalf("码率或帧率解析错误: bitrate=%d fps=%v", media.Bitrate, media.FrameRate)
+	}
+	if !media.IsHDR || media.ColorTransfer != "smpte2084" || media.Rotation != 90 {
+		t.Fatalf("HDR 或旋转解析错误: %+v", media)
+	}
+	if media.AudioLanguages != "jpn,eng" || media.SubtitleLanguages != "chi,eng" || media.SubtitleStreamCount != 2 {
+		t.Fatalf("音轨或字幕解析错误: %+v", media)
+	}
+	if media.MediaCreatedAt == nil || !media.MediaCreatedAt.Equal(time.Date(2023, 6, 1, 8, 30, 0, 0, time.UTC)) {
+		t.Fatalf("创建时间解析错误: %v", media.MediaCreatedAt)
+	}
+}
+
+func TestParseFFProbeDetailsRejectsAudioOnlyOutput(t *testing.T) {
+	output := []byte(`{"streams": [{"codec_type": "audio", "codec_name": "mp3"}], "format": {"duration": "180"}}`)
+	if _, err := parseFFProbeDetails(output); err == nil {
+		t.Fatalf("期望纯音频输出返回错误")
+	}
+}
+
+func TestSearchVideosWithFiltersMatchesMediaInfo(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	svc := &VideoService{}
+	probedAt := time.Now()
+	shotAt := time.Date(2022, 5, 1, 0, 0, 0, 0, time.Local)
+	videos := []models.Video{
+		{Name: "hdr.mkv", Path: "/library/hdr.mkv", Directory: "/library", Size: 300, MediaInfo: models.VideoMediaInfo{
+			VideoCodec: "hevc", AudioCodecs: "eac3,aac", Bitrate: 20000000, FrameRate: 23.98, IsHDR: true,
+			ContainerFormat: "matroska,webm", AudioLanguages: "jpn,eng", SubtitleLanguages: "chi", SubtitleStreamCount: 1,
+			MediaCreatedAt: &shotAt, MediaProbedAt: &probedAt,
+		}},
+		{Name: "phone.mp4", Path: "/library/phone.mp4", Directory: "/library", Size: 200, MediaInfo: models.VideoMediaInfo{
+			VideoCodec: "h264", AudioCodecs: "aac", Bitrate: 8000000, FrameRate: 60, Rotation: 90,
+			ContainerFormat: "mov,mp4,m4a,3gp,3g2,mj2", AudioLanguages: "eng", MediaProbedAt: &probedAt,
+		}},
+		{Name: "old.avi", Path: "/library/old.avi", Directory: "/library", Size: 100, MediaInfo: models.VideoMediaInfo{
+			VideoCodec: "mpeg4", AudioCodecs: "ac3", Bitrate: 400000, FrameRate: 25,
+			ContainerFormat: "avi", MediaProbedAt: &probedAt,
+		}},
+		{Name: "pending.mp4", Path: "/library/pending.mp4", Directory: "/library", Size: 50},
+	}
+	for idx := range videos {
+		if err := database.DB.Create(&videos[idx]).Error; err != nil {
+			t.Fatalf("创建视频失败: %v", err)
+		}
+	}
+
+	search := func(filter VideoMediaFilter) []string {
+		t.Helper()
+		result, err := svc.SearchVideosWithFilters("", nil, 0, 0, 0, 0, filter, 0, 0, 0, 100)
+		if err != nil {
+			t.Fatalf("媒体信息过滤失败: %v", err)
+		}
+		names := make([]string, 0, len(result))
+		for _, video := range result {
+			names = append(names, video.Name)
+		}
+		return names
+	}
+	cases := []struct {
+		name   string
+		filter VideoMediaFilter
+		want   []string
+	}{
+		{"视频编码", VideoMediaFilter{VideoCodecs: []string{"HEVC", "mpeg4"}}, []string{"hdr.mkv", "old.avi"}},
+		{"音频编码按完整元素匹配", VideoMediaFilter{AudioCodecs: []string{"ac3"}}, []string{"old.avi"}},
+		{"容器格式", VideoMediaFilter{ContainerFormats: []string{"mp4"}}, []string{"phone.mp4"}},
+		{"音轨语言", VideoMediaFilter{AudioLanguages: []string{"jpn"}}, []string{"hdr.mkv"}},
+		{"字幕语言", VideoMediaFilter{SubtitleLanguages: []string{"chi"}}, []string{"hdr.mkv"}},
+		{"码率区间", VideoMediaFilter{MinBitrate: 1000000, MaxBitrate: 10000000}, []string{"phone.mp4"}},
+		{"帧率下限", VideoMediaFilter{MinFrameRate: 50}, []string{"phone.mp4"}},
+		{"旋转", VideoMediaFilter{Rotations: []int{90, 270}}, []string{"phone.mp4"}},
+		{"HDR", VideoMediaFilter{DynamicRange: "hdr"}, []string{"hdr.mkv"}},
+		{"SDR 排除未解析记录", VideoMediaFilter{DynamicRange: "sdr"}, []string{"phone.mp4", "old.avi"}},
+		{"无内嵌字幕", VideoMediaFilter{EmbeddedSubtitles: "without"}, []string{"phone.mp4", "old.avi"}},
+		{"创建时间", VideoMediaFilter{CreatedAfter: "2022-01-01", CreatedBefore: "2023-01-01"}, []string{"hdr.mkv"}},
+	}
+	for _, tc := range cases {
+		got := search(tc.filter)
+		if len(got) != len(tc.want) {
+			t.Fatalf("%s: got=%v want=%v", tc.name, got, tc.want)
+		}
+		for idx := range got {
+			if got[idx] != tc.want[idx] {
+				t.Fatalf("%s: got=%v want=%v", tc.name, got, tc.want)
+			}
+		}
+	}
+
+	if _, err := svc.SearchVideosWithFilters("", nil, 0, 0, 0, 0, VideoMediaFilter{CreatedAfter: "yesterday"}, 0, 0, 0, 100); err == nil {
+		t.Fatalf("期望无效日期返回错误")
+	}
+}
@@ -0,0 +1,277 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"video-master/database"
+	"video-master/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	PlaybackEventSourceFormal          = "formal"
+	PlaybackEventSourceRandom          = "random"
+	PlaybackEventSourcePreview         = "preview"
+	PlaybackEventSourceExternalPreview = "external_preview"
+	PlaybackEventSourceShortFeed       = "short_feed"
+
+	defaultPlaybackHistoryLimit = 50
+	maxPlaybackHistoryLimit     = 500
+)
+
+var playbackEventSources = map[string]bool{
+	PlaybackEventSourceFormal:          true,
+	PlaybackEventSourceRandom:          true,
+	PlaybackEventSourcePreview:         true,
+	PlaybackEventSourceExternalPreview: true,
+	PlaybackEventSourceShortFeed:       true,
+}
+
+// PlaybackHistoryFilter 播放历史查询条件；From/To 为 YYYY-MM-DD（按本地日期，含当天）或 RFC3339，空值不限
+type PlaybackHistoryFilter struct {
+	VideoID       uint     `json:"video_id"`
+	Sources       []string `json:"sources"`
+	From          string   `json:"from"`
+	To            string   `json:"to"`
+	SucceededOnly bool     `json:"succeeded_only"`
+	Limit         int      `json:"limit"`
+	Offset        int      `json:"offset"`
+}
+
+type PlaybackHistoryPage struct {
+	Events []models.PlaybackEvent `json:"events"`
+	Total  int64                  `json:"total"`
+}
+
+// PlaybackHistoryService 浏览与删除播放历史，删除时回算视频上的聚合统计
+type PlaybackHistoryService struct{}
+
+// ListHistory 按播放时间倒序分页返回播放历史
+func (s *PlaybackHistoryService) ListHistory(filter PlaybackHistoryFilter) (*PlaybackHistoryPage, error) {
+	query := database.DB.Model(&models.PlaybackEvent{})
+	if filter.VideoID > 0 {
+		query = query.Where("video_id = ?", filter.VideoID)
+	}
+	if len(filter.Sources) > 0 {
+		for _, source := range filter.Sources {
+			if !playbackEventSources[source] {
+				return nil, fmt.Errorf("无效的播放来源: %s", source)
+			}
+		}
+		query = query.Where("source IN ?", filter.Sources)
+	}
+	if filter.SucceededOnly {
+		query = query.Where("succeeded = ?", true)
+	}
+	if from, ok, err := parsePlaybackHistoryBound(filter.From, false); err != nil {
+		return nil, err
+	} else if ok {
+		query = query.Where("played_at >= ?", from)
+	}
+	if to, ok, err := parsePlaybackHistoryBound(filter.To, true); err != nil {
+		return nil, err
+	} else if ok {
+		query = query.Where("played_at < ?", to)
+	}
+
+	page := &PlaybackHistoryPage{Events: []models.PlaybackEvent{}}
+	if err := query.Count(&page.Total).Error; err != nil {
+		return nil, err
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultPlaybackHistoryLimit
+	}
+	if limit > maxPlaybackHistoryLimit {
+		limit = maxPlaybackHistoryLimit
+	}
+	offset := filter.Offset
+	if offset < 0 {
+		offset = 0
+	}
+	// 已软删除的视频仍保留历史，Preload 需带上 Unscoped 才能显示名称
+	err := query.
+		Preload("Video", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Order("played_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&page.Events).Error
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// DeleteEvents 删除播放历史并回算受影响视频的播放次数与最后播放时间，返回实际删除条数
+func (s *PlaybackHistoryService) DeleteEvents(eventIDs []uint) (int64, error) {
+	if len(eventIDs) == 0 {
+		return 0, nil
+	}
+	var deleted int64
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var events []models.PlaybackEvent
+		if err := tx.Where("id IN ?", eventIDs).Find(&events).Error; err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+		ids := make([]uint, 0, len(events))
+		for _, event := range events {
+			ids = append(ids, event.ID)
+		}
+		result := tx.Where("id IN ?", ids).Delete(&models.PlaybackEvent{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted = result.RowsAffected
+		return recomputePlaybackAggregates(tx, events)
+	})
+	return deleted, err
+}
+
+func newPlaybackEvent(videoID uint, source string, outcome string, playedAt time.Time) models.PlaybackEvent {
+	return models.PlaybackEvent{
+		VideoID:   videoID,
+		Source:    source,
+		Outcome:   outcome,
+		Succeeded: outcome == "",
+		PlayedAt:  playedAt,
+	}
+}
+
+// recordPlaybackEvent 追加一条播放历史；写入失败只记日志，不影响播放本身
+func recordPlaybackEvent(db *gorm.DB, videoID uint, source string, outcome string, playedAt time.Time) {
+	event := newPlaybackEvent(videoID, source, outcome, playedAt)
+	if err := db.Create(&event).Error; err != nil {
+		log.Printf("记录播放历史失败 id=%d source=%s err=%v", videoID, source, err)
+	}
+}
+
+// recomputePlaybackAggregates 按被删除的成功事件扣减计数（历史表之前的播放不在表内，不能全量重算），
+// 最后播放时间取剩余事件中的最新值；剩余事件为空时，若原值来自被删事件则清空
+func recomputePlaybackAggregates(tx *gorm.DB, deleted []models.PlaybackEvent) error {
+	type counters struct {
+		formal, random, shortFeed int
+		latest                    time.Time
+	}
+	byVideo := map[uint]*counters{}
+	for _, event := range deleted {
+		if !event.Succeeded {
+			continue
+		}
+		c := byVideo[event.VideoID]
+		if c == nil {
+			c = &counters{}
+			byVideo[event.VideoID] = c
+		}
+		switch event.Source {
+		case PlaybackEventSourceFormal:
+			c.formal++
+		case PlaybackEventSourceRandom:
+			c.random++
+		case PlaybackEventSourceShortFeed:
+			// 短视频播放同时计入随机播放次数与 Feed 观看次数
+			c.random++
+			c.shortFeed++
+		default:
+			continue
+		}
+		if event.PlayedAt.After(c.latest) {
+			c.latest = event.PlayedAt
+		}
+	}
+
+	for videoID, c := range byVideo {
+		var video models.Video
+		if err := tx.Unscoped().Select("id", "play_count", "random_play_count", "last_played_at").First(&video, videoID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			return err
+		}
+		updates := map[string]interface{}{
+			"play_count":        max(video.PlayCount-c.formal, 0),
+			"random_play_count": max(video.RandomPlayCount-c.random, 0),
+		}
+		if c.formal+c.random > 0 {
+			lastPlayed, err := latestPlaybackEventTime(tx, videoID, PlaybackEventSourceFormal, PlaybackEventSourceRandom, PlaybackEventSourceShortFeed)
+			if err != nil {
+				return err
+			}
+			switch {
+			case lastPlayed != nil:
+				updates["last_played_at"] = *lastPlayed
+			case video.LastPlayedAt != nil && !video.LastPlayedAt.After(c.latest):
+				updates["last_played_at"] = nil
+			}
+		}
+		if err := tx.Unscoped().Model(&models.Video{}).Where("id = ?", videoID).UpdateColumns(updates).Error; err != nil {
+			return err
+		}
+
+		if c.shortFeed == 0 {
+			continue
+		}
+		var interaction models.ShortFeedInteraction
+		if err := tx.Where("video_id = ?", videoID).First(&interaction).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			return err
+		}
+		interactionUpdates := map[string]interface{}{"view_count": max(interaction.ViewCount-c.shortFeed, 0)}
+		lastViewed, err := latestPlaybackEventTime(tx, videoID, PlaybackEventSourceShortFeed)
+		if err != nil {
+			return err
+		}
+		switch {
+		case lastViewed != nil:
+			interactionUpdates["last_viewed_at"] = *lastViewed
+		case interaction.LastViewedAt != nil && !interaction.LastViewedAt.After(c.latest):
+			interactionUpdates["last_viewed_at"] = nil
+		}
+		if err := tx.Model(&interaction).Updates(interactionUpdates).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func latestPlaybackEventTime(tx *gorm.DB, videoID uint, sources ...string) (*time.Time, error) {
+	var event models.PlaybackEvent
+	err := tx.Where("video_id = ? AND succeeded = ? AND source IN ?", videoID, true, sources).
+		Order("played_at DESC").
+		Limit(1).
+		Find(&event).Error
+	if err != nil || event.ID == 0 {
+		return nil, err
+	}
+	return &event.PlayedAt, nil
+}
+
+func parsePlaybackHistoryBound(value string, upper bool) (time.Time, bool, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false, nil
+	}
+	if day, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		if upper {
+			day = day.AddDate(0, 0, 1)
+		}
+		return day, true, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("无效的日期: %s", value)
+	}
+	if upper {
+		parsed = parsed.Add(time.Nanosecond)
+	}
+	return parsed, true, nil
+}
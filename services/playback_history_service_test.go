@@ -0,0 +1,167 @@
+package services
+
+import (
+	"os"
+	"testing"
+	"time"
+	"video-master/database"
+	"video-master/models"
+)
+
+func TestPlaybackEventsRecordedForEachSource(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	root := t.TempDir()
+	video := createShortFeedVideo(t, root, "clip.mp4", 60, false)
+	svc := &VideoService{}
+
+	oldOpen := openWithDefaultFn
+	openWithDefaultFn = func(path string, isDir bool) error { return nil }
+	defer func() { openWithDefaultFn = oldOpen }()
+
+	if result, err := svc.PlayVideo(video.ID); err != nil || !result.DispatchSucceeded {
+		t.Fatalf("正式播放失败: result=%+v err=%v", result, err)
+	}
+	if _, err := svc.GetPreviewSession(video.ID); err != nil {
+		t.Fatalf("获取预览失败: %v", err)
+	}
+	if err := svc.PreviewExternally(video.ID); err != nil {
+		t.Fatalf("外部预览失败: %v", err)
+	}
+	if _, err := NewShortFeedService(svc).RecordShortFeedPlayback(video.ID); err != nil {
+		t.Fatalf("短视频播放失败: %v", err)
+	}
+	if err := os.Remove(video.Path); err != nil {
+		t.Fatalf("删除文件失败: %v", err)
+	}
+	if result, err := svc.PlayVideo(video.ID); err != nil || result.DispatchSucceeded {
+		t.Fatalf("文件缺失时应播放失败: result=%+v err=%v", result, err)
+	}
+
+	page, err := (&PlaybackHistoryService{}).ListHistory(PlaybackHistoryFilter{VideoID: video.ID})
+	if err != nil {
+		t.Fatalf("查询播放历史失败: %v", err)
+	}
+	if page.Total != 5 {
+		t.Fatalf("期望 5 条播放历史，got=%d", page.Total)
+	}
+	latest := page.Events[0]
+	if latest.Source != PlaybackEventSourceFormal || latest.Succeeded || latest.Outcome != "file_missing" {
+		t.Fatalf("失败的正式播放应记录原因码，got=%+v", latest)
+	}
+	if latest.Video.Name != "clip.mp4" {
+		t.Fatalf("播放历史应附带视频信息，got=%+v", latest.Video)
+	}
+	sources := map[string]bool{}
+	for _, event := range page.Events {
+		sources[event.Source] = true
+	}
+	for _, source := range []string{PlaybackEventSourceFormal, PlaybackEventSourcePreview, PlaybackEventSourceExternalPreview, PlaybackEventSourceShortFeed} {
+		if !sources[source] {
+			t.Fatalf("缺少来源 %s 的播放历史: %+v", source, page.Events)
+		}
+	}
+
+	succeeded, err := (&PlaybackHistoryService{}).ListHistory(PlaybackHistoryFilter{
+		Sources:       []string{PlaybackEventSourceFormal},
+		SucceededOnly: true,
+	})
+	if err != nil {
+		t.Fatalf("按来源过滤失败: %v", err)
+	}
+	if succeeded.Total != 1 || !succeeded.Events[0].Succeeded {
+		t.Fatalf("期望 1 条成功的正式播放，got=%+v", succeeded.Events)
+	}
+	if _, err := (&PlaybackHistoryService{}).ListHistory(PlaybackHistoryFilter{Sources: []string{"unknown"}}); err == nil {
+		t.Fatalf("未知来源应返回错误")
+	}
+}
+
+func TestPlaybackHistoryDateRangeFilter(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	root := t.TempDir()
+	video := createShortFeedVideo(t, root, "clip.mp4", 60, false)
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 12, 0, 0, 0, time.Local) }
+	for _, d := range []int{1, 5, 9} {
+		recordPlaybackEvent(database.DB, video.ID, PlaybackEventSourceFormal, "", day(d))
+	}
+
+	page, err := (&PlaybackHistoryService{}).ListHistory(PlaybackHistoryFilter{From: "2026-03-05", To: "2026-03-09"})
+	if err != nil {
+		t.Fatalf("按日期过滤失败: %v", err)
+	}
+	if page.Total != 2 || !page.Events[0].PlayedAt.Equal(day(9)) || !page.Events[1].PlayedAt.Equal(day(5)) {
+		t.Fatalf("日期范围应包含首尾两天，got=%+v", page.Events)
+	}
+
+	page, err = (&PlaybackHistoryService{}).ListHistory(PlaybackHistoryFilter{Limit: 1, Offset: 1})
+	if err != nil {
+		t.Fatalf("分页查询失败: %v", err)
+	}
+	if page.Total != 3 || len(page.Events) != 1 || !page.Events[0].PlayedAt.Equal(day(5)) {
+		t.Fatalf("分页结果错误，got=%+v", page)
+	}
+	if _, err := (&PlaybackHistoryService{}).ListHistory(PlaybackHistoryFilter{From: "last week"}); err == nil {
+		t.Fatalf("无效日期应返回错误")
+	}
+}
+
+func TestDeletePlaybackEventsRecomputesCounters(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	root := t.TempDir()
+	video := createShortFeedVideo(t, root, "clip.mp4", 60, false)
+	earlier := time.Now().Add(-2 * time.Hour).Round(time.Second)
+	later := time.Now().Add(-time.Hour).Round(time.Second)
+	if err := database.DB.Model(&video).Updates(map[string]interface{}{
+		"play_count":        3, // 其中一次早于播放历史
+		"random_play_count": 1,
+		"last_played_at":    later,
+	}).Error; err != nil {
+		t.Fatalf("初始化播放统计失败: %v", err)
+	}
+	if err := database.DB.Create(&models.ShortFeedInteraction{VideoID: video.ID, ViewCount: 1, LastViewedAt: &later}).Error; err != nil {
+		t.Fatalf("初始化短视频互动失败: %v", err)
+	}
+	recordPlaybackEvent(database.DB, video.ID, PlaybackEventSourceFormal, "", earlier)
+	recordPlaybackEvent(database.DB, video.ID, PlaybackEventSourceFormal, "", later.Add(-time.Minute))
+	recordPlaybackEvent(database.DB, video.ID, PlaybackEventSourceShortFeed, "", later)
+	recordPlaybackEvent(database.DB, video.ID, PlaybackEventSourceFormal, "file_missing", later)
+
+	var events []models.PlaybackEvent
+	if err := database.DB.Order("id").Find(&events).Error; err != nil {
+		t.Fatalf("读取播放历史失败: %v", err)
+	}
+	svc := &PlaybackHistoryService{}
+
+	deleted, err := svc.DeleteEvents([]uint{events[2].ID, events[3].ID})
+	if err != nil || deleted != 2 {
+		t.Fatalf("删除播放历史失败 deleted=%d err=%v", deleted, err)
+	}
+	var reloaded models.Video
+	if err := database.DB.First(&reloaded, video.ID).Error; err != nil {
+		t.Fatalf("读取视频失败: %v", err)
+	}
+	if reloaded.PlayCount != 3 || reloaded.RandomPlayCount != 0 {
+		t.Fatalf("删除短视频事件应扣减随机播放次数，失败事件不影响计数，got play=%d random=%d", reloaded.PlayCount, reloaded.RandomPlayCount)
+	}
+	if reloaded.LastPlayedAt == nil || !reloaded.LastPlayedAt.Equal(later.Add(-time.Minute)) {
+		t.Fatalf("最后播放时间应回退到剩余最新事件，got=%v", reloaded.LastPlayedAt)
+	}
+	interaction, err := interactionForVideo(video.ID)
+	if err != nil {
+		t.Fatalf("读取短视频互动失败: %v", err)
+	}
+	if interaction.ViewCount != 0 || interaction.LastViewedAt != nil {
+		t.Fatalf("删除短视频事件应回算观看次数，got=%+v", interaction)
+	}
+
+	if _, err := svc.DeleteEvents([]uint{events[0].ID, events[1].ID}); err != nil {
+		t.Fatalf("删除播放历史失败: %v", err)
+	}
+	reloaded = models.Video{}
+	if err := database.DB.First(&reloaded, video.ID).Error; err != nil {
+		t.Fatalf("读取视频失败: %v", err)
+	}
+	if reloaded.PlayCount != 1 || reloaded.LastPlayedAt != nil {
+		t.Fatalf("历史之前的播放次数应保留，最后播放时间应清空，got play=%d last=%v", reloaded.PlayCount, reloaded.LastPlayedAt)
+	}
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+	"video-master/database"
+	"video-master/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	PlaybackSourcePreview   = "preview"
+	PlaybackSourceShortFeed = "short_feed"
+
+	playbackCompletedPercent     = 90.0 // 观看超过该比例视为看完，不再续播
+	playbackMinResumeSeconds     = 5.0  // 播放不足该秒数不续播，也不进入继续观看
+	defaultContinueWatchingLimit = 20
+	maxContinueWatchingLimit     = 100
+)
+
+// PlaybackProgressService 维护每个视频的续播位置与观看比例
+type PlaybackProgressService struct{}
+
+// UpdateProgress 记录播放位置；duration 缺失时使用库内时长，位置会被限制在时长内
+func (s *PlaybackProgressService) UpdateProgress(videoID uint, position float64, duration float64, source string) (*models.PlaybackProgress, error) {
+	if source != PlaybackSourcePreview && source != PlaybackSourceShortFeed {
+		return nil, fmt.Errorf("%w: 无效的播放来源 %s", ErrInvalidPlaybackProgress, source)
+	}
+	if math.IsNaN(position) || math.IsInf(position, 0) || position < 0 {
+		return nil, fmt.Errorf("%w: 无效的播放位置 %v", ErrInvalidPlaybackProgress, position)
+	}
+	var video models.Video
+	if err := database.DB.Select("id", "duration").First(&video, videoID).Error; err != nil {
+		return nil, err
+	}
+	if math.IsNaN(duration) || math.IsInf(duration, 0) || duration <= 0 {
+		duration = video.Duration
+	}
+
+	percent := 0.0
+	if duration > 0 {
+		position = math.Min(position, duration)
+		percent = math.Round(position/duration*1000) / 10
+	}
+	now := time.Now()
+	progress := models.PlaybackProgress{
+		VideoID:         videoID,
+		PositionSeconds: position,
+		DurationSeconds: duration,
+		Percent:         percent,
+		Completed:       percent >= playbackCompletedPercent,
+		Source:          source,
+		LastWatchedAt:   now,
+	}
+	if err := database.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "video_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"position_seconds", "duration_seconds", "percent", "completed", "source", "last_watched_at", "updated_at",
+		}),
+	}).Create(&progress).Error; err != nil {
+		return nil, err
+	}
+	return s.GetProgress(videoID)
+}
+
+// GetProgress 返回视频的播放进度，从未播放过时返回 nil
+func (s *PlaybackProgressService) GetProgress(videoID uint) (*models.PlaybackProgress, error) {
+	var progress models.PlaybackProgress
+	err := database.DB.Where("video_id = ?", videoID).First(&progress).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &progress, nil
+}
+
+// ClearProgress 清除视频的播放进度（从继续观看中移除）
+func (s *PlaybackProgressService) ClearProgress(videoID uint) error {
+	return database.DB.Where("video_id = ?", videoID).Delete(&models.PlaybackProgress{}).Error
+}
+
+// ContinueWatching 按最近观看时间列出看了一部分、尚未看完的视频
+func (s *PlaybackProgressService) ContinueWatching(limit int) ([]models.PlaybackProgress, error) {
+	if limit <= 0 {
+		limit = defaultContinueWatchingLimit
+	}
+	if limit > maxContinueWatchingLimit {
+		limit = maxContinueWatchingLimit
+	}
+	var rows []models.PlaybackProgress
+	err := database.DB.
+		Joins("JOIN videos ON videos.id = playback_progresses.video_id AND videos.deleted_at IS NULL").
+		Where("playback_progresses.completed = ? AND playback_progresses.position_seconds >= ?", false, playbackMinResumeSeconds).
+		Where("videos.is_stale = ?", false).
+		Preload("Video.Tags").
+		Order("playback_progresses.last_watched_at DESC, playback_progresses.id DESC").
+		Limit(limit).
+		Find(&rows).Error
+	return rows, err
+}
+
+// resumePositionForVideo 返回应续播的位置；已看完或进度太短时从头开始
+func resumePositionForVideo(videoID uint) float64 {
+	var progress models.PlaybackProgress
+	if err := database.DB.Where("video_id = ?", videoID).First(&progress).Error; err != nil {
+		return 0
+	}
+	if progress.Completed || progress.PositionSeconds < playbackMinResumeSeconds {
+		return 0
+	}
+	return progress.PositionSeconds
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+	"video-master/database"
+	"video-master/models"
+)
+
+func TestPlaybackProgressUpdateClampsAndMarksCompleted(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	root := t.TempDir()
+	video := createShortFeedVideo(t, root, "movie.mp4", 200, false)
+	svc := &PlaybackProgressService{}
+
+	progress, err := svc.UpdateProgress(video.ID, 50, 0, PlaybackSourcePreview)
+	if err != nil {
+		t.Fatalf("记录进度失败: %v", err)
+	}
+	if progress.DurationSeconds != 200 || progress.Percent != 25 || progress.Completed {
+		t.Fatalf("缺少时长时应使用库内时长，got=%+v", progress)
+	}
+
+	progress, err = svc.UpdateProgress(video.ID, 500, 200, PlaybackSourceShortFeed)
+	if err != nil {
+		t.Fatalf("更新进度失败: %v", err)
+	}
+	if progress.PositionSeconds != 200 || progress.Percent != 100 || !progress.Completed || progress.Source != PlaybackSourceShortFeed {
+		t.Fatalf("超出时长的位置应被限制并标记看完，got=%+v", progress)
+	}
+	if count := countShortFeedRows(t, "playback_progresses"); count != 1 {
+		t.Fatalf("同一视频只应保留一条进度，got=%d", count)
+	}
+	if pos := resumePositionForVideo(video.ID); pos != 0 {
+		t.Fatalf("已看完的视频应从头播放，got=%v", pos)
+	}
+
+	if _, err := svc.UpdateProgress(video.ID, -1, 200, PlaybackSourcePreview); !errors.Is(err, ErrInvalidPlaybackProgress) {
+		t.Fatalf("负数位置应被拒绝，err=%v", err)
+	}
+	if _, err := svc.UpdateProgress(video.ID, 10, 200, "external"); !errors.Is(err, ErrInvalidPlaybackProgress) {
+		t.Fatalf("未知来源应被拒绝，err=%v", err)
+	}
+}
+
+func TestContinueWatchingListsPartiallyWatchedVideos(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	root := t.TempDir()
+	older := createShortFeedVideo(t, root, "older.mp4", 100, false)
+	newer := createShortFeedVideo(t, root, "newer.mp4", 100, false)
+	finished := createShortFeedVideo(t, root, "finished.mp4", 100, false)
+	barelyStarted := createShortFeedVideo(t, root, "barely.mp4", 100, false)
+	stale := createShortFeedVideo(t, root, "stale.mp4", 100, true)
+	svc := &PlaybackProgressService{}
+
+	for _, item := range []struct {
+		videoID  uint
+		position float64
+	}{
+		{older.ID, 30}, {newer.ID, 40}, {finished.ID, 95}, {barelyStarted.ID, 2}, {stale.ID, 30},
+	} {
+		if _, err := svc.UpdateProgress(item.videoID, item.position, 100, PlaybackSourcePreview); err != nil {
+			t.Fatalf("记录进度失败: %v", err)
+		}
+	}
+	if err := database.DB.Model(&models.PlaybackProgress{}).Where("video_id = ?", older.ID).
+		Update("last_watched_at", time.Now().Add(-time.Hour)).Error; err != nil {
+		t.Fatalf("调整观看时间失败: %v", err)
+	}
+
+	rows, err := svc.ContinueWatching(0)
+	if err != nil {
+		t.Fatalf("查询继续观看失败: %v", err)
+	}
+	if len(rows) != 2 || rows[0].VideoID != newer.ID || rows[1].VideoID != older.ID {
+		t.Fatalf("继续观看应只含未看完的视频并按最近观看排序，got=%+v", rows)
+	}
+	if rows[0].Video.Name != "newer.mp4" {
+		t.Fatalf("继续观看应附带视频信息，got=%+v", rows[0].Video)
+	}
+
+	session, err := (&VideoService{}).GetPreviewSession(newer.ID)
+	if err != nil {
+		t.Fatalf("获取预览失败: %v", err)
+	}
+	if session.ResumePosition != 40 {
+		t.Fatalf("预览应从上次位置续播，got=%v", session.ResumePosition)
+	}
+
+	if err := svc.ClearProgress(newer.ID); err != nil {
+		t.Fatalf("清除进度失败: %v", err)
+	}
+	rows, err = svc.ContinueWatching(10)
+	if err != nil {
+		t.Fatalf("查询继续观看失败: %v", err)
+	}
+	if len(rows) != 1 || rows[0].VideoID != older.ID {
+		t.Fatalf("清除后应从继续观看中移除，got=%+v", rows)
+	}
+}
+
+func TestShortFeedHTTPProgressAndContinueWatching(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	root := t.TempDir()
+	video := createShortFeedVideo(t, root, "clip.mp4", 60, false)
+	long := createShortFeedVideo(t, root, "long.mp4", 900, false)
+	server := NewShortFeedHTTPServer(NewShortFeedService(&VideoService{}), fstest.MapFS{
+		"short.html": &fstest.MapFile{Data: []byte("<div>short</div>"), ModTime: time.Now()},
+	}, ShortFeedHTTPServerConfig{BindAddress: "127.0.0.1", PortStart: 18088, PortEnd: 18088})
+	handler := server.Handler()
+
+	post := func(videoID uint, body string) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/short-api/videos/"+strconvUint(videoID)+"/progress", strings.NewReader(body))
+		req.RemoteAddr = "127.0.0.1:1234"
+		req.Host = "127.0.0.1:18088"
+		req.Header.Set("Content-Type", "application/json")
+		handler.ServeHTTP(resp, req)
+		return resp
+	}
+
+	if resp := post(video.ID, `{"position_seconds":20,"duration_seconds":60}`); resp.Code != http.StatusOK || !strings.Contains(resp.Body.String(), `"percent":33.3`) {
+		t.Fatalf("记录短视频进度失败 code=%d body=%s", resp.Code, resp.Body.String())
+	}
+	if resp := post(video.ID, `{"position_seconds":-5,"duration_seconds":60}`); resp.Code != http.StatusBadRequest {
+		t.Fatalf("无效进度应返回 400，got=%d", resp.Code)
+	}
+	if resp := post(long.ID, `{"position_seconds":20,"duration_seconds":900}`); resp.Code != http.StatusBadRequest {
+		t.Fatalf("超出短视频时长上限的视频应被拒绝，got=%d", resp.Code)
+	}
+
+	resp := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/short-api/continue-watching", nil)
+	req.RemoteAddr = "127.0.0.1:1234"
+	handler.ServeHTTP(resp, req)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("继续观看接口失败 code=%d body=%s", resp.Code, resp.Body.String())
+	}
+	body := resp.Body.String()
+	if !strings.Contains(body, `"name":"clip.mp4"`) || !strings.Contains(body, `"resume_position":20`) {
+		t.Fatalf("继续观看应返回带续播位置的视频，body=%s", body)
+	}
+}
@@ -0,0 +1,277 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"video-master/database"
+	"video-master/models"
+	"video-master/services/subtitleparser"
+)
+
+const (
+	playerPlaceholderPath     = "{path}"
+	playerPlaceholderStart    = "{start}"
+	playerPlaceholderSubtitle = "{subtitle}"
+
+	DefaultPlayerArgsTemplate = playerPlaceholderPath
+)
+
+var startPlayerFn = startPlayerProcess
+
+// PlayerProfileService 管理外部播放器配置，并为正式播放/外部预览选择播放器
+type PlayerProfileService struct{}
+
+// GetAllPlayerProfiles 按优先级列出外部播放器配置
+func (s *PlayerProfileService) GetAllPlayerProfiles() ([]models.PlayerProfile, error) {
+	var profiles []models.PlayerProfile
+	err := database.DB.Order("priority asc, id asc").Find(&profiles).Error
+	return profiles, err
+}
+
+// AddPlayerProfile 新增外部播放器配置
+func (s *PlayerProfileService) AddPlayerProfile(input models.PlayerProfile) (*models.PlayerProfile, error) {
+	profile, err := normalizePlayerProfile(input)
+	if err != nil {
+		return nil, err
+	}
+	profile.ID = 0
+	if err := database.DB.Create(&profile).Error; err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// UpdatePlayerProfile 更新外部播放器配置
+func (s *PlayerProfileService) UpdatePlayerProfile(input models.PlayerProfile) error {
+	profile, err := normalizePlayerProfile(input)
+	if err != nil {
+		return err
+	}
+	result := database.DB.Model(&models.PlayerProfile{}).Where("id = ?", input.ID).Updates(map[string]interface{}{
+		"name":            profile.Name,
+		"executable_path": profile.ExecutablePath,
+		"args_template":   profile.ArgsTemplate,
+		"extensions":      profile.Extensions,
+		"priority":        profile.Priority,
+		"enabled":         profile.Enabled,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("播放器配置不存在: %d", input.ID)
+	}
+	return nil
+}
+
+// DeletePlayerProfile 删除外部播放器配置
+func (s *PlayerProfileService) DeletePlayerProfile(id uint) error {
+	return database.DB.Delete(&models.PlayerProfile{}, id).Error
+}
+
+func normalizePlayerProfile(input models.PlayerProfile) (models.PlayerProfile, error) {
+	profile := input
+	profile.Name = strings.TrimSpace(profile.Name)
+	profile.ExecutablePath = strings.TrimSpace(profile.ExecutablePath)
+	profile.ArgsTemplate = strings.TrimSpace(profile.ArgsTemplate)
+	if profile.ArgsTemplate == "" {
+		profile.ArgsTemplate = DefaultPlayerArgsTemplate
+	}
+	profile.Extensions = strings.Join(normalizeVideoExtensions(profile.Extensions), ",")
+	if profile.Name == "" {
+		return profile, fmt.Errorf("播放器名称不能为空")
+	}
+	if profile.ExecutablePath == "" {
+		return profile, fmt.Errorf("播放器可执行文件不能为空")
+	}
+	if err := validatePlayerArgsTemplate(profile.ArgsTemplate); err != nil {
+		return profile, err
+	}
+	return profile, nil
+}
+
+func validatePlayerArgsTemplate(template string) error {
+	tokens, err := splitPlayerArgs(template)
+	if err != nil {
+		return err
+	}
+	for _, token := range tokens {
+		rest := token
+		for {
+			start := strings.Index(rest, "{")
+			if start < 0 {
+				break
+			}
+			end := strings.Index(rest[start:], "}")
+			if end < 0 {
+				break
+			}
+			switch placeholder := rest[start : start+end+1]; placeholder {
+			case playerPlaceholderPath, playerPlaceholderStart, playerPlaceholderSubtitle:
+			default:
+				return fmt.Errorf("未知的参数占位符 %s，可用 {path}、{start}、{subtitle}", placeholder)
+			}
+			rest = rest[start+end+1:]
+		}
+	}
+	return nil
+}
+
+// selectPlayerProfile 选择视频适用的已启用播放器：指定了该格式的配置优先于未限定格式的配置，同类按优先级
+func selectPlayerProfile(videoPath string) (*models.PlayerProfile, error) {
+	var profiles []models.PlayerProfile
+	if err := database.DB.Where("enabled = ?", true).Order("priority asc, id asc").Find(&profiles).Error; err != nil {
+		return nil, err
+	}
+	ext := strings.ToLower(filepath.Ext(videoPath))
+	var fallback *models.PlayerProfile
+	for i := range profiles {
+		exts := normalizeVideoExtensions(profiles[i].Extensions)
+		if len(exts) == 0 {
+			if fallback == nil {
+				fallback = &profiles[i]
+			}
+			continue
+		}
+		for _, candidate := range exts {
+			if candidate == ext {
+				return &profiles[i], nil
+			}
+		}
+	}
+	return fallback, nil
+}
+
+// buildPlayerArgs 展开参数模板；占位符取值为空（无字幕、起点为 0）时整段参数被省略，模板未引用 {path} 时把路径追加到末尾
+func buildPlayerArgs(template string, videoPath string, startSeconds float64, subtitlePath string) ([]string, error) {
+	tokens, err := splitPlayerArgs(template)
+	if err != nil {
+		return nil, err
+	}
+	start := ""
+	if startSeconds > 0 {
+		start = strconv.FormatFloat(math.Round(startSeconds*1000)/1000, 'f', -1, 64)
+	}
+	values := map[string]string{
+		playerPlaceholderPath:     videoPath,
+		playerPlaceholderStart:    start,
+		playerPlaceholderSubtitle: subtitlePath,
+	}
+	// 一次性替换原始参数中的全部占位符，避免路径等取值里恰好含有占位符文本时被再次展开
+	replacer := strings.NewReplacer(
+		playerPlaceholderPath, videoPath,
+		playerPlaceholderStart, start,
+		playerPlaceholderSubtitle, subtitlePath,
+	)
+
+	args := make([]string, 0, len(tokens)+1)
+	hasPath := false
+	for _, token := range tokens {
+		if strings.Contains(token, playerPlaceholderPath) {
+			hasPath = true
+		}
+		skip := false
+		for placeholder, value := range values {
+			if value == "" && strings.Contains(token, placeholder) {
+				skip = true
+				break
+			}
+		}
+		if !skip {
+			args = append(args, replacer.Replace(token))
+		}
+	}
+	if !hasPath {
+		args = append(args, videoPath)
+	}
+	return args, nil
+}
+
+// splitPlayerArgs 按空白切分参数模板，单/双引号内的空白保留，不经过 shell
+func splitPlayerArgs(template string) ([]string, error) {
+	var tokens []string
+	var current strings.Builder
+	var quote rune
+	inToken := false
+	for _, r := range template {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inToken = true
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inToken {
+				tokens = append(tokens, current.String())
+				current.Reset()
+				inToken = false
+			}
+		default:
+			current.WriteRune(r)
+			inToken = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("参数模板中的引号未闭合")
+	}
+	if inToken {
+		tokens = append(tokens, current.String())
+	}
+	return tokens, nil
+}
+
+// siblingSubtitlePath 返回与视频同名且存在的 .srt 字幕路径
+func siblingSubtitlePath(videoPath string) string {
+	srtPath := subtitleparser.SRTPathForVideo(videoPath)
+	if info, err := os.Stat(srtPath); err == nil && !info.IsDir() {
+		return srtPath
+	}
+	return ""
+}
+
+// launchInPlayer 用匹配的外部播放器打开视频；未配置播放器时退回系统默认方式（此时不支持起始位置）
+func launchInPlayer(videoPath string, startSeconds float64) error {
+	return launchPathInPlayer(videoPath, videoPath, startSeconds, siblingSubtitlePath(videoPath))
+}
+
+// launchPlaylistInPlayer 用适用于首个视频的外部播放器打开 .m3u8 播放列表；起始位置与字幕只对单个文件有意义，不再传入
+func launchPlaylistInPlayer(firstVideoPath string, playlistPath string) error {
+	return launchPathInPlayer(firstVideoPath, playlistPath, 0, "")
+}
+
+// launchPathInPlayer 按 profilePath 的格式选择播放器，把 targetPath 代入 {path} 启动
+func launchPathInPlayer(profilePath string, targetPath string, startSeconds float64, subtitlePath string) error {
+	profile, err := selectPlayerProfile(profilePath)
+	if err != nil {
+		log.Printf("读取播放器配置失败，改用系统默认播放器 err=%v", err)
+	}
+	if profile == nil {
+		return openWithDefaultFn(targetPath, false)
+	}
+	args, err := buildPlayerArgs(profile.ArgsTemplate, targetPath, startSeconds, subtitlePath)
+	if err != nil {
+		return fmt.Errorf("播放器 %s 参数模板无效: %w", profile.Name, err)
+	}
+	log.Printf("使用外部播放器 profile=%s exe=%s args=%q", profile.Name, profile.ExecutablePath, args)
+	return startPlayerFn(profile.ExecutablePath, args)
+}
+
+func startPlayerProcess(executable string, args []string) error {
+	cmd := exec.Command(executable, args...)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	// 回收子进程，避免播放器退出后留下僵尸进程
+	go func() { _ = cmd.Wait() }()
+	return nil
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"video-master/database"
+	"video-master/models"
+	"video-master/services/subtitleparser"
+)
+
+func TestBuildPlayerArgsExpandsPlaceholders(t *testing.T) {
+	args, err := buildPlayerArgs(`--start={start} "--sub-file={subtitle}" --title "My Player" {path}`, "/v/a b.mkv", 12.3456, "/v/a b.srt")
+	if err != nil {
+		t.Fatalf("展开参数失败: %v", err)
+	}
+	want := []string{"--start=12.346", "--sub-file=/v/a b.srt", "--title", "My Player", "/v/a b.mkv"}
+	if !reflect.DeepEqual(args, want) {
+		t.Fatalf("参数展开错误，got=%q want=%q", args, want)
+	}
+
+	args, err = buildPlayerArgs("--start={start} --sub-file={subtitle} --fullscreen", "/v/a.mp4", 0, "")
+	if err != nil {
+		t.Fatalf("展开参数失败: %v", err)
+	}
+	want = []string{"--fullscreen", "/v/a.mp4"}
+	if !reflect.DeepEqual(args, want) {
+		t.Fatalf("空值占位符应省略对应参数且路径追加到末尾，got=%q", args)
+	}
+
+	// 取值中含占位符文本时不应被再次展开或导致参数被省略
+	for i := 0; i < 20; i++ {
+		args, err = buildPlayerArgs("--start={start} --sub={subtitle} {path}", "/v/clip {subtitle} {start}.mp4", 10, "")
+		if err != nil {
+			t.Fatalf("展开参数失败: %v", err)
+		}
+		want = []string{"--start=10", "/v/clip {subtitle} {start}.mp4"}
+		if !reflect.DeepEqual(args, want) {
+			t.Fatalf("路径中的占位符文本应原样保留，got=%q want=%q", args, want)
+		}
+	}
+
+	if _, err := buildPlayerArgs(`"--unterminated {path}`, "/v/a.mp4", 0, ""); err == nil {
+		t.Fatalf("未闭合引号应返回错误")
+	}
+}
+
+func TestPlayerProfileValidationAndSelection(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	svc := &PlayerProfileService{}
+
+	if _, err := svc.AddPlayerProfile(models.PlayerProfile{Name: "bad", ExecutablePath: "/bin/mpv", ArgsTemplate: "{file}"}); err == nil {
+		t.Fatalf("未知占位符应被拒绝")
+	}
+	if _, err := svc.AddPlayerProfile(models.PlayerProfile{Name: "bad"}); err == nil {
+		t.Fatalf("缺少可执行文件应被拒绝")
+	}
+
+	general, err := svc.AddPlayerProfile(models.PlayerProfile{Name: "vlc", ExecutablePath: "/bin/vlc", Enabled: true})
+	if err != nil {
+		t.Fatalf("新增播放器失败: %v", err)
+	}
+	if general.ArgsTemplate != DefaultPlayerArgsTemplate {
+		t.Fatalf("空模板应使用默认模板，got=%q", general.ArgsTemplate)
+	}
+	mkv, err := svc.AddPlayerProfile(models.PlayerProfile{Name: "mpv", ExecutablePath: "/bin/mpv", Extensions: "MKV, webm", Priority: 5, Enabled: true})
+	if err != nil {
+		t.Fatalf("新增播放器失败: %v", err)
+	}
+	if mkv.Extensions != ".mkv,.webm" {
+		t.Fatalf("格式列表应被规范化，got=%q", mkv.Extensions)
+	}
+
+	if profile, _ := selectPlayerProfile("/v/movie.MKV"); profile == nil || profile.ID != mkv.ID {
+		t.Fatalf("指定格式的配置应优先于通用配置，got=%+v", profile)
+	}
+	if profile, _ := selectPlayerProfile("/v/movie.mp4"); profile == nil || profile.ID != general.ID {
+		t.Fatalf("未匹配格式时应使用通用配置，got=%+v", profile)
+	}
+
+	general.Enabled = false
+	if err := svc.UpdatePlayerProfile(*general); err != nil {
+		t.Fatalf("更新播放器失败: %v", err)
+	}
+	if profile, _ := selectPlayerProfile("/v/movie.mp4"); profile != nil {
+		t.Fatalf("停用的配置不应被选中，got=%+v", profile)
+	}
+	if err := svc.UpdatePlayerProfile(models.PlayerProfile{ID: 999, Name: "x", ExecutablePath: "/bin/x"}); err == nil {
+		t.Fatalf("更新不存在的配置应返回错误")
+	}
+}
+
+func TestFormalPlaybackUsesPlayerProfileWithResumeAndSubtitle(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	root := t.TempDir()
+	video := createShortFeedVideo(t, root, "movie.mkv", 600, false)
+	srtPath := subtitleparser.SRTPathForVideo(video.Path)
+	if err := os.WriteFile(srtPath, []byte("1\n00:00:01,000 --> 00:00:02,000\nhi\n"), 0644); err != nil {
+		t.Fatalf("写入字幕失败: %v", err)
+	}
+	if _, err := (&PlaybackProgressService{}).UpdateProgress(video.ID, 95.5, 600, PlaybackSourcePreview); err != nil {
+		t.Fatalf("记录进度失败: %v", err)
+	}
+	if err := database.DB.Create(&models.PlayerProfile{
+		Name:           "mpv",
+		ExecutablePath: "/usr/bin/mpv",
+		ArgsTemplate:   "--start={start} --sub-file={subtitle} {path}",
+		Enabled:        true,
+	}).Error; err != nil {
+		t.Fatalf("创建播放器配置失败: %v", err)
+	}
+
+	var gotExe string
+	var gotArgs []string
+	oldStart := startPlayerFn
+	startPlayerFn = func(executable string, args []string) error {
+		gotExe, gotArgs = executable, args
+		return nil
+	}
+	defer func() { startPlayerFn = oldStart }()
+	oldOpen := openWithDefaultFn
+	openWithDefaultFn = func(path string, isDir bool) error {
+		t.Fatalf("配置了播放器时不应使用系统默认方式打开")
+		return nil
+	}
+	defer func() { openWithDefaultFn = oldOpen }()
+
+	result, err := (&VideoService{}).PlayVideo(video.ID)
+	if err != nil || !result.DispatchSucceeded {
+		t.Fatalf("正式播放失败: result=%+v err=%v", result, err)
+	}
+	want := []string{"--start=95.5", "--sub-file=" + srtPath, video.Path}
+	if gotExe != "/usr/bin/mpv" || !reflect.DeepEqual(gotArgs, want) {
+		t.Fatalf("播放器调用错误 exe=%s args=%q", gotExe, gotArgs)
+	}
+
+	if err := (&VideoService{}).PreviewExternally(video.ID); err != nil {
+		t.Fatalf("外部预览失败: %v", err)
+	}
+	want = []string{"--sub-file=" + srtPath, video.Path}
+	if !reflect.DeepEqual(gotArgs, want) {
+		t.Fatalf("外部预览应从头播放，args=%q", gotArgs)
+	}
+}
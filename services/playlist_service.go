@@ -0,0 +1,603 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"video-master/database"
+	"video-master/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	PlaylistKindManual = "manual"
+	PlaylistKindSmart  = "smart"
+
+	PlaylistRepeatOff = "off"
+	PlaylistRepeatAll = "all"
+	PlaylistRepeatOne = "one"
+
+	PlaylistTargetExternal = "external"
+	PlaylistTargetInline   = "inline"
+
+	PlaylistStepNext     = "next"
+	PlaylistStepPrevious = "previous"
+	PlaylistStepEnded    = "ended" // 当前项自然播完，单曲循环时重播
+
+	defaultSmartPlaylistLimit = 200
+	maxSmartPlaylistLimit     = 1000
+)
+
+// PlaylistSmartFilter 智能播放列表的条件：与保存的搜索相同的组合搜索条件，外加取用的视频数上限
+type PlaylistSmartFilter struct {
+	VideoSearchFilter
+	Limit int `json:"limit"` // 最多取多少个视频，0 表示默认 200
+}
+
+// PlaylistInput 新建/编辑播放列表的参数；SmartFilter 仅对智能列表有效
+type PlaylistInput struct {
+	Name        string               `json:"name"`
+	Kind        string               `json:"kind"`
+	SmartFilter *PlaylistSmartFilter `json:"smart_filter"`
+	Shuffle     bool                 `json:"shuffle"`
+	RepeatMode  string               `json:"repeat_mode"`
+}
+
+// PlaylistPlaybackResult 播放列表当前项的播放结果；Target 为 inline 时附带预览会话供内嵌播放器使用
+type PlaylistPlaybackResult struct {
+	PlaylistID     uint                   `json:"playlist_id"`
+	Position       int                    `json:"position"`
+	Total          int                    `json:"total"`
+	Finished       bool                   `json:"finished"` // 已播完且不循环，此时不返回视频
+	Video          *models.Video          `json:"video,omitempty"`
+	Attempt        *PlaybackAttemptResult `json:"attempt,omitempty"`
+	PreviewSession *PreviewSession        `json:"preview_session,omitempty"`
+}
+
+// PlaylistService 管理播放列表，并按随机/循环模式逐项发起播放
+type PlaylistService struct {
+	videoService *VideoService
+	shuffle      func(n int, swap func(i, j int))
+}
+
+func NewPlaylistService(videoService *VideoService) *PlaylistService {
+	return &PlaylistService{
+		videoService: videoService,
+		shuffle:      rand.Shuffle,
+	}
+}
+
+// GetPlaylists 列出全部播放列表（不含条目）
+func (s *PlaylistService) GetPlaylists() ([]models.Playlist, error) {
+	var playlists []models.Playlist
+	if err := database.DB.Order("name asc, id asc").Find(&playlists).Error; err != nil {
+		return nil, err
+	}
+	type countRow struct {
+		PlaylistID uint
+		Count      int
+	}
+	var rows []countRow
+	if err := database.DB.Model(&models.PlaylistItem{}).
+		Select("playlist_id, COUNT(*) AS count").
+		Group("playlist_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	counts := make(map[uint]int, len(rows))
+	for _, row := range rows {
+		counts[row.PlaylistID] = row.Count
+	}
+	for i := range playlists {
+		playlists[i].ItemCount = counts[playlists[i].ID]
+	}
+	return playlists, nil
+}
+
+// GetPlaylist 获取播放列表及按顺序排列的条目
+func (s *PlaylistService) GetPlaylist(id uint) (*models.Playlist, error) {
+	var playlist models.Playlist
+	err := database.DB.
+		Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("position asc, id asc") }).
+		Preload("Items.Video").
+		First(&playlist, id).Error
+	if err != nil {
+		return nil, err
+	}
+	playlist.ItemCount = len(playlist.Items)
+	return &playlist, nil
+}
+
+// CreatePlaylist 新建播放列表
+func (s *PlaylistService) CreatePlaylist(input PlaylistInput) (*models.Playlist, error) {
+	playlist := models.Playlist{}
+	if err := applyPlaylistInput(&playlist, input); err != nil {
+		return nil, err
+	}
+	if err := database.DB.Create(&playlist).Error; err != nil {
+		return nil, err
+	}
+	return &playlist, nil
+}
+
+// UpdatePlaylist 修改名称、类型、筛选条件与播放模式；会清空当前播放队列
+func (s *PlaylistService) UpdatePlaylist(id uint, input PlaylistInput) error {
+	var playlist models.Playlist
+	if err := database.DB.First(&playlist, id).Error; err != nil {
+		return err
+	}
+	if err := applyPlaylistInput(&playlist, input); err != nil {
+		return err
+	}
+	return database.DB.Model(&playlist).Updates(map[string]interface{}{
+		"name":            playlist.Name,
+		"kind":            playlist.Kind,
+		"smart_filter":    playlist.SmartFilter,
+		"shuffle":         playlist.Shuffle,
+		"repeat_mode":     playlist.RepeatMode,
+		"queue_video_ids": "",
+		"queue_position":  0,
+	}).Error
+}
+
+// DeletePlaylist 删除播放列表及其条目
+func (s *PlaylistService) DeletePlaylist(id uint) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("playlist_id = ?", id).Delete(&models.PlaylistItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Playlist{}, id).Error
+	})
+}
+
+// AddVideosToPlaylist 把视频追加到手动列表末尾，已在列表中的视频跳过，返回实际新增数量
+func (s *PlaylistService) AddVideosToPlaylist(id uint, videoIDs []uint) (int, error) {
+	added := 0
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		playlist, err := loadManualPlaylist(tx, id)
+		if err != nil {
+			return err
+		}
+		var existing []uint
+		if err := tx.Model(&models.PlaylistItem{}).Where("playlist_id = ?", playlist.ID).Pluck("video_id", &existing).Error; err != nil {
+			return err
+		}
+		seen := make(map[uint]bool, len(existing))
+		for _, videoID := range existing {
+			seen[videoID] = true
+		}
+		var maxPosition int
+		if err := tx.Model(&models.PlaylistItem{}).Where("playlist_id = ?", playlist.ID).
+			Select("COALESCE(MAX(position), -1)").Scan(&maxPosition).Error; err != nil {
+			return err
+		}
+
+		var valid []uint
+		if err := tx.Model(&models.Video{}).Where("id IN ?", videoIDs).Pluck("id", &valid).Error; err != nil {
+			return err
+		}
+		validSet := make(map[uint]bool, len(valid))
+		for _, videoID := range valid {
+			validSet[videoID] = true
+		}
+		for _, videoID := range videoIDs {
+			if !validSet[videoID] || seen[videoID] {
+				continue
+			}
+			seen[videoID] = true
+			maxPosition++
+			item := models.PlaylistItem{PlaylistID: playlist.ID, VideoID: videoID, Position: maxPosition}
+			if err := tx.Create(&item).Error; err != nil {
+				return err
+			}
+			added++
+		}
+		return nil
+	})
+	return added, err
+}
+
+// RemovePlaylistItems 从手动列表中移除条目
+func (s *PlaylistService) RemovePlaylistItems(id uint, itemIDs []uint) error {
+	if len(itemIDs) == 0 {
+		return nil
+	}
+	if _, err := loadManualPlaylist(database.DB, id); err != nil {
+		return err
+	}
+	return database.DB.Where("playlist_id = ? AND id IN ?", id, itemIDs).Delete(&models.PlaylistItem{}).Error
+}
+
+// ReorderPlaylist 按给定的条目 ID 顺序重排手动列表，必须包含列表中的全部条目
+func (s *PlaylistService) ReorderPlaylist(id uint, itemIDs []uint) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if _, err := loadManualPlaylist(tx, id); err != nil {
+			return err
+		}
+		var current []uint
+		if err := tx.Model(&models.PlaylistItem{}).Where("playlist_id = ?", id).Pluck("id", &current).Error; err != nil {
+			return err
+		}
+		if len(current) != len(itemIDs) {
+			return fmt.Errorf("排序需包含播放列表的全部 %d 个条目", len(current))
+		}
+		remaining := make(map[uint]bool, len(current))
+		for _, itemID := range current {
+			remaining[itemID] = true
+		}
+		for position, itemID := range itemIDs {
+			if !remaining[itemID] {
+				return fmt.Errorf("条目 %d 不属于该播放列表或重复出现", itemID)
+			}
+			delete(remaining, itemID)
+			if err := tx.Model(&models.PlaylistItem{}).Where("id = ?", itemID).Update("position", position).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ResolvePlaylistVideos 按列表顺序返回播放列表中的视频：手动列表跳过已删除的视频，智能列表实时执行筛选
+func (s *PlaylistService) ResolvePlaylistVideos(id uint) ([]models.Video, error) {
+	playlist, err := s.GetPlaylist(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.resolveVideos(playlist)
+}
+
+func (s *PlaylistService) resolveVideos(playlist *models.Playlist) ([]models.Video, error) {
+	if playlist.Kind == PlaylistKindSmart {
+		filter, err := decodePlaylistSmartFilter(playlist.SmartFilter)
+		if err != nil {
+			return nil, err
+		}
+		return s.videoService.SearchVideosByFilter(filter.VideoSearchFilter, 0, 0, 0, normalizeSmartPlaylistLimit(filter.Limit))
+	}
+	videos := make([]models.Video, 0, len(playlist.Items))
+	for _, item := range playlist.Items {
+		// 软删除的视频不会被 Preload，ID 为零
+		if item.Video.ID == 0 {
+			continue
+		}
+		videos = append(videos, item.Video)
+	}
+	return videos, nil
+}
+
+// StartPlaylist 生成播放队列（随机模式下打乱，startVideoID 非零时从该视频开始）并播放起始项
+func (s *PlaylistService) StartPlaylist(id uint, startVideoID uint, target string) (*PlaylistPlaybackResult, error) {
+	if err := validatePlaylistTarget(target); err != nil {
+		return nil, err
+	}
+	playlist, err := s.GetPlaylist(id)
+	if err != nil {
+		return nil, err
+	}
+	videos, err := s.resolveVideos(playlist)
+	if err != nil {
+		return nil, err
+	}
+	if len(videos) == 0 {
+		return nil, ErrNoVideos
+	}
+	queue, position := s.buildQueue(videos, playlist.Shuffle, startVideoID)
+	if err := saveQueueState(playlist.ID, queue, position); err != nil {
+		return nil, err
+	}
+	return s.playQueueItem(playlist, queue, position, target)
+}
+
+// StepPlaylist 在当前队列中前进/后退一项并播放；step 为 next、previous 或 ended
+func (s *PlaylistService) StepPlaylist(id uint, step string, target string) (*PlaylistPlaybackResult, error) {
+	if err := validatePlaylistTarget(target); err != nil {
+		return nil, err
+	}
+	playlist, err := s.GetPlaylist(id)
+	if err != nil {
+		return nil, err
+	}
+	queue := parsePlaylistQueue(playlist.QueueVideoIDs)
+	if len(queue) == 0 {
+		return s.StartPlaylist(id, 0, target)
+	}
+
+	position := playlist.QueuePosition
+	switch step {
+	case PlaylistStepEnded:
+		if playlist.RepeatMode != PlaylistRepeatOne {
+			position++
+		}
+	case PlaylistStepNext:
+		position++
+	case PlaylistStepPrevious:
+		position--
+	default:
+		return nil, fmt.Errorf("无效的播放列表操作: %s", step)
+	}
+
+	switch {
+	case position >= len(queue):
+		if playlist.RepeatMode != PlaylistRepeatAll {
+			if err := saveQueueState(playlist.ID, queue, len(queue)-1); err != nil {
+				return nil, err
+			}
+			return &PlaylistPlaybackResult{PlaylistID: playlist.ID, Position: len(queue) - 1, Total: len(queue), Finished: true}, nil
+		}
+		// 列表循环时每一轮重新打乱
+		if playlist.Shuffle {
+			s.shuffle(len(queue), func(i, j int) { queue[i], queue[j] = queue[j], queue[i] })
+		}
+		position = 0
+	case position < 0:
+		if playlist.RepeatMode == PlaylistRepeatAll {
+			position = len(queue) - 1
+		} else {
+			position = 0
+		}
+	}
+	if err := saveQueueState(playlist.ID, queue, position); err != nil {
+		return nil, err
+	}
+	return s.playQueueItem(playlist, queue, position, target)
+}
+
+// playQueueItem 播放队列中的一项。外部模式把从该项起的剩余队列写成 .m3u8 交给播放器，由播放器自行连播，
+// 只有该项计入正式播放；内嵌模式只返回预览会话，等前端上报真正开播后由 RecordInlinePlayback 计入统计
+func (s *PlaylistService) playQueueItem(playlist *models.Playlist, queue []uint, position int, target string) (*PlaylistPlaybackResult, error) {
+	result := &PlaylistPlaybackResult{PlaylistID: playlist.ID, Position: position, Total: len(queue)}
+	var video models.Video
+	if err := database.DB.First(&video, queue[position]).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("播放列表中的视频已被删除: %d", queue[position])
+		}
+		return nil, err
+	}
+
+	if target == PlaylistTargetInline {
+		session, err := s.videoService.buildPreviewSession(video.ID)
+		if err != nil {
+			return nil, err
+		}
+		result.Video = &video
+		result.PreviewSession = session
+		return result, nil
+	}
+
+	launch := func(video *models.Video) error {
+		entries, err := playlistM3UEntries(queue, position, playlist.RepeatMode == PlaylistRepeatAll)
+		if err != nil {
+			return err
+		}
+		// 只剩当前一项时直接打开文件，保留续播位置与外挂字幕
+		if len(entries) <= 1 {
+			return launchWithPlayerProfile(video)
+		}
+		m3uPath, err := writePlaylistM3U(playlist.ID, entries)
+		if err != nil {
+			return err
+		}
+		return launchPlaylistInPlayer(video.Path, m3uPath)
+	}
+	attempt, err := s.videoService.dispatchFormalPlaybackVia(&video, false, launch)
+	if err != nil {
+		return nil, err
+	}
+	result.Video = attempt.Video
+	result.Attempt = attempt
+	return result, nil
+}
+
+// RecordInlinePlayback 内嵌播放器真正开始播放队列项时由前端调用，此时才计入正式播放统计
+func (s *PlaylistService) RecordInlinePlayback(id uint, videoID uint) (*PlaybackAttemptResult, error) {
+	playlist, err := s.GetPlaylist(id)
+	if err != nil {
+		return nil, err
+	}
+	inQueue := false
+	for _, queued := range parsePlaylistQueue(playlist.QueueVideoIDs) {
+		if queued == videoID {
+			inQueue = true
+			break
+		}
+	}
+	if !inQueue {
+		return nil, fmt.Errorf("视频不在播放列表的当前队列中: %d", videoID)
+	}
+	var video models.Video
+	if err := database.DB.First(&video, videoID).Error; err != nil {
+		return nil, err
+	}
+	// 画面已在内嵌播放器中播放，无需再启动播放端
+	return s.videoService.dispatchFormalPlaybackVia(&video, false, func(*models.Video) error { return nil })
+}
+
+// playlistM3UEntries 按队列顺序取从 position 起的视频（列表循环时接上队首到 position 之前的部分），
+// 跳过已删除的记录与当前无法访问的文件
+func playlistM3UEntries(queue []uint, position int, wrap bool) ([]models.Video, error) {
+	ordered := append([]uint{}, queue[position:]...)
+	if wrap {
+		ordered = append(ordered, queue[:position]...)
+	}
+	var videos []models.Video
+	if err := database.DB.Where("id IN ?", ordered).Find(&videos).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]models.Video, len(videos))
+	for _, video := range videos {
+		byID[video.ID] = video
+	}
+	entries := make([]models.Video, 0, len(ordered))
+	for _, videoID := range ordered {
+		video, ok := byID[videoID]
+		if !ok {
+			continue
+		}
+		if info, err := os.Stat(video.Path); err != nil || info.IsDir() {
+			continue
+		}
+		entries = append(entries, video)
+	}
+	return entries, nil
+}
+
+// writePlaylistM3U 把视频写成 UTF-8 的 .m3u8 播放列表（每个播放列表一个文件，重复播放时覆盖），返回文件路径
+func writePlaylistM3U(playlistID uint, videos []models.Video) (string, error) {
+	dir := filepath.Join(os.TempDir(), "video-master-playlists")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("创建播放列表临时目录失败: %w", err)
+	}
+	var content strings.Builder
+	content.WriteString("#EXTM3U\n")
+	for _, video := range videos {
+		duration := -1
+		if video.Duration > 0 {
+			duration = int(math.Round(video.Duration))
+		}
+		fmt.Fprintf(&content, "#EXTINF:%d,%s\n%s\n", duration, strings.ReplaceAll(video.Name, "\n", " "), video.Path)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("playlist-%d.m3u8", playlistID))
+	if err := os.WriteFile(path, []byte(content.String()), 0644); err != nil {
+		return "", fmt.Errorf("写入播放列表文件失败: %w", err)
+	}
+	return path, nil
+}
+
+// buildQueue 生成播放队列与起始下标；随机模式下把起始视频换到队首
+func (s *PlaylistService) buildQueue(videos []models.Video, shuffle bool, startVideoID uint) ([]uint, int) {
+	queue := make([]uint, 0, len(videos))
+	for _, video := range videos {
+		queue = append(queue, video.ID)
+	}
+	if shuffle {
+		s.shuffle(len(queue), func(i, j int) { queue[i], queue[j] = queue[j], queue[i] })
+	}
+	for i, videoID := range queue {
+		if startVideoID == 0 || videoID != startVideoID {
+			continue
+		}
+		if !shuffle {
+			return queue, i
+		}
+		queue[0], queue[i] = queue[i], queue[0]
+		break
+	}
+	return queue, 0
+}
+
+func applyPlaylistInput(playlist *models.Playlist, input PlaylistInput) error {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return fmt.Errorf("播放列表名称不能为空")
+	}
+	kind := strings.TrimSpace(input.Kind)
+	if kind == "" {
+		kind = PlaylistKindManual
+	}
+	if kind != PlaylistKindManual && kind != PlaylistKindSmart {
+		return fmt.Errorf("无效的播放列表类型: %s", input.Kind)
+	}
+	repeat := strings.TrimSpace(input.RepeatMode)
+	if repeat == "" {
+		repeat = PlaylistRepeatOff
+	}
+	if repeat != PlaylistRepeatOff && repeat != PlaylistRepeatAll && repeat != PlaylistRepeatOne {
+		return fmt.Errorf("无效的循环模式: %s", input.RepeatMode)
+	}
+
+	playlist.SmartFilter = ""
+	if kind == PlaylistKindSmart {
+		filter := PlaylistSmartFilter{}
+		if input.SmartFilter != nil {
+			filter = *input.SmartFilter
+		}
+		searchFilter, err := normalizeVideoSearchFilter(filter.VideoSearchFilter)
+		if err != nil {
+			return err
+		}
+		filter.VideoSearchFilter = searchFilter
+		filter.Limit = normalizeSmartPlaylistLimit(filter.Limit)
+		encoded, err := json.Marshal(filter)
+		if err != nil {
+			return err
+		}
+		playlist.SmartFilter = string(encoded)
+	}
+	playlist.Name = name
+	playlist.Kind = kind
+	playlist.Shuffle = input.Shuffle
+	playlist.RepeatMode = repeat
+	playlist.QueueVideoIDs = ""
+	playlist.QueuePosition = 0
+	return nil
+}
+
+func decodePlaylistSmartFilter(text string) (PlaylistSmartFilter, error) {
+	var filter PlaylistSmartFilter
+	if strings.TrimSpace(text) == "" {
+		return filter, nil
+	}
+	if err := json.Unmarshal([]byte(text), &filter); err != nil {
+		return filter, fmt.Errorf("智能播放列表筛选条件无效: %w", err)
+	}
+	return filter, nil
+}
+
+func normalizeSmartPlaylistLimit(limit int) int {
+	if limit <= 0 {
+		return defaultSmartPlaylistLimit
+	}
+	if limit > maxSmartPlaylistLimit {
+		return maxSmartPlaylistLimit
+	}
+	return limit
+}
+
+func validatePlaylistTarget(target string) error {
+	if target != PlaylistTargetExternal && target != PlaylistTargetInline {
+		return fmt.Errorf("无效的播放方式: %s", target)
+	}
+	return nil
+}
+
+func loadManualPlaylist(db *gorm.DB, id uint) (*models.Playlist, error) {
+	var playlist models.Playlist
+	if err := db.First(&playlist, id).Error; err != nil {
+		return nil, err
+	}
+	if playlist.Kind != PlaylistKindManual {
+		return nil, fmt.Errorf("智能播放列表的内容由筛选条件决定，不能手动编辑")
+	}
+	return &playlist, nil
+}
+
+func saveQueueState(playlistID uint, queue []uint, position int) error {
+	parts := make([]string, 0, len(queue))
+	for _, videoID := range queue {
+		parts = append(parts, strconv.FormatUint(uint64(videoID), 10))
+	}
+	return database.DB.Model(&models.Playlist{}).Where("id = ?", playlistID).Updates(map[string]interface{}{
+		"queue_video_ids": strings.Join(parts, ","),
+		"queue_position":  position,
+	}).Error
+}
+
+func parsePlaylistQueue(text string) []uint {
+	queue := make([]uint, 0)
+	for _, part := range strings.Split(text, ",") {
+		videoID, err := strconv.ParseUint(strings.TrimSpace(part), 10, 64)
+		if err != nil || videoID == 0 {
+			continue
+		}
+		queue = append(queue, uint(videoID))
+	}
+	return queue
+}
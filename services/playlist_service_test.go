@@ -0,0 +1,255 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"video-master/database"
+	"video-master/models"
+)
+
+func TestManualPlaylistItemsAddRemoveAndReorder(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	root := t.TempDir()
+	a := createShortFeedVideo(t, root, "a.mp4", 60, false)
+	b := createShortFeedVideo(t, root, "b.mp4", 60, false)
+	c := createShortFeedVideo(t, root, "c.mp4", 60, false)
+	svc := NewPlaylistService(&VideoService{})
+
+	if _, err := svc.CreatePlaylist(PlaylistInput{Name: " "}); err == nil {
+		t.Fatalf("空名称应被拒绝")
+	}
+	if _, err := svc.CreatePlaylist(PlaylistInput{Name: "x", RepeatMode: "forever"}); err == nil {
+		t.Fatalf("无效循环模式应被拒绝")
+	}
+	playlist, err := svc.CreatePlaylist(PlaylistInput{Name: "周末"})
+	if err != nil {
+		t.Fatalf("创建播放列表失败: %v", err)
+	}
+	if playlist.Kind != PlaylistKindManual || playlist.RepeatMode != PlaylistRepeatOff {
+		t.Fatalf("默认应为手动列表且不循环，got=%+v", playlist)
+	}
+
+	added, err := svc.AddVideosToPlaylist(playlist.ID, []uint{a.ID, b.ID, a.ID, 9999})
+	if err != nil || added != 2 {
+		t.Fatalf("添加视频失败 added=%d err=%v", added, err)
+	}
+	if added, err = svc.AddVideosToPlaylist(playlist.ID, []uint{b.ID, c.ID}); err != nil || added != 1 {
+		t.Fatalf("已存在的视频应跳过 added=%d err=%v", added, err)
+	}
+
+	loaded, err := svc.GetPlaylist(playlist.ID)
+	if err != nil {
+		t.Fatalf("读取播放列表失败: %v", err)
+	}
+	if got := playlistVideoIDs(loaded.Items); !reflect.DeepEqual(got, []uint{a.ID, b.ID, c.ID}) {
+		t.Fatalf("条目应按添加顺序排列，got=%v", got)
+	}
+
+	items := loaded.Items
+	if err := svc.ReorderPlaylist(playlist.ID, []uint{items[2].ID, items[0].ID}); err == nil {
+		t.Fatalf("排序缺少条目时应返回错误")
+	}
+	if err := svc.ReorderPlaylist(playlist.ID, []uint{items[2].ID, items[0].ID, items[1].ID}); err != nil {
+		t.Fatalf("重排失败: %v", err)
+	}
+	if err := svc.RemovePlaylistItems(playlist.ID, []uint{items[0].ID}); err != nil {
+		t.Fatalf("移除条目失败: %v", err)
+	}
+	if err := database.DB.Delete(&models.Video{}, b.ID).Error; err != nil {
+		t.Fatalf("删除视频失败: %v", err)
+	}
+	videos, err := svc.ResolvePlaylistVideos(playlist.ID)
+	if err != nil {
+		t.Fatalf("解析播放列表失败: %v", err)
+	}
+	if len(videos) != 1 || videos[0].ID != c.ID {
+		t.Fatalf("应按新顺序且跳过已删除视频，got=%+v", videos)
+	}
+
+	lists, err := svc.GetPlaylists()
+	if err != nil || len(lists) != 1 || lists[0].ItemCount != 2 {
+		t.Fatalf("列表应返回条目数 lists=%+v err=%v", lists, err)
+	}
+	if err := svc.DeletePlaylist(playlist.ID); err != nil {
+		t.Fatalf("删除播放列表失败: %v", err)
+	}
+	if count := countShortFeedRows(t, "playlist_items"); count != 0 {
+		t.Fatalf("删除播放列表应清除条目，got=%d", count)
+	}
+}
+
+func TestSmartPlaylistResolvesFilter(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	root := t.TempDir()
+	match := createShortFeedVideo(t, root, "holiday-beach.mp4", 60, false)
+	createShortFeedVideo(t, root, "work.mp4", 60, false)
+	createShortFeedVideo(t, root, "holiday-clip.mp4", 10, false)
+	svc := NewPlaylistService(&VideoService{})
+
+	filter := &PlaylistSmartFilter{VideoSearchFilter: VideoSearchFilter{Keyword: " holiday ", MinDuration: 30}}
+	playlist, err := svc.CreatePlaylist(PlaylistInput{Name: "假期", Kind: PlaylistKindSmart, SmartFilter: filter})
+	if err != nil {
+		t.Fatalf("创建智能列表失败: %v", err)
+	}
+	var stored map[string]interface{}
+	if err := json.Unmarshal([]byte(playlist.SmartFilter), &stored); err != nil || stored["keyword"] != "holiday" || stored["min_duration"] != float64(30) || stored["limit"] != float64(defaultSmartPlaylistLimit) {
+		t.Fatalf("智能列表条件应与保存的搜索同格式平铺存储 filter=%s err=%v", playlist.SmartFilter, err)
+	}
+	invalid := &PlaylistSmartFilter{VideoSearchFilter: VideoSearchFilter{MinSize: 10, MaxSize: 5}}
+	if _, err := svc.CreatePlaylist(PlaylistInput{Name: "无效", Kind: PlaylistKindSmart, SmartFilter: invalid}); err == nil {
+		t.Fatalf("下限超过上限的条件应被拒绝")
+	}
+	videos, err := svc.ResolvePlaylistVideos(playlist.ID)
+	if err != nil || len(videos) != 1 || videos[0].ID != match.ID {
+		t.Fatalf("智能列表应按筛选条件返回视频 videos=%+v err=%v", videos, err)
+	}
+	if _, err := svc.AddVideosToPlaylist(playlist.ID, []uint{match.ID}); err == nil {
+		t.Fatalf("智能列表不应允许手动添加")
+	}
+}
+
+func TestPlaylistPlaybackStepsWithRepeatAndCountsPlays(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	root := t.TempDir()
+	a := createShortFeedVideo(t, root, "a.mp4", 60, false)
+	b := createShortFeedVideo(t, root, "b.mp4", 60, false)
+	svc := NewPlaylistService(&VideoService{})
+	tmpDir := t.TempDir()
+	t.Setenv("TMPDIR", tmpDir)
+
+	var opened []string
+	oldOpen := openWithDefaultFn
+	openWithDefaultFn = func(path string, isDir bool) error {
+		opened = append(opened, path)
+		return nil
+	}
+	defer func() { openWithDefaultFn = oldOpen }()
+
+	playlist, err := svc.CreatePlaylist(PlaylistInput{Name: "循环", RepeatMode: PlaylistRepeatAll})
+	if err != nil {
+		t.Fatalf("创建播放列表失败: %v", err)
+	}
+	if _, err := svc.StartPlaylist(playlist.ID, 0, PlaylistTargetExternal); err != ErrNoVideos {
+		t.Fatalf("空列表应返回 ErrNoVideos，err=%v", err)
+	}
+	if _, err := svc.AddVideosToPlaylist(playlist.ID, []uint{a.ID, b.ID}); err != nil {
+		t.Fatalf("添加视频失败: %v", err)
+	}
+
+	m3uPath := filepath.Join(tmpDir, "video-master-playlists", fmt.Sprintf("playlist-%d.m3u8", playlist.ID))
+	result, err := svc.StartPlaylist(playlist.ID, b.ID, PlaylistTargetExternal)
+	if err != nil || result.Video.ID != b.ID || result.Position != 1 || !result.Attempt.DispatchSucceeded {
+		t.Fatalf("应从指定视频开始播放 result=%+v err=%v", result, err)
+	}
+	if !reflect.DeepEqual(opened, []string{m3uPath}) {
+		t.Fatalf("外部播放应把整个队列写成播放列表交给播放器，got=%v", opened)
+	}
+	if got := readPlaylistM3UPaths(t, m3uPath); !reflect.DeepEqual(got, []string{b.Path, a.Path}) {
+		t.Fatalf("列表循环时播放列表应从当前项开始并接上队首，got=%v", got)
+	}
+	result, err = svc.StepPlaylist(playlist.ID, PlaylistStepEnded, PlaylistTargetExternal)
+	if err != nil || result.Video.ID != a.ID || result.Position != 0 {
+		t.Fatalf("列表循环时末尾应回到开头 result=%+v err=%v", result, err)
+	}
+	if got := readPlaylistM3UPaths(t, m3uPath); !reflect.DeepEqual(got, []string{a.Path, b.Path}) {
+		t.Fatalf("播放列表应按队列顺序排列，got=%v", got)
+	}
+
+	result, err = svc.StepPlaylist(playlist.ID, PlaylistStepNext, PlaylistTargetInline)
+	if err != nil || result.Video.ID != b.ID || result.PreviewSession == nil || result.PreviewSession.VideoID != b.ID || result.Attempt != nil {
+		t.Fatalf("内嵌播放应返回预览会话 result=%+v err=%v", result, err)
+	}
+	if len(opened) != 2 {
+		t.Fatalf("内嵌播放不应启动外部播放器，got=%v", opened)
+	}
+
+	var reloadedA, reloadedB models.Video
+	database.DB.First(&reloadedA, a.ID)
+	database.DB.First(&reloadedB, b.ID)
+	if reloadedA.PlayCount != 1 || reloadedB.PlayCount != 1 {
+		t.Fatalf("外部播放只计入当前项，内嵌播放在开播前不计数，got a=%d b=%d", reloadedA.PlayCount, reloadedB.PlayCount)
+	}
+	attempt, err := svc.RecordInlinePlayback(playlist.ID, b.ID)
+	if err != nil || !attempt.DispatchSucceeded {
+		t.Fatalf("上报内嵌开播失败 attempt=%+v err=%v", attempt, err)
+	}
+	database.DB.First(&reloadedB, b.ID)
+	if reloadedB.PlayCount != 2 {
+		t.Fatalf("内嵌开播后应计入正式播放，got=%d", reloadedB.PlayCount)
+	}
+	other := createShortFeedVideo(t, root, "other.mp4", 60, false)
+	if _, err := svc.RecordInlinePlayback(playlist.ID, other.ID); err == nil {
+		t.Fatalf("不在队列中的视频不应计入")
+	}
+
+	if err := svc.UpdatePlaylist(playlist.ID, PlaylistInput{Name: "单曲", RepeatMode: PlaylistRepeatOne}); err != nil {
+		t.Fatalf("更新播放列表失败: %v", err)
+	}
+	if _, err := svc.StartPlaylist(playlist.ID, 0, PlaylistTargetExternal); err != nil {
+		t.Fatalf("开始播放失败: %v", err)
+	}
+	result, err = svc.StepPlaylist(playlist.ID, PlaylistStepEnded, PlaylistTargetExternal)
+	if err != nil || result.Video.ID != a.ID {
+		t.Fatalf("单曲循环播完后应重播当前项 result=%+v err=%v", result, err)
+	}
+	if _, err := svc.StepPlaylist(playlist.ID, PlaylistStepNext, PlaylistTargetExternal); err != nil {
+		t.Fatalf("下一项失败: %v", err)
+	}
+	result, err = svc.StepPlaylist(playlist.ID, PlaylistStepNext, PlaylistTargetExternal)
+	if err != nil || !result.Finished || result.Video != nil {
+		t.Fatalf("不循环整个列表时越过末尾应结束 result=%+v err=%v", result, err)
+	}
+}
+
+func TestPlaylistShuffleMovesStartVideoToFront(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	svc := NewPlaylistService(&VideoService{})
+	svc.shuffle = func(n int, swap func(i, j int)) {
+		// 反转顺序，模拟确定性的打乱
+		for i := 0; i < n/2; i++ {
+			swap(i, n-1-i)
+		}
+	}
+	videos := []models.Video{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}}
+
+	queue, position := svc.buildQueue(videos, true, 0)
+	if !reflect.DeepEqual(queue, []uint{4, 3, 2, 1}) || position != 0 {
+		t.Fatalf("随机模式应打乱队列，got=%v pos=%d", queue, position)
+	}
+	queue, position = svc.buildQueue(videos, true, 2)
+	if !reflect.DeepEqual(queue, []uint{2, 3, 4, 1}) || position != 0 {
+		t.Fatalf("随机模式下起始视频应在队首，got=%v pos=%d", queue, position)
+	}
+	queue, position = svc.buildQueue(videos, false, 3)
+	if !reflect.DeepEqual(queue, []uint{1, 2, 3, 4}) || position != 2 {
+		t.Fatalf("顺序模式应从起始视频所在位置开始，got=%v pos=%d", queue, position)
+	}
+}
+
+func playlistVideoIDs(items []models.PlaylistItem) []uint {
+	ids := make([]uint, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.VideoID)
+	}
+	return ids
+}
+
+func readPlaylistM3UPaths(t *testing.T, path string) []string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("读取播放列表文件失败: %v", err)
+	}
+	var paths []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if line != "" && !strings.HasPrefix(line, "#") {
+			paths = append(paths, line)
+		}
+	}
+	return paths
+}
@@ -3,13 +3,22 @@ package services
 import (
 	"fmt"
 	"mime"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
+	"video-master/database"
+	"video-master/models"
 )
 
-const previewMediaRoutePrefix = "/preview/media/"
+const (
+	previewMediaRoutePrefix  = "/preview/media/"
+	previewSpriteRoutePrefix = "/preview/sprite/"
+)
+
+// LocatorStrategyTranscode 表示 LocatorValue 是实时转码的分片 MP4 流，HLSPlaylist 为同一内容的 HLS 地址
+const LocatorStrategyTranscode = "transcode"
 
 type PreviewSession struct {
 	VideoID        uint                     `json:"video_id"`
@@ -19,12 +28,20 @@ type PreviewSession struct {
 	ExternalAction *PreviewExternalAction   `json:"external_action,omitempty"`
 	ReasonCode     string                   `json:"reason_code,omitempty"`
 	ReasonMessage  string                   `json:"reason_message,omitempty"`
+	// ResumePosition 上次未看完时的续播位置（秒），从头播放时为 0
+	ResumePosition float64 `json:"resume_position,omitempty"`
 }
 
 type PreviewSourceDescriptor struct {
 	LocatorStrategy string `json:"locator_strategy"`
 	LocatorValue    string `json:"locator_value"`
 	MIME            string `json:"mime"`
+	// ThumbnailTrack 拖动预览用的 WebVTT 缩略图轨道地址；尚未生成时请求返回 404，thumbnail-ready 事件后可重试
+	ThumbnailTrack string `json:"thumbnail_track,omitempty"`
+	// 以下字段仅在转码预览时返回：流式输出的时长未知，由前端按 Duration 显示进度，跳转时带 ?start= 重新请求
+	TranscodeMode string  `json:"transcode_mode,omitempty"`
+	HLSPlaylist   string  `json:"hls_playlist,omitempty"`
+	Duration      float64 `json:"duration,omitempty"`
 }
 
 type PreviewExternalAction struct {
@@ -48,7 +65,21 @@ var inlinePreviewMIMEs = map[string]string{
 	".ogg":  "video/ogg",
 }
 
+// GetPreviewSession 生成内嵌预览 session，并记录一条预览历史（不可内嵌时以原因码记为失败）
 func (s *VideoService) GetPreviewSession(videoID uint) (*PreviewSession, error) {
+	session, err := s.buildPreviewSession(videoID)
+	if err != nil {
+		return nil, err
+	}
+	outcome := ""
+	if session.Mode != "inline" {
+		outcome = session.ReasonCode
+	}
+	recordPlaybackEvent(database.DB, session.VideoID, PlaybackEventSourcePreview, outcome, time.Now())
+	return session, nil
+}
+
+func (s *VideoService) buildPreviewSession(videoID uint) (*PreviewSession, error) {
 	video, err := s.GetVideo(videoID)
 	if err != nil {
 		return nil, err
@@ -56,6 +87,15 @@ func (s *VideoService) GetPreviewSession(videoID uint) (*PreviewSession, error)
 
 	info, err := os.Stat(video.Path)
 	if err != nil {
+		if offlineDir, checkErr := findOfflineRootForVideo(video); checkErr == nil && offlineDir != nil {
+			return &PreviewSession{
+				VideoID:       video.ID,
+				Mode:          "unsupported",
+				DisplayName:   video.Name,
+				ReasonCode:    "volume_offline",
+				ReasonMessage: fmt.Sprintf("所在扫描目录当前离线：%s", offlineDir.Path),
+			}, nil
+		}
 		if os.IsNotExist(err) {
 			return &PreviewSession{
 				VideoID:       video.ID,
@@ -86,7 +126,28 @@ func (s *VideoService) GetPreviewSession(videoID uint) (*PreviewSession, error)
 				LocatorStrategy: "asset_route",
 				LocatorValue:    previewMediaPath(video.ID),
 				MIME:            mimeType,
+				ThumbnailTrack:  s.previewThumbnailTrack(video),
+			},
+			ResumePosition: resumePositionForVideo(video.ID),
+		}, nil
+	}
+
+	if s.transcoder.Available() {
+		plan := PlanTranscode(*video)
+		return &PreviewSession{
+			VideoID:     video.ID,
+			Mode:        "inline",
+			DisplayName: video.Name,
+			InlineSource: &PreviewSourceDescriptor{
+				LocatorStrategy: LocatorStrategyTranscode,
+				LocatorValue:    previewTranscodePath(video.ID, TranscodeStreamResource),
+				MIME:            "video/mp4",
+				ThumbnailTrack:  s.previewThumbnailTrack(video),
+				TranscodeMode:   plan.Mode,
+				HLSPlaylist:     previewTranscodePath(video.ID, transcodeHLSPrefix+TranscodeHLSPlaylist),
+				Duration:        video.Duration,
 			},
+			ResumePosition: resumePositionForVideo(video.ID),
 		}, nil
 	}
 
@@ -131,18 +192,62 @@ func (s *VideoService) ResolvePreviewMedia(videoID uint) (*PreviewMedia, error)
 	}, nil
 }
 
+// ResolveTranscodeVideo 返回可转码预览的视频记录
+func (s *VideoService) ResolveTranscodeVideo(videoID uint) (*models.Video, error) {
+	if !s.transcoder.Available() {
+		return nil, ErrTranscodeUnavailable
+	}
+	video, err := s.GetVideo(videoID)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(video.Path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("预览路径不是文件")
+	}
+	return video, nil
+}
+
+// ServeTranscode 输出 /preview/media/<id>/ 下的转码资源
+func (s *VideoService) ServeTranscode(w http.ResponseWriter, r *http.Request, videoID uint, resource string) error {
+	video, err := s.ResolveTranscodeVideo(videoID)
+	if err != nil {
+		return err
+	}
+	return s.transcoder.ServeAsset(w, r, *video, resource)
+}
+
 func (s *VideoService) PreviewExternally(videoID uint) error {
 	video, err := s.GetVideo(videoID)
 	if err != nil {
 		return err
 	}
-	return openWithDefaultFn(video.Path, false)
+	if err := launchInPlayer(video.Path, 0); err != nil {
+		recordPlaybackEvent(database.DB, video.ID, PlaybackEventSourceExternalPreview, "dispatch_failed", time.Now())
+		return err
+	}
+	recordPlaybackEvent(database.DB, video.ID, PlaybackEventSourceExternalPreview, "", time.Now())
+	return nil
 }
 
 func previewMediaPath(videoID uint) string {
 	return fmt.Sprintf("%s%d", previewMediaRoutePrefix, videoID)
 }
 
+func previewTranscodePath(videoID uint, resource string) string {
+	return fmt.Sprintf("%s%d/%s", previewMediaRoutePrefix, videoID, resource)
+}
+
+func (s *VideoService) previewThumbnailTrack(video *models.Video) string {
+	if s.thumbnails == nil || video.Duration <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s%d.vtt", previewSpriteRoutePrefix, video.ID)
+}
+
 func inlinePreviewMIME(path string) (string, bool) {
 	ext := strings.ToLower(filepath.Ext(path))
 	mimeType, ok := inlinePreviewMIMEs[ext]
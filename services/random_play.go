@@ -0,0 +1,412 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"video-master/database"
+	"video-master/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	RandomStrategyLeastPlayed   = "least_played"
+	RandomStrategyUniform       = "uniform"
+	RandomStrategyRecencyDecay  = "recency_decay"
+	RandomStrategyTagPreference = "tag_preference"
+
+	// recencyHalfLife 刚播放过的视频权重接近 0，经过一个半衰期恢复到一半
+	recencyHalfLife = 72 * time.Hour
+	// minRandomWeight 保证每个候选都有被选中的可能
+	minRandomWeight = 0.01
+
+	RandomPickSourceDesktop   = "desktop"
+	RandomPickSourceShortFeed = "short_feed"
+
+	// maxRandomNoRepeat 防重复窗口上限，选片记录也只保留这么多条
+	maxRandomNoRepeat = 500
+)
+
+// RandomPlayScope 随机播放范围，零值字段不限；已失效或离线的视频始终排除
+type RandomPlayScope struct {
+	Keyword   string `json:"keyword"`    // 搜索语法，普通词匹配文件名或路径
+	TagIDs    []uint `json:"tag_ids"`    // 需同时包含全部标签
+	Directory string `json:"directory"`  // 目录及其子目录
+	MinSize   int64  `json:"min_size"`   // 体积下限（含）
+	MaxSize   int64  `json:"max_size"`   // 体积上限（不含）
+	MinHeight int    `json:"min_height"` // 高度下限（含）
+	MaxHeight int    `json:"max_height"` // 高度上限（含）
+	// SavedSearchID 非零时再叠加该保存的搜索的条件
+	SavedSearchID uint `json:"saved_search_id"`
+
+	IncludeTagDescendants bool `json:"include_tag_descendants"` // 标签也匹配其子孙标签
+}
+
+// RandomPlayOptions 单次随机播放的策略与范围；Strategy 为空时使用最少播放优先
+type RandomPlayOptions struct {
+	Strategy string          `json:"strategy"`
+	Scope    RandomPlayScope `json:"scope"`
+}
+
+// RandomPlayStrategyInfo 供前端展示的策略说明
+type RandomPlayStrategyInfo struct {
+	Name        string `json:"name"`
+	Label       string `json:"label"`
+	Description string `json:"description"`
+}
+
+// randomCandidate 计算权重所需的最少字段，避免全量加载
+type randomCandidate struct {
+	ID              uint
+	PlayCount       int
+	RandomPlayCount int
+	LastPlayedAt    *time.Time
+}
+
+// randomPlayEnv 策略计算权重时可用的环境
+type randomPlayEnv struct {
+	db         *gorm.DB
+	playWeight float64
+	now        time.Time
+}
+
+// RandomPlayStrategy 为候选视频计算选择权重，返回值与 candidates 一一对应且均为正数
+type RandomPlayStrategy interface {
+	Info() RandomPlayStrategyInfo
+	Weights(env randomPlayEnv, candidates []randomCandidate) ([]float64, error)
+}
+
+var randomPlayStrategies = map[string]RandomPlayStrategy{}
+
+// randomPlayStrategyOrder 保持策略列表的展示顺序
+var randomPlayStrategyOrder []string
+
+// registerRandomPlayStrategy 注册随机播放策略，同名覆盖
+func registerRandomPlayStrategy(strategy RandomPlayStrategy) {
+	name := strategy.Info().Name
+	if _, exists := randomPlayStrategies[name]; !exists {
+		randomPlayStrategyOrder = append(randomPlayStrategyOrder, name)
+	}
+	randomPlayStrategies[name] = strategy
+}
+
+func init() {
+	registerRandomPlayStrategy(leastPlayedStrategy{})
+	registerRandomPlayStrategy(uniformStrategy{})
+	registerRandomPlayStrategy(recencyDecayStrategy{halfLife: recencyHalfLife})
+	registerRandomPlayStrategy(tagPreferenceStrategy{})
+}
+
+// RandomPlayStrategies 列出可用的随机播放策略
+func RandomPlayStrategies() []RandomPlayStrategyInfo {
+	infos := make([]RandomPlayStrategyInfo, 0, len(randomPlayStrategyOrder))
+	for _, name := range randomPlayStrategyOrder {
+		infos = append(infos, randomPlayStrategies[name].Info())
+	}
+	return infos
+}
+
+func randomPlayStrategyFor(name string) (RandomPlayStrategy, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = RandomStrategyLeastPlayed
+	}
+	strategy, ok := randomPlayStrategies[name]
+	if !ok {
+		return nil, fmt.Errorf("未知的随机播放策略: %s", name)
+	}
+	return strategy, nil
+}
+
+// leastPlayedStrategy 原有算法：播放分数越低权重越高（maxScore - score + 1）
+type leastPlayedStrategy struct{}
+
+func (leastPlayedStrategy) Info() RandomPlayStrategyInfo {
+	return RandomPlayStrategyInfo{Name: RandomStrategyLeastPlayed, Label: "少播优先", Description: "播放次数越少越容易被选中，普通播放按播放权重折算"}
+}
+
+func (leastPlayedStrategy) Weights(env randomPlayEnv, candidates []randomCandidate) ([]float64, error) {
+	scores := make([]float64, len(candidates))
+	maxScore := 0.0
+	for i, c := range candidates {
+		scores[i] = float64(c.PlayCount)*env.playWeight + float64(c.RandomPlayCount)
+		if scores[i] > maxScore {
+			maxScore = scores[i]
+		}
+	}
+	weights := make([]float64, len(candidates))
+	for i, score := range scores {
+		weights[i] = maxScore - score + 1.0
+	}
+	return weights, nil
+}
+
+// uniformStrategy 等概率随机
+type uniformStrategy struct{}
+
+func (uniformStrategy) Info() RandomPlayStrategyInfo {
+	return RandomPlayStrategyInfo{Name: RandomStrategyUniform, Label: "完全随机", Description: "范围内每个视频概率相同"}
+}
+
+func (uniformStrategy) Weights(_ randomPlayEnv, candidates []randomCandidate) ([]float64, error) {
+	weights := make([]float64, len(candidates))
+	for i := range weights {
+		weights[i] = 1
+	}
+	return weights, nil
+}
+
+// recencyDecayStrategy 按距上次播放的时间恢复权重：1 - 0.5^(经过时长/半衰期)，从未播放为 1
+type recencyDecayStrategy struct {
+	halfLife time.Duration
+}
+
+func (recencyDecayStrategy) Info() RandomPlayStrategyInfo {
+	return RandomPlayStrategyInfo{Name: RandomStrategyRecencyDecay, Label: "久未播放优先", Description: "最近播放过的视频暂时降权，随时间逐渐恢复"}
+}
+
+func (s recencyDecayStrategy) Weights(env randomPlayEnv, candidates []randomCandidate) ([]float64, error) {
+	weights := make([]float64, len(candidates))
+	for i, c := range candidates {
+		if c.LastPlayedAt == nil {
+			weights[i] = 1
+			continue
+		}
+		elapsed := env.now.Sub(*c.LastPlayedAt)
+		if elapsed < 0 {
+			elapsed = 0
+		}
+		weights[i] = math.Max(1-math.Pow(0.5, elapsed.Hours()/s.halfLife.Hours()), minRandomWeight)
+	}
+	return weights, nil
+}
+
+// tagPreferenceStrategy 按短视频互动积累的标签偏好加权：1 + 视频各标签正向偏好分之和
+type tagPreferenceStrategy struct{}
+
+func (tagPreferenceStrategy) Info() RandomPlayStrategyInfo {
+	return RandomPlayStrategyInfo{Name: RandomStrategyTagPreference, Label: "偏好标签优先", Description: "带有常点赞、收藏标签的视频更容易被选中"}
+}
+
+func (tagPreferenceStrategy) Weights(env randomPlayEnv, candidates []randomCandidate) ([]float64, error) {
+	weights := make([]float64, len(candidates))
+	index := make(map[uint]int, len(candidates))
+	ids := make([]uint, 0, len(candidates))
+	for i, c := range candidates {
+		weights[i] = 1
+		index[c.ID] = i
+		ids = append(ids, c.ID)
+	}
+	type preferenceRow struct {
+		VideoID uint
+		Score   float64
+	}
+	var rows []preferenceRow
+	for start := 0; start < len(ids); start += 500 {
+		end := min(start+500, len(ids))
+		var batch []preferenceRow
+		err := env.db.Table("video_tags").
+			Select("video_tags.video_id AS video_id, SUM(short_feed_tag_preferences.score) AS score").
+			Joins("JOIN short_feed_tag_preferences ON short_feed_tag_preferences.tag_id = video_tags.tag_id").
+			Where("video_tags.video_id IN ? AND short_feed_tag_preferences.score > 0", ids[start:end]).
+			Group("video_tags.video_id").
+			Scan(&batch).Error
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, batch...)
+	}
+	for _, row := range rows {
+		if i, ok := index[row.VideoID]; ok {
+			weights[i] += row.Score
+		}
+	}
+	return weights, nil
+}
+
+// applyRandomPlayScope 把范围条件追加到 videos 查询；Keyword 与搜索框使用同一搜索语法
+func applyRandomPlayScope(query *gorm.DB, scope RandomPlayScope) (*gorm.DB, error) {
+	query = query.Where("videos.is_stale = ? AND videos.is_offline = ?", false, false)
+	query, err := applyVideoSearchFilter(query, VideoSearchFilter{
+		Keyword:               scope.Keyword,
+		TagIDs:                scope.TagIDs,
+		IncludeTagDescendants: scope.IncludeTagDescendants,
+		MinSize:               scope.MinSize,
+		MaxSize:               scope.MaxSize,
+		MinHeight:             scope.MinHeight,
+		MaxHeight:             scope.MaxHeight,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if dir := strings.TrimSpace(scope.Directory); dir != "" {
+		cleanDir := filepath.Clean(dir)
+		childPrefix := escapeSQLLike(cleanDir+string(os.PathSeparator)) + "%"
+		query = query.Where("(videos.directory = ? OR videos.directory LIKE ? ESCAPE '\\')", cleanDir, childPrefix)
+	}
+	if scope.SavedSearchID > 0 {
+		filter, err := loadSavedSearchFilter(scope.SavedSearchID)
+		if err != nil {
+			return nil, err
+		}
+		if query, err = applyVideoSearchFilter(query, filter); err != nil {
+			return nil, err
+		}
+	}
+	return query, nil
+}
+
+func isEmptyRandomPlayScope(scope RandomPlayScope) bool {
+	return strings.TrimSpace(scope.Keyword) == "" && len(scope.TagIDs) == 0 && strings.TrimSpace(scope.Directory) == "" &&
+		scope.MinSize == 0 && scope.MaxSize == 0 && scope.MinHeight == 0 && scope.MaxHeight == 0 && scope.SavedSearchID == 0
+}
+
+// pickWeighted 按权重随机选择下标；randomValue ∈ [0,1)
+func pickWeighted(weights []float64, randomValue float64) int {
+	total := 0.0
+	for _, w := range weights {
+		total += w
+	}
+	target := randomValue * total
+	cumulative := 0.0
+	for i, w := range weights {
+		cumulative += w
+		if target <= cumulative {
+			return i
+		}
+	}
+	return len(weights) - 1 // 防御浮点精度
+}
+
+func clampRandomNoRepeat(value int) int {
+	return max(0, min(value, maxRandomNoRepeat))
+}
+
+// recentRandomPickIDs 返回防重复窗口内的视频 ID（新到旧去重）；最近 N 次与最近 T 小时取并集
+func recentRandomPickIDs(settings models.Settings, now time.Time) ([]uint, error) {
+	count := clampRandomNoRepeat(settings.RandomNoRepeatCount)
+	hours := clampRandomNoRepeat(settings.RandomNoRepeatHours)
+	if count == 0 && hours == 0 {
+		return nil, nil
+	}
+	var picks []models.RandomPick
+	if err := database.DB.Order("picked_at DESC, id DESC").Limit(maxRandomNoRepeat).Find(&picks).Error; err != nil {
+		return nil, err
+	}
+	cutoff := now.Add(-time.Duration(hours) * time.Hour)
+	seen := make(map[uint]bool, len(picks))
+	ids := make([]uint, 0, len(picks))
+	for i, pick := range picks {
+		inWindow := i < count || (hours > 0 && pick.PickedAt.After(cutoff))
+		if !inWindow {
+			break
+		}
+		if !seen[pick.VideoID] {
+			seen[pick.VideoID] = true
+			ids = append(ids, pick.VideoID)
+		}
+	}
+	return ids, nil
+}
+
+// randomPickExclusions 从新到旧排除窗口内的视频，但至少保留一个候选，候选不足时只排除最近的几个
+func randomPickExclusions(candidateIDs []uint, recentIDs []uint) map[uint]bool {
+	isCandidate := make(map[uint]bool, len(candidateIDs))
+	for _, id := range candidateIDs {
+		isCandidate[id] = true
+	}
+	excluded := map[uint]bool{}
+	remaining := len(isCandidate)
+	for _, id := range recentIDs {
+		if remaining <= 1 {
+			break
+		}
+		if isCandidate[id] && !excluded[id] {
+			excluded[id] = true
+			remaining--
+		}
+	}
+	return excluded
+}
+
+// recordRandomPick 记录一次随机选片并裁剪旧记录，写入失败只影响防重复效果
+func recordRandomPick(videoID uint, source string, now time.Time) error {
+	if err := database.DB.Create(&models.RandomPick{VideoID: videoID, Source: source, PickedAt: now}).Error; err != nil {
+		return err
+	}
+	var stale []models.RandomPick
+	if err := database.DB.Order("id DESC").Offset(maxRandomNoRepeat).Limit(1).Find(&stale).Error; err != nil {
+		return err
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+	return database.DB.Where("id <= ?", stale[0].ID).Delete(&models.RandomPick{}).Error
+}
+
+// pickRandomVideoID 在范围内避开防重复窗口、按策略选出一个视频 ID；范围内没有视频时返回 0。
+// 选片记录由调用方在成功发起播放后写入，播放失败的视频不占用防重复窗口
+func pickRandomVideoID(options RandomPlayOptions) (uint, error) {
+	strategy, err := randomPlayStrategyFor(options.Strategy)
+	if err != nil {
+		return 0, err
+	}
+	var settings models.Settings
+	if err := database.DB.First(&settings).Error; err != nil {
+		return 0, fmt.Errorf("获取设置失败: %w", err)
+	}
+	playWeight := settings.PlayWeight
+	if playWeight < 0.1 {
+		playWeight = 0.1
+	}
+
+	var candidates []randomCandidate
+	query, err := applyRandomPlayScope(database.DB.Model(&models.Video{}), options.Scope)
+	if err != nil {
+		return 0, err
+	}
+	if err := query.Select("videos.id, videos.play_count, videos.random_play_count, videos.last_played_at").
+		Find(&candidates).Error; err != nil {
+		return 0, err
+	}
+	if len(candidates) == 0 {
+		return 0, nil
+	}
+
+	now := time.Now()
+	recentIDs, err := recentRandomPickIDs(settings, now)
+	if err != nil {
+		return 0, err
+	}
+	if len(recentIDs) > 0 {
+		ids := make([]uint, len(candidates))
+		for i, c := range candidates {
+			ids[i] = c.ID
+		}
+		excluded := randomPickExclusions(ids, recentIDs)
+		kept := candidates[:0]
+		for _, c := range candidates {
+			if !excluded[c.ID] {
+				kept = append(kept, c)
+			}
+		}
+		candidates = kept
+	}
+
+	weights, err := strategy.Weights(randomPlayEnv{db: database.DB, playWeight: playWeight, now: now}, candidates)
+	if err != nil {
+		return 0, err
+	}
+	for i := range weights {
+		if !(weights[i] > 0) {
+			weights[i] = minRandomWeight
+		}
+	}
+	// Go 1.20+ 全局 rand 已自动 seed，无需手动调用
+	return candidates[pickWeighted(weights, rand.Float64())].ID, nil
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"video-master/database"
+	"video-master/models"
+)
+
+func TestRandomPlayStrategyWeights(t *testing.T) {
+	now := time.Now()
+	recent := now.Add(-time.Hour)
+	halfLifeAgo := now.Add(-recencyHalfLife)
+	candidates := []randomCandidate{
+		{ID: 1, PlayCount: 2, RandomPlayCount: 1},
+		{ID: 2, LastPlayedAt: &recent},
+		{ID: 3, LastPlayedAt: &halfLifeAgo},
+	}
+	env := randomPlayEnv{db: database.DB, playWeight: 2, now: now}
+
+	weights, _ := leastPlayedStrategy{}.Weights(env, candidates)
+	if weights[0] != 1 || weights[1] != 6 || weights[2] != 6 {
+		t.Fatalf("少播优先应沿用 maxScore-score+1，got=%v", weights)
+	}
+	weights, _ = uniformStrategy{}.Weights(env, candidates)
+	if weights[0] != 1 || weights[1] != 1 || weights[2] != 1 {
+		t.Fatalf("完全随机权重应相同，got=%v", weights)
+	}
+	weights, _ = recencyDecayStrategy{halfLife: recencyHalfLife}.Weights(env, candidates)
+	if weights[0] != 1 || weights[1] >= 0.05 || weights[2] < 0.49 || weights[2] > 0.51 {
+		t.Fatalf("久未播放优先权重错误，got=%v", weights)
+	}
+
+	if got := pickWeighted([]float64{1, 0.01, 3}, 0.5); got != 2 {
+		t.Fatalf("加权选择错误，got=%d", got)
+	}
+	if _, err := randomPlayStrategyFor("nope"); err == nil {
+		t.Fatalf("未知策略应返回错误")
+	}
+	if len(RandomPlayStrategies()) != 4 {
+		t.Fatalf("应注册 4 种策略，got=%+v", RandomPlayStrategies())
+	}
+}
+
+func TestTagPreferenceStrategyBoostsPreferredTags(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	root := t.TempDir()
+	liked := createShortFeedTag(t, "liked")
+	disliked := createShortFeedTag(t, "disliked")
+	a := createShortFeedVideo(t, root, "a.mp4", 60, false, &liked)
+	b := createShortFeedVideo(t, root, "b.mp4", 60, false, &disliked)
+	c := createShortFeedVideo(t, root, "c.mp4", 60, false)
+	database.DB.Create(&models.ShortFeedTagPreference{TagID: liked.ID, Score: 4})
+	database.DB.Create(&models.ShortFeedTagPreference{TagID: disliked.ID, Score: -3})
+
+	weights, err := tagPreferenceStrategy{}.Weights(randomPlayEnv{db: database.DB, now: time.Now()},
+		[]randomCandidate{{ID: a.ID}, {ID: b.ID}, {ID: c.ID}})
+	if err != nil {
+		t.Fatalf("计算权重失败: %v", err)
+	}
+	if weights[0] != 5 || weights[1] != 1 || weights[2] != 1 {
+		t.Fatalf("只有正向偏好参与加权，got=%v", weights)
+	}
+}
+
+func TestPlayRandomVideoWithOptionsRespectsScope(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	root := t.TempDir()
+	tripDir := filepath.Join(root, "trip")
+	if err := os.MkdirAll(tripDir, 0755); err != nil {
+		t.Fatalf("创建目录失败: %v", err)
+	}
+	tag := createShortFeedTag(t, "trip")
+	inScope := createShortFeedVideo(t, tripDir, "beach.mp4", 60, false, &tag)
+	createShortFeedVideo(t, tripDir, "stale.mp4", 60, true, &tag)
+	offline := createShortFeedVideo(t, tripDir, "offline.mp4", 60, false, &tag)
+	createShortFeedVideo(t, root, "untagged.mp4", 60, false)
+	createShortFeedVideo(t, t.TempDir(), "elsewhere.mp4", 60, false, &tag)
+	database.DB.Model(&offline).Update("is_offline", true)
+
+	oldOpen := openWithDefaultFn
+	openWithDefaultFn = func(path string, isDir bool) error { return nil }
+	defer func() { openWithDefaultFn = oldOpen }()
+
+	svc := &VideoService{}
+	for i := 0; i < 5; i++ {
+		result, err := svc.PlayRandomVideoWithOptions(RandomPlayOptions{
+			Strategy: RandomStrategyUniform,
+			Scope:    RandomPlayScope{TagIDs: []uint{tag.ID}, Directory: root},
+		})
+		if err != nil || !result.DispatchSucceeded || result.Video.ID != inScope.ID {
+			t.Fatalf("应只选中范围内未失效且在线的视频 result=%+v err=%v", result, err)
+		}
+	}
+
+	result, err := svc.PlayRandomVideoWithOptions(RandomPlayOptions{Scope: RandomPlayScope{Keyword: "nothing-matches"}})
+	if err != nil || result.DispatchSucceeded || result.ReasonCode != "no_videos" {
+		t.Fatalf("范围内无视频应返回 no_videos result=%+v err=%v", result, err)
+	}
+	if _, err := svc.PlayRandomVideoWithOptions(RandomPlayOptions{Strategy: "nope"}); err == nil {
+		t.Fatalf("未知策略应返回错误")
+	}
+
+	reloaded := models.Video{}
+	database.DB.First(&reloaded, inScope.ID)
+	if reloaded.RandomPlayCount != 5 {
+		t.Fatalf("随机播放应计入随机播放次数，got=%d", reloaded.RandomPlayCount)
+	}
+}
+
+func TestRandomPickExclusionsKeepsAtLeastOneCandidate(t *testing.T) {
+	excluded := randomPickExclusions([]uint{1, 2, 3}, []uint{3, 9, 1, 2})
+	if !excluded[3] || !excluded[1] || excluded[2] || len(excluded) != 2 {
+		t.Fatalf("应从新到旧排除且至少保留一个候选，got=%v", excluded)
+	}
+	if excluded := randomPickExclusions([]uint{5}, []uint{5}); len(excluded) != 0 {
+		t.Fatalf("唯一候选不应被排除，got=%v", excluded)
+	}
+}
+
+func TestRandomPickWindowSharedWithShortFeed(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	root := t.TempDir()
+	a := createShortFeedVideo(t, root, "a.mp4", 60, false)
+	b := createShortFeedVideo(t, root, "b.mp4", 60, false)
+
+	oldOpen := openWithDefaultFn
+	openWithDefaultFn = func(path string, isDir bool) error { return nil }
+	defer func() { openWithDefaultFn = oldOpen }()
+
+	// 默认不重复最近 1 次：两个视频应交替出现
+	svc := &VideoService{}
+	first, err := svc.PlayRandomVideoWithOptions(RandomPlayOptions{Strategy: RandomStrategyUniform})
+	if err != nil || first.Video == nil {
+		t.Fatalf("随机播放失败 result=%+v err=%v", first, err)
+	}
+	second, err := svc.PlayRandomVideoWithOptions(RandomPlayOptions{Strategy: RandomStrategyUniform})
+	if err != nil || second.Video.ID == first.Video.ID {
+		t.Fatalf("连续两次不应选中同一视频 first=%d second=%+v err=%v", first.Video.ID, second, err)
+	}
+
+	feed := NewShortFeedService(svc)
+	next, err := feed.NextVideo(nil)
+	if err != nil || next.ID != first.Video.ID {
+		t.Fatalf("短视频应避开桌面端刚选中的视频 next=%+v err=%v", next, err)
+	}
+
+	// 按时间窗口：最近 1 小时内选过的都排除，仍保留至少一个候选
+	database.DB.Model(&models.Settings{}).Where("1 = 1").Updates(map[string]interface{}{"random_no_repeat_count": 0, "random_no_repeat_hours": 1})
+	c := createShortFeedVideo(t, root, "c.mp4", 60, false)
+	for i := 0; i < 3; i++ {
+		next, err = feed.NextVideo(nil)
+		if err != nil {
+			t.Fatalf("获取短视频失败: %v", err)
+		}
+		if i == 0 && next.ID != c.ID {
+			t.Fatalf("一小时内选过的 a/b 应被排除，got=%d", next.ID)
+		}
+	}
+
+	feed.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	recent, err := recentRandomPickIDs(models.Settings{RandomNoRepeatHours: 1}, feed.now())
+	if err != nil || len(recent) != 0 {
+		t.Fatalf("超出时间窗口的记录不应计入 recent=%v err=%v", recent, err)
+	}
+	recent, err = recentRandomPickIDs(models.Settings{RandomNoRepeatCount: 2}, feed.now())
+	if err != nil || len(recent) == 0 || len(recent) > 2 {
+		t.Fatalf("按次数窗口应返回最近的选片 recent=%v err=%v", recent, err)
+	}
+	if _, ok := map[uint]bool{a.ID: true, b.ID: true, c.ID: true}[recent[0]]; !ok {
+		t.Fatalf("选片记录应指向已有视频，got=%v", recent)
+	}
+}
+
+func TestRandomPickRecordedOnlyAfterSuccessfulDispatch(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	createShortFeedVideo(t, t.TempDir(), "a.mp4", 60, false)
+
+	oldOpen := openWithDefaultFn
+	openWithDefaultFn = func(path string, isDir bool) error { return errors.New("no player") }
+	defer func() { openWithDefaultFn = oldOpen }()
+
+	svc := &VideoService{}
+	result, err := svc.PlayRandomVideoWithOptions(RandomPlayOptions{})
+	if err != nil || result.DispatchSucceeded {
+		t.Fatalf("播放器启动失败时应返回失败结果 result=%+v err=%v", result, err)
+	}
+	if countRows(t, "random_picks") != 0 {
+		t.Fatalf("播放失败的视频不应记入选片记录")
+	}
+
+	openWithDefaultFn = func(path string, isDir bool) error { return nil }
+	if result, err := svc.PlayRandomVideoWithOptions(RandomPlayOptions{}); err != nil || !result.DispatchSucceeded {
+		t.Fatalf("随机播放失败 result=%+v err=%v", result, err)
+	}
+	if countRows(t, "random_picks") != 1 {
+		t.Fatalf("成功发起播放后应记入选片记录")
+	}
+
+	database.DB.Where("1 = 1").Delete(&models.Settings{})
+	if _, err := NewShortFeedService(svc).withoutRecentPicks(nil); err == nil {
+		t.Fatalf("读取设置失败时应返回错误")
+	}
+}
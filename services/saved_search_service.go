@@ -0,0 +1,180 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"video-master/database"
+	"video-master/models"
+)
+
+// SavedSearchInput 新建/编辑保存的搜索
+type SavedSearchInput struct {
+	Name   string            `json:"name"`
+	Filter VideoSearchFilter `json:"filter"`
+}
+
+// SavedSearchResult 执行保存的搜索的一页结果；Total 为当前匹配总数
+type SavedSearchResult struct {
+	SavedSearch models.SavedSearch `json:"saved_search"`
+	Videos      []models.Video     `json:"videos"`
+	Total       int64              `json:"total"`
+}
+
+// SavedSearchService 管理保存的搜索，并复用组合搜索的游标分页执行
+type SavedSearchService struct {
+	videoService *VideoService
+}
+
+func NewSavedSearchService(videoService *VideoService) *SavedSearchService {
+	if videoService == nil {
+		videoService = &VideoService{}
+	}
+	return &SavedSearchService{videoService: videoService}
+}
+
+// GetSavedSearches 列出全部保存的搜索，并实时统计匹配数；条件失效（如语法错误）的记为 0
+func (s *SavedSearchService) GetSavedSearches() ([]models.SavedSearch, error) {
+	var searches []models.SavedSearch
+	if err := database.DB.Order("name asc, id asc").Find(&searches).Error; err != nil {
+		return nil, err
+	}
+	for i := range searches {
+		filter, err := decodeSavedSearchFilter(searches[i].Filter)
+		if err != nil {
+			continue
+		}
+		if count, err := s.videoService.CountVideosByFilter(filter); err == nil {
+			searches[i].MatchCount = count
+		}
+	}
+	return searches, nil
+}
+
+// CreateSavedSearch 新建保存的搜索
+func (s *SavedSearchService) CreateSavedSearch(input SavedSearchInput) (*models.SavedSearch, error) {
+	search := models.SavedSearch{}
+	if err := applySavedSearchInput(&search, input); err != nil {
+		return nil, err
+	}
+	if err := database.DB.Create(&search).Error; err != nil {
+		return nil, err
+	}
+	return &search, nil
+}
+
+// UpdateSavedSearch 修改名称与搜索条件
+func (s *SavedSearchService) UpdateSavedSearch(id uint, input SavedSearchInput) error {
+	var search models.SavedSearch
+	if err := database.DB.First(&search, id).Error; err != nil {
+		return err
+	}
+	if err := applySavedSearchInput(&search, input); err != nil {
+		return err
+	}
+	return database.DB.Save(&search).Error
+}
+
+// DeleteSavedSearch 删除保存的搜索
+func (s *SavedSearchService) DeleteSavedSearch(id uint) error {
+	return database.DB.Delete(&models.SavedSearch{}, id).Error
+}
+
+// RunSavedSearch 按保存的条件游标分页搜索，游标参数与 SearchVideosWithFilters 一致
+func (s *SavedSearchService) RunSavedSearch(id uint, cursorScore float64, cursorSize int64, cursorID uint, limit int) (*SavedSearchResult, error) {
+	var search models.SavedSearch
+	if err := database.DB.First(&search, id).Error; err != nil {
+		return nil, err
+	}
+	filter, err := decodeSavedSearchFilter(search.Filter)
+	if err != nil {
+		return nil, err
+	}
+	videos, err := s.videoService.SearchVideosByFilter(filter, cursorScore, cursorSize, cursorID, limit)
+	if err != nil {
+		return nil, err
+	}
+	total, err := s.videoService.CountVideosByFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	search.MatchCount = total
+	return &SavedSearchResult{SavedSearch: search, Videos: videos, Total: total}, nil
+}
+
+// loadSavedSearchFilter 读取保存的搜索条件，供随机播放与短视频作为范围使用
+func loadSavedSearchFilter(id uint) (VideoSearchFilter, error) {
+	var search models.SavedSearch
+	if err := database.DB.First(&search, id).Error; err != nil {
+		return VideoSearchFilter{}, fmt.Errorf("保存的搜索不存在: %w", err)
+	}
+	return decodeSavedSearchFilter(search.Filter)
+}
+
+func applySavedSearchInput(search *models.SavedSearch, input SavedSearchInput) error {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return fmt.Errorf("保存的搜索名称不能为空")
+	}
+	var sameName int64
+	if err := database.DB.Model(&models.SavedSearch{}).Where("name = ? AND id <> ?", name, search.ID).Count(&sameName).Error; err != nil {
+		return err
+	}
+	if sameName > 0 {
+		return fmt.Errorf("已存在同名的保存搜索: %s", name)
+	}
+
+	filter, err := normalizeVideoSearchFilter(input.Filter)
+	if err != nil {
+		return err
+	}
+	encoded, err := json.Marshal(filter)
+	if err != nil {
+		return err
+	}
+	search.Name = name
+	search.Filter = string(encoded)
+	return nil
+}
+
+// normalizeVideoSearchFilter 校验并整理要持久化的组合搜索条件（保存的搜索与智能播放列表共用）
+func normalizeVideoSearchFilter(filter VideoSearchFilter) (VideoSearchFilter, error) {
+	filter.Keyword = strings.TrimSpace(filter.Keyword)
+	if _, err := ParseSearchQuery(filter.Keyword); err != nil {
+		return filter, err
+	}
+	filter.TagIDs = uniqueUintIDs(filter.TagIDs)
+	if filter.MinSize < 0 || filter.MaxSize < 0 || filter.MinHeight < 0 || filter.MaxHeight < 0 || filter.MinDuration < 0 || filter.MaxDuration < 0 {
+		return filter, fmt.Errorf("筛选范围不能为负数")
+	}
+	if (filter.MaxSize > 0 && filter.MinSize >= filter.MaxSize) ||
+		(filter.MaxHeight > 0 && filter.MinHeight > filter.MaxHeight) ||
+		(filter.MaxDuration > 0 && filter.MinDuration >= filter.MaxDuration) {
+		return filter, fmt.Errorf("筛选范围下限不能超过上限")
+	}
+	return filter, nil
+}
+
+func decodeSavedSearchFilter(text string) (VideoSearchFilter, error) {
+	var filter VideoSearchFilter
+	if strings.TrimSpace(text) == "" {
+		return filter, nil
+	}
+	if err := json.Unmarshal([]byte(text), &filter); err != nil {
+		return filter, fmt.Errorf("保存的搜索条件无效: %w", err)
+	}
+	return filter, nil
+}
+
+func uniqueUintIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	result := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if id == 0 || seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+	return result
+}
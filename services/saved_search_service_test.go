@@ -0,0 +1,131 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+	"video-master/database"
+	"video-master/models"
+)
+
+func TestSavedSearchCRUDAndRunWithCursor(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	root := t.TempDir()
+	dance := createShortFeedTag(t, "dance")
+	a := createShortFeedVideo(t, root, "dance-a.mp4", 120, false, &dance)
+	b := createShortFeedVideo(t, root, "dance-b.mp4", 200, false, &dance)
+	createShortFeedVideo(t, root, "dance-short.mp4", 30, false, &dance)
+	createShortFeedVideo(t, root, "untagged.mp4", 120, false)
+	svc := NewSavedSearchService(&VideoService{})
+
+	if _, err := svc.CreateSavedSearch(SavedSearchInput{Name: " "}); err == nil {
+		t.Fatalf("空名称应被拒绝")
+	}
+	if _, err := svc.CreateSavedSearch(SavedSearchInput{Name: "bad", Filter: VideoSearchFilter{Keyword: `"open`}}); err == nil {
+		t.Fatalf("搜索语法错误应被拒绝")
+	}
+	if _, err := svc.CreateSavedSearch(SavedSearchInput{Name: "bad", Filter: VideoSearchFilter{MinDuration: 100, MaxDuration: 50}}); err == nil {
+		t.Fatalf("下限超过上限应被拒绝")
+	}
+
+	search, err := svc.CreateSavedSearch(SavedSearchInput{
+		Name:   "长舞蹈",
+		Filter: VideoSearchFilter{Keyword: "dance", TagIDs: []uint{dance.ID, dance.ID}, MinDuration: 60},
+	})
+	if err != nil {
+		t.Fatalf("保存搜索失败: %v", err)
+	}
+	if _, err := svc.CreateSavedSearch(SavedSearchInput{Name: "长舞蹈"}); err == nil {
+		t.Fatalf("重名应被拒绝")
+	}
+
+	first, err := svc.RunSavedSearch(search.ID, 0, 0, 0, 1)
+	if err != nil || len(first.Videos) != 1 || first.Total != 2 {
+		t.Fatalf("第一页应返回 1 个视频且总数为 2 result=%+v err=%v", first, err)
+	}
+	last := first.Videos[0]
+	second, err := svc.RunSavedSearch(search.ID, 0, last.Size, last.ID, 1)
+	if err != nil || len(second.Videos) != 1 || second.Videos[0].ID == last.ID {
+		t.Fatalf("游标分页应返回下一个视频 result=%+v err=%v", second, err)
+	}
+	got := map[uint]bool{last.ID: true, second.Videos[0].ID: true}
+	if !got[a.ID] || !got[b.ID] {
+		t.Fatalf("应只匹配时长达标且带标签的视频，got=%v", got)
+	}
+
+	if err := svc.UpdateSavedSearch(search.ID, SavedSearchInput{Name: "长舞蹈", Filter: VideoSearchFilter{TagIDs: []uint{dance.ID}, MinDuration: 150}}); err != nil {
+		t.Fatalf("更新保存的搜索失败: %v", err)
+	}
+	searches, err := svc.GetSavedSearches()
+	if err != nil || len(searches) != 1 || searches[0].MatchCount != 1 {
+		t.Fatalf("列表应返回实时匹配数 searches=%+v err=%v", searches, err)
+	}
+
+	if err := svc.DeleteSavedSearch(search.ID); err != nil {
+		t.Fatalf("删除保存的搜索失败: %v", err)
+	}
+	if _, err := svc.RunSavedSearch(search.ID, 0, 0, 0, 10); err == nil {
+		t.Fatalf("删除后执行应返回错误")
+	}
+}
+
+func TestSavedSearchScopesRandomPlayAndShortFeed(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	root := t.TempDir()
+	favorite := createShortFeedTag(t, "favorite")
+	inScope := createShortFeedVideo(t, root, "keep.mp4", 60, false, &favorite)
+	createShortFeedVideo(t, root, "other.mp4", 60, false)
+	svc := NewSavedSearchService(&VideoService{})
+	search, err := svc.CreateSavedSearch(SavedSearchInput{Name: "收藏", Filter: VideoSearchFilter{TagIDs: []uint{favorite.ID}}})
+	if err != nil {
+		t.Fatalf("保存搜索失败: %v", err)
+	}
+	// 关闭防重复窗口，范围内只有一个视频时每次都应选中它
+	database.DB.Model(&models.Settings{}).Where("1 = 1").Update("random_no_repeat_count", 0)
+
+	oldOpen := openWithDefaultFn
+	openWithDefaultFn = func(path string, isDir bool) error { return nil }
+	defer func() { openWithDefaultFn = oldOpen }()
+
+	videoService := &VideoService{}
+	for i := 0; i < 3; i++ {
+		result, err := videoService.PlayRandomVideoWithOptions(RandomPlayOptions{
+			Strategy: RandomStrategyUniform,
+			Scope:    RandomPlayScope{SavedSearchID: search.ID},
+		})
+		if err != nil || result.Video == nil || result.Video.ID != inScope.ID {
+			t.Fatalf("随机播放应限定在保存的搜索内 result=%+v err=%v", result, err)
+		}
+	}
+	if _, err := videoService.PlayRandomVideoWithOptions(RandomPlayOptions{Scope: RandomPlayScope{SavedSearchID: 9999}}); err == nil {
+		t.Fatalf("不存在的保存搜索应返回错误")
+	}
+
+	server := NewShortFeedHTTPServer(NewShortFeedService(videoService), fstest.MapFS{
+		"short.html": &fstest.MapFile{Data: []byte("<div>short</div>"), ModTime: time.Now()},
+	}, ShortFeedHTTPServerConfig{BindAddress: "127.0.0.1", PortStart: 18088, PortEnd: 18088})
+	get := func(path string) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = "127.0.0.1:1234"
+		req.Host = "127.0.0.1:18088"
+		server.Handler().ServeHTTP(resp, req)
+		return resp
+	}
+
+	if resp := get("/short-api/saved-searches"); resp.Code != http.StatusOK || !strings.Contains(resp.Body.String(), `"name":"收藏"`) {
+		t.Fatalf("应列出保存的搜索 code=%d body=%s", resp.Code, resp.Body.String())
+	}
+	for i := 0; i < 3; i++ {
+		resp := get("/short-api/feed/next?saved_search=" + strconvUint(search.ID))
+		if resp.Code != http.StatusOK || !strings.Contains(resp.Body.String(), `"id":`+strconvUint(inScope.ID)+`,`) {
+			t.Fatalf("短视频应限定在保存的搜索内 code=%d body=%s", resp.Code, resp.Body.String())
+		}
+	}
+	if resp := get("/short-api/feed/next?saved_search=9999"); resp.Code != http.StatusNotFound {
+		t.Fatalf("不存在的保存搜索应返回 404，got=%d", resp.Code)
+	}
+}
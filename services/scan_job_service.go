@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+	"video-master/models"
+
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+type ScanJobConfig struct {
+	Workers int // ffprobe/哈希并发数，<=0 时使用默认值
+}
+
+type ScanJobProgress struct {
+	Stage   string `json:"stage"`
+	Message string `json:"message"`
+	Current int    `json:"current"`
+	Total   int    `json:"total"`
+	Path    string `json:"path"`
+}
+
+type ScanJobStatus struct {
+	Running   bool            `json:"running"`
+	Completed bool            `json:"completed"`
+	Cancelled bool            `json:"cancelled"`
+	Error     string          `json:"error"`
+	Progress  ScanJobProgress `json:"progress"`
+	Result    *ScanSyncResult `json:"result,omitempty"`
+	StartedAt *time.Time      `json:"started_at,omitempty" ts_type:"string"`
+	UpdatedAt *time.Time      `json:"updated_at,omitempty" ts_type:"string"`
+}
+
+// ScanJobService 在后台执行扫描目录增量同步，支持取消与进度推送
+type ScanJobService struct {
+	videoService *VideoService
+	config       ScanJobConfig
+	ctx          context.Context
+	mu           sync.Mutex
+	cancel       context.CancelFunc
+	done         chan struct{}
+	status       ScanJobStatus
+}
+
+func NewScanJobService(videoService *VideoService, config ScanJobConfig) *ScanJobService {
+	if config.Workers <= 0 {
+		config.Workers = defaultScanSyncWorkers
+	}
+	return &ScanJobService{
+		videoService: videoService,
+		config:       config,
+	}
+}
+
+func (s *ScanJobService) SetContext(ctx context.Context) {
+	s.mu.Lock()
+	s.ctx = ctx
+	s.mu.Unlock()
+}
+
+// Start 启动后台同步；已有任务在运行时直接返回其状态
+func (s *ScanJobService) Start(dirs []models.ScanDirectory) (*ScanJobStatus, error) {
+	s.mu.Lock()
+	if s.status.Running {
+		status := s.statusSnapshotLocked()
+		s.mu.Unlock()
+		return &status, nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	now := time.Now()
+	s.cancel = cancel
+	s.done = done
+	s.status = ScanJobStatus{
+		Running:   true,
+		StartedAt: &now,
+		UpdatedAt: &now,
+		Progress: ScanJobProgress{
+			Stage:   "load",
+			Message: "正在准备扫描…",
+		},
+	}
+	status := s.statusSnapshotLocked()
+	s.mu.Unlock()
+
+	log.Printf("[ScanJob] started dirs=%d workers=%d", len(dirs), s.config.Workers)
+	go func() {
+		defer close(done)
+		defer cancel()
+		result := s.videoService.syncScanDirectories(ctx, dirs, scanSyncOptions{
+			workers:  s.config.Workers,
+			progress: s.emitProgress,
+		})
+
+		s.mu.Lock()
+		now := time.Now()
+		s.status.Running = false
+		s.status.Cancelled = result.Cancelled
+		s.status.Completed = !result.Cancelled
+		s.status.Result = result
+		s.status.UpdatedAt = &now
+		s.mu.Unlock()
+
+		if result.Cancelled {
+			s.emitProgress("cancelled", 0, 0, "", fmt.Sprintf("扫描已取消：已新增 %d，迁移 %d，未删除任何记录。", result.Added, result.Relocated))
+			return
+		}
+		s.emitProgress("done", result.Scanned, result.Scanned, "", fmt.Sprintf(
+			"扫描完成：新增 %d，迁移 %d，删除 %d，补齐元数据 %d，错误 %d。",
+			result.Added, result.Relocated, result.Deleted, result.MetadataRefreshed, len(result.Errors),
+		))
+	}()
+
+	return &status, nil
+}
+
+// Cancel 请求取消当前任务并等待其停止；没有运行中的任务时直接返回当前状态
+func (s *ScanJobService) Cancel() *ScanJobStatus {
+	s.mu.Lock()
+	cancel := s.cancel
+	done := s.done
+	running := s.status.Running
+	s.mu.Unlock()
+
+	if running && cancel != nil {
+		log.Printf("[ScanJob] cancel requested")
+		cancel()
+		<-done
+	}
+	return s.Status()
+}
+
+func (s *ScanJobService) Status() *ScanJobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := s.statusSnapshotLocked()
+	return &status
+}
+
+func (s *ScanJobService) statusSnapshotLocked() ScanJobStatus {
+	status := s.status
+	if status.Result != nil {
+		resultCopy := *status.Result
+		status.Result = &resultCopy
+	}
+	return status
+}
+
+func (s *ScanJobService) emitProgress(stage string, current int, total int, currentPath string, message string) {
+	progress := ScanJobProgress{
+		Stage:   stage,
+		Message: message,
+		Current: current,
+		Total:   total,
+		Path:    currentPath,
+	}
+	s.mu.Lock()
+	now := time.Now()
+	s.status.Progress = progress
+	s.status.UpdatedAt = &now
+	ctx := s.ctx
+	s.mu.Unlock()
+
+	if ctx == nil {
+		return
+	}
+	wailsRuntime.EventsEmit(ctx, "scan-job-progress", progress)
+}
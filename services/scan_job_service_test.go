@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"video-master/database"
+	"video-master/models"
+)
+
+func mockSlowFFProbe(t *testing.T, root string, delay string) {
+	t.Helper()
+	ffprobeDir := filepath.Join(root, "bin")
+	if err := os.MkdirAll(ffprobeDir, 0755); err != nil {
+		t.Fatalf("创建 ffprobe 目录失败: %v", err)
+	}
+	script := fmt.Sprintf(`#!/bin/bash
+sleep %s
+echo '{"streams":[{"width":1920,"height":1080,"duration":"12.0"}],"format":{"duration":"12.0"}}'
+`, delay)
+	if err := os.WriteFile(filepath.Join(ffprobeDir, "ffprobe"), []byte(script), 0755); err != nil {
+		t.Fatalf("写入 ffprobe stub 失败: %v", err)
+	}
+	t.Setenv("PATH", ffprobeDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func waitForScanJob(t *testing.T, jobs *ScanJobService) *ScanJobStatus {
+	t.Helper()
+	waitForCondition(t, 10*time.Second, "扫描任务应结束", func() bool {
+		return !jobs.Status().Running
+	})
+	return jobs.Status()
+}
+
+func TestScanJobAddsFilesWithMetadataInBackground(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	mockFFProbe(t, t.TempDir())
+	root := t.TempDir()
+	for i := 0; i < 6; i++ {
+		mustCreateStableFiles(t, filepath.Join(root, fmt.Sprintf("clip-%d.mp4", i)))
+	}
+	missing := models.Video{Name: "gone.mp4", Path: filepath.Join(root, "gone.mp4"), Directory: root, Size: 99}
+	if err := database.DB.Create(&missing).Error; err != nil {
+		t.Fatalf("创建视频失败: %v", err)
+	}
+
+	jobs := NewScanJobService(&VideoService{}, ScanJobConfig{Workers: 3})
+	status, err := jobs.Start([]models.ScanDirectory{{Path: root}})
+	if err != nil || !status.Running {
+		t.Fatalf("启动扫描任务失败: status=%#v err=%v", status, err)
+	}
+
+	status = waitForScanJob(t, jobs)
+	if !status.Completed || status.Cancelled || status.Result == nil {
+		t.Fatalf("扫描任务状态错误: %#v", status)
+	}
+	if status.Result.Added != 6 || status.Result.Deleted != 1 || len(status.Result.Errors) != 0 {
+		t.Fatalf("扫描结果错误: %#v", status.Result)
+	}
+	if status.Progress.Stage != "done" {
+		t.Fatalf("最终进度阶段错误: %#v", status.Progress)
+	}
+
+	var withoutMetadata int64
+	if err := database.DB.Model(&models.Video{}).Where("height = 0 OR content_hash = ''").Count(&withoutMetadata).Error; err != nil {
+		t.Fatalf("查询视频失败: %v", err)
+	}
+	if withoutMetadata != 0 {
+		t.Fatalf("并发探测后所有新记录都应带元数据与内容哈希，缺失 %d 条", withoutMetadata)
+	}
+}
+
+func TestScanJobCancelStopsBeforeDeletingRecords(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	mockSlowFFProbe(t, t.TempDir(), "0.3")
+	root := t.TempDir()
+	const fileCount = 10
+	for i := 0; i < fileCount; i++ {
+		mustCreateStableFiles(t, filepath.Join(root, fmt.Sprintf("clip-%d.mp4", i)))
+	}
+	missing := models.Video{Name: "gone.mp4", Path: filepath.Join(root, "gone.mp4"), Directory: root, Size: 99}
+	if err := database.DB.Create(&missing).Error; err != nil {
+		t.Fatalf("创建视频失败: %v", err)
+	}
+
+	jobs := NewScanJobService(&VideoService{}, ScanJobConfig{Workers: 2})
+	if _, err := jobs.Start([]models.ScanDirectory{{Path: root}}); err != nil {
+		t.Fatalf("启动扫描任务失败: %v", err)
+	}
+	waitForCondition(t, 5*time.Second, "任务应进入新文件入库阶段", func() bool {
+		return jobs.Status().Progress.Stage == "add"
+	})
+
+	status := jobs.Cancel()
+	if status.Running || !status.Cancelled || status.Completed || status.Result == nil || !status.Result.Cancelled {
+		t.Fatalf("取消后状态错误: %#v", status)
+	}
+	if status.Result.Added >= fileCount || status.Result.Deleted != 0 {
+		t.Fatalf("取消后不应继续入库或删除: %#v", status.Result)
+	}
+	if countVideosByPath(t, missing.Path) != 1 {
+		t.Fatalf("扫描被取消时不应删除缺失记录")
+	}
+	var withoutMetadata int64
+	if err := database.DB.Model(&models.Video{}).Where("id <> ? AND height = 0", missing.ID).Count(&withoutMetadata).Error; err != nil {
+		t.Fatalf("查询视频失败: %v", err)
+	}
+	if withoutMetadata != 0 {
+		t.Fatalf("ffprobe 被取消中断的文件不应入库，发现 %d 条缺少元数据的记录", withoutMetadata)
+	}
+}
+
+func TestScanJobCancelDoesNotWaitForOtherSync(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	mockFFProbe(t, t.TempDir())
+	root := t.TempDir()
+	mustCreateStableFiles(t, filepath.Join(root, "clip.mp4"))
+
+	// 模拟目录监听正在执行不可取消的同步
+	if !acquireScanSync(context.Background(), nil) {
+		t.Fatalf("获取同步槽位失败")
+	}
+	defer releaseScanSync()
+
+	jobs := NewScanJobService(&VideoService{}, ScanJobConfig{Workers: 1})
+	if _, err := jobs.Start([]models.ScanDirectory{{Path: root}}); err != nil {
+		t.Fatalf("启动扫描任务失败: %v", err)
+	}
+	waitForCondition(t, 5*time.Second, "任务应进入等待状态", func() bool {
+		return jobs.Status().Progress.Stage == "load" && jobs.Status().Progress.Message == "正在等待其他扫描完成…"
+	})
+
+	cancelled := make(chan *ScanJobStatus, 1)
+	go func() { cancelled <- jobs.Cancel() }()
+	select {
+	case status := <-cancelled:
+		if !status.Cancelled || status.Running {
+			t.Fatalf("取消后状态错误: %#v", status)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("等待其他同步时取消不应阻塞")
+	}
+	if countVideosByPath(t, filepath.Join(root, "clip.mp4")) != 0 {
+		t.Fatalf("取消的任务不应入库")
+	}
+}
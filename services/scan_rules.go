@@ -0,0 +1,215 @@
+package services
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"video-master/database"
+	"video-master/models"
+)
+
+// scanDirectoryRules 是 models.ScanDirectoryRules 解析后的形式，绑定到具体扫描根目录
+type scanDirectoryRules struct {
+	root           string
+	include        []string
+	exclude        []string
+	maxDepth       int
+	followSymlinks bool
+	videoExts      []string
+}
+
+func newScanDirectoryRules(dir models.ScanDirectory, globalExts []string) scanDirectoryRules {
+	rules := scanDirectoryRules{
+		root:           filepath.Clean(strings.TrimSpace(dir.Path)),
+		include:        splitScanPatterns(dir.Rules.IncludePatterns),
+		exclude:        splitScanPatterns(dir.Rules.ExcludePatterns),
+		maxDepth:       dir.Rules.MaxDepth,
+		followSymlinks: dir.Rules.FollowSymlinks,
+		videoExts:      globalExts,
+	}
+	if overrides := normalizeVideoExtensions(dir.Rules.VideoExtensions); len(overrides) > 0 {
+		rules.videoExts = overrides
+	}
+	return rules
+}
+
+// loadScanDirectoryRules 为扫描根目录加载规则；未登记的目录只使用全局视频格式
+func loadScanDirectoryRules(root string) (scanDirectoryRules, error) {
+	globalExts, err := loadScanVideoExtensions()
+	if err != nil {
+		return scanDirectoryRules{}, err
+	}
+	root = filepath.Clean(strings.TrimSpace(root))
+	dir := models.ScanDirectory{Path: root}
+	var configured models.ScanDirectory
+	if err := database.DB.Where("path = ?", root).Order("id asc").Limit(1).Find(&configured).Error; err != nil {
+		return scanDirectoryRules{}, fmt.Errorf("读取目录扫描规则失败: %w", err)
+	}
+	if configured.ID != 0 {
+		dir = configured
+	}
+	return newScanDirectoryRules(dir, globalExts), nil
+}
+
+func loadScanDirectoryRulesList(dirs []models.ScanDirectory) ([]scanDirectoryRules, error) {
+	globalExts, err := loadScanVideoExtensions()
+	if err != nil {
+		return nil, err
+	}
+	rules := make([]scanDirectoryRules, 0, len(dirs))
+	for _, dir := range dirs {
+		rule := newScanDirectoryRules(dir, globalExts)
+		if rule.root == "" || rule.root == "." {
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func splitScanPatterns(text string) []string {
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return r == ',' || r == '\n' || r == '\r'
+	})
+	patterns := make([]string, 0, len(fields))
+	for _, field := range fields {
+		field = strings.Trim(filepath.ToSlash(strings.TrimSpace(field)), "/")
+		if field != "" {
+			patterns = append(patterns, field)
+		}
+	}
+	return patterns
+}
+
+// validateScanDirectoryRules 在保存前检查模式语法，避免扫描时静默失效
+func validateScanDirectoryRules(rules models.ScanDirectoryRules) error {
+	if rules.MaxDepth < 0 {
+		return fmt.Errorf("最大扫描深度不能为负数")
+	}
+	if marker := strings.TrimSpace(rules.VolumeMarker); marker != "" {
+		cleaned := filepath.Clean(filepath.FromSlash(marker))
+		if filepath.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("卷标识必须是扫描目录内的相对路径: %s", marker)
+		}
+	}
+	for _, pattern := range append(splitScanPatterns(rules.IncludePatterns), splitScanPatterns(rules.ExcludePatterns)...) {
+		for _, segment := range strings.Split(pattern, "/") {
+			if segment == "**" {
+				continue
+			}
+			if _, err := path.Match(segment, ""); err != nil {
+				return fmt.Errorf("无效的扫描模式 %q: %w", pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// relPath 返回相对扫描根目录的路径（斜杠分隔）；不在根目录下时返回 false
+func (r scanDirectoryRules) relPath(target string) (string, bool) {
+	rel, err := filepath.Rel(r.root, filepath.Clean(target))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	if rel == "." {
+		return "", true
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// allowsDir 判断是否进入目录，depth 为相对根目录的层级（根目录为 0）
+func (r scanDirectoryRules) allowsDir(rel string, depth int) bool {
+	if rel == "" {
+		return true
+	}
+	if r.maxDepth > 0 && depth >= r.maxDepth {
+		return false
+	}
+	return !matchesAnyScanPattern(r.exclude, rel)
+}
+
+// allowsFile 判断文件是否满足深度、包含/排除模式与视频格式
+func (r scanDirectoryRules) allowsFile(rel string) bool {
+	if rel == "" {
+		return false
+	}
+	if r.maxDepth > 0 && strings.Count(rel, "/") >= r.maxDepth {
+		return false
+	}
+	if !hasVideoExtension(rel, r.videoExts) {
+		return false
+	}
+	if matchesAnyScanPattern(r.exclude, rel) {
+		return false
+	}
+	if len(r.include) > 0 && !matchesAnyScanPattern(r.include, rel) {
+		return false
+	}
+	return true
+}
+
+// matchesPath 判断已入库路径是否仍归属该扫描根目录的规则范围
+func (r scanDirectoryRules) matchesPath(target string) bool {
+	rel, ok := r.relPath(target)
+	if !ok || rel == "" {
+		return false
+	}
+	dirRel := path.Dir(rel)
+	if dirRel != "." {
+		segments := strings.Split(dirRel, "/")
+		for depth := 1; depth <= len(segments); depth++ {
+			if !r.allowsDir(strings.Join(segments[:depth], "/"), depth) {
+				return false
+			}
+		}
+	}
+	return r.allowsFile(rel)
+}
+
+func matchesAnyScanPattern(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		if matchScanPattern(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchScanPattern 支持 path.Match 语法与跨层级的 "**"；不含斜杠的模式匹配任意一级名称
+func matchScanPattern(pattern string, rel string) bool {
+	segments := strings.Split(rel, "/")
+	if !strings.Contains(pattern, "/") && pattern != "**" {
+		for _, segment := range segments {
+			if ok, _ := path.Match(pattern, segment); ok {
+				return true
+			}
+		}
+		return false
+	}
+	return matchScanSegments(strings.Split(pattern, "/"), segments)
+}
+
+func matchScanSegments(pattern []string, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			rest := pattern[1:]
+			for skip := 0; skip <= len(segments); skip++ {
+				if matchScanSegments(rest, segments[skip:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+			return false
+		}
+		pattern = pattern[1:]
+		segments = segments[1:]
+	}
+	// 模式匹配到目录前缀时，目录下的所有内容都视为匹配
+	return true
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+	"video-master/database"
+	"video-master/models"
+)
+
+func mustCreateStableFiles(t *testing.T, paths ...string) {
+	t.Helper()
+	oldTime := time.Now().Add(-10 * time.Minute)
+	for _, path := range paths {
+		mustCreateFile(t, path)
+		mustSetFileModTime(t, path, oldTime)
+	}
+}
+
+func scanPathsSorted(t *testing.T, svc *VideoService, root string) []string {
+	t.Helper()
+	files, err := svc.ScanDirectory(root)
+	if err != nil {
+		t.Fatalf("扫描失败: %v", err)
+	}
+	sort.Strings(files)
+	return files
+}
+
+func TestScanDirectoryAppliesIncludeExcludeAndDepthRules(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	svc := &VideoService{}
+	root := t.TempDir()
+
+	topLevel := filepath.Join(root, "top.mp4")
+	camera := filepath.Join(root, "DCIM", "clip.mp4")
+	proxy := filepath.Join(root, "DCIM", "proxy", "clip_proxy.mp4")
+	tooDeep := filepath.Join(root, "DCIM", "2024", "01", "deep.mp4")
+	sample := filepath.Join(root, "DCIM", "sample-clip.mp4")
+	mustCreateStableFiles(t, topLevel, camera, proxy, tooDeep, sample)
+
+	dirService := &DirectoryService{}
+	if _, err := dirService.AddDirectory(root, "相机", models.ScanDirectoryRules{
+		IncludePatterns: "DCIM/**",
+		ExcludePatterns: "proxy\n*sample*",
+		MaxDepth:        3,
+	}); err != nil {
+		t.Fatalf("添加目录失败: %v", err)
+	}
+
+	files := scanPathsSorted(t, svc, root)
+	if len(files) != 1 || files[0] != camera {
+		t.Fatalf("扫描规则未生效: %v", files)
+	}
+}
+
+func TestScanDirectoryUsesExtensionOverrideAndDepthLimit(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	svc := &VideoService{}
+	root := t.TempDir()
+
+	mkvTop := filepath.Join(root, "movie.mkv")
+	mp4Top := filepath.Join(root, "movie.mp4")
+	mkvNested := filepath.Join(root, "sub", "nested.mkv")
+	mustCreateStableFiles(t, mkvTop, mp4Top, mkvNested)
+
+	dirService := &DirectoryService{}
+	dir, err := dirService.AddDirectory(root, "", models.ScanDirectoryRules{})
+	if err != nil {
+		t.Fatalf("添加目录失败: %v", err)
+	}
+	if err := dirService.UpdateDirectory(dir.ID, root, "归档", models.ScanDirectoryRules{
+		MaxDepth:        1,
+		VideoExtensions: "MKV",
+	}); err != nil {
+		t.Fatalf("更新目录失败: %v", err)
+	}
+
+	files := scanPathsSorted(t, svc, root)
+	if len(files) != 1 || files[0] != mkvTop {
+		t.Fatalf("扩展名覆盖或深度限制未生效: %v", files)
+	}
+}
+
+func TestScanDirectoryFollowsSymlinkedDirsOnlyWhenEnabled(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	svc := &VideoService{}
+	root := t.TempDir()
+	external := t.TempDir()
+
+	local := filepath.Join(root, "local.mp4")
+	linked := filepath.Join(external, "linked.mp4")
+	mustCreateStableFiles(t, local, linked)
+	if err := os.Symlink(external, filepath.Join(root, "external")); err != nil {
+		t.Skipf("当前环境不支持符号链接: %v", err)
+	}
+	// 指向文件的符号链接无论是否跟随都应收录
+	alias := filepath.Join(root, "alias.mp4")
+	if err := os.Symlink(linked, alias); err != nil {
+		t.Fatalf("创建文件链接失败: %v", err)
+	}
+	// 指回根目录的链接不应导致无限递归
+	if err := os.Symlink(root, filepath.Join(external, "loop")); err != nil {
+		t.Fatalf("创建循环链接失败: %v", err)
+	}
+
+	dirService := &DirectoryService{}
+	dir, err := dirService.AddDirectory(root, "", models.ScanDirectoryRules{})
+	if err != nil {
+		t.Fatalf("添加目录失败: %v", err)
+	}
+	if files := scanPathsSorted(t, svc, root); len(files) != 2 || files[0] != alias || files[1] != local {
+		t.Fatalf("默认只收录链接文件、不进入链接目录: %v", files)
+	}
+
+	if err := dirService.UpdateDirectory(dir.ID, root, "", models.ScanDirectoryRules{FollowSymlinks: true}); err != nil {
+		t.Fatalf("更新目录失败: %v", err)
+	}
+	files := scanPathsSorted(t, svc, root)
+	want := []string{alias, filepath.Join(root, "external", "linked.mp4"), local}
+	if !reflect.DeepEqual(files, want) {
+		t.Fatalf("跟随符号链接扫描结果错误: got=%v want=%v", files, want)
+	}
+}
+
+func TestSyncScanDirectoriesKeepsVideosOutsideRuleScope(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	svc := &VideoService{}
+	root := t.TempDir()
+
+	kept := filepath.Join(root, "keep.mp4")
+	excluded := filepath.Join(root, "raw", "excluded.mp4")
+	mustCreateStableFiles(t, kept, excluded)
+	excludedVideo, err := svc.AddVideo(excluded)
+	if err != nil {
+		t.Fatalf("添加视频失败: %v", err)
+	}
+
+	dir, err := (&DirectoryService{}).AddDirectory(root, "", models.ScanDirectoryRules{ExcludePatterns: "raw"})
+	if err != nil {
+		t.Fatalf("添加目录失败: %v", err)
+	}
+	// 被排除的文件即使已从磁盘删除，也不归该目录管理，不应被同步删除
+	if err := os.Remove(excluded); err != nil {
+		t.Fatalf("删除文件失败: %v", err)
+	}
+
+	result := svc.SyncScanDirectories([]models.ScanDirectory{*dir})
+	if len(result.Errors) != 0 {
+		t.Fatalf("同步出错: %#v", result.Errors)
+	}
+	if result.Added != 1 || result.Deleted != 0 {
+		t.Fatalf("同步结果错误: %#v", result)
+	}
+	var count int64
+	if err := database.DB.Model(&models.Video{}).Where("id = ?", excludedVideo.ID).Count(&count).Error; err != nil {
+		t.Fatalf("查询视频失败: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("规则范围外的记录不应被删除")
+	}
+}
+
+func TestDirectoryServiceRejectsInvalidScanRules(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	dirService := &DirectoryService{}
+
+	if _, err := dirService.AddDirectory(t.TempDir(), "", models.ScanDirectoryRules{IncludePatterns: "[abc"}); err == nil {
+		t.Fatalf("无效的模式应被拒绝")
+	}
+	if _, err := dirService.AddDirectory(t.TempDir(), "", models.ScanDirectoryRules{MaxDepth: -1}); err == nil {
+		t.Fatalf("负数深度应被拒绝")
+	}
+}
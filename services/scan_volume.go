@@ -0,0 +1,178 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"video-master/database"
+	"video-master/models"
+)
+
+// checkScanDirectoryAvailability 判断扫描根目录当前是否在线：根目录可访问，且设置了卷标识时标识文件存在。
+// 外接盘或 NAS 未挂载时挂载点可能仍是一个空目录，卷标识用于区分这种情况。
+func checkScanDirectoryAvailability(dir models.ScanDirectory) (bool, string) {
+	root := filepath.Clean(strings.TrimSpace(dir.Path))
+	info, err := os.Stat(root)
+	if err != nil {
+		return false, fmt.Sprintf("根目录不可访问: %v", err)
+	}
+	if !info.IsDir() {
+		return false, "根路径不是目录"
+	}
+	if marker := strings.TrimSpace(dir.Rules.VolumeMarker); marker != "" {
+		if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(marker))); err != nil {
+			return false, fmt.Sprintf("未找到卷标识 %s", marker)
+		}
+	}
+	return true, ""
+}
+
+// refreshScanDirectoryStates 检测并持久化扫描目录的在线状态，同步更新其下视频的 is_offline；
+// 返回带最新状态的目录副本。未入库的目录（ID 为 0）只检测不保存目录状态。
+func refreshScanDirectoryStates(dirs []models.ScanDirectory) []models.ScanDirectory {
+	now := time.Now()
+	refreshed := make([]models.ScanDirectory, len(dirs))
+	online := make([]string, 0, len(dirs))
+	offline := make([]string, 0)
+	for idx, dir := range dirs {
+		ok, reason := checkScanDirectoryAvailability(dir)
+		wasOffline := dir.Offline
+		dir.Offline = !ok
+		dir.OfflineReason = reason
+		dir.LastCheckedAt = &now
+		if !ok && (!wasOffline || dir.OfflineSince == nil) {
+			dir.OfflineSince = &now
+		} else if ok {
+			dir.OfflineSince = nil
+		}
+		if dir.ID != 0 {
+			updates := map[string]interface{}{
+				"offline":         dir.Offline,
+				"offline_reason":  dir.OfflineReason,
+				"offline_since":   nil,
+				"last_checked_at": now,
+			}
+			if dir.OfflineSince != nil {
+				updates["offline_since"] = *dir.OfflineSince
+			}
+			if err := database.DB.Model(&models.ScanDirectory{}).Where("id = ?", dir.ID).Updates(updates).Error; err != nil {
+				log.Printf("保存扫描目录在线状态失败 path=%s err=%v", dir.Path, err)
+			}
+		}
+		if wasOffline != dir.Offline {
+			log.Printf("扫描目录在线状态变化 path=%s offline=%v reason=%s", dir.Path, dir.Offline, reason)
+		}
+
+		root := filepath.Clean(strings.TrimSpace(dir.Path))
+		if ok {
+			online = append(online, root)
+		} else {
+			offline = append(offline, root)
+		}
+		refreshed[idx] = dir
+	}
+
+	// 先恢复在线目录，再标记离线目录，嵌套根目录中离线状态优先。
+	// 只检测部分目录时，未参与本次检测但仍记录为离线的更深层根目录下的视频也保持离线
+	var offlineRoots []string
+	if err := database.DB.Model(&models.ScanDirectory{}).Where("offline = ?", true).Pluck("path", &offlineRoots).Error; err != nil {
+		log.Printf("读取离线扫描目录失败 err=%v", err)
+	}
+	for idx := range offlineRoots {
+		offlineRoots[idx] = filepath.Clean(strings.TrimSpace(offlineRoots[idx]))
+	}
+	for _, root := range online {
+		nested := make([]string, 0)
+		for _, offlineRoot := range offlineRoots {
+			if isPathUnderRoot(offlineRoot, root) {
+				nested = append(nested, offlineRoot)
+			}
+		}
+		if err := setVideosOnlineUnderRoot(root, nested); err != nil {
+			log.Printf("更新视频在线状态失败 root=%s err=%v", root, err)
+		}
+	}
+	for _, root := range offline {
+		if err := setVideosOfflineUnderRoot(root, true); err != nil {
+			log.Printf("更新视频离线状态失败 root=%s err=%v", root, err)
+		}
+	}
+	return refreshed
+}
+
+func setVideosOfflineUnderRoot(root string, offline bool) error {
+	if root == "" || root == "." {
+		return nil
+	}
+	return database.DB.Model(&models.Video{}).
+		Where("path LIKE ? ESCAPE '\\'", rootChildPattern(root)).
+		Where("is_offline <> ?", offline).
+		Update("is_offline", offline).Error
+}
+
+// setVideosOnlineUnderRoot 清除根目录下视频的离线标记，跳过仍离线的嵌套根目录下的视频
+func setVideosOnlineUnderRoot(root string, offlineNested []string) error {
+	if root == "" || root == "." {
+		return nil
+	}
+	query := database.DB.Model(&models.Video{}).
+		Where("path LIKE ? ESCAPE '\\'", rootChildPattern(root)).
+		Where("is_offline <> ?", false)
+	for _, nested := range offlineNested {
+		query = query.Where("path NOT LIKE ? ESCAPE '\\'", rootChildPattern(nested))
+	}
+	return query.Update("is_offline", false).Error
+}
+
+func rootChildPattern(root string) string {
+	return escapeSQLLike(root+string(os.PathSeparator)) + "%"
+}
+
+func isPathUnderRoot(path string, root string) bool {
+	return strings.HasPrefix(path, root+string(os.PathSeparator))
+}
+
+// findOfflineRootForVideo 重新检测视频所属扫描根目录的在线状态，所属根目录离线时返回该目录
+func findOfflineRootForVideo(video *models.Video) (*models.ScanDirectory, error) {
+	var dirs []models.ScanDirectory
+	if err := database.DB.Order("path asc").Find(&dirs).Error; err != nil {
+		return nil, err
+	}
+	owners := make([]models.ScanDirectory, 0, 1)
+	for _, dir := range dirs {
+		if isPathUnderRoot(video.Path, filepath.Clean(strings.TrimSpace(dir.Path))) {
+			owners = append(owners, dir)
+		}
+	}
+	if len(owners) == 0 {
+		return nil, nil
+	}
+	for _, dir := range refreshScanDirectoryStates(owners) {
+		if dir.Offline {
+			offlineDir := dir
+			video.IsOffline = true
+			return &offlineDir, nil
+		}
+	}
+	video.IsOffline = false
+	return nil, nil
+}
+
+// markVideoUnavailable 在文件不可访问时区分卷离线与路径失效：所属卷离线只标记离线，否则标记失效。
+// 返回离线的扫描目录（路径失效时为 nil）。
+func markVideoUnavailable(video *models.Video) *models.ScanDirectory {
+	offlineDir, err := findOfflineRootForVideo(video)
+	if err != nil {
+		log.Printf("检测视频所属卷状态失败 id=%d err=%v", video.ID, err)
+	}
+	if offlineDir != nil {
+		return offlineDir
+	}
+	if err := database.DB.Model(&models.Video{}).Where("id = ?", video.ID).Update("is_stale", true).Error; err == nil {
+		video.IsStale = true
+	}
+	return nil
+}
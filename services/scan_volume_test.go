@@ -0,0 +1,129 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"video-master/database"
+	"video-master/models"
+)
+
+func TestSyncScanDirectoriesSkipsOfflineVolumeWithoutDeleting(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	mockFFProbe(t, t.TempDir())
+	svc := &VideoService{}
+	root := t.TempDir()
+	marker := filepath.Join(root, ".volume-id")
+	videoPath := filepath.Join(root, "movie.mp4")
+
+	dir, err := (&DirectoryService{}).AddDirectory(root, "NAS", models.ScanDirectoryRules{VolumeMarker: ".volume-id"})
+	if err != nil {
+		t.Fatalf("添加目录失败: %v", err)
+	}
+	video := models.Video{Name: "movie.mp4", Path: videoPath, Directory: root, Size: 1}
+	if err := database.DB.Create(&video).Error; err != nil {
+		t.Fatalf("创建视频失败: %v", err)
+	}
+
+	// 挂载点仍在但卷标识缺失：视为离线，记录保留且不标记失效
+	result := svc.SyncScanDirectories([]models.ScanDirectory{*dir})
+	if len(result.Offline) != 1 || result.Offline[0] != root || result.Deleted != 0 || len(result.Errors) != 0 {
+		t.Fatalf("离线目录同步结果错误: %#v", result)
+	}
+	offline := previewStatsSnapshot(t, video.ID)
+	if !offline.IsOffline || offline.IsStale {
+		t.Fatalf("离线卷上的视频应标记离线而非失效: %+v", offline)
+	}
+	var savedDir models.ScanDirectory
+	if err := database.DB.First(&savedDir, dir.ID).Error; err != nil {
+		t.Fatalf("读取目录失败: %v", err)
+	}
+	if !savedDir.Offline || savedDir.OfflineSince == nil || savedDir.LastCheckedAt == nil {
+		t.Fatalf("目录离线状态未保存: %+v", savedDir)
+	}
+
+	// 卷重新挂载后恢复在线，并正常同步
+	mustCreateStableFiles(t, marker, videoPath)
+	result = svc.SyncScanDirectories([]models.ScanDirectory{savedDir})
+	if len(result.Offline) != 0 || result.Deleted != 0 || len(result.Errors) != 0 {
+		t.Fatalf("重新挂载后同步结果错误: %#v", result)
+	}
+	online := previewStatsSnapshot(t, video.ID)
+	if online.IsOffline {
+		t.Fatalf("重新挂载后视频应恢复在线: %+v", online)
+	}
+	var reloadedDir models.ScanDirectory
+	if err := database.DB.First(&reloadedDir, dir.ID).Error; err != nil {
+		t.Fatalf("读取目录失败: %v", err)
+	}
+	if reloadedDir.Offline || reloadedDir.OfflineSince != nil {
+		t.Fatalf("目录应恢复在线: %+v", reloadedDir)
+	}
+}
+
+func TestPlayVideoOnOfflineVolumeSkipsReconcile(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	svc := &VideoService{}
+	mount := filepath.Join(t.TempDir(), "nas")
+	videoPath := filepath.Join(mount, "movie.mp4")
+	mustCreateStableFiles(t, videoPath)
+	if _, err := (&DirectoryService{}).AddDirectory(mount, "NAS", models.ScanDirectoryRules{}); err != nil {
+		t.Fatalf("添加目录失败: %v", err)
+	}
+	video, err := svc.AddVideo(videoPath)
+	if err != nil {
+		t.Fatalf("添加视频失败: %v", err)
+	}
+	// 模拟卷被卸载：整个挂载目录消失
+	if err := os.RemoveAll(mount); err != nil {
+		t.Fatalf("移除挂载目录失败: %v", err)
+	}
+
+	result, err := svc.PlayVideo(video.ID)
+	if err != nil {
+		t.Fatalf("期望领域失败走返回值而非 error: %v", err)
+	}
+	if result.DispatchSucceeded || result.ReasonCode != "volume_offline" || result.ReconcileResult != nil {
+		t.Fatalf("离线卷播放结果错误: %#v", result)
+	}
+	after := previewStatsSnapshot(t, video.ID)
+	if after.IsStale || !after.IsOffline {
+		t.Fatalf("离线卷上的视频不应标记失效: %+v", after)
+	}
+}
+
+func TestRefreshOuterRootKeepsNestedOfflineRootOffline(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	outer := t.TempDir()
+	inner := filepath.Join(outer, "nas")
+	if err := os.MkdirAll(inner, 0755); err != nil {
+		t.Fatalf("创建目录失败: %v", err)
+	}
+	dirService := &DirectoryService{}
+	outerDir, err := dirService.AddDirectory(outer, "", models.ScanDirectoryRules{})
+	if err != nil {
+		t.Fatalf("添加目录失败: %v", err)
+	}
+	innerDir, err := dirService.AddDirectory(inner, "NAS", models.ScanDirectoryRules{VolumeMarker: ".volume-id"})
+	if err != nil {
+		t.Fatalf("添加目录失败: %v", err)
+	}
+	outerVideo := models.Video{Name: "a.mp4", Path: filepath.Join(outer, "a.mp4"), Directory: outer, Size: 1, IsOffline: true}
+	innerVideo := models.Video{Name: "b.mp4", Path: filepath.Join(inner, "b.mp4"), Directory: inner, Size: 1}
+	database.DB.Create(&outerVideo)
+	database.DB.Create(&innerVideo)
+
+	refreshScanDirectoryStates([]models.ScanDirectory{*outerDir, *innerDir})
+	if !previewStatsSnapshot(t, innerVideo.ID).IsOffline || previewStatsSnapshot(t, outerVideo.ID).IsOffline {
+		t.Fatalf("嵌套根目录离线时只有其下视频应离线")
+	}
+
+	// 只检测外层目录时不应清除仍离线的内层目录下视频的离线标记
+	refreshScanDirectoryStates([]models.ScanDirectory{*outerDir})
+	if !previewStatsSnapshot(t, innerVideo.ID).IsOffline {
+		t.Fatalf("内层根目录仍离线，其下视频应保持离线")
+	}
+	if previewStatsSnapshot(t, outerVideo.ID).IsOffline {
+		t.Fatalf("外层根目录在线，其下视频应在线")
+	}
+}
@@ -0,0 +1,482 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"video-master/models"
+
+	"github.com/fsnotify/fsnotify"
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+const (
+	defaultScanWatcherDebounce = 3 * time.Second
+	scanWatcherMaxWaitFactor   = 10
+	scanWatcherEventName       = "scan-watcher-progress"
+)
+
+type ScanWatcherConfig struct {
+	// Debounce 目录变化后等待静默的时长，期间的新变化会顺延同步
+	Debounce time.Duration
+	// SettleDelay 正在写入的文件在最后修改后多久复查，默认与扫描的活跃文件阈值一致
+	SettleDelay time.Duration
+}
+
+type ScanWatcherProgress struct {
+	Stage   string          `json:"stage"`
+	Message string          `json:"message"`
+	Roots   []string        `json:"roots"`
+	Result  *ScanSyncResult `json:"result,omitempty"`
+}
+
+type ScanWatcherStatus struct {
+	Running      bool            `json:"running"`
+	WatchedRoots []string        `json:"watched_roots"`
+	WatchedDirs  int             `json:"watched_dirs"`
+	PendingRoots []string        `json:"pending_roots"`
+	Error        string          `json:"error"`
+	LastResult   *ScanSyncResult `json:"last_result,omitempty"`
+	LastSyncAt   *time.Time      `json:"last_sync_at,omitempty" ts_type:"string"`
+}
+
+// ScanWatcherService 监听扫描目录的文件变化，并把新增/删除/重命名交给增量同步处理。
+type ScanWatcherService struct {
+	videoService *VideoService
+	config       ScanWatcherConfig
+	ctx          context.Context
+	mu           sync.Mutex
+	syncMu       sync.Mutex
+	watcher      *fsnotify.Watcher
+	cancel       context.CancelFunc
+	generation   int
+	roots        map[string]models.ScanDirectory
+	watchedDirs  map[string]struct{}
+	rules        map[string]scanDirectoryRules
+	pending      map[string]struct{}
+	pendingSince time.Time
+	// removalPending 防抖窗口内有文件被删除或移出；此时同步全部根目录，避免跨根目录移动被当作删除
+	removalPending bool
+	timer          *time.Timer
+	settleTimers   map[string]*time.Timer
+	settleAt       map[string]time.Time
+	status         ScanWatcherStatus
+}
+
+func NewScanWatcherService(videoService *VideoService, config ScanWatcherConfig) *ScanWatcherService {
+	if config.Debounce <= 0 {
+		config.Debounce = defaultScanWatcherDebounce
+	}
+	if config.SettleDelay <= 0 {
+		config.SettleDelay = recentActiveFileThreshold
+	}
+	return &ScanWatcherService{
+		videoService: videoService,
+		config:       config,
+	}
+}
+
+func (s *ScanWatcherService) SetContext(ctx context.Context) {
+	s.mu.Lock()
+	s.ctx = ctx
+	s.mu.Unlock()
+}
+
+// Start 开始监听给定扫描目录；已在运行时等同于 Reload。
+func (s *ScanWatcherService) Start(dirs []models.ScanDirectory) error {
+	if s == nil {
+		return nil
+	}
+	s.Stop()
+
+	rulesList, err := loadScanDirectoryRulesList(dirs)
+	if err != nil {
+		s.recordError(err)
+		return err
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		err = fmt.Errorf("创建目录监听失败: %w", err)
+		s.recordError(err)
+		return err
+	}
+
+	roots := make(map[string]models.ScanDirectory, len(dirs))
+	for _, dir := range dirs {
+		root := filepath.Clean(strings.TrimSpace(dir.Path))
+		if root == "" || root == "." {
+			continue
+		}
+		roots[root] = dir
+	}
+	rules := make(map[string]scanDirectoryRules, len(rulesList))
+	for _, rule := range rulesList {
+		rules[rule.root] = rule
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.mu.Lock()
+	s.generation++
+	s.watcher = watcher
+	s.cancel = cancel
+	s.roots = roots
+	s.watchedDirs = make(map[string]struct{})
+	s.rules = rules
+	s.pending = make(map[string]struct{})
+	s.removalPending = false
+	s.settleTimers = make(map[string]*time.Timer)
+	s.settleAt = make(map[string]time.Time)
+	s.status.Running = true
+	s.status.Error = ""
+	s.mu.Unlock()
+
+	var errs []string
+	for root := range roots {
+		if err := s.watchTree(watcher, root); err != nil {
+			log.Printf("[ScanWatcher] watch root failed root=%s err=%v", root, err)
+			errs = append(errs, fmt.Sprintf("%s: %v", root, err))
+		}
+	}
+	if len(errs) > 0 {
+		s.recordError(fmt.Errorf("部分目录无法监听: %s", strings.Join(errs, "; ")))
+	}
+
+	go s.loop(ctx, watcher)
+
+	status := s.Status()
+	log.Printf("[ScanWatcher] started roots=%d dirs=%d", len(status.WatchedRoots), status.WatchedDirs)
+	s.emit(ScanWatcherProgress{
+		Stage:   "watching",
+		Message: fmt.Sprintf("正在监听 %d 个扫描目录", len(status.WatchedRoots)),
+		Roots:   status.WatchedRoots,
+	})
+	return nil
+}
+
+// Reload 在扫描目录配置变化后重建监听。
+func (s *ScanWatcherService) Reload(dirs []models.ScanDirectory) error {
+	return s.Start(dirs)
+}
+
+func (s *ScanWatcherService) Stop() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	watcher := s.watcher
+	cancel := s.cancel
+	s.watcher = nil
+	s.cancel = nil
+	s.generation++
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	for root, timer := range s.settleTimers {
+		timer.Stop()
+		delete(s.settleTimers, root)
+	}
+	s.pending = make(map[string]struct{})
+	s.removalPending = false
+	s.status.Running = false
+	s.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+	if watcher != nil {
+		_ = watcher.Close()
+	}
+}
+
+func (s *ScanWatcherService) Status() *ScanWatcherStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := s.status
+	status.WatchedRoots = sortedKeys(s.roots)
+	status.PendingRoots = sortedKeys(s.pending)
+	status.WatchedDirs = len(s.watchedDirs)
+	if status.LastResult != nil {
+		resultCopy := *status.LastResult
+		status.LastResult = &resultCopy
+	}
+	return &status
+}
+
+func (s *ScanWatcherService) loop(ctx context.Context, watcher *fsnotify.Watcher) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			s.handleEvent(watcher, event)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("[ScanWatcher] watcher error: %v", err)
+			s.recordError(err)
+		}
+	}
+}
+
+func (s *ScanWatcherService) handleEvent(watcher *fsnotify.Watcher, event fsnotify.Event) {
+	path := filepath.Clean(event.Name)
+	root := s.rootForPath(path)
+	if root == "" || isTrashPath(path) || hasTempVideoSuffix(path) {
+		return
+	}
+	if name := filepath.Base(path); name != "." && strings.HasPrefix(name, ".") {
+		return
+	}
+
+	if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+		if s.forgetWatchedDir(path) || s.isVideoPath(root, path) {
+			s.mu.Lock()
+			s.removalPending = true
+			s.mu.Unlock()
+			s.markDirty(root)
+		}
+		return
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	if info.IsDir() {
+		if event.Has(fsnotify.Create) {
+			// 整个目录被移入时其中可能已有视频，补充监听后触发一次同步
+			if err := s.watchTree(watcher, path); err != nil {
+				log.Printf("[ScanWatcher] watch new dir failed path=%s err=%v", path, err)
+			}
+			s.markDirty(root)
+		}
+		return
+	}
+	if !s.isVideoPath(root, path) || isKnownNonVideoSourcePath(path) {
+		return
+	}
+	if isRecentlyActiveFile(info) {
+		// 扫描会跳过仍在写入的文件，等文件稳定后再复查
+		s.scheduleSettle(root, info.ModTime().Add(s.config.SettleDelay))
+		return
+	}
+	s.markDirty(root)
+}
+
+func (s *ScanWatcherService) watchTree(watcher *fsnotify.Watcher, dir string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if path == dir {
+				return err
+			}
+			return nil
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if path != dir && (shouldSkipHiddenPath(info) || isTrashDirName(info.Name())) {
+			return filepath.SkipDir
+		}
+		if err := watcher.Add(path); err != nil {
+			if path == dir {
+				return err
+			}
+			log.Printf("[ScanWatcher] add watch failed path=%s err=%v", path, err)
+			return nil
+		}
+		s.mu.Lock()
+		if s.watchedDirs != nil {
+			s.watchedDirs[path] = struct{}{}
+		}
+		s.mu.Unlock()
+		return nil
+	})
+}
+
+func (s *ScanWatcherService) forgetWatchedDir(path string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.watchedDirs[path]; !ok {
+		return false
+	}
+	prefix := path + string(os.PathSeparator)
+	for dir := range s.watchedDirs {
+		if dir == path || strings.HasPrefix(dir, prefix) {
+			delete(s.watchedDirs, dir)
+		}
+	}
+	return true
+}
+
+func (s *ScanWatcherService) rootForPath(path string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	matched := ""
+	for root := range s.roots {
+		if path == root || strings.HasPrefix(path, root+string(os.PathSeparator)) {
+			if len(root) > len(matched) {
+				matched = root
+			}
+		}
+	}
+	return matched
+}
+
+// isVideoPath 按所属根目录的扫描规则判断路径是否可能影响同步结果
+func (s *ScanWatcherService) isVideoPath(root string, path string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rules, ok := s.rules[root]
+	return ok && rules.matchesPath(path)
+}
+
+func (s *ScanWatcherService) scheduleSettle(root string, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.settleTimers == nil {
+		return
+	}
+	if existing, ok := s.settleAt[root]; ok && !at.After(existing) {
+		return
+	}
+	s.settleAt[root] = at
+	if timer, ok := s.settleTimers[root]; ok {
+		timer.Stop()
+	}
+	generation := s.generation
+	s.settleTimers[root] = time.AfterFunc(time.Until(at), func() {
+		s.mu.Lock()
+		if generation != s.generation {
+			s.mu.Unlock()
+			return
+		}
+		delete(s.settleTimers, root)
+		delete(s.settleAt, root)
+		s.mu.Unlock()
+		s.markDirty(root)
+	})
+}
+
+func (s *ScanWatcherService) markDirty(root string) {
+	s.mu.Lock()
+	if s.pending == nil || s.watcher == nil {
+		s.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	if len(s.pending) == 0 {
+		s.pendingSince = now
+	}
+	s.pending[root] = struct{}{}
+	generation := s.generation
+	if s.timer == nil {
+		s.timer = time.AfterFunc(s.config.Debounce, func() { s.flush(generation) })
+	} else if now.Sub(s.pendingSince) < s.config.Debounce*scanWatcherMaxWaitFactor {
+		// 持续变化时顺延，但不超过最大等待，避免长时间拷贝期间一直不同步
+		s.timer.Reset(s.config.Debounce)
+	}
+	roots := sortedKeys(s.pending)
+	s.mu.Unlock()
+
+	s.emit(ScanWatcherProgress{
+		Stage:   "changed",
+		Message: "检测到目录变化，等待变化结束后同步…",
+		Roots:   roots,
+	})
+}
+
+func (s *ScanWatcherService) flush(generation int) {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	s.mu.Lock()
+	if generation != s.generation {
+		s.mu.Unlock()
+		return
+	}
+	s.timer = nil
+	// 跨根目录移动的两端可能落在不同的防抖窗口，只同步移出的一端会把视频当作删除；
+	// 有删除时同步全部根目录，并在仍有文件写入（可能是跨卷移动的目标端）时顺延，但不超过最大等待
+	if s.removalPending {
+		if len(s.settleTimers) > 0 && time.Since(s.pendingSince) < s.config.Debounce*scanWatcherMaxWaitFactor {
+			s.timer = time.AfterFunc(s.config.Debounce, func() { s.flush(generation) })
+			s.mu.Unlock()
+			return
+		}
+		for root := range s.roots {
+			s.pending[root] = struct{}{}
+		}
+		s.removalPending = false
+	}
+	roots := sortedKeys(s.pending)
+	dirs := make([]models.ScanDirectory, 0, len(roots))
+	for _, root := range roots {
+		dirs = append(dirs, s.roots[root])
+	}
+	s.pending = make(map[string]struct{})
+	s.mu.Unlock()
+
+	if len(dirs) == 0 {
+		return
+	}
+
+	s.emit(ScanWatcherProgress{
+		Stage:   "syncing",
+		Message: fmt.Sprintf("正在同步 %d 个变化的扫描目录…", len(dirs)),
+		Roots:   roots,
+	})
+	result := s.videoService.SyncScanDirectories(dirs)
+	log.Printf("[ScanWatcher] synced roots=%v added=%d relocated=%d deleted=%d errors=%d",
+		roots, result.Added, result.Relocated, result.Deleted, len(result.Errors))
+
+	now := time.Now()
+	s.mu.Lock()
+	s.status.LastResult = result
+	s.status.LastSyncAt = &now
+	s.mu.Unlock()
+
+	s.emit(ScanWatcherProgress{
+		Stage: "synced",
+		Message: fmt.Sprintf("目录变化已同步：新增 %d，迁移 %d，删除 %d。",
+			result.Added, result.Relocated, result.Deleted),
+		Roots:  roots,
+		Result: result,
+	})
+}
+
+func (s *ScanWatcherService) recordError(err error) {
+	s.mu.Lock()
+	s.status.Error = err.Error()
+	s.mu.Unlock()
+	s.emit(ScanWatcherProgress{Stage: "error", Message: err.Error()})
+}
+
+func (s *ScanWatcherService) emit(progress ScanWatcherProgress) {
+	s.mu.Lock()
+	ctx := s.ctx
+	s.mu.Unlock()
+	if ctx == nil {
+		return
+	}
+	wailsRuntime.EventsEmit(ctx, scanWatcherEventName, progress)
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
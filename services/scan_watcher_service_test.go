@@ -0,0 +1,141 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"video-master/database"
+	"video-master/models"
+)
+
+func waitForCondition(t *testing.T, timeout time.Duration, message string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if condition() {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("等待超时: %s", message)
+}
+
+func countVideosByPath(t *testing.T, path string) int64 {
+	t.Helper()
+	var count int64
+	if err := database.DB.Model(&models.Video{}).Where("path = ?", path).Count(&count).Error; err != nil {
+		t.Fatalf("统计视频失败: %v", err)
+	}
+	return count
+}
+
+func TestScanWatcherAddsNewFilesAndDeletesRemovedFiles(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	root := t.TempDir()
+	watcher := NewScanWatcherService(&VideoService{}, ScanWatcherConfig{Debounce: 50 * time.Millisecond})
+	if err := watcher.Start([]models.ScanDirectory{{Path: root}}); err != nil {
+		t.Fatalf("启动监听失败: %v", err)
+	}
+	defer watcher.Stop()
+
+	videoPath := filepath.Join(root, "incoming", "new.mp4")
+	mustCreateFile(t, videoPath)
+	mustSetFileModTime(t, videoPath, time.Now().Add(-10*time.Minute))
+	waitForCondition(t, 5*time.Second, "新文件应被自动入库", func() bool {
+		return countVideosByPath(t, videoPath) == 1
+	})
+
+	if err := os.Remove(videoPath); err != nil {
+		t.Fatalf("删除文件失败: %v", err)
+	}
+	waitForCondition(t, 5*time.Second, "删除的文件应被同步移除", func() bool {
+		return countVideosByPath(t, videoPath) == 0
+	})
+
+	status := watcher.Status()
+	if !status.Running || status.LastResult == nil || status.WatchedDirs < 2 {
+		t.Fatalf("监听状态错误: %#v", status)
+	}
+}
+
+func TestScanWatcherIgnoresTempFilesAndDefersActiveFiles(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	root := t.TempDir()
+	watcher := NewScanWatcherService(&VideoService{}, ScanWatcherConfig{
+		Debounce:    50 * time.Millisecond,
+		SettleDelay: 300 * time.Millisecond,
+	})
+	if err := watcher.Start([]models.ScanDirectory{{Path: root}}); err != nil {
+		t.Fatalf("启动监听失败: %v", err)
+	}
+	defer watcher.Stop()
+
+	tempPath := filepath.Join(root, "downloading.temp.mp4")
+	mustCreateFile(t, tempPath)
+	activePath := filepath.Join(root, "active.mp4")
+	mustCreateFile(t, activePath)
+
+	time.Sleep(200 * time.Millisecond)
+	if status := watcher.Status(); len(status.PendingRoots) != 0 || status.LastResult != nil {
+		t.Fatalf("临时文件和正在写入的文件不应立即触发同步: %#v", status)
+	}
+
+	// 模拟写入结束：文件修改时间早于活跃阈值后，复查应将其入库
+	mustSetFileModTime(t, activePath, time.Now().Add(-10*time.Minute))
+	waitForCondition(t, 5*time.Second, "稳定后的文件应被入库", func() bool {
+		return countVideosByPath(t, activePath) == 1
+	})
+	if countVideosByPath(t, tempPath) != 0 {
+		t.Fatalf("临时后缀文件不应入库")
+	}
+}
+
+func TestScanWatcherRelocatesMoveAcrossRootsInSeparateWindows(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	rootA := t.TempDir()
+	rootB := t.TempDir()
+	oldPath := filepath.Join(rootA, "clip.mp4")
+	mustCreateStableFiles(t, oldPath)
+	svc := &VideoService{}
+	dirs := []models.ScanDirectory{{Path: rootA}, {Path: rootB}}
+	if result := svc.SyncScanDirectories(dirs); result.Added != 1 {
+		t.Fatalf("初始同步结果错误: %#v", result)
+	}
+	var original models.Video
+	if err := database.DB.Where("path = ?", oldPath).First(&original).Error; err != nil {
+		t.Fatalf("查询视频失败: %v", err)
+	}
+
+	// 防抖足够长，由测试手动触发 flush，模拟移动两端落在不同窗口
+	watcher := NewScanWatcherService(svc, ScanWatcherConfig{Debounce: time.Hour})
+	if err := watcher.Start(dirs); err != nil {
+		t.Fatalf("启动监听失败: %v", err)
+	}
+	defer watcher.Stop()
+
+	newPath := filepath.Join(rootB, "clip.mp4")
+	if err := os.Rename(oldPath, newPath); err != nil {
+		t.Fatalf("移动文件失败: %v", err)
+	}
+	waitForCondition(t, 5*time.Second, "移出事件应被记录", func() bool {
+		watcher.mu.Lock()
+		defer watcher.mu.Unlock()
+		_, ok := watcher.pending[rootA]
+		return ok && watcher.removalPending
+	})
+
+	watcher.mu.Lock()
+	watcher.pending = map[string]struct{}{rootA: {}}
+	generation := watcher.generation
+	watcher.mu.Unlock()
+	watcher.flush(generation)
+
+	var moved models.Video
+	if err := database.DB.Where("path = ?", newPath).First(&moved).Error; err != nil {
+		t.Fatalf("跨根目录移动应迁移记录: %v", err)
+	}
+	if moved.ID != original.ID || countVideosByPath(t, oldPath) != 0 {
+		t.Fatalf("跨根目录移动应保留原记录: original=%d moved=%d", original.ID, moved.ID)
+	}
+}
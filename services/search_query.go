@@ -0,0 +1,395 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// 搜索框查询语言：空白分隔的条件取 AND，前缀 - 取反。
+//
+//	tag:舞蹈 tag:运动/* -tag:4K duration>120 height>=1080 dir:"Downloads" played:never ext:mkv "exact phrase"
+//
+// 普通词与引号短语匹配文件名或路径；字段值含空格时用引号包裹。
+
+// SearchQuery 解析后的查询，Nodes 之间为 AND 关系
+type SearchQuery struct {
+	Nodes []SearchNode
+}
+
+// SearchNode 查询条件节点
+type SearchNode interface {
+	Position() int
+}
+
+// TextNode 普通词或引号短语，匹配文件名或路径
+type TextNode struct {
+	Pos    int
+	Text   string
+	Phrase bool
+}
+
+// TagNode tag:名称，按标签名或别名精确匹配（不区分大小写）；tag:名称/* 同时匹配其子孙标签
+type TagNode struct {
+	Pos         int
+	Name        string
+	Descendants bool
+}
+
+// DirNode dir:目录，绝对路径匹配该目录及子目录，否则匹配目录路径中的片段
+type DirNode struct {
+	Pos  int
+	Path string
+}
+
+// ExtNode ext:扩展名
+type ExtNode struct {
+	Pos int
+	Ext string
+}
+
+// CodecNode codec:视频编码
+type CodecNode struct {
+	Pos   int
+	Codec string
+}
+
+// PlayedNode played:never / played:yes
+type PlayedNode struct {
+	Pos    int
+	Played bool
+}
+
+// CompareNode 数值比较，如 duration>120、size<1GB
+type CompareNode struct {
+	Pos   int
+	Field string
+	Op    string
+	Value float64
+}
+
+// NotNode -条件
+type NotNode struct {
+	Pos  int
+	Node SearchNode
+}
+
+func (n TextNode) Position() int    { return n.Pos }
+func (n TagNode) Position() int     { return n.Pos }
+func (n DirNode) Position() int     { return n.Pos }
+func (n ExtNode) Position() int     { return n.Pos }
+func (n CodecNode) Position() int   { return n.Pos }
+func (n PlayedNode) Position() int  { return n.Pos }
+func (n CompareNode) Position() int { return n.Pos }
+func (n NotNode) Position() int     { return n.Pos }
+
+// SearchQueryError 查询语法错误，Position 为从 0 开始的字符（rune）下标
+type SearchQueryError struct {
+	Position int    `json:"position"`
+	Message  string `json:"message"`
+}
+
+func (e *SearchQueryError) Error() string {
+	return fmt.Sprintf("搜索语法错误（第 %d 个字符）: %s", e.Position+1, e.Message)
+}
+
+// SearchQueryParseResult 供前端在搜索前校验语法
+type SearchQueryParseResult struct {
+	Valid bool              `json:"valid"`
+	Error *SearchQueryError `json:"error"`
+}
+
+// searchCompareColumns 可比较字段与对应列
+var searchCompareColumns = map[string]string{
+	"duration": "videos.duration",
+	"size":     "videos.size",
+	"height":   "videos.height",
+	"width":    "videos.width",
+	"plays":    "videos.play_count",
+	"fps":      "videos.frame_rate",
+}
+
+var searchMatchFields = map[string]bool{"tag": true, "dir": true, "ext": true, "codec": true, "played": true}
+
+func isSearchField(field string) bool {
+	return searchCompareColumns[field] != "" || searchMatchFields[field]
+}
+
+// ParseSearchQuery 把查询文本解析为 AST；语法错误返回 *SearchQueryError
+func ParseSearchQuery(input string) (*SearchQuery, error) {
+	p := searchQueryParser{src: []rune(input)}
+	query := &SearchQuery{}
+	for {
+		p.skipSpaces()
+		if p.eof() {
+			return query, nil
+		}
+		node, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		query.Nodes = append(query.Nodes, node)
+	}
+}
+
+// CheckSearchQuery 校验查询语法，空查询视为有效
+func CheckSearchQuery(input string) SearchQueryParseResult {
+	if _, err := ParseSearchQuery(input); err != nil {
+		if qerr, ok := err.(*SearchQueryError); ok {
+			return SearchQueryParseResult{Error: qerr}
+		}
+		return SearchQueryParseResult{Error: &SearchQueryError{Message: err.Error()}}
+	}
+	return SearchQueryParseResult{Valid: true}
+}
+
+type searchQueryParser struct {
+	src []rune
+	pos int
+}
+
+func (p *searchQueryParser) eof() bool { return p.pos >= len(p.src) }
+
+func (p *searchQueryParser) peek() rune { return p.src[p.pos] }
+
+func (p *searchQueryParser) skipSpaces() {
+	for !p.eof() && unicode.IsSpace(p.peek()) {
+		p.pos++
+	}
+}
+
+func (p *searchQueryParser) errorAt(pos int, format string, args ...interface{}) error {
+	return &SearchQueryError{Position: pos, Message: fmt.Sprintf(format, args...)}
+}
+
+func (p *searchQueryParser) parseTerm() (SearchNode, error) {
+	start := p.pos
+	if p.peek() == '-' && p.pos+1 < len(p.src) && !unicode.IsSpace(p.src[p.pos+1]) {
+		p.pos++
+		if p.peek() == '-' {
+			return nil, p.errorAt(p.pos, "重复的取反符号")
+		}
+		node, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		return NotNode{Pos: start, Node: node}, nil
+	}
+	if p.peek() == '"' {
+		text, err := p.readQuoted()
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(text) == "" {
+			return nil, p.errorAt(start, "引号内容为空")
+		}
+		return TextNode{Pos: start, Text: text, Phrase: true}, nil
+	}
+
+	// 只有已知字段才按字段条件解析；未知字段（如 Re:Zero）与冒号后紧跟空白（如 Mission: Impossible）按普通词处理
+	field := strings.ToLower(p.readFieldName())
+	if isSearchField(field) && !p.eof() {
+		if op := p.readOperator(); op != "" && !(op == ":" && !p.eof() && unicode.IsSpace(p.peek())) {
+			return p.parseFieldTerm(start, field, op)
+		}
+	}
+	p.pos = start
+	return TextNode{Pos: start, Text: p.readWord()}, nil
+}
+
+func (p *searchQueryParser) parseFieldTerm(start int, field, op string) (SearchNode, error) {
+	valuePos := p.pos
+	var value string
+	if !p.eof() && p.peek() == '"' {
+		quoted, err := p.readQuoted()
+		if err != nil {
+			return nil, err
+		}
+		value = quoted
+	} else {
+		value = p.readWord()
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, p.errorAt(valuePos, "%s 缺少取值", field)
+	}
+
+	if column := searchCompareColumns[field]; column != "" {
+		if op == ":" {
+			op = "="
+		}
+		number, err := parseSearchNumber(field, value)
+		if err != nil {
+			return nil, p.errorAt(valuePos, "%s", err.Error())
+		}
+		return CompareNode{Pos: start, Field: field, Op: op, Value: number}, nil
+	}
+	if op != ":" {
+		return nil, p.errorAt(start+len([]rune(field)), "%s 只支持 : 匹配", field)
+	}
+	switch field {
+	case "tag":
+		if name, ok := strings.CutSuffix(value, "/*"); ok && strings.TrimSpace(name) != "" {
+			return TagNode{Pos: start, Name: name, Descendants: true}, nil
+		}
+		return TagNode{Pos: start, Name: value}, nil
+	case "dir":
+		return DirNode{Pos: start, Path: value}, nil
+	case "ext":
+		return ExtNode{Pos: start, Ext: strings.ToLower(strings.TrimPrefix(value, "."))}, nil
+	case "codec":
+		return CodecNode{Pos: start, Codec: strings.ToLower(value)}, nil
+	default: // played
+		switch strings.ToLower(value) {
+		case "never", "no", "false":
+			return PlayedNode{Pos: start}, nil
+		case "yes", "ever", "true":
+			return PlayedNode{Pos: start, Played: true}, nil
+		}
+		return nil, p.errorAt(valuePos, "played 只支持 never 或 yes")
+	}
+}
+
+// readFieldName 读取字段名（仅 ASCII 字母），不移动到字段名之外
+func (p *searchQueryParser) readFieldName() string {
+	start := p.pos
+	for !p.eof() && p.peek() < unicode.MaxASCII && unicode.IsLetter(p.peek()) {
+		p.pos++
+	}
+	return string(p.src[start:p.pos])
+}
+
+func (p *searchQueryParser) readOperator() string {
+	for _, op := range []string{">=", "<=", ">", "<", "=", ":"} {
+		ops := []rune(op)
+		if p.pos+len(ops) <= len(p.src) && string(p.src[p.pos:p.pos+len(ops)]) == op {
+			p.pos += len(ops)
+			return op
+		}
+	}
+	return ""
+}
+
+func (p *searchQueryParser) readWord() string {
+	start := p.pos
+	for !p.eof() && !unicode.IsSpace(p.peek()) {
+		p.pos++
+	}
+	return string(p.src[start:p.pos])
+}
+
+func (p *searchQueryParser) readQuoted() (string, error) {
+	open := p.pos
+	p.pos++
+	start := p.pos
+	for !p.eof() && p.peek() != '"' {
+		p.pos++
+	}
+	if p.eof() {
+		return "", p.errorAt(open, "引号未闭合")
+	}
+	text := string(p.src[start:p.pos])
+	p.pos++
+	return text, nil
+}
+
+// parseSearchNumber 解析比较值：duration 支持 90、90s、2m、1h30m；size 支持 B/KB/MB/GB/TB（1024 进制）
+func parseSearchNumber(field, value string) (float64, error) {
+	lower := strings.ToLower(value)
+	switch field {
+	case "duration":
+		if n, err := strconv.ParseFloat(lower, 64); err == nil {
+			return n, nil
+		}
+		d, err := time.ParseDuration(lower)
+		if err != nil {
+			return 0, fmt.Errorf("无效的时长 %q，可写作 90、90s、2m 或 1h30m", value)
+		}
+		return d.Seconds(), nil
+	case "size":
+		units := []struct {
+			suffix string
+			scale  float64
+		}{{"tb", 1 << 40}, {"gb", 1 << 30}, {"mb", 1 << 20}, {"kb", 1 << 10}, {"t", 1 << 40}, {"g", 1 << 30}, {"m", 1 << 20}, {"k", 1 << 10}, {"b", 1}}
+		scale := 1.0
+		for _, unit := range units {
+			if strings.HasSuffix(lower, unit.suffix) {
+				lower, scale = strings.TrimSuffix(lower, unit.suffix), unit.scale
+				break
+			}
+		}
+		n, err := strconv.ParseFloat(strings.TrimSpace(lower), 64)
+		if err != nil {
+			return 0, fmt.Errorf("无效的体积 %q，可写作 500MB 或 1.5GB", value)
+		}
+		return math.Round(n * scale), nil
+	default:
+		n, err := strconv.ParseFloat(lower, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%s 需要数字，got %q", field, value)
+		}
+		return n, nil
+	}
+}
+
+// applySearchQuery 解析查询文本并把条件追加到 videos 查询；只使用 WHERE，可与游标分页组合
+func applySearchQuery(query *gorm.DB, input string) (*gorm.DB, error) {
+	parsed, err := ParseSearchQuery(input)
+	if err != nil {
+		return nil, err
+	}
+	for _, node := range parsed.Nodes {
+		sql, args := compileSearchNode(node)
+		query = query.Where(sql, args...)
+	}
+	return query, nil
+}
+
+// compileSearchNode 把节点编译为 SQL 片段与参数
+func compileSearchNode(node SearchNode) (string, []interface{}) {
+	switch n := node.(type) {
+	case NotNode:
+		sql, args := compileSearchNode(n.Node)
+		return "NOT (" + sql + ")", args
+	case TextNode:
+		kw := "%" + escapeSQLLike(n.Text) + "%"
+		return "(videos.name LIKE ? ESCAPE '\\' OR videos.path LIKE ? ESCAPE '\\')", []interface{}{kw, kw}
+	case TagNode:
+		if n.Descendants {
+			// UNION 去重，父子关系即使成环也会终止
+			return "EXISTS (WITH RECURSIVE tag_tree(id) AS (" +
+				"SELECT id FROM tags WHERE deleted_at IS NULL AND (LOWER(name) = LOWER(?) OR id IN (SELECT tag_id FROM tag_aliases WHERE LOWER(tag_aliases.name) = LOWER(?))) " +
+				"UNION SELECT tags.id FROM tags JOIN tag_tree ON tags.parent_id = tag_tree.id WHERE tags.deleted_at IS NULL) " +
+				"SELECT 1 FROM video_tags WHERE video_tags.video_id = videos.id AND video_tags.tag_id IN (SELECT id FROM tag_tree))", []interface{}{n.Name, n.Name}
+		}
+		return "EXISTS (SELECT 1 FROM video_tags JOIN tags ON tags.id = video_tags.tag_id " +
+			"WHERE video_tags.video_id = videos.id AND tags.deleted_at IS NULL AND (LOWER(tags.name) = LOWER(?) " +
+			"OR tags.id IN (SELECT tag_id FROM tag_aliases WHERE LOWER(tag_aliases.name) = LOWER(?))))", []interface{}{n.Name, n.Name}
+	case DirNode:
+		if filepath.IsAbs(n.Path) {
+			cleanDir := filepath.Clean(n.Path)
+			childPrefix := escapeSQLLike(cleanDir+string(os.PathSeparator)) + "%"
+			return "(videos.directory = ? OR videos.directory LIKE ? ESCAPE '\\')", []interface{}{cleanDir, childPrefix}
+		}
+		return "videos.directory LIKE ? ESCAPE '\\'", []interface{}{"%" + escapeSQLLike(n.Path) + "%"}
+	case ExtNode:
+		return "LOWER(videos.path) LIKE ? ESCAPE '\\'", []interface{}{"%." + escapeSQLLike(n.Ext)}
+	case CodecNode:
+		return "LOWER(videos.video_codec) = ?", []interface{}{n.Codec}
+	case PlayedNode:
+		if n.Played {
+			return "(videos.play_count > 0 OR videos.random_play_count > 0)", nil
+		}
+		return "(videos.play_count = 0 AND videos.random_play_count = 0)", nil
+	case CompareNode:
+		return fmt.Sprintf("%s %s ?", searchCompareColumns[n.Field], n.Op), []interface{}{n.Value}
+	}
+	return "1 = 1", nil
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"video-master/database"
+	"video-master/models"
+)
+
+func TestParseSearchQueryBuildsTypedNodes(t *testing.T) {
+	query, err := ParseSearchQuery(`tag:舞蹈 -tag:4K duration>2m height>=1080 dir:"My Downloads" played:never ext:.MKV "exact phrase" size<1.5GB cat`)
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	want := []SearchNode{
+		TagNode{Pos: 0, Name: "舞蹈"},
+		NotNode{Pos: 7, Node: TagNode{Pos: 8, Name: "4K"}},
+		CompareNode{Pos: 15, Field: "duration", Op: ">", Value: 120},
+		CompareNode{Pos: 27, Field: "height", Op: ">=", Value: 1080},
+		DirNode{Pos: 40, Path: "My Downloads"},
+		PlayedNode{Pos: 59},
+		ExtNode{Pos: 72, Ext: "mkv"},
+		TextNode{Pos: 81, Text: "exact phrase", Phrase: true},
+		CompareNode{Pos: 96, Field: "size", Op: "<", Value: 1.5 * (1 << 30)},
+		TextNode{Pos: 107, Text: "cat"},
+	}
+	if !reflect.DeepEqual(query.Nodes, want) {
+		t.Fatalf("AST 不符合预期\n got=%+v\nwant=%+v", query.Nodes, want)
+	}
+}
+
+func TestParseSearchQueryTreatsUnknownFieldsAsText(t *testing.T) {
+	query, err := ParseSearchQuery(`Re:Zero Mission: Impossible tag: 舞蹈 tga:舞蹈`)
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	want := []SearchNode{
+		TextNode{Pos: 0, Text: "Re:Zero"},
+		TextNode{Pos: 8, Text: "Mission:"},
+		TextNode{Pos: 17, Text: "Impossible"},
+		TextNode{Pos: 28, Text: "tag:"},
+		TextNode{Pos: 33, Text: "舞蹈"},
+		TextNode{Pos: 36, Text: "tga:舞蹈"},
+	}
+	if !reflect.DeepEqual(query.Nodes, want) {
+		t.Fatalf("未知字段与冒号后空白应按普通词处理\n got=%+v\nwant=%+v", query.Nodes, want)
+	}
+}
+
+func TestParseSearchQueryReportsErrorPosition(t *testing.T) {
+	cases := []struct {
+		input    string
+		position int
+	}{
+		{`cat "unclosed`, 4},
+		{`height>=abc`, 8},
+		{`猫 played:sometimes`, 9},
+		{`tag>3`, 3},
+		{`tag:`, 4},
+	}
+	for _, tc := range cases {
+		_, err := ParseSearchQuery(tc.input)
+		qerr, ok := err.(*SearchQueryError)
+		if !ok || qerr.Position != tc.position {
+			t.Fatalf("%q 应在位置 %d 报错，got=%v", tc.input, tc.position, err)
+		}
+	}
+	if result := CheckSearchQuery(`"x`); result.Valid || result.Error == nil || result.Error.Position != 0 {
+		t.Fatalf("校验结果应带错误位置，got=%+v", result)
+	}
+	if result := CheckSearchQuery("  "); !result.Valid {
+		t.Fatalf("空查询应有效，got=%+v", result)
+	}
+}
+
+func TestSearchVideosWithQueryLanguageAndCursor(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	root := t.TempDir()
+	downloads := filepath.Join(root, "Downloads")
+	if err := os.MkdirAll(downloads, 0755); err != nil {
+		t.Fatalf("创建目录失败: %v", err)
+	}
+	dance := createShortFeedTag(t, "舞蹈")
+	uhd := createShortFeedTag(t, "4K")
+	a := createShortFeedVideo(t, downloads, "a.mkv", 300, false, &dance)
+	b := createShortFeedVideo(t, downloads, "b.mkv", 200, false, &dance)
+	createShortFeedVideo(t, downloads, "c.mkv", 300, false, &dance, &uhd)
+	createShortFeedVideo(t, downloads, "d.mp4", 300, false, &dance)
+	createShortFeedVideo(t, root, "e.mkv", 300, false, &dance)
+	createShortFeedVideo(t, downloads, "f.mkv", 60, false, &dance)
+	played := createShortFeedVideo(t, downloads, "g.mkv", 300, false, &dance)
+	database.DB.Model(&models.Video{}).Where("1 = 1").Update("height", 1080)
+	database.DB.Model(&played).Update("play_count", 1)
+
+	svc := &VideoService{}
+	query := `tag:舞蹈 -tag:4K duration>120 height>=1080 dir:"Downloads" played:never ext:mkv`
+	var ids []uint
+	var cursorScore float64
+	var cursorSize int64
+	var cursorID uint
+	for page := 0; page < 3; page++ {
+		videos, err := svc.SearchVideosWithFilters(query, nil, 0, 0, 0, 0, VideoMediaFilter{}, cursorScore, cursorSize, cursorID, 1)
+		if err != nil {
+			t.Fatalf("搜索失败: %v", err)
+		}
+		if len(videos) == 0 {
+			break
+		}
+		last := videos[len(videos)-1]
+		ids = append(ids, last.ID)
+		cursorScore, cursorSize, cursorID = float64(last.PlayCount)*2+float64(last.RandomPlayCount), last.Size, last.ID
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	if !reflect.DeepEqual(ids, []uint{a.ID, b.ID}) {
+		t.Fatalf("查询语法配合游标分页应返回 a、b，got=%v", ids)
+	}
+
+	videos, err := svc.SearchVideos(`"b.mkv"`, 0, 0, 0, 100)
+	if err != nil || len(videos) != 1 || videos[0].ID != b.ID {
+		t.Fatalf("引号短语应匹配文件名 videos=%+v err=%v", videos, err)
+	}
+	if _, err := svc.SearchVideos(`height>=x`, 0, 0, 0, 100); err == nil {
+		t.Fatalf("语法错误应返回错误")
+	}
+}
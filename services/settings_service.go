@@ -1,6 +1,8 @@
 package services
 
 import (
+	"fmt"
+	"strings"
 	"video-master/database"
 	"video-master/models"
 )
@@ -16,6 +18,13 @@ func (s *SettingsService) GetSettings() (*models.Settings, error) {
 
 // UpdateSettings 更新设置
 func (s *SettingsService) UpdateSettings(input models.Settings) error {
+	// 提供方留空表示沿用环境变量 AI_TAGGING_PROVIDER
+	provider := strings.ToLower(strings.TrimSpace(input.AITaggingProvider))
+	switch provider {
+	case "", AITaggingProviderOpenAI, AITaggingProviderOllama, AITaggingProviderAnthropic, AITaggingProviderCLIP:
+	default:
+		return fmt.Errorf("不支持的 AI 标签提供方: %s", input.AITaggingProvider)
+	}
 	var settings models.Settings
 	if err := database.DB.First(&settings).Error; err != nil {
 		return err
@@ -26,6 +35,8 @@ func (s *SettingsService) UpdateSettings(input models.Settings) error {
 	settings.VideoExtensions = input.VideoExtensions
 	settings.PlayWeight = input.PlayWeight
 	settings.AutoScanOnStartup = input.AutoScanOnStartup
+	settings.RandomNoRepeatCount = clampRandomNoRepeat(input.RandomNoRepeatCount)
+	settings.RandomNoRepeatHours = clampRandomNoRepeat(input.RandomNoRepeatHours)
 	settings.ShortFeedMaxDurationMinutes = positiveOrDefault(input.ShortFeedMaxDurationMinutes, DefaultShortFeedMaxDurationMinutes)
 	settings.Theme = input.Theme
 	settings.LogEnabled = input.LogEnabled
@@ -38,6 +49,19 @@ func (s *SettingsService) UpdateSettings(input models.Settings) error {
 	settings.AITaggingFrameCount = positiveOrDefault(input.AITaggingFrameCount, defaultAITaggingFrameCount)
 	settings.AITaggingSubtitleCharLimit = positiveOrDefault(input.AITaggingSubtitleCharLimit, defaultAITaggingSubtitleCharLimit)
 	settings.AITaggingStartupBatchSize = positiveOrDefault(input.AITaggingStartupBatchSize, defaultAITaggingStartupBatchSize)
+	settings.AITaggingConcurrency = min(positiveOrDefault(input.AITaggingConcurrency, defaultAITaggingConcurrency), maxAITaggingConcurrency)
+	settings.AITaggingRequestsPerMinute = max(input.AITaggingRequestsPerMinute, 0)
+	settings.AITaggingTokensPerMinute = max(input.AITaggingTokensPerMinute, 0)
+	settings.AITaggingMaxAttempts = positiveOrDefault(input.AITaggingMaxAttempts, defaultAITaggingMaxAttempts)
+	settings.AITaggingProvider = provider
+	settings.AITaggingOllamaBaseURL = strings.TrimSpace(input.AITaggingOllamaBaseURL)
+	settings.AITaggingOllamaModel = strings.TrimSpace(input.AITaggingOllamaModel)
+	settings.AITaggingAnthropicBaseURL = strings.TrimSpace(input.AITaggingAnthropicBaseURL)
+	settings.AITaggingAnthropicAPIKey = strings.TrimSpace(input.AITaggingAnthropicAPIKey)
+	settings.AITaggingAnthropicModel = strings.TrimSpace(input.AITaggingAnthropicModel)
+	settings.AITaggingClipBaseURL = strings.TrimSpace(input.AITaggingClipBaseURL)
+	settings.AITaggingClipModel = strings.TrimSpace(input.AITaggingClipModel)
+	settings.AITaggingClipMinScore = min(max(input.AITaggingClipMinScore, 0), 1)
 
 	return database.DB.Save(&settings).Error
 }
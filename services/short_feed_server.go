@@ -12,6 +12,7 @@ import (
 	"net/netip"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
@@ -157,8 +158,12 @@ func (s *ShortFeedHTTPServer) Handler() http.Handler {
 	mux.HandleFunc("/short-api/status", s.handleStatus)
 	mux.HandleFunc("/short-api/feed/next", s.handleNext)
 	mux.HandleFunc("/short-api/favorites", s.handleFavorites)
+	mux.HandleFunc("/short-api/saved-searches", s.handleSavedSearches)
+	mux.HandleFunc("/short-api/continue-watching", s.handleContinueWatching)
 	mux.HandleFunc("/short-api/videos/", s.handleVideoMutation)
 	mux.HandleFunc("/short-media/", s.handleMedia)
+	mux.HandleFunc("/short-thumbnail/", s.handleThumbnail)
+	mux.HandleFunc("/short-sprite/", s.handleSprite)
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if !shortFeedRemoteAllowed(r.RemoteAddr) {
@@ -221,10 +226,15 @@ func (s *ShortFeedHTTPServer) handleNext(w http.ResponseWriter, r *http.Request)
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
-	dto, err := s.feed.NextVideo(parseShortFeedExcludeIDs(r.URL.Query().Get("exclude")))
+	savedSearchID, _ := strconv.ParseUint(r.URL.Query().Get("saved_search"), 10, 64)
+	dto, err := s.feed.NextVideoInScope(parseShortFeedExcludeIDs(r.URL.Query().Get("exclude")), uint(savedSearchID))
 	if err != nil {
 		status := http.StatusInternalServerError
 		code := "next_failed"
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			status = http.StatusNotFound
+			code = "saved_search_not_found"
+		}
 		if errors.Is(err, ErrShortFeedNoEligibleVideos) {
 			status = http.StatusNotFound
 			code = "no_eligible_videos"
@@ -248,6 +258,33 @@ func (s *ShortFeedHTTPServer) handleFavorites(w http.ResponseWriter, r *http.Req
 	writeShortFeedJSON(w, http.StatusOK, map[string]interface{}{"videos": dtos})
 }
 
+func (s *ShortFeedHTTPServer) handleSavedSearches(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	searches, err := s.feed.SavedSearches()
+	if err != nil {
+		writeShortFeedError(w, http.StatusInternalServerError, "saved_searches_failed", err.Error())
+		return
+	}
+	writeShortFeedJSON(w, http.StatusOK, map[string]interface{}{"saved_searches": searches})
+}
+
+func (s *ShortFeedHTTPServer) handleContinueWatching(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	dtos, err := s.feed.ContinueWatchingVideos(limit)
+	if err != nil {
+		writeShortFeedError(w, http.StatusInternalServerError, "continue_watching_failed", err.Error())
+		return
+	}
+	writeShortFeedJSON(w, http.StatusOK, map[string]interface{}{"videos": dtos})
+}
+
 func (s *ShortFeedHTTPServer) handleVideoMutation(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		w.WriteHeader(http.StatusMethodNotAllowed)
@@ -276,6 +313,21 @@ func (s *ShortFeedHTTPServer) handleVideoMutation(w http.ResponseWriter, r *http
 		}
 		result, err := s.feed.RecordShortFeedPlayback(videoID)
 		writeShortFeedMutationResult(w, result, err)
+	case "progress":
+		var req ShortFeedProgressRequest
+		if !decodeShortFeedMutation(w, r, &req) {
+			return
+		}
+		result, err := s.feed.RecordProgress(videoID, req.PositionSeconds, req.DurationSeconds)
+		if errors.Is(err, ErrInvalidPlaybackProgress) {
+			writeShortFeedError(w, http.StatusBadRequest, "invalid_progress", err.Error())
+			return
+		}
+		if err != nil {
+			writeShortFeedMutationResult(w, nil, err)
+			return
+		}
+		writeShortFeedJSON(w, http.StatusOK, result)
 	case "like":
 		var req ShortFeedLikeRequest
 		if !decodeShortFeedMutation(w, r, &req) {
@@ -316,6 +368,10 @@ func (s *ShortFeedHTTPServer) handleMedia(w http.ResponseWriter, r *http.Request
 		return
 	}
 	videoIDText := strings.TrimPrefix(r.URL.Path, "/short-media/")
+	if strings.Contains(videoIDText, "/") {
+		s.handleTranscode(w, r, videoIDText)
+		return
+	}
 	videoID64, err := strconv.ParseUint(videoIDText, 10, 64)
 	if err != nil || videoID64 == 0 {
 		writeShortFeedError(w, http.StatusBadRequest, "invalid_media_id", "invalid short media id")
@@ -347,6 +403,85 @@ func (s *ShortFeedHTTPServer) handleMedia(w http.ResponseWriter, r *http.Request
 	http.ServeContent(w, r, media.DisplayName, media.ModTime, file)
 }
 
+func (s *ShortFeedHTTPServer) handleTranscode(w http.ResponseWriter, r *http.Request, rest string) {
+	videoID, resource, ok := ParseTranscodeAssetPath(rest)
+	if !ok {
+		writeShortFeedError(w, http.StatusBadRequest, "invalid_media_id", "invalid short media path")
+		return
+	}
+	if err := s.feed.ServeTranscode(w, r, videoID, resource); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, ErrShortFeedNoEligibleVideos):
+			writeShortFeedError(w, http.StatusNotFound, "media_not_found", "short feed media not found")
+		case errors.Is(err, ErrTranscodeBusy):
+			writeShortFeedError(w, http.StatusServiceUnavailable, "transcode_busy", "too many transcode sessions")
+		default:
+			writeShortFeedError(w, TranscodeHTTPStatus(err), "transcode_unavailable", err.Error())
+		}
+	}
+}
+
+func (s *ShortFeedHTTPServer) handleThumbnail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	videoID64, err := strconv.ParseUint(strings.TrimPrefix(r.URL.Path, "/short-thumbnail/"), 10, 64)
+	if err != nil || videoID64 == 0 {
+		writeShortFeedError(w, http.StatusBadRequest, "invalid_thumbnail_id", "invalid short thumbnail id")
+		return
+	}
+	s.serveThumbnailAsset(w, r, uint(videoID64), ThumbnailKindPoster)
+}
+
+// handleSprite 返回拖动预览雪碧图（<id>.jpg）或其 WebVTT 轨道（<id>.vtt）
+func (s *ShortFeedHTTPServer) handleSprite(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, "/short-sprite/")
+	kind := ThumbnailKindSprite
+	switch filepath.Ext(name) {
+	case ".jpg":
+	case ".vtt":
+		kind = ThumbnailKindSpriteTrack
+	default:
+		writeShortFeedError(w, http.StatusBadRequest, "invalid_thumbnail_id", "invalid short sprite path")
+		return
+	}
+	videoID64, err := strconv.ParseUint(strings.TrimSuffix(name, filepath.Ext(name)), 10, 64)
+	if err != nil || videoID64 == 0 {
+		writeShortFeedError(w, http.StatusBadRequest, "invalid_thumbnail_id", "invalid short sprite id")
+		return
+	}
+	s.serveThumbnailAsset(w, r, uint(videoID64), kind)
+}
+
+func (s *ShortFeedHTTPServer) serveThumbnailAsset(w http.ResponseWriter, r *http.Request, videoID uint, kind string) {
+	asset, err := s.feed.ResolveThumbnail(videoID, kind)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrThumbnailPending):
+			writeShortFeedError(w, http.StatusNotFound, "thumbnail_pending", "thumbnail is being generated")
+		case errors.Is(err, os.ErrNotExist) || errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, ErrShortFeedNoEligibleVideos):
+			writeShortFeedError(w, http.StatusNotFound, "thumbnail_not_found", "short feed thumbnail not found")
+		default:
+			writeShortFeedError(w, http.StatusInternalServerError, "thumbnail_unavailable", err.Error())
+		}
+		return
+	}
+	file, err := os.Open(asset.Path)
+	if err != nil {
+		writeShortFeedError(w, http.StatusNotFound, "thumbnail_not_found", "short feed thumbnail not found")
+		return
+	}
+	defer file.Close()
+	w.Header().Set("Content-Type", asset.MIME)
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	http.ServeContent(w, r, filepath.Base(asset.Path), asset.ModTime, file)
+}
+
 func parseShortFeedVideoAction(path string) (uint, string, bool) {
 	trimmed := strings.TrimPrefix(path, "/short-api/videos/")
 	parts := strings.Split(strings.Trim(trimmed, "/"), "/")
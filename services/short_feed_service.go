@@ -3,7 +3,9 @@ package services
 import (
 	"errors"
 	"fmt"
+	"log"
 	"math/rand"
+	"net/http"
 	"os"
 	"time"
 	"video-master/database"
@@ -24,6 +26,7 @@ type ShortFeedMedia struct {
 
 type ShortFeedService struct {
 	videoService *VideoService
+	progress     *PlaybackProgressService
 	now          func() time.Time
 	randFloat64  func() float64
 }
@@ -34,13 +37,27 @@ func NewShortFeedService(videoService *VideoService) *ShortFeedService {
 	}
 	return &ShortFeedService{
 		videoService: videoService,
+		progress:     &PlaybackProgressService{},
 		now:          time.Now,
 		randFloat64:  rand.Float64,
 	}
 }
 
 func (s *ShortFeedService) NextVideo(excludeIDs []uint) (*ShortFeedVideoDTO, error) {
-	videos, err := s.loadEligibleVideos(nil)
+	return s.NextVideoInScope(excludeIDs, 0)
+}
+
+// NextVideoInScope 与 NextVideo 相同，savedSearchID 非零时只在该保存的搜索范围内选片
+func (s *ShortFeedService) NextVideoInScope(excludeIDs []uint, savedSearchID uint) (*ShortFeedVideoDTO, error) {
+	var scope *VideoSearchFilter
+	if savedSearchID > 0 {
+		filter, err := loadSavedSearchFilter(savedSearchID)
+		if err != nil {
+			return nil, err
+		}
+		scope = &filter
+	}
+	videos, err := s.loadEligibleVideos(nil, scope)
 	if err != nil {
 		return nil, err
 	}
@@ -50,7 +67,7 @@ func (s *ShortFeedService) NextVideo(excludeIDs []uint) (*ShortFeedVideoDTO, err
 
 	filtered := videos
 	if len(excludeIDs) > 0 {
-		excludedVideos, err := s.loadEligibleVideos(excludeIDs)
+		excludedVideos, err := s.loadEligibleVideos(excludeIDs, scope)
 		if err != nil {
 			return nil, err
 		}
@@ -64,9 +81,11 @@ func (s *ShortFeedService) NextVideo(excludeIDs []uint) (*ShortFeedVideoDTO, err
 		return nil, ErrShortFeedNoEligibleVideos
 	}
 
+	// 可实时转码时非内嵌格式也能进入 Feed
+	canTranscode := s.videoService.transcoder.Available()
 	supported := make([]models.Video, 0, len(existing))
 	for _, video := range existing {
-		if _, ok := inlinePreviewMIME(video.Path); ok {
+		if _, ok := inlinePreviewMIME(video.Path); ok || canTranscode {
 			supported = append(supported, video)
 		}
 	}
@@ -74,14 +93,36 @@ func (s *ShortFeedService) NextVideo(excludeIDs []uint) (*ShortFeedVideoDTO, err
 		return s.videoDTO(&existing[0], "inline_not_supported", "当前文件格式不适合浏览器内播放。")
 	}
 
+	// 与桌面随机播放共用防重复窗口
+	supported, err = s.withoutRecentPicks(supported)
+	if err != nil {
+		return nil, err
+	}
+
 	prefs, err := s.tagPreferenceMap()
 	if err != nil {
 		return nil, err
 	}
 	selected := s.weightedSelect(supported, prefs)
+	if err := recordRandomPick(selected.ID, RandomPickSourceShortFeed, s.now()); err != nil {
+		log.Printf("记录短视频选片失败: %v", err)
+	}
 	return s.videoDTO(&selected, "", "")
 }
 
+// SavedSearches 列出可作为短视频范围的保存的搜索
+func (s *ShortFeedService) SavedSearches() ([]ShortFeedSavedSearchDTO, error) {
+	var searches []models.SavedSearch
+	if err := database.DB.Select("id", "name").Order("name asc, id asc").Find(&searches).Error; err != nil {
+		return nil, err
+	}
+	result := make([]ShortFeedSavedSearchDTO, 0, len(searches))
+	for _, search := range searches {
+		result = append(result, ShortFeedSavedSearchDTO{ID: search.ID, Name: search.Name})
+	}
+	return result, nil
+}
+
 func (s *ShortFeedService) FavoriteVideos() ([]ShortFeedVideoDTO, error) {
 	var videos []models.Video
 	maxDurationSeconds := s.maxDurationSeconds()
@@ -89,7 +130,7 @@ func (s *ShortFeedService) FavoriteVideos() ([]ShortFeedVideoDTO, error) {
 		Preload("Tags").
 		Joins("JOIN short_feed_interactions ON short_feed_interactions.video_id = videos.id").
 		Where("short_feed_interactions.favorited = ?", true).
-		Where("videos.is_stale = ?", false).
+		Where("videos.is_stale = ? AND videos.is_offline = ?", false, false).
 		Where("videos.duration > ? AND videos.duration < ?", 0, maxDurationSeconds).
 		Order("short_feed_interactions.updated_at DESC").
 		Find(&videos).Error
@@ -128,6 +169,10 @@ func (s *ShortFeedService) RecordShortFeedPlayback(videoID uint) (*ShortFeedInte
 		}).Error; err != nil {
 			return err
 		}
+		event := newPlaybackEvent(videoID, PlaybackEventSourceShortFeed, "", now)
+		if err := tx.Create(&event).Error; err != nil {
+			return err
+		}
 
 		return upsertShortFeedInteraction(tx, videoID, func(row *models.ShortFeedInteraction) {
 			row.ViewCount++
@@ -211,6 +256,54 @@ func (s *ShortFeedService) SetFavorited(videoID uint, favorited bool) (*ShortFee
 	return interactionDTO(&interaction), nil
 }
 
+// RecordProgress 记录短视频播放位置，仅接受当前可进入 Feed 的视频
+func (s *ShortFeedService) RecordProgress(videoID uint, position float64, duration float64) (*ShortFeedProgressDTO, error) {
+	var video models.Video
+	if err := database.DB.First(&video, videoID).Error; err != nil {
+		return nil, err
+	}
+	if !shortFeedEligible(video, s.maxDurationSeconds()) {
+		return nil, ErrShortFeedNoEligibleVideos
+	}
+	progress, err := s.progress.UpdateProgress(videoID, position, duration, PlaybackSourceShortFeed)
+	if err != nil {
+		return nil, err
+	}
+	return &ShortFeedProgressDTO{
+		VideoID:         progress.VideoID,
+		PositionSeconds: progress.PositionSeconds,
+		DurationSeconds: progress.DurationSeconds,
+		Percent:         progress.Percent,
+		Completed:       progress.Completed,
+	}, nil
+}
+
+// ContinueWatchingVideos 列出看了一部分的短视频，按最近观看排序
+func (s *ShortFeedService) ContinueWatchingVideos(limit int) ([]ShortFeedVideoDTO, error) {
+	rows, err := s.progress.ContinueWatching(limit)
+	if err != nil {
+		return nil, err
+	}
+	maxDurationSeconds := s.maxDurationSeconds()
+	videos := make([]models.Video, 0, len(rows))
+	for _, row := range rows {
+		if shortFeedEligible(row.Video, maxDurationSeconds) {
+			videos = append(videos, row.Video)
+		}
+	}
+
+	existing := s.filterExistingVideos(videos)
+	result := make([]ShortFeedVideoDTO, 0, len(existing))
+	for i := range existing {
+		dto, err := s.videoDTO(&existing[i], "", "")
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *dto)
+	}
+	return result, nil
+}
+
 func (s *ShortFeedService) DeleteVideo(videoID uint) error {
 	return s.videoService.DeleteVideo(videoID, true)
 }
@@ -226,12 +319,12 @@ func (s *ShortFeedService) ResolveMedia(videoID uint) (*ShortFeedMedia, error) {
 	info, err := os.Stat(video.Path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			s.markStale(video.ID)
+			markVideoUnavailable(&video)
 		}
 		return nil, err
 	}
 	if info.IsDir() {
-		s.markStale(video.ID)
+		markVideoUnavailable(&video)
 		return nil, fmt.Errorf("short-feed media path is directory")
 	}
 	mimeType, ok := inlinePreviewMIME(video.Path)
@@ -246,16 +339,62 @@ func (s *ShortFeedService) ResolveMedia(videoID uint) (*ShortFeedMedia, error) {
 	}, nil
 }
 
-func (s *ShortFeedService) loadEligibleVideos(excludeIDs []uint) ([]models.Video, error) {
+// ServeTranscode 输出 /short-media/<id>/ 下的实时转码资源；仅对可进入 Feed 的视频开放
+func (s *ShortFeedService) ServeTranscode(w http.ResponseWriter, r *http.Request, videoID uint, resource string) error {
+	var video models.Video
+	if err := database.DB.First(&video, videoID).Error; err != nil {
+		return err
+	}
+	if !shortFeedEligible(video, s.maxDurationSeconds()) {
+		return ErrShortFeedNoEligibleVideos
+	}
+	info, err := os.Stat(video.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			markVideoUnavailable(&video)
+		}
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("short-feed media path is directory")
+	}
+	return s.videoService.transcoder.ServeAsset(w, r, video, resource)
+}
+
+// ResolvePoster 返回短视频封面缓存；仅对可进入 Feed 的视频开放
+func (s *ShortFeedService) ResolvePoster(videoID uint) (*ThumbnailAsset, error) {
+	return s.ResolveThumbnail(videoID, ThumbnailKindPoster)
+}
+
+// ResolveThumbnail 返回短视频的指定缩略图缓存（封面、雪碧图或其 WebVTT 轨道）
+func (s *ShortFeedService) ResolveThumbnail(videoID uint, kind string) (*ThumbnailAsset, error) {
+	var video models.Video
+	if err := database.DB.First(&video, videoID).Error; err != nil {
+		return nil, err
+	}
+	if !shortFeedEligible(video, s.maxDurationSeconds()) {
+		return nil, ErrShortFeedNoEligibleVideos
+	}
+	return s.videoService.thumbnails.ResolveThumbnail(videoID, kind)
+}
+
+// loadEligibleVideos 加载可进入短视频的视频；scope 非空时再叠加保存的搜索条件
+func (s *ShortFeedService) loadEligibleVideos(excludeIDs []uint, scope *VideoSearchFilter) ([]models.Video, error) {
 	var videos []models.Video
 	maxDurationSeconds := s.maxDurationSeconds()
 	query := database.DB.Model(&models.Video{}).
 		Preload("Tags").
-		Where("is_stale = ?", false).
-		Where("duration > ? AND duration < ?", 0, maxDurationSeconds).
-		Order("id ASC")
+		Where("videos.is_stale = ? AND videos.is_offline = ?", false, false).
+		Where("videos.duration > ? AND videos.duration < ?", 0, maxDurationSeconds).
+		Order("videos.id ASC")
 	if len(excludeIDs) > 0 {
-		query = query.Where("id NOT IN ?", excludeIDs)
+		query = query.Where("videos.id NOT IN ?", excludeIDs)
+	}
+	if scope != nil {
+		var err error
+		if query, err = applyVideoSearchFilter(query, *scope); err != nil {
+			return nil, err
+		}
 	}
 	if err := query.Find(&videos).Error; err != nil {
 		return nil, err
@@ -277,21 +416,17 @@ func (s *ShortFeedService) videoFileExists(video models.Video) bool {
 	info, err := os.Stat(video.Path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			s.markStale(video.ID)
+			markVideoUnavailable(&video)
 		}
 		return false
 	}
 	if info.IsDir() {
-		s.markStale(video.ID)
+		markVideoUnavailable(&video)
 		return false
 	}
 	return true
 }
 
-func (s *ShortFeedService) markStale(videoID uint) {
-	_ = database.DB.Model(&models.Video{}).Where("id = ?", videoID).Update("is_stale", true).Error
-}
-
 func (s *ShortFeedService) tagPreferenceMap() (map[uint]float64, error) {
 	var rows []models.ShortFeedTagPreference
 	if err := database.DB.Find(&rows).Error; err != nil {
@@ -349,34 +484,77 @@ func (s *ShortFeedService) videoDTO(video *models.Video, reasonCode string, reas
 	}
 	mediaURL := ""
 	mediaMIME := ""
+	hlsURL := ""
+	transcodeMode := ""
 	if mimeType, ok := inlinePreviewMIME(video.Path); ok {
 		mediaURL = fmt.Sprintf("/short-media/%d", video.ID)
 		mediaMIME = mimeType
+	} else if s.videoService.transcoder.Available() {
+		mediaURL = fmt.Sprintf("/short-media/%d/%s", video.ID, TranscodeStreamResource)
+		mediaMIME = "video/mp4"
+		hlsURL = fmt.Sprintf("/short-media/%d/%s%s", video.ID, transcodeHLSPrefix, TranscodeHLSPlaylist)
+		transcodeMode = PlanTranscode(*video).Mode
 	} else if reasonCode == "" {
 		reasonCode = "inline_not_supported"
 		reasonMessage = "当前文件格式不适合浏览器内播放。"
 	}
 
+	posterURL := ""
+	thumbnailTrackURL := ""
+	if s.videoService.thumbnails != nil {
+		posterURL = fmt.Sprintf("/short-thumbnail/%d", video.ID)
+		thumbnailTrackURL = fmt.Sprintf("/short-sprite/%d.vtt", video.ID)
+	}
+
 	tags := make([]ShortFeedTagDTO, 0, len(video.Tags))
 	for _, tag := range video.Tags {
 		tags = append(tags, ShortFeedTagDTO{ID: tag.ID, Name: tag.Name, Color: tag.Color})
 	}
 	return &ShortFeedVideoDTO{
-		ID:            video.ID,
-		Name:          video.Name,
-		Duration:      video.Duration,
-		Width:         video.Width,
-		Height:        video.Height,
-		Tags:          tags,
-		MediaURL:      mediaURL,
-		MediaMIME:     mediaMIME,
-		Liked:         interaction.Liked,
-		Favorited:     interaction.Favorited,
-		ReasonCode:    reasonCode,
-		ReasonMessage: reasonMessage,
+		ID:             video.ID,
+		Name:           video.Name,
+		Duration:       video.Duration,
+		Width:          video.Width,
+		Height:         video.Height,
+		Tags:           tags,
+		MediaURL:       mediaURL,
+		MediaMIME:      mediaMIME,
+		PosterURL:      posterURL,
+		ThumbnailTrack: thumbnailTrackURL,
+		HLSURL:         hlsURL,
+		TranscodeMode:  transcodeMode,
+		Liked:          interaction.Liked,
+		Favorited:      interaction.Favorited,
+		ResumePosition: resumePositionForVideo(video.ID),
+		ReasonCode:     reasonCode,
+		ReasonMessage:  reasonMessage,
 	}, nil
 }
 
+// withoutRecentPicks 去掉防重复窗口内的视频，至少保留一个候选
+func (s *ShortFeedService) withoutRecentPicks(videos []models.Video) ([]models.Video, error) {
+	var settings models.Settings
+	if err := database.DB.First(&settings).Error; err != nil {
+		return nil, fmt.Errorf("获取设置失败: %w", err)
+	}
+	recentIDs, err := recentRandomPickIDs(settings, s.now())
+	if err != nil || len(recentIDs) == 0 {
+		return videos, err
+	}
+	ids := make([]uint, len(videos))
+	for i, video := range videos {
+		ids[i] = video.ID
+	}
+	excluded := randomPickExclusions(ids, recentIDs)
+	kept := make([]models.Video, 0, len(videos))
+	for _, video := range videos {
+		if !excluded[video.ID] {
+			kept = append(kept, video)
+		}
+	}
+	return kept, nil
+}
+
 func (s *ShortFeedService) maxDurationSeconds() float64 {
 	if database.DB == nil {
 		return defaultShortFeedMaxDurationSeconds
@@ -395,7 +573,7 @@ func shortFeedEligible(video models.Video, maxDurationSeconds float64) bool {
 	if maxDurationSeconds <= 0 {
 		maxDurationSeconds = defaultShortFeedMaxDurationSeconds
 	}
-	return !video.IsStale && video.Duration > 0 && video.Duration < maxDurationSeconds
+	return !video.IsStale && !video.IsOffline && video.Duration > 0 && video.Duration < maxDurationSeconds
 }
 
 func interactionForVideo(videoID uint) (models.ShortFeedInteraction, error) {
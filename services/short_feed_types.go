@@ -18,18 +18,29 @@ type ShortFeedTagDTO struct {
 }
 
 type ShortFeedVideoDTO struct {
-	ID            uint              `json:"id"`
-	Name          string            `json:"name"`
-	Duration      float64           `json:"duration"`
-	Width         int               `json:"width"`
-	Height        int               `json:"height"`
-	Tags          []ShortFeedTagDTO `json:"tags"`
-	MediaURL      string            `json:"media_url"`
-	MediaMIME     string            `json:"media_mime"`
-	Liked         bool              `json:"liked"`
-	Favorited     bool              `json:"favorited"`
-	ReasonCode    string            `json:"reason_code,omitempty"`
-	ReasonMessage string            `json:"reason_message,omitempty"`
+	ID             uint              `json:"id"`
+	Name           string            `json:"name"`
+	Duration       float64           `json:"duration"`
+	Width          int               `json:"width"`
+	Height         int               `json:"height"`
+	Tags           []ShortFeedTagDTO `json:"tags"`
+	MediaURL       string            `json:"media_url"`
+	MediaMIME      string            `json:"media_mime"`
+	PosterURL      string            `json:"poster_url,omitempty"`
+	ThumbnailTrack string            `json:"thumbnail_track,omitempty"`
+	HLSURL         string            `json:"hls_url,omitempty"`        // 转码播放时的 HLS 地址，支持原生 HLS 的浏览器优先使用
+	TranscodeMode  string            `json:"transcode_mode,omitempty"` // remux 或 transcode，直接播放时为空
+	Liked          bool              `json:"liked"`
+	Favorited      bool              `json:"favorited"`
+	ResumePosition float64           `json:"resume_position,omitempty"` // 上次未看完时的续播位置（秒）
+	ReasonCode     string            `json:"reason_code,omitempty"`
+	ReasonMessage  string            `json:"reason_message,omitempty"`
+}
+
+// ShortFeedSavedSearchDTO 手机端可选的保存的搜索范围
+type ShortFeedSavedSearchDTO struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
 }
 
 type ShortFeedInteractionDTO struct {
@@ -42,6 +53,14 @@ type ShortFeedInteractionDTO struct {
 	FavoritedAt  *time.Time `json:"favorited_at,omitempty"`
 }
 
+type ShortFeedProgressDTO struct {
+	VideoID         uint    `json:"video_id"`
+	PositionSeconds float64 `json:"position_seconds"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	Percent         float64 `json:"percent"`
+	Completed       bool    `json:"completed"`
+}
+
 type ShortFeedServerStatus struct {
 	Running       bool     `json:"running"`
 	BindAddress   string   `json:"bind_address"`
@@ -68,3 +87,8 @@ type ShortFeedFavoriteRequest struct {
 type ShortFeedDeleteRequest struct {
 	ConfirmMoveToTrash bool `json:"confirm_move_to_trash"`
 }
+
+type ShortFeedProgressRequest struct {
+	PositionSeconds float64 `json:"position_seconds"`
+	DurationSeconds float64 `json:"duration_seconds"`
+}
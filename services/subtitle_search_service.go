@@ -16,6 +16,52 @@ import (
 type SubtitleSearchMatch struct {
 	Video   models.Video           `json:"video"`
 	Segment subtitleparser.Segment `json:"segment"`
+	Rank    float64                `json:"rank"`
+	Snippet SearchSnippet          `json:"snippet"`
+}
+
+const (
+	defaultSubtitleHitsPerVideo = 20
+	maxSubtitleHitsPerVideo     = 200
+	maxSubtitleHitContext       = 5
+	maxSubtitleHitPageSize      = 100
+)
+
+// SubtitleHitQuery 逐条命中搜索参数；游标取上一页返回的 NextCursor*，CursorVideoID 为 0 表示第一页
+type SubtitleHitQuery struct {
+	Keyword         string  `json:"keyword"`
+	ContextBefore   int     `json:"context_before"`
+	ContextAfter    int     `json:"context_after"`
+	MaxHitsPerVideo int     `json:"max_hits_per_video"`
+	CursorRank      float64 `json:"cursor_rank"`
+	CursorHitCount  int     `json:"cursor_hit_count"`
+	CursorVideoID   uint    `json:"cursor_video_id"`
+	Limit           int     `json:"limit"`
+}
+
+// SubtitleHit 一条命中字幕及其前后上下文（按 segment_index 相邻）
+type SubtitleHit struct {
+	Segment subtitleparser.Segment   `json:"segment"`
+	Snippet SearchSnippet            `json:"snippet"`
+	Before  []subtitleparser.Segment `json:"before"`
+	After   []subtitleparser.Segment `json:"after"`
+}
+
+// SubtitleVideoHits 一个视频的全部命中；HitCount 为总命中数，Hits 最多 MaxHitsPerVideo 条
+type SubtitleVideoHits struct {
+	Video    models.Video  `json:"video"`
+	Rank     float64       `json:"rank"`
+	HitCount int           `json:"hit_count"`
+	Hits     []SubtitleHit `json:"hits"`
+}
+
+// SubtitleHitPage 一页按视频分组的命中结果
+type SubtitleHitPage struct {
+	Videos             []SubtitleVideoHits `json:"videos"`
+	HasMore            bool                `json:"has_more"`
+	NextCursorRank     float64             `json:"next_cursor_rank"`
+	NextCursorHitCount int                 `json:"next_cursor_hit_count"`
+	NextCursorVideoID  uint                `json:"next_cursor_video_id"`
 }
 
 type SubtitleSearchService struct{}
@@ -53,28 +99,236 @@ func (s *SubtitleSearchService) SearchSubtitleMatches(keyword string, limit int)
 	return matches, err
 }
 
+// searchIndexedSubtitleMatches 每个视频取相关度最高的一条字幕，视频间按该条相关度降序
 func (s *SubtitleSearchService) searchIndexedSubtitleMatches(keyword string, limit int) ([]SubtitleSearchMatch, bool, error) {
-	pattern := "%" + strings.ToLower(escapeSQLLike(keyword)) + "%"
-	type firstHit struct {
-		VideoID      uint
-		SegmentIndex int
-	}
-
-	var hits []firstHit
-	err := database.DB.Model(&models.SubtitleSegment{}).
-		Select("video_id, MIN(segment_index) AS segment_index").
-		Where("LOWER(text) LIKE ? ESCAPE '\\'", pattern).
-		Group("video_id").
-		Order("video_id desc").
-		Limit(limit).
-		Scan(&hits).Error
+	terms := splitFullTextTerms(keyword)
+	if len(terms) == 0 {
+		return []SubtitleSearchMatch{}, false, nil
+	}
+	hits, err := queryRankedSubtitleVideos(terms, nil, limit)
 	if err != nil {
 		return nil, false, err
 	}
 	if len(hits) == 0 {
 		return []SubtitleSearchMatch{}, false, nil
 	}
+	videosByID, staleIndex, err := resolveSubtitleSearchVideos(hits)
+	if err != nil {
+		return nil, false, err
+	}
+
+	matches := make([]SubtitleSearchMatch, 0, len(hits))
+	for _, hit := range hits {
+		video, ok := videosByID[hit.VideoID]
+		if !ok {
+			continue
+		}
+		var indexed models.SubtitleSegment
+		if err := database.DB.
+			Where("video_id = ? AND segment_index = ?", hit.VideoID, hit.SegmentIndex).
+			First(&indexed).Error; err != nil {
+			staleIndex = true
+			continue
+		}
+		matches = append(matches, SubtitleSearchMatch{
+			Video:   video,
+			Segment: segmentFromIndexed(indexed),
+			Rank:    hit.HitRank,
+			Snippet: buildSearchSnippet(indexed.Text, terms, fullTextSnippetRunes),
+		})
+	}
+
+	return matches, staleIndex, nil
+}
+
+// SearchSubtitleHits 返回每条命中字幕及其上下文，按视频游标分页（视频间按最佳命中相关度、命中数降序，视频内按时间顺序）
+func (s *SubtitleSearchService) SearchSubtitleHits(query SubtitleHitQuery) (*SubtitleHitPage, error) {
+	query = normalizeSubtitleHitQuery(query)
+	if query.Keyword == "" {
+		return &SubtitleHitPage{Videos: []SubtitleVideoHits{}}, nil
+	}
+	firstPage := query.CursorVideoID == 0
+
+	page, stale, err := s.searchIndexedSubtitleHits(query)
+	if err != nil {
+		return nil, err
+	}
+	if stale {
+		page, stale, err = s.searchIndexedSubtitleHits(query)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !firstPage || (len(page.Videos) >= query.Limit && !stale) {
+		return page, nil
+	}
+
+	if err := syncSubtitleIndexesFromFilesystem(); err != nil {
+		return nil, err
+	}
+	page, _, err = s.searchIndexedSubtitleHits(query)
+	return page, err
+}
+
+func normalizeSubtitleHitQuery(query SubtitleHitQuery) SubtitleHitQuery {
+	query.Keyword = strings.TrimSpace(query.Keyword)
+	query.ContextBefore = min(max(query.ContextBefore, 0), maxSubtitleHitContext)
+	query.ContextAfter = min(max(query.ContextAfter, 0), maxSubtitleHitContext)
+	if query.MaxHitsPerVideo <= 0 {
+		query.MaxHitsPerVideo = defaultSubtitleHitsPerVideo
+	}
+	query.MaxHitsPerVideo = min(query.MaxHitsPerVideo, maxSubtitleHitsPerVideo)
+	if query.Limit <= 0 {
+		query.Limit = 20
+	}
+	query.Limit = min(query.Limit, maxSubtitleHitPageSize)
+	return query
+}
+
+func (s *SubtitleSearchService) searchIndexedSubtitleHits(query SubtitleHitQuery) (*SubtitleHitPage, bool, error) {
+	page := &SubtitleHitPage{Videos: []SubtitleVideoHits{}}
+	terms := splitFullTextTerms(query.Keyword)
+	if len(terms) == 0 {
+		return page, false, nil
+	}
+	// 多取一个用于判断是否还有下一页
+	ranked, err := queryRankedSubtitleVideos(terms, &query, query.Limit+1)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(ranked) > query.Limit {
+		ranked = ranked[:query.Limit]
+		page.HasMore = true
+	}
+	if len(ranked) == 0 {
+		return page, false, nil
+	}
+	last := ranked[len(ranked)-1]
+	page.NextCursorRank, page.NextCursorHitCount, page.NextCursorVideoID = last.HitRank, last.HitCount, last.VideoID
 
+	videosByID, staleIndex, err := resolveSubtitleSearchVideos(ranked)
+	if err != nil {
+		return nil, false, err
+	}
+	condSQL, condArgs := fullTextCondition("text_tsv", "text", terms)
+	for _, hit := range ranked {
+		video, ok := videosByID[hit.VideoID]
+		if !ok {
+			continue
+		}
+		hits, err := loadSubtitleVideoHits(hit.VideoID, condSQL, condArgs, terms, query)
+		if err != nil {
+			return nil, false, err
+		}
+		if len(hits) == 0 {
+			staleIndex = true
+			continue
+		}
+		page.Videos = append(page.Videos, SubtitleVideoHits{
+			Video:    video,
+			Rank:     hit.HitRank,
+			HitCount: hit.HitCount,
+			Hits:     hits,
+		})
+	}
+	return page, staleIndex, nil
+}
+
+// loadSubtitleVideoHits 读取一个视频内按时间顺序的命中字幕（最多 MaxHitsPerVideo 条），并附上前后上下文
+func loadSubtitleVideoHits(videoID uint, condSQL string, condArgs []interface{}, terms []string, query SubtitleHitQuery) ([]SubtitleHit, error) {
+	var hitIndexes []int
+	if err := database.DB.Model(&models.SubtitleSegment{}).
+		Where("video_id = ?", videoID).
+		Where(condSQL, condArgs...).
+		Order("segment_index asc").
+		Limit(query.MaxHitsPerVideo).
+		Pluck("segment_index", &hitIndexes).Error; err != nil {
+		return nil, err
+	}
+	if len(hitIndexes) == 0 {
+		return nil, nil
+	}
+
+	needed := make([]int, 0, len(hitIndexes)*(1+query.ContextBefore+query.ContextAfter))
+	seen := make(map[int]bool)
+	for _, index := range hitIndexes {
+		for i := index - query.ContextBefore; i <= index+query.ContextAfter; i++ {
+			if i > 0 && !seen[i] {
+				seen[i] = true
+				needed = append(needed, i)
+			}
+		}
+	}
+	var segments []models.SubtitleSegment
+	if err := database.DB.Where("video_id = ? AND segment_index IN ?", videoID, needed).Find(&segments).Error; err != nil {
+		return nil, err
+	}
+	byIndex := make(map[int]models.SubtitleSegment, len(segments))
+	for _, segment := range segments {
+		byIndex[segment.SegmentIndex] = segment
+	}
+
+	hits := make([]SubtitleHit, 0, len(hitIndexes))
+	for _, index := range hitIndexes {
+		indexed, ok := byIndex[index]
+		if !ok {
+			continue
+		}
+		hit := SubtitleHit{
+			Segment: segmentFromIndexed(indexed),
+			Snippet: buildSearchSnippet(indexed.Text, terms, fullTextSnippetRunes),
+			Before:  []subtitleparser.Segment{},
+			After:   []subtitleparser.Segment{},
+		}
+		for i := index - query.ContextBefore; i < index; i++ {
+			if segment, ok := byIndex[i]; ok {
+				hit.Before = append(hit.Before, segmentFromIndexed(segment))
+			}
+		}
+		for i := index + 1; i <= index+query.ContextAfter; i++ {
+			if segment, ok := byIndex[i]; ok {
+				hit.After = append(hit.After, segmentFromIndexed(segment))
+			}
+		}
+		hits = append(hits, hit)
+	}
+	return hits, nil
+}
+
+type rankedSubtitleVideo struct {
+	VideoID      uint
+	SegmentIndex int
+	HitRank      float64
+	HitCount     int
+}
+
+// queryRankedSubtitleVideos 每个视频取相关度最高的一条命中并统计命中数，按 (相关度, 命中数, 视频 ID) 降序游标分页；
+// cursor 为 nil 或 CursorVideoID 为 0 表示第一页
+func queryRankedSubtitleVideos(terms []string, cursor *SubtitleHitQuery, limit int) ([]rankedSubtitleVideo, error) {
+	condSQL, condArgs := fullTextCondition("text_tsv", "text", terms)
+	rankSQL, rankArgs := fullTextRankExpr("text_tsv", "text", terms)
+	selectArgs := append(append([]interface{}{}, rankArgs...), rankArgs...)
+	ranked := database.DB.Model(&models.SubtitleSegment{}).
+		Select("video_id, segment_index, "+rankSQL+" AS hit_rank, "+
+			"ROW_NUMBER() OVER (PARTITION BY video_id ORDER BY "+rankSQL+" DESC, segment_index ASC) AS hit_order, "+
+			"COUNT(*) OVER (PARTITION BY video_id) AS hit_count", selectArgs...).
+		Where(condSQL, condArgs...)
+
+	query := database.DB.Table("(?) AS ranked", ranked).
+		Select("video_id, segment_index, hit_rank, hit_count").
+		Where("hit_order = 1")
+	if cursor != nil && cursor.CursorVideoID > 0 {
+		query = query.Where("(hit_rank < ? OR (hit_rank = ? AND (hit_count < ? OR (hit_count = ? AND video_id < ?))))",
+			cursor.CursorRank, cursor.CursorRank, cursor.CursorHitCount, cursor.CursorHitCount, cursor.CursorVideoID)
+	}
+	var hits []rankedSubtitleVideo
+	err := query.Order("hit_rank desc, hit_count desc, video_id desc").Limit(limit).Scan(&hits).Error
+	return hits, err
+}
+
+// resolveSubtitleSearchVideos 读取命中视频并校验字幕索引是否仍与文件一致；
+// 视频已删除或索引过期的会顺带清理/重建，并返回 stale=true 让调用方重查
+func resolveSubtitleSearchVideos(hits []rankedSubtitleVideo) (map[uint]models.Video, bool, error) {
 	videoIDs := make([]uint, 0, len(hits))
 	for _, hit := range hits {
 		videoIDs = append(videoIDs, hit.VideoID)
@@ -84,18 +338,18 @@ func (s *SubtitleSearchService) searchIndexedSubtitleMatches(keyword string, lim
 	if err := database.DB.Preload("Tags").Where("id IN ?", videoIDs).Find(&videos).Error; err != nil {
 		return nil, false, err
 	}
-	videosByID := make(map[uint]models.Video, len(videos))
+	loaded := make(map[uint]models.Video, len(videos))
 	for _, video := range videos {
-		videosByID[video.ID] = video
+		loaded[video.ID] = video
 	}
 
-	matches := make([]SubtitleSearchMatch, 0, len(hits))
+	videosByID := make(map[uint]models.Video, len(videos))
 	staleIndex := false
-	for _, hit := range hits {
-		video, ok := videosByID[hit.VideoID]
+	for _, videoID := range videoIDs {
+		video, ok := loaded[videoID]
 		if !ok {
 			staleIndex = true
-			_ = deleteSubtitleIndex(hit.VideoID)
+			_ = deleteSubtitleIndex(videoID)
 			continue
 		}
 		current, err := isSubtitleIndexCurrent(video, subtitleparser.SRTPathForVideo(video.Path))
@@ -104,27 +358,19 @@ func (s *SubtitleSearchService) searchIndexedSubtitleMatches(keyword string, lim
 			_ = ensureSubtitleIndexForVideo(video)
 			continue
 		}
-
-		var indexed models.SubtitleSegment
-		if err := database.DB.
-			Where("video_id = ? AND segment_index = ?", hit.VideoID, hit.SegmentIndex).
-			First(&indexed).Error; err != nil {
-			staleIndex = true
-			continue
-		}
-		matches = append(matches, SubtitleSearchMatch{
-			Video: video,
-			Segment: subtitleparser.Segment{
-				Index:       indexed.SegmentIndex,
-				StartTimeMs: indexed.StartTimeMs,
-				EndTimeMs:   indexed.EndTimeMs,
-				Text:        indexed.Text,
-				Lines:       splitSubtitleLines(indexed.Text),
-			},
-		})
+		videosByID[videoID] = video
 	}
+	return videosByID, staleIndex, nil
+}
 
-	return matches, staleIndex, nil
+func segmentFromIndexed(indexed models.SubtitleSegment) subtitleparser.Segment {
+	return subtitleparser.Segment{
+		Index:       indexed.SegmentIndex,
+		StartTimeMs: indexed.StartTimeMs,
+		EndTimeMs:   indexed.EndTimeMs,
+		Text:        indexed.Text,
+		Lines:       splitSubtitleLines(indexed.Text),
+	}
 }
 
 func syncSubtitleIndexesFromFilesystem() error {
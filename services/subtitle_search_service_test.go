@@ -1,6 +1,7 @@
 package services
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -174,3 +175,64 @@ func setupSubtitleSearchTestDB(t *testing.T) {
 	}
 	database.DB = db
 }
+
+func TestSearchSubtitleHitsReturnsEveryHitWithContextAndCursor(t *testing.T) {
+	setupSubtitleSearchTestDB(t)
+	root := t.TempDir()
+	write := func(name string, lines ...string) models.Video {
+		videoPath := filepath.Join(root, name+".mp4")
+		if err := os.WriteFile(videoPath, []byte("fake-video"), 0644); err != nil {
+			t.Fatalf("写入视频文件失败: %v", err)
+		}
+		srt := ""
+		for i, line := range lines {
+			srt += fmt.Sprintf("%d\n00:00:%02d,000 --> 00:00:%02d,500\n%s\n\n", i+1, i, i, line)
+		}
+		if err := os.WriteFile(filepath.Join(root, name+".srt"), []byte(srt), 0644); err != nil {
+			t.Fatalf("写入字幕文件失败: %v", err)
+		}
+		video := models.Video{Name: name + ".mp4", Path: videoPath, Directory: root, Size: 10}
+		if err := database.DB.Create(&video).Error; err != nil {
+			t.Fatalf("创建视频失败: %v", err)
+		}
+		return video
+	}
+	multi := write("multi", "intro", "the rain starts", "thunder", "more rain", "sun", "outro")
+	single := write("single", "rain only once")
+	write("none", "nothing here")
+
+	svc := &SubtitleSearchService{}
+	query := SubtitleHitQuery{Keyword: "rain", ContextBefore: 1, ContextAfter: 1, Limit: 1}
+	first, err := svc.SearchSubtitleHits(query)
+	if err != nil {
+		t.Fatalf("搜索字幕命中失败: %v", err)
+	}
+	if len(first.Videos) != 1 || !first.HasMore || first.Videos[0].Video.ID != multi.ID {
+		t.Fatalf("第一页应返回命中更多的视频并提示还有下一页 page=%+v", first)
+	}
+	hits := first.Videos[0]
+	if hits.HitCount != 2 || len(hits.Hits) != 2 || hits.Hits[0].Segment.Index != 2 || hits.Hits[1].Segment.Index != 4 {
+		t.Fatalf("应按时间顺序返回视频内每条命中 hits=%+v", hits)
+	}
+	if len(hits.Hits[0].Before) != 1 || hits.Hits[0].Before[0].Text != "intro" ||
+		len(hits.Hits[0].After) != 1 || hits.Hits[0].After[0].Text != "thunder" {
+		t.Fatalf("命中应带前后各一条上下文 hit=%+v", hits.Hits[0])
+	}
+	if hits.Hits[1].Segment.StartTimeMs != 3000 || len(hits.Hits[1].Snippet.Highlights) != 1 {
+		t.Fatalf("命中应带时间与高亮片段 hit=%+v", hits.Hits[1])
+	}
+
+	query.CursorRank, query.CursorHitCount, query.CursorVideoID = first.NextCursorRank, first.NextCursorHitCount, first.NextCursorVideoID
+	second, err := svc.SearchSubtitleHits(query)
+	if err != nil || len(second.Videos) != 1 || second.HasMore || second.Videos[0].Video.ID != single.ID {
+		t.Fatalf("第二页应返回剩余视频 page=%+v err=%v", second, err)
+	}
+	if len(second.Videos[0].Hits[0].Before) != 0 || len(second.Videos[0].Hits[0].After) != 0 {
+		t.Fatalf("没有相邻字幕时上下文应为空 hit=%+v", second.Videos[0].Hits[0])
+	}
+
+	capped, err := svc.SearchSubtitleHits(SubtitleHitQuery{Keyword: "rain", MaxHitsPerVideo: 1, Limit: 10})
+	if err != nil || len(capped.Videos) != 2 || capped.Videos[0].HitCount != 2 || len(capped.Videos[0].Hits) != 1 {
+		t.Fatalf("单视频命中条数应受限但总数不变 page=%+v err=%v", capped, err)
+	}
+}
@@ -0,0 +1,383 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"video-master/database"
+	"video-master/models"
+
+	"gorm.io/gorm"
+)
+
+// 标签别名、合并与拆分。别名在搜索语法 tag: 与 AI 候选匹配中视同所属标签；
+// 合并把来源标签的视频关联、AI 审批记录和短视频偏好整体转移到目标标签，来源名称转为目标的别名。
+
+// AddTagAlias 为标签添加别名；别名不能与已有别名或任一标签名重复（不区分大小写）
+func (s *TagService) AddTagAlias(tagID uint, name string) (*models.TagAlias, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("别名不能为空")
+	}
+	var alias *models.TagAlias
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var tag models.Tag
+		if err := tx.First(&tag, tagID).Error; err != nil {
+			return err
+		}
+		created, err := addTagAliasInTx(tx, tag, name)
+		alias = created
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("添加标签别名 tag_id=%d alias=%s", tagID, name)
+	return alias, nil
+}
+
+// RemoveTagAlias 删除别名
+func (s *TagService) RemoveTagAlias(aliasID uint) error {
+	return database.DB.Delete(&models.TagAlias{}, aliasID).Error
+}
+
+func addTagAliasInTx(tx *gorm.DB, tag models.Tag, name string) (*models.TagAlias, error) {
+	if strings.EqualFold(tag.Name, name) {
+		return nil, ErrTagExists
+	}
+	var count int64
+	if err := tx.Model(&models.Tag{}).Where("LOWER(name) = LOWER(?)", name).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrTagExists
+	}
+	if err := tx.Model(&models.TagAlias{}).Where("LOWER(name) = LOWER(?)", name).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrTagExists
+	}
+	alias := &models.TagAlias{TagID: tag.ID, Name: name}
+	if err := tx.Create(alias).Error; err != nil {
+		return nil, err
+	}
+	return alias, nil
+}
+
+// findTagByAliasInTx 按别名查找所属的活跃标签，未命中返回 gorm.ErrRecordNotFound
+func findTagByAliasInTx(tx *gorm.DB, name string) (models.Tag, error) {
+	var tag models.Tag
+	err := tx.Where("id IN (?)", tx.Model(&models.TagAlias{}).Select("tag_id").Where("LOWER(name) = LOWER(?)", strings.TrimSpace(name))).
+		First(&tag).Error
+	return tag, err
+}
+
+// MergeTags 把 sourceIDs 合并到 targetID：视频关联、自动打标规则及其打标记录、AI 审批记录、AI 候选匹配、短视频偏好分数
+// 以及保存的搜索与智能播放列表条件中的标签 ID 全部转移到目标，来源的子标签挂到目标下，
+// 来源名称及其别名成为目标的别名，最后删除来源标签。
+// 全部在一个事务中完成，任一步失败整体回滚。
+func (s *TagService) MergeTags(sourceIDs []uint, targetID uint) error {
+	sources := make([]uint, 0, len(sourceIDs))
+	for _, id := range uniqueUintIDs(sourceIDs) {
+		if id != targetID {
+			sources = append(sources, id)
+		}
+	}
+	if len(sources) == 0 {
+		return fmt.Errorf("没有需要合并的标签")
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var target models.Tag
+		if err := tx.First(&target, targetID).Error; err != nil {
+			return fmt.Errorf("目标标签不存在: %w", err)
+		}
+		var sourceTags []models.Tag
+		if err := tx.Where("id IN ?", sources).Find(&sourceTags).Error; err != nil {
+			return err
+		}
+		if len(sourceTags) != len(sources) {
+			return fmt.Errorf("部分来源标签不存在")
+		}
+
+		// 自动打标规则改为给目标打标；打标记录须在补齐视频关联之前转移，才能区分视频原本是否已有目标标签
+		if err := tx.Model(&models.TagRule{}).Where("tag_id IN ?", sources).Update("tag_id", targetID).Error; err != nil {
+			return err
+		}
+		if err := moveTagRuleLinksInTx(tx, sources, targetID, nil); err != nil {
+			return err
+		}
+
+		// 视频关联：先补齐目标关联再删除来源关联，已有目标标签的视频不会重复
+		if err := tx.Exec("INSERT INTO video_tags (video_id, tag_id) SELECT DISTINCT video_id, ? FROM video_tags "+
+			"WHERE tag_id IN ? AND video_id NOT IN (SELECT video_id FROM video_tags WHERE tag_id = ?)",
+			targetID, sources, targetID).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM video_tags WHERE tag_id IN ?", sources).Error; err != nil {
+			return err
+		}
+
+		if err := moveAIApprovalRecordsInTx(tx, sources, targetID, nil); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.AITagCandidate{}).Where("matched_tag_id IN ?", sources).
+			Update("matched_tag_id", targetID).Error; err != nil {
+			return err
+		}
+		if err := mergeShortFeedTagPreferencesInTx(tx, sources, targetID); err != nil {
+			return err
+		}
+		// 保存的搜索与智能播放列表的条件里按 ID 引用标签，来源删除前改为目标
+		if err := rewriteFilterTagIDsInTx(tx, &models.SavedSearch{}, "filter", sources, targetID); err != nil {
+			return err
+		}
+		if err := rewriteFilterTagIDsInTx(tx, &models.Playlist{}, "smart_filter", sources, targetID); err != nil {
+			return err
+		}
+
+		// 来源的子标签挂到目标下；目标本身是来源的后代时改挂到最近的非来源祖先
+		if err := checkChildReparentInTx(tx, sources, targetID, targetID); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Tag{}).Where("parent_id IN ? AND id != ?", sources, targetID).
+			Update("parent_id", targetID).Error; err != nil {
+			if strings.Contains(strings.ToLower(err.Error()), "unique") {
+				return ErrTagExists
+			}
+			return err
+		}
+		sourceParents := make(map[uint]uint, len(sourceTags))
+		for _, tag := range sourceTags {
+			sourceParents[tag.ID] = tag.ParentID
+		}
+		newParent := target.ParentID
+		for guard := 0; guard < len(sourceTags); guard++ {
+			parent, ok := sourceParents[newParent]
+			if !ok {
+				break
+			}
+			newParent = parent
+		}
+		if newParent != target.ParentID {
+			if err := tx.Model(&models.Tag{}).Where("id = ?", targetID).Update("parent_id", newParent).Error; err != nil {
+				if strings.Contains(strings.ToLower(err.Error()), "unique") {
+					return ErrTagExists
+				}
+				return err
+			}
+		}
+
+		// 来源的别名转给目标，来源名称删除后也登记为别名
+		if err := tx.Model(&models.TagAlias{}).Where("tag_id IN ?", sources).Update("tag_id", targetID).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.Tag{}, sources).Error; err != nil {
+			return err
+		}
+		for _, tag := range sourceTags {
+			if strings.EqualFold(tag.Name, target.Name) {
+				continue
+			}
+			if _, err := addTagAliasInTx(tx, target, tag.Name); err != nil && !errors.Is(err, ErrTagExists) {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("合并标签失败 sources=%v target=%d err=%v", sources, targetID, err)
+		return err
+	}
+	log.Printf("合并标签 sources=%v target=%d", sources, targetID)
+	return nil
+}
+
+// SplitTag 在来源标签同级新建 newName 标签，并把 videoIDs 的来源标签关联（含规则打标记录）改为新标签
+func (s *TagService) SplitTag(sourceID uint, newName string, videoIDs []uint) (*models.Tag, error) {
+	newName = strings.TrimSpace(newName)
+	if newName == "" {
+		return nil, fmt.Errorf("新标签名称不能为空")
+	}
+	videoIDs = uniqueUintIDs(videoIDs)
+	if len(videoIDs) == 0 {
+		return nil, fmt.Errorf("没有选择需要拆分的视频")
+	}
+
+	var created models.Tag
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var source models.Tag
+		if err := tx.First(&source, sourceID).Error; err != nil {
+			return err
+		}
+		var count int64
+		if err := tx.Model(&models.Tag{}).Where("name = ? AND parent_id = ?", newName, source.ParentID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrTagExists
+		}
+		if _, err := findTagByAliasInTx(tx, newName); err == nil {
+			return ErrTagExists
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		tx.Unscoped().Where("name = ? AND parent_id = ? AND deleted_at IS NOT NULL", newName, source.ParentID).Delete(&models.Tag{})
+		if err := tx.Model(&models.Tag{}).Count(&count).Error; err != nil {
+			return err
+		}
+		created = models.Tag{Name: newName, ParentID: source.ParentID, Color: tagColorPalette[int(count)%len(tagColorPalette)]}
+		if err := tx.Create(&created).Error; err != nil {
+			return err
+		}
+
+		if err := moveTagRuleLinksInTx(tx, []uint{sourceID}, created.ID, videoIDs); err != nil {
+			return err
+		}
+		if err := tx.Exec("INSERT INTO video_tags (video_id, tag_id) SELECT video_id, ? FROM video_tags WHERE tag_id = ? AND video_id IN ?",
+			created.ID, sourceID, videoIDs).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM video_tags WHERE tag_id = ? AND video_id IN ?", sourceID, videoIDs).Error; err != nil {
+			return err
+		}
+		return moveAIApprovalRecordsInTx(tx, []uint{sourceID}, created.ID, videoIDs)
+	})
+	if err != nil {
+		log.Printf("拆分标签失败 source=%d name=%s videos=%d err=%v", sourceID, newName, len(videoIDs), err)
+		return nil, err
+	}
+	log.Printf("拆分标签 source=%d new_id=%d name=%s videos=%d", sourceID, created.ID, newName, len(videoIDs))
+	return &created, nil
+}
+
+// moveAIApprovalRecordsInTx 把审批记录改挂到目标标签；(video_id, tag_id) 唯一，目标已有记录的直接删除。
+// videoIDs 非空时只处理这些视频
+func moveAIApprovalRecordsInTx(tx *gorm.DB, sourceIDs []uint, targetID uint, videoIDs []uint) error {
+	query := tx.Where("tag_id IN ?", sourceIDs).Order("id")
+	if len(videoIDs) > 0 {
+		query = query.Where("video_id IN ?", videoIDs)
+	}
+	var records []models.AITagApprovalRecord
+	if err := query.Find(&records).Error; err != nil {
+		return err
+	}
+	for _, record := range records {
+		var count int64
+		if err := tx.Model(&models.AITagApprovalRecord{}).Where("video_id = ? AND tag_id = ?", record.VideoID, targetID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			if err := tx.Delete(&models.AITagApprovalRecord{}, record.ID).Error; err != nil {
+				return err
+			}
+			continue
+		}
+		if err := tx.Model(&models.AITagApprovalRecord{}).Where("id = ?", record.ID).Update("tag_id", targetID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moveTagRuleLinksInTx 把规则打标记录改挂到目标标签，须在转移 video_tags 之前调用。
+// 视频原本已有目标标签时删除记录（撤销规则不应删掉并非规则打上的标签），多个来源记录同一视频时只保留最早一条；
+// videoIDs 非空时只处理这些视频
+func moveTagRuleLinksInTx(tx *gorm.DB, sourceIDs []uint, targetID uint, videoIDs []uint) error {
+	scoped := func() *gorm.DB {
+		query := tx.Where("tag_id IN ?", sourceIDs)
+		if len(videoIDs) > 0 {
+			query = query.Where("video_id IN ?", videoIDs)
+		}
+		return query
+	}
+	if err := scoped().Where("video_id IN (SELECT video_id FROM video_tags WHERE tag_id = ?)", targetID).
+		Delete(&models.TagRuleLink{}).Error; err != nil {
+		return err
+	}
+	if err := scoped().Where("EXISTS (SELECT 1 FROM tag_rule_links earlier WHERE earlier.video_id = tag_rule_links.video_id "+
+		"AND earlier.tag_id IN ? AND earlier.id < tag_rule_links.id)", sourceIDs).
+		Delete(&models.TagRuleLink{}).Error; err != nil {
+		return err
+	}
+	return scoped().Model(&models.TagRuleLink{}).Update("tag_id", targetID).Error
+}
+
+// rewriteFilterTagIDsInTx 把 model 表 column 列（筛选条件 JSON）的 tag_ids 中的来源标签替换为目标并去重，其余字段原样保留
+func rewriteFilterTagIDsInTx(tx *gorm.DB, model interface{}, column string, sourceIDs []uint, targetID uint) error {
+	var rows []struct {
+		ID     uint
+		Filter string
+	}
+	if err := tx.Model(model).Select("id, "+column+" AS filter").Where(column+" LIKE ?", "%tag_ids%").Scan(&rows).Error; err != nil {
+		return err
+	}
+	sources := make(map[uint]bool, len(sourceIDs))
+	for _, id := range sourceIDs {
+		sources[id] = true
+	}
+	for _, row := range rows {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(row.Filter), &fields); err != nil {
+			log.Printf("跳过无法解析的筛选条件 column=%s id=%d err=%v", column, row.ID, err)
+			continue
+		}
+		var tagIDs []uint
+		if err := json.Unmarshal(fields["tag_ids"], &tagIDs); err != nil {
+			continue
+		}
+		changed := false
+		for i, id := range tagIDs {
+			if sources[id] {
+				tagIDs[i] = targetID
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+		encodedIDs, err := json.Marshal(uniqueUintIDs(tagIDs))
+		if err != nil {
+			return err
+		}
+		fields["tag_ids"] = encodedIDs
+		encoded, err := json.Marshal(fields)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(model).Where("id = ?", row.ID).Update(column, string(encoded)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeShortFeedTagPreferencesInTx 来源偏好分数累加到目标（每个标签只有一行偏好）
+func mergeShortFeedTagPreferencesInTx(tx *gorm.DB, sourceIDs []uint, targetID uint) error {
+	var prefs []models.ShortFeedTagPreference
+	if err := tx.Where("tag_id IN ?", sourceIDs).Find(&prefs).Error; err != nil {
+		return err
+	}
+	if len(prefs) == 0 {
+		return nil
+	}
+	total := 0.0
+	for _, pref := range prefs {
+		total += pref.Score
+	}
+	if err := tx.Where("tag_id IN ?", sourceIDs).Delete(&models.ShortFeedTagPreference{}).Error; err != nil {
+		return err
+	}
+	var target models.ShortFeedTagPreference
+	err := tx.Where("tag_id = ?", targetID).First(&target).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return tx.Create(&models.ShortFeedTagPreference{TagID: targetID, Score: total}).Error
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Model(&target).Update("score", target.Score+total).Error
+}
@@ -0,0 +1,420 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"video-master/database"
+	"video-master/models"
+
+	"gorm.io/gorm"
+)
+
+// 自动打标规则：按路径 glob、文件名正则、时长、分辨率、体积给视频打标签。
+// 新增视频（AddVideo、增量扫描）时自动执行已启用的规则，也可对整个库预览或执行；
+// 规则新建的关联记入 TagRuleLink，撤销时只删除这些关联，视频原有或手动添加的标签不受影响。
+
+const (
+	tagRuleBatchSize      = 500
+	maxTagRulePreviewRows = 200
+)
+
+type TagRuleService struct{}
+
+// TagRuleInput 新建或修改规则的参数
+type TagRuleInput struct {
+	Name        string  `json:"name"`
+	TagID       uint    `json:"tag_id"`
+	Enabled     bool    `json:"enabled"`
+	PathGlob    string  `json:"path_glob"`
+	NameRegex   string  `json:"name_regex"`
+	MinDuration float64 `json:"min_duration"`
+	MaxDuration float64 `json:"max_duration"`
+	MinHeight   int     `json:"min_height"`
+	MaxHeight   int     `json:"max_height"`
+	MinSize     int64   `json:"min_size"`
+	MaxSize     int64   `json:"max_size"`
+}
+
+// TagRuleChange 一条（将要）新建的关联
+type TagRuleChange struct {
+	RuleID    uint   `json:"rule_id"`
+	RuleName  string `json:"rule_name"`
+	TagID     uint   `json:"tag_id"`
+	TagName   string `json:"tag_name"`
+	VideoID   uint   `json:"video_id"`
+	VideoName string `json:"video_name"`
+	VideoPath string `json:"video_path"`
+}
+
+// TagRuleRunResult 规则执行结果；DryRun 时 Linked 为将要新建的关联数，数据库不变。
+// Changes 最多返回 maxTagRulePreviewRows 条
+type TagRuleRunResult struct {
+	DryRun  bool            `json:"dry_run"`
+	Rules   int             `json:"rules"`
+	Scanned int             `json:"scanned"`
+	Linked  int             `json:"linked"`
+	Changes []TagRuleChange `json:"changes"`
+}
+
+// compiledTagRule 预编译的规则
+type compiledTagRule struct {
+	rule    models.TagRule
+	pathRe  *regexp.Regexp
+	nameRe  *regexp.Regexp
+	tagName string
+}
+
+// GetTagRules 列出全部规则（含目标标签）
+func (s *TagRuleService) GetTagRules() ([]models.TagRule, error) {
+	var rules []models.TagRule
+	err := database.DB.Preload("Tag").Order("name").Find(&rules).Error
+	return rules, err
+}
+
+// CreateTagRule 新建规则
+func (s *TagRuleService) CreateTagRule(input TagRuleInput) (*models.TagRule, error) {
+	rule := models.TagRule{}
+	if err := applyTagRuleInput(&rule, input); err != nil {
+		return nil, err
+	}
+	if err := database.DB.Omit("Tag").Create(&rule).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// UpdateTagRule 修改规则；已建立的关联保留，需要时先撤销再执行
+func (s *TagRuleService) UpdateTagRule(id uint, input TagRuleInput) error {
+	var rule models.TagRule
+	if err := database.DB.First(&rule, id).Error; err != nil {
+		return err
+	}
+	if err := applyTagRuleInput(&rule, input); err != nil {
+		return err
+	}
+	return database.DB.Omit("Tag").Save(&rule).Error
+}
+
+// DeleteTagRule 删除规则；revert 为 true 时先撤销其建立的关联，否则关联保留为普通标签
+func (s *TagRuleService) DeleteTagRule(id uint, revert bool) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if revert {
+			if _, err := revertTagRuleInTx(tx, id); err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("rule_id = ?", id).Delete(&models.TagRuleLink{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.TagRule{}, id).Error
+	})
+}
+
+// PreviewTagRules 对整个库试运行规则（ruleID 为 0 时为全部已启用规则），不写数据库
+func (s *TagRuleService) PreviewTagRules(ruleID uint) (*TagRuleRunResult, error) {
+	return runTagRulesOnLibrary(ruleID, true)
+}
+
+// RunTagRules 对整个库执行规则（ruleID 为 0 时为全部已启用规则）
+func (s *TagRuleService) RunTagRules(ruleID uint) (*TagRuleRunResult, error) {
+	return runTagRulesOnLibrary(ruleID, false)
+}
+
+// RevertTagRule 删除规则建立的全部关联，返回删除的关联数
+func (s *TagRuleService) RevertTagRule(ruleID uint) (int, error) {
+	var removed int
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		count, err := revertTagRuleInTx(tx, ruleID)
+		removed = count
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	log.Printf("撤销自动打标规则 rule_id=%d removed=%d", ruleID, removed)
+	return removed, nil
+}
+
+func revertTagRuleInTx(tx *gorm.DB, ruleID uint) (int, error) {
+	var links []models.TagRuleLink
+	if err := tx.Where("rule_id = ?", ruleID).Find(&links).Error; err != nil {
+		return 0, err
+	}
+	for _, link := range links {
+		if err := tx.Exec("DELETE FROM video_tags WHERE video_id = ? AND tag_id = ?", link.VideoID, link.TagID).Error; err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Where("rule_id = ?", ruleID).Delete(&models.TagRuleLink{}).Error; err != nil {
+		return 0, err
+	}
+	return len(links), nil
+}
+
+func applyTagRuleInput(rule *models.TagRule, input TagRuleInput) error {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return fmt.Errorf("规则名称不能为空")
+	}
+	var sameName int64
+	if err := database.DB.Model(&models.TagRule{}).Where("name = ? AND id <> ?", name, rule.ID).Count(&sameName).Error; err != nil {
+		return err
+	}
+	if sameName > 0 {
+		return fmt.Errorf("已存在同名规则: %s", name)
+	}
+	var tag models.Tag
+	if err := database.DB.First(&tag, input.TagID).Error; err != nil {
+		return fmt.Errorf("目标标签不存在: %w", err)
+	}
+
+	candidate := models.TagRule{
+		ID:          rule.ID,
+		Name:        name,
+		TagID:       input.TagID,
+		Enabled:     input.Enabled,
+		PathGlob:    strings.TrimSpace(input.PathGlob),
+		NameRegex:   strings.TrimSpace(input.NameRegex),
+		MinDuration: input.MinDuration,
+		MaxDuration: input.MaxDuration,
+		MinHeight:   input.MinHeight,
+		MaxHeight:   input.MaxHeight,
+		MinSize:     input.MinSize,
+		MaxSize:     input.MaxSize,
+		CreatedAt:   rule.CreatedAt,
+	}
+	if candidate.MinDuration < 0 || candidate.MaxDuration < 0 || candidate.MinHeight < 0 || candidate.MaxHeight < 0 || candidate.MinSize < 0 || candidate.MaxSize < 0 {
+		return fmt.Errorf("规则范围不能为负数")
+	}
+	if (candidate.MaxSize > 0 && candidate.MinSize >= candidate.MaxSize) ||
+		(candidate.MaxHeight > 0 && candidate.MinHeight > candidate.MaxHeight) ||
+		(candidate.MaxDuration > 0 && candidate.MinDuration >= candidate.MaxDuration) {
+		return fmt.Errorf("规则范围下限不能超过上限")
+	}
+	if candidate.PathGlob == "" && candidate.NameRegex == "" && candidate.MinDuration == 0 && candidate.MaxDuration == 0 &&
+		candidate.MinHeight == 0 && candidate.MaxHeight == 0 && candidate.MinSize == 0 && candidate.MaxSize == 0 {
+		return fmt.Errorf("规则至少需要一个条件")
+	}
+	if _, err := compileTagRule(candidate); err != nil {
+		return err
+	}
+	*rule = candidate
+	return nil
+}
+
+func compileTagRule(rule models.TagRule) (compiledTagRule, error) {
+	compiled := compiledTagRule{rule: rule, tagName: rule.Tag.Name}
+	if rule.PathGlob != "" {
+		re, err := regexp.Compile(tagRuleGlobToRegexp(rule.PathGlob))
+		if err != nil {
+			return compiled, fmt.Errorf("路径通配符无效: %w", err)
+		}
+		compiled.pathRe = re
+	}
+	if rule.NameRegex != "" {
+		re, err := regexp.Compile(rule.NameRegex)
+		if err != nil {
+			return compiled, fmt.Errorf("文件名正则无效: %w", err)
+		}
+		compiled.nameRe = re
+	}
+	return compiled, nil
+}
+
+// tagRuleGlobToRegexp 把路径 glob 转成不区分大小写的正则：** 匹配任意字符（含 /），* 与 ? 不跨目录，
+// **/ 也可匹配零层目录
+func tagRuleGlobToRegexp(glob string) string {
+	glob = strings.ReplaceAll(glob, "\\", "/")
+	var b strings.Builder
+	b.WriteString("(?i)^")
+	runes := []rune(glob)
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; r {
+		case '*':
+			if i+1 < len(runes) && runes[i+1] == '*' {
+				i++
+				if i+1 < len(runes) && runes[i+1] == '/' {
+					i++
+					b.WriteString("(?:.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+				continue
+			}
+			b.WriteString("[^/]*")
+		case '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+func (r compiledTagRule) matches(video models.Video) bool {
+	rule := r.rule
+	if r.pathRe != nil && !r.pathRe.MatchString(filepath.ToSlash(video.Path)) {
+		return false
+	}
+	if r.nameRe != nil && !r.nameRe.MatchString(video.Name) {
+		return false
+	}
+	if rule.MinDuration > 0 && video.Duration < rule.MinDuration {
+		return false
+	}
+	if rule.MaxDuration > 0 && video.Duration >= rule.MaxDuration {
+		return false
+	}
+	if rule.MinHeight > 0 && video.Height < rule.MinHeight {
+		return false
+	}
+	if rule.MaxHeight > 0 && video.Height > rule.MaxHeight {
+		return false
+	}
+	if rule.MinSize > 0 && video.Size < rule.MinSize {
+		return false
+	}
+	if rule.MaxSize > 0 && video.Size >= rule.MaxSize {
+		return false
+	}
+	return true
+}
+
+// loadCompiledTagRules 加载规则；ruleID 为 0 时加载全部已启用规则。目标标签已删除或规则无效时跳过
+func loadCompiledTagRules(ruleID uint) ([]compiledTagRule, error) {
+	query := database.DB.Preload("Tag").Order("id")
+	if ruleID > 0 {
+		query = query.Where("id = ?", ruleID)
+	} else {
+		query = query.Where("enabled = ?", true)
+	}
+	var rules []models.TagRule
+	if err := query.Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	if ruleID > 0 && len(rules) == 0 {
+		return nil, fmt.Errorf("规则不存在: %d", ruleID)
+	}
+	compiled := make([]compiledTagRule, 0, len(rules))
+	for _, rule := range rules {
+		if rule.Tag.ID == 0 {
+			continue
+		}
+		item, err := compileTagRule(rule)
+		if err != nil {
+			log.Printf("跳过无效的自动打标规则 id=%d err=%v", rule.ID, err)
+			continue
+		}
+		compiled = append(compiled, item)
+	}
+	return compiled, nil
+}
+
+func runTagRulesOnLibrary(ruleID uint, dryRun bool) (*TagRuleRunResult, error) {
+	rules, err := loadCompiledTagRules(ruleID)
+	if err != nil {
+		return nil, err
+	}
+	result := &TagRuleRunResult{DryRun: dryRun, Rules: len(rules), Changes: []TagRuleChange{}}
+	if len(rules) == 0 {
+		return result, nil
+	}
+	var batch []models.Video
+	err = database.DB.Model(&models.Video{}).Order("id").FindInBatches(&batch, tagRuleBatchSize, func(tx *gorm.DB, _ int) error {
+		result.Scanned += len(batch)
+		return applyCompiledTagRules(rules, batch, dryRun, result)
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("自动打标规则执行 rule_id=%d dry_run=%v rules=%d scanned=%d linked=%d", ruleID, dryRun, result.Rules, result.Scanned, result.Linked)
+	return result, nil
+}
+
+// applyCompiledTagRules 对一批视频执行规则；视频已有目标标签时不重复关联也不记录
+func applyCompiledTagRules(rules []compiledTagRule, videos []models.Video, dryRun bool, result *TagRuleRunResult) error {
+	if len(videos) == 0 {
+		return nil
+	}
+	ids := make([]uint, 0, len(videos))
+	for _, video := range videos {
+		ids = append(ids, video.ID)
+	}
+	type videoTagPair struct {
+		VideoID uint
+		TagID   uint
+	}
+	var pairs []videoTagPair
+	if err := database.DB.Table("video_tags").Select("video_id, tag_id").Where("video_id IN ?", ids).Scan(&pairs).Error; err != nil {
+		return err
+	}
+	linked := make(map[[2]uint]bool, len(pairs))
+	for _, pair := range pairs {
+		linked[[2]uint{pair.VideoID, pair.TagID}] = true
+	}
+
+	for _, video := range videos {
+		for _, rule := range rules {
+			key := [2]uint{video.ID, rule.rule.TagID}
+			if linked[key] || !rule.matches(video) {
+				continue
+			}
+			linked[key] = true
+			if !dryRun {
+				err := database.DB.Transaction(func(tx *gorm.DB) error {
+					if err := tx.Exec("INSERT INTO video_tags (video_id, tag_id) VALUES (?, ?)", video.ID, rule.rule.TagID).Error; err != nil {
+						return err
+					}
+					return tx.Create(&models.TagRuleLink{RuleID: rule.rule.ID, VideoID: video.ID, TagID: rule.rule.TagID}).Error
+				})
+				if err != nil {
+					return err
+				}
+			}
+			result.Linked++
+			if len(result.Changes) < maxTagRulePreviewRows {
+				result.Changes = append(result.Changes, TagRuleChange{
+					RuleID:    rule.rule.ID,
+					RuleName:  rule.rule.Name,
+					TagID:     rule.rule.TagID,
+					TagName:   rule.tagName,
+					VideoID:   video.ID,
+					VideoName: video.Name,
+					VideoPath: video.Path,
+				})
+			}
+		}
+	}
+	return nil
+}
+
+// applyTagRulesToNewVideos 新增视频入库后执行已启用规则；失败只记日志，不影响入库，返回新建的关联数
+func applyTagRulesToNewVideos(videos []models.Video) int {
+	if len(videos) == 0 {
+		return 0
+	}
+	rules, err := loadCompiledTagRules(0)
+	if err != nil {
+		log.Printf("加载自动打标规则失败: %v", err)
+		return 0
+	}
+	if len(rules) == 0 {
+		return 0
+	}
+	result := &TagRuleRunResult{}
+	for start := 0; start < len(videos); start += tagRuleBatchSize {
+		end := min(start+tagRuleBatchSize, len(videos))
+		if err := applyCompiledTagRules(rules, videos[start:end], false, result); err != nil {
+			log.Printf("新增视频执行自动打标规则失败: %v", err)
+			break
+		}
+	}
+	if result.Linked > 0 {
+		log.Printf("新增视频自动打标 videos=%d linked=%d", len(videos), result.Linked)
+	}
+	return result.Linked
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+	"video-master/database"
+	"video-master/models"
+)
+
+func TestTagRuleGlobToRegexp(t *testing.T) {
+	cases := []struct {
+		glob  string
+		path  string
+		match bool
+	}{
+		{"/Courses/**", "/courses/go/ep1.mp4", true},
+		{"/Courses/**", "/Movies/ep1.mp4", false},
+		{"/Courses/*.mp4", "/Courses/go/ep1.mp4", false},
+		{"**/教程/*.mp4", "/data/教程/第一课.mp4", true},
+		{"**/教程/*.mp4", "教程/第一课.mp4", true},
+		{"**/教程/*.mp4", "/data/教程/第一课.mkv", false},
+		{`D:\Videos\**`, "D:/Videos/a/b.mp4", true},
+		{"/clips/ep?.mp4", "/clips/ep1.mp4", true},
+		{"/clips/ep?.mp4", "/clips/ep10.mp4", false},
+	}
+	for _, c := range cases {
+		re := regexp.MustCompile(tagRuleGlobToRegexp(c.glob))
+		if got := re.MatchString(c.path); got != c.match {
+			t.Fatalf("glob=%s path=%s 期望 %v got=%v", c.glob, c.path, c.match, got)
+		}
+	}
+}
+
+func TestTagRuleValidation(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	tag := createShortFeedTag(t, "课程")
+	svc := &TagRuleService{}
+
+	invalid := []TagRuleInput{
+		{Name: "空条件", TagID: tag.ID},
+		{Name: "坏正则", TagID: tag.ID, NameRegex: "ep(\\d+"},
+		{Name: "范围颠倒", TagID: tag.ID, MinDuration: 600, MaxDuration: 60},
+		{Name: "标签缺失", TagID: 9999, PathGlob: "/a/**"},
+		{Name: " ", TagID: tag.ID, PathGlob: "/a/**"},
+	}
+	for _, input := range invalid {
+		if _, err := svc.CreateTagRule(input); err == nil {
+			t.Fatalf("无效规则应被拒绝: %+v", input)
+		}
+	}
+	rule, err := svc.CreateTagRule(TagRuleInput{Name: "课程目录", TagID: tag.ID, PathGlob: "/Courses/**"})
+	if err != nil {
+		t.Fatalf("创建规则失败: %v", err)
+	}
+	if rule.Enabled {
+		t.Fatalf("未勾选启用的规则应保持停用")
+	}
+	if _, err := svc.CreateTagRule(TagRuleInput{Name: "课程目录", TagID: tag.ID, PathGlob: "/x/**"}); err == nil {
+		t.Fatalf("同名规则应被拒绝")
+	}
+}
+
+func TestTagRulePreviewRunAndRevert(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	root := t.TempDir()
+	tag := createShortFeedTag(t, "长视频")
+	manual := createShortFeedVideo(t, root, "manual.mp4", 3600, false, &tag)
+	long := createShortFeedVideo(t, root, "long.mp4", 1800, false)
+	createShortFeedVideo(t, root, "short.mp4", 30, false)
+
+	svc := &TagRuleService{}
+	rule, err := svc.CreateTagRule(TagRuleInput{Name: "超过 20 分钟", TagID: tag.ID, Enabled: true, MinDuration: 1200})
+	if err != nil {
+		t.Fatalf("创建规则失败: %v", err)
+	}
+
+	preview, err := svc.PreviewTagRules(rule.ID)
+	if err != nil {
+		t.Fatalf("预览失败: %v", err)
+	}
+	if !preview.DryRun || preview.Scanned != 3 || preview.Linked != 1 || len(preview.Changes) != 1 || preview.Changes[0].VideoID != long.ID {
+		t.Fatalf("预览结果错误: %+v", preview)
+	}
+	if countRows(t, "video_tags") != 1 || countRows(t, "tag_rule_links") != 0 {
+		t.Fatalf("预览不应修改数据库")
+	}
+
+	result, err := svc.RunTagRules(0)
+	if err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	if result.DryRun || result.Linked != 1 || countRows(t, "video_tags") != 2 || countRows(t, "tag_rule_links") != 1 {
+		t.Fatalf("执行应只关联缺少标签的视频 result=%+v", result)
+	}
+	if again, _ := svc.RunTagRules(rule.ID); again.Linked != 0 {
+		t.Fatalf("重复执行不应再新建关联 result=%+v", again)
+	}
+
+	removed, err := svc.RevertTagRule(rule.ID)
+	if err != nil || removed != 1 {
+		t.Fatalf("撤销失败 removed=%d err=%v", removed, err)
+	}
+	var tagged []uint
+	database.DB.Table("video_tags").Pluck("video_id", &tagged)
+	if len(tagged) != 1 || tagged[0] != manual.ID {
+		t.Fatalf("撤销只应删除规则建立的关联 tagged=%v", tagged)
+	}
+}
+
+func TestTagRulesApplyToNewVideos(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	mockFFProbe(t, t.TempDir())
+	root := t.TempDir()
+	course := createShortFeedTag(t, "课程")
+	clip := createShortFeedTag(t, "片段")
+	svc := &TagRuleService{}
+	if _, err := svc.CreateTagRule(TagRuleInput{Name: "课程目录", TagID: course.ID, Enabled: true, PathGlob: filepath.ToSlash(root) + "/courses/**"}); err != nil {
+		t.Fatalf("创建规则失败: %v", err)
+	}
+	if _, err := svc.CreateTagRule(TagRuleInput{Name: "停用规则", TagID: clip.ID, NameRegex: `\.mp4$`}); err != nil {
+		t.Fatalf("创建规则失败: %v", err)
+	}
+
+	videoService := &VideoService{}
+	addedPath := filepath.Join(root, "courses", "go", "ep1.mp4")
+	mustCreateFile(t, addedPath)
+	mustSetFileModTime(t, addedPath, time.Now().Add(-10*time.Minute))
+	video, err := videoService.AddVideo(addedPath)
+	if err != nil {
+		t.Fatalf("添加视频失败: %v", err)
+	}
+	var tagIDs []uint
+	database.DB.Table("video_tags").Where("video_id = ?", video.ID).Pluck("tag_id", &tagIDs)
+	if len(tagIDs) != 1 || tagIDs[0] != course.ID {
+		t.Fatalf("新增视频应执行已启用规则且跳过停用规则 tagIDs=%v", tagIDs)
+	}
+
+	syncedPath := filepath.Join(root, "courses", "rust", "ep2.mp4")
+	mustCreateFile(t, syncedPath)
+	mustSetFileModTime(t, syncedPath, time.Now().Add(-10*time.Minute))
+	mustCreateFile(t, filepath.Join(root, "other", "movie.mp4"))
+	mustSetFileModTime(t, filepath.Join(root, "other", "movie.mp4"), time.Now().Add(-10*time.Minute))
+	result := videoService.SyncScanDirectories([]models.ScanDirectory{{Path: root, Alias: "root"}})
+	if result.Added != 2 || result.RuleTagged != 1 {
+		t.Fatalf("增量扫描应对新增视频执行规则: %#v", result)
+	}
+}
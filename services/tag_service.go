@@ -1,10 +1,14 @@
 package services
 
 import (
+	"fmt"
 	"log"
+	"sort"
 	"strings"
 	"video-master/database"
 	"video-master/models"
+
+	"gorm.io/gorm"
 )
 
 type TagService struct{}
@@ -12,7 +16,7 @@ type TagService struct{}
 // GetAllTags 获取所有标签
 func (s *TagService) GetAllTags() ([]models.Tag, error) {
 	var tags []models.Tag
-	err := database.DB.Order("name").Find(&tags).Error
+	err := database.DB.Preload("Aliases").Order("name").Find(&tags).Error
 	return tags, err
 }
 
@@ -33,13 +37,29 @@ var tagColorPalette = []string{
 	"#84cc16", // 黄绿
 }
 
-// CreateTag 创建标签
+// CreateTag 创建顶层标签
 func (s *TagService) CreateTag(name, color string) (*models.Tag, error) {
-	// 先检查是否存在活跃的同名标签
+	return s.CreateChildTag(name, color, 0)
+}
+
+// CreateChildTag 在 parentID 下创建标签（0 为顶层），名称在同一父级下唯一
+func (s *TagService) CreateChildTag(name, color string, parentID uint) (*models.Tag, error) {
+	if parentID > 0 {
+		var parent models.Tag
+		if err := database.DB.First(&parent, parentID).Error; err != nil {
+			return nil, fmt.Errorf("父标签不存在: %w", err)
+		}
+	}
+
+	// 先检查同一父级下是否存在活跃的同名标签
 	var existing models.Tag
-	if err := database.DB.Where("name = ?", name).First(&existing).Error; err == nil {
+	if err := database.DB.Where("name = ? AND parent_id = ?", name, parentID).First(&existing).Error; err == nil {
 		return &existing, ErrTagExists
 	}
+	// 名称已是某个标签的别名时视为已存在，返回别名所属标签
+	if aliased, err := findTagByAliasInTx(database.DB, name); err == nil {
+		return &aliased, ErrTagExists
+	}
 
 	// 颜色为空时自动分配
 	if color == "" {
@@ -50,7 +70,7 @@ func (s *TagService) CreateTag(name, color string) (*models.Tag, error) {
 
 	// 检查是否存在被软删除的同名标签，如果有则恢复
 	var softDeleted models.Tag
-	if err := database.DB.Unscoped().Where("name = ? AND deleted_at IS NOT NULL", name).First(&softDeleted).Error; err == nil {
+	if err := database.DB.Unscoped().Where("name = ? AND parent_id = ? AND deleted_at IS NOT NULL", name, parentID).First(&softDeleted).Error; err == nil {
 		// 恢复软删除的标签
 		softDeleted.Color = color
 		softDeleted.DeletedAt.Clear()
@@ -63,8 +83,9 @@ func (s *TagService) CreateTag(name, color string) (*models.Tag, error) {
 	}
 
 	tag := &models.Tag{
-		Name:  name,
-		Color: color,
+		Name:     name,
+		ParentID: parentID,
+		Color:    color,
 	}
 	err := database.DB.Create(tag).Error
 	if err != nil && strings.Contains(strings.ToLower(err.Error()), "unique") {
@@ -75,14 +96,21 @@ func (s *TagService) CreateTag(name, color string) (*models.Tag, error) {
 
 // UpdateTag 更新标签
 func (s *TagService) UpdateTag(id uint, name, color string) error {
-	// 检查是否存在同名的活跃标签（排除自身）
+	var tag models.Tag
+	if err := database.DB.First(&tag, id).Error; err != nil {
+		return err
+	}
+	// 检查同一父级下是否存在同名的活跃标签（排除自身）
 	var existing models.Tag
-	if err := database.DB.Where("name = ? AND id != ?", name, id).First(&existing).Error; err == nil {
+	if err := database.DB.Where("name = ? AND parent_id = ? AND id != ?", name, tag.ParentID, id).First(&existing).Error; err == nil {
+		return ErrTagExists
+	}
+	if _, err := findTagByAliasInTx(database.DB, name); err == nil {
 		return ErrTagExists
 	}
 
 	// 如果存在被软删除的同名标签，先彻底删除它以避免唯一约束冲突
-	database.DB.Unscoped().Where("name = ? AND deleted_at IS NOT NULL", name).Delete(&models.Tag{})
+	database.DB.Unscoped().Where("name = ? AND parent_id = ? AND deleted_at IS NOT NULL", name, tag.ParentID).Delete(&models.Tag{})
 
 	return database.DB.Model(&models.Tag{}).Where("id = ?", id).Updates(map[string]interface{}{
 		"name":  name,
@@ -90,18 +118,195 @@ func (s *TagService) UpdateTag(id uint, name, color string) error {
 	}).Error
 }
 
-// DeleteTag 删除标签
+// MoveTag 把标签（连同其子标签）移到 parentID 下，0 为顶层
+func (s *TagService) MoveTag(id, parentID uint) error {
+	var tag models.Tag
+	if err := database.DB.First(&tag, id).Error; err != nil {
+		return err
+	}
+	if tag.ParentID == parentID {
+		return nil
+	}
+	if parentID > 0 {
+		var parent models.Tag
+		if err := database.DB.First(&parent, parentID).Error; err != nil {
+			return fmt.Errorf("父标签不存在: %w", err)
+		}
+		subtree, err := tagSubtreeIDs([]uint{id})
+		if err != nil {
+			return err
+		}
+		for _, subtreeID := range subtree {
+			if subtreeID == parentID {
+				return ErrTagCycle
+			}
+		}
+	}
+
+	var existing models.Tag
+	if err := database.DB.Where("name = ? AND parent_id = ? AND id != ?", tag.Name, parentID, id).First(&existing).Error; err == nil {
+		return ErrTagExists
+	}
+	database.DB.Unscoped().Where("name = ? AND parent_id = ? AND deleted_at IS NOT NULL", tag.Name, parentID).Delete(&models.Tag{})
+	return database.DB.Model(&models.Tag{}).Where("id = ?", id).Update("parent_id", parentID).Error
+}
+
+// DeleteTag 删除标签，子标签上移到被删标签的父级
 func (s *TagService) DeleteTag(id uint) error {
 	var tag models.Tag
 	if err := database.DB.First(&tag, id).Error; err != nil {
 		log.Printf("删除标签失败: 未找到 id=%d err=%v", id, err)
 		return err
 	}
-	// 清理关联关系
-	if err := database.DB.Model(&tag).Association("Videos").Clear(); err != nil {
-		log.Printf("清理标签关联失败 id=%d err=%v", id, err)
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tag_id = ?", id).Delete(&models.TagAlias{}).Error; err != nil {
+			return err
+		}
+		// 清理关联关系
+		if err := tx.Model(&tag).Association("Videos").Clear(); err != nil {
+			log.Printf("清理标签关联失败 id=%d err=%v", id, err)
+			return err
+		}
+		log.Printf("删除标签 id=%d name=%s", id, tag.Name)
+		if err := tx.Delete(&tag).Error; err != nil {
+			return err
+		}
+		// 先删除自身再上移子标签，与自身同名的子标签可以接替它的位置
+		if err := checkChildReparentInTx(tx, []uint{id}, tag.ParentID, 0); err != nil {
+			log.Printf("上移子标签失败 id=%d err=%v", id, err)
+			return err
+		}
+		if err := tx.Model(&models.Tag{}).Where("parent_id = ?", id).Update("parent_id", tag.ParentID).Error; err != nil {
+			log.Printf("上移子标签失败 id=%d err=%v", id, err)
+			return err
+		}
+		return nil
+	})
+}
+
+// checkChildReparentInTx 在把 parentIDs 的子标签（excludeID 除外）挂到 newParentID 下之前检查同名冲突。
+// 唯一索引也覆盖软删除的行：目标位置已软删除的同名标签被彻底删除；与活跃标签或彼此重名时返回带名称的 ErrTagExists
+func checkChildReparentInTx(tx *gorm.DB, parentIDs []uint, newParentID uint, excludeID uint) error {
+	var children []models.Tag
+	if err := tx.Where("parent_id IN ? AND id != ?", parentIDs, excludeID).Find(&children).Error; err != nil {
+		return err
+	}
+	if len(children) == 0 {
+		return nil
+	}
+	clashes := make(map[string]bool)
+	seen := make(map[string]bool, len(children))
+	names := make([]string, 0, len(children))
+	childIDs := make([]uint, 0, len(children))
+	for _, child := range children {
+		if seen[child.Name] {
+			clashes[child.Name] = true
+		}
+		seen[child.Name] = true
+		names = append(names, child.Name)
+		childIDs = append(childIDs, child.ID)
+	}
+
+	var existing []models.Tag
+	if err := tx.Unscoped().Where("parent_id = ? AND name IN ? AND id NOT IN ?", newParentID, names, childIDs).
+		Find(&existing).Error; err != nil {
 		return err
 	}
-	log.Printf("删除标签 id=%d name=%s", id, tag.Name)
-	return database.DB.Delete(&tag).Error
+	var staleIDs []uint
+	for _, tag := range existing {
+		if tag.DeletedAt.IsValid() {
+			staleIDs = append(staleIDs, tag.ID)
+			continue
+		}
+		clashes[tag.Name] = true
+	}
+	if len(clashes) > 0 {
+		clashNames := make([]string, 0, len(clashes))
+		for name := range clashes {
+			clashNames = append(clashNames, name)
+		}
+		sort.Strings(clashNames)
+		return fmt.Errorf("%w: 子标签与目标位置的标签同名: %s", ErrTagExists, strings.Join(clashNames, "、"))
+	}
+	if len(staleIDs) > 0 {
+		return tx.Unscoped().Delete(&models.Tag{}, staleIDs).Error
+	}
+	return nil
+}
+
+// TagTreeNode 标签树节点；Path 为从顶层到自身的名称路径，用 “/” 连接
+type TagTreeNode struct {
+	Tag      models.Tag    `json:"tag"`
+	Path     string        `json:"path"`
+	Children []TagTreeNode `json:"children"`
+}
+
+// GetTagTree 以树形返回全部标签，同级按名称排序
+func (s *TagService) GetTagTree() ([]TagTreeNode, error) {
+	tags, err := s.GetAllTags()
+	if err != nil {
+		return nil, err
+	}
+	return buildTagTree(tags), nil
+}
+
+func buildTagTree(tags []models.Tag) []TagTreeNode {
+	byID := make(map[uint]bool, len(tags))
+	for _, tag := range tags {
+		byID[tag.ID] = true
+	}
+	children := make(map[uint][]models.Tag)
+	for _, tag := range tags {
+		parentID := tag.ParentID
+		if !byID[parentID] {
+			// 父标签已删除或不存在时当作顶层
+			parentID = 0
+		}
+		children[parentID] = append(children[parentID], tag)
+	}
+	var build func(parentID uint, prefix string, visited map[uint]bool) []TagTreeNode
+	build = func(parentID uint, prefix string, visited map[uint]bool) []TagTreeNode {
+		nodes := []TagTreeNode{}
+		items := children[parentID]
+		sort.SliceStable(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+		for _, tag := range items {
+			if visited[tag.ID] {
+				continue
+			}
+			visited[tag.ID] = true
+			path := tag.Name
+			if prefix != "" {
+				path = prefix + "/" + tag.Name
+			}
+			nodes = append(nodes, TagTreeNode{Tag: tag, Path: path, Children: build(tag.ID, path, visited)})
+		}
+		return nodes
+	}
+	return build(0, "", map[uint]bool{})
+}
+
+// tagSubtreeIDs 返回 rootIDs 及其全部后代标签 ID（不含已删除标签）
+func tagSubtreeIDs(rootIDs []uint) ([]uint, error) {
+	var tags []models.Tag
+	if err := database.DB.Select("id", "parent_id").Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	children := make(map[uint][]uint)
+	for _, tag := range tags {
+		children[tag.ParentID] = append(children[tag.ParentID], tag.ID)
+	}
+	seen := make(map[uint]bool)
+	result := make([]uint, 0, len(rootIDs))
+	queue := append([]uint{}, rootIDs...)
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if id == 0 || seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+		queue = append(queue, children[id]...)
+	}
+	return result, nil
 }
@@ -0,0 +1,465 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"video-master/database"
+	"video-master/models"
+)
+
+func TestTagHierarchyUniquePerParentAndMove(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	svc := &TagService{}
+
+	sports, err := svc.CreateTag("运动", "")
+	if err != nil {
+		t.Fatalf("创建顶层标签失败: %v", err)
+	}
+	music, _ := svc.CreateTag("音乐", "")
+	ball, err := svc.CreateChildTag("球类", "", sports.ID)
+	if err != nil {
+		t.Fatalf("创建子标签失败: %v", err)
+	}
+	if _, err := svc.CreateChildTag("现场", "", sports.ID); err != nil {
+		t.Fatalf("创建子标签失败: %v", err)
+	}
+	if _, err := svc.CreateChildTag("现场", "", music.ID); err != nil {
+		t.Fatalf("不同父级下应允许同名标签: %v", err)
+	}
+	if _, err := svc.CreateChildTag("现场", "", sports.ID); !errors.Is(err, ErrTagExists) {
+		t.Fatalf("同一父级下重名应返回 ErrTagExists，got=%v", err)
+	}
+	if _, err := svc.CreateChildTag("x", "", 9999); err == nil {
+		t.Fatalf("父标签不存在应返回错误")
+	}
+
+	if err := svc.MoveTag(sports.ID, ball.ID); !errors.Is(err, ErrTagCycle) {
+		t.Fatalf("移到自身子标签下应返回 ErrTagCycle，got=%v", err)
+	}
+	if err := svc.MoveTag(ball.ID, music.ID); err != nil {
+		t.Fatalf("移动标签失败: %v", err)
+	}
+
+	tree, err := svc.GetTagTree()
+	if err != nil {
+		t.Fatalf("获取标签树失败: %v", err)
+	}
+	paths := map[string]bool{}
+	var walk func(nodes []TagTreeNode)
+	walk = func(nodes []TagTreeNode) {
+		for _, node := range nodes {
+			paths[node.Path] = true
+			walk(node.Children)
+		}
+	}
+	walk(tree)
+	for _, want := range []string{"运动", "运动/现场", "音乐", "音乐/现场", "音乐/球类"} {
+		if !paths[want] {
+			t.Fatalf("标签树缺少路径 %s，got=%v", want, paths)
+		}
+	}
+
+	if err := svc.DeleteTag(music.ID); err != nil {
+		t.Fatalf("删除标签失败: %v", err)
+	}
+	var moved models.Tag
+	if err := database.DB.First(&moved, ball.ID).Error; err != nil || moved.ParentID != 0 {
+		t.Fatalf("删除父标签后子标签应上移到顶层 tag=%+v err=%v", moved, err)
+	}
+}
+
+func TestDeleteAndMergeTagsReportChildNameClashes(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	svc := &TagService{}
+
+	category, _ := svc.CreateTag("A", "")
+	if _, err := svc.CreateChildTag("X", "", category.ID); err != nil {
+		t.Fatalf("创建子标签失败: %v", err)
+	}
+	if _, err := svc.CreateTag("X", ""); err != nil {
+		t.Fatalf("创建顶层标签失败: %v", err)
+	}
+	err := svc.DeleteTag(category.ID)
+	if !errors.Is(err, ErrTagExists) || !strings.Contains(err.Error(), "X") {
+		t.Fatalf("子标签与上移位置的标签重名时应返回带名称的 ErrTagExists，got=%v", err)
+	}
+	if err := database.DB.First(&models.Tag{}, category.ID).Error; err != nil {
+		t.Fatalf("删除失败时标签应保留: %v", err)
+	}
+
+	// 软删除的同名标签与被删标签自身都不应阻止子标签上移
+	other, _ := svc.CreateTag("B", "")
+	child, _ := svc.CreateChildTag("Y", "", other.ID)
+	self, _ := svc.CreateChildTag("B", "", other.ID)
+	staleTop, _ := svc.CreateTag("Y", "")
+	if err := svc.DeleteTag(staleTop.ID); err != nil {
+		t.Fatalf("删除顶层标签失败: %v", err)
+	}
+	if err := svc.DeleteTag(other.ID); err != nil {
+		t.Fatalf("只与软删除标签重名时应能删除: %v", err)
+	}
+	for _, id := range []uint{child.ID, self.ID} {
+		var moved models.Tag
+		if err := database.DB.First(&moved, id).Error; err != nil || moved.ParentID != 0 {
+			t.Fatalf("子标签应上移到顶层 tag=%+v err=%v", moved, err)
+		}
+	}
+
+	source, _ := svc.CreateTag("来源", "")
+	target, _ := svc.CreateTag("目标", "")
+	if _, err := svc.CreateChildTag("Z", "", source.ID); err != nil {
+		t.Fatalf("创建子标签失败: %v", err)
+	}
+	if _, err := svc.CreateChildTag("Z", "", target.ID); err != nil {
+		t.Fatalf("创建子标签失败: %v", err)
+	}
+	err = svc.MergeTags([]uint{source.ID}, target.ID)
+	if !errors.Is(err, ErrTagExists) || !strings.Contains(err.Error(), "Z") {
+		t.Fatalf("合并后子标签重名时应返回带名称的 ErrTagExists，got=%v", err)
+	}
+}
+
+func TestSearchIncludesTagDescendants(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	root := t.TempDir()
+	svc := &TagService{}
+	sports, _ := svc.CreateTag("运动", "")
+	ball, _ := svc.CreateChildTag("球类", "", sports.ID)
+	football, _ := svc.CreateChildTag("足球", "", ball.ID)
+	hd := createShortFeedTag(t, "4K")
+	leaf := createShortFeedVideo(t, root, "leaf.mp4", 60, false, football, &hd)
+	direct := createShortFeedVideo(t, root, "direct.mp4", 60, false, sports)
+	createShortFeedVideo(t, root, "other.mp4", 60, false, &hd)
+
+	videoService := &VideoService{}
+	ids := func(filter VideoSearchFilter) map[uint]bool {
+		t.Helper()
+		videos, err := videoService.SearchVideosByFilter(filter, 0, 0, 0, 10)
+		if err != nil {
+			t.Fatalf("搜索失败: %v", err)
+		}
+		got := map[uint]bool{}
+		for _, video := range videos {
+			got[video.ID] = true
+		}
+		return got
+	}
+
+	if got := ids(VideoSearchFilter{TagIDs: []uint{sports.ID}}); len(got) != 1 || !got[direct.ID] {
+		t.Fatalf("未展开子标签时只匹配直接关联，got=%v", got)
+	}
+	if got := ids(VideoSearchFilter{TagIDs: []uint{sports.ID}, IncludeTagDescendants: true}); len(got) != 2 || !got[leaf.ID] || !got[direct.ID] {
+		t.Fatalf("展开子标签后应匹配子孙标签，got=%v", got)
+	}
+	if got := ids(VideoSearchFilter{TagIDs: []uint{sports.ID, hd.ID}, IncludeTagDescendants: true}); len(got) != 1 || !got[leaf.ID] {
+		t.Fatalf("多个标签仍需同时命中，got=%v", got)
+	}
+	if got := ids(VideoSearchFilter{Keyword: "tag:运动/*"}); len(got) != 2 || !got[leaf.ID] || !got[direct.ID] {
+		t.Fatalf("tag:名称/* 应匹配子孙标签，got=%v", got)
+	}
+	if got := ids(VideoSearchFilter{Keyword: "tag:球类/* -tag:4K"}); len(got) != 0 {
+		t.Fatalf("子树取反后不应有结果，got=%v", got)
+	}
+}
+
+func TestAITaggingPromptListsTagHierarchyAndPrefersLeaves(t *testing.T) {
+	tags := []models.Tag{
+		{ID: 1, Name: "运动"},
+		{ID: 2, Name: "足球", ParentID: 1},
+		{ID: 3, Name: "现场", ParentID: 1},
+		{ID: 4, Name: "音乐"},
+		{ID: 5, Name: "现场", ParentID: 4},
+		{ID: 6, Name: "4K"},
+	}
+	library := formatExistingTagLibrary(tags)
+	for _, want := range []string{"运动: 现场, 足球", "音乐: 现场", "未分组: 4K"} {
+		if !strings.Contains(library, want) {
+			t.Fatalf("标签库应按类别分组，缺少 %q: %s", want, library)
+		}
+	}
+	if flat := formatExistingTagLibrary([]models.Tag{{ID: 1, Name: "4K"}, {ID: 2, Name: "舞蹈"}}); flat != "4K, 舞蹈" {
+		t.Fatalf("没有层级时应保持逗号分隔，got=%q", flat)
+	}
+
+	lookup := buildAITagLookup(tags)
+	if tag := lookup["音乐/现场"]; tag.ID != 5 {
+		t.Fatalf("应能按路径匹配标签，got=%+v", tag)
+	}
+	if tag := lookup["足球"]; tag.ID != 2 {
+		t.Fatalf("应能按名称匹配叶子标签，got=%+v", tag)
+	}
+}
+
+func TestTagAliasMergeAndSplit(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	root := t.TempDir()
+	svc := &TagService{}
+	hd := createShortFeedTag(t, "4K")
+	dup := createShortFeedTag(t, "4K超清")
+	both := createShortFeedVideo(t, root, "both.mp4", 60, false, &hd, &dup)
+	onlyDup := createShortFeedVideo(t, root, "dup.mp4", 60, false, &dup)
+	candidate := models.AITagCandidate{VideoID: onlyDup.ID, SuggestedName: "4K超清", NormalizedName: "4k超清", MatchedTagID: &dup.ID, Confidence: "high", Status: "approved"}
+	database.DB.Create(&candidate)
+	database.DB.Create(&models.AITagApprovalRecord{VideoID: onlyDup.ID, TagID: dup.ID, CandidateID: candidate.ID})
+	database.DB.Create(&models.ShortFeedTagPreference{TagID: hd.ID, Score: 1})
+	database.DB.Create(&models.ShortFeedTagPreference{TagID: dup.ID, Score: 2})
+
+	if _, err := svc.AddTagAlias(hd.ID, "4k超清"); !errors.Is(err, ErrTagExists) {
+		t.Fatalf("别名与已有标签名重复应被拒绝，got=%v", err)
+	}
+	if _, err := svc.AddTagAlias(hd.ID, "UHD"); err != nil {
+		t.Fatalf("添加别名失败: %v", err)
+	}
+	if _, err := svc.AddTagAlias(dup.ID, "uhd"); !errors.Is(err, ErrTagExists) {
+		t.Fatalf("别名应全局唯一，got=%v", err)
+	}
+
+	if err := svc.MergeTags([]uint{dup.ID}, hd.ID); err != nil {
+		t.Fatalf("合并标签失败: %v", err)
+	}
+	var links []struct{ VideoID, TagID uint }
+	database.DB.Table("video_tags").Order("video_id").Find(&links)
+	if len(links) != 2 || links[0].TagID != hd.ID || links[1].TagID != hd.ID {
+		t.Fatalf("视频关联应全部转到目标且不重复 links=%+v", links)
+	}
+	var record models.AITagApprovalRecord
+	if err := database.DB.First(&record).Error; err != nil || record.TagID != hd.ID {
+		t.Fatalf("审批记录应转到目标 record=%+v err=%v", record, err)
+	}
+	var prefs []models.ShortFeedTagPreference
+	database.DB.Find(&prefs)
+	if len(prefs) != 1 || prefs[0].TagID != hd.ID || prefs[0].Score != 3 {
+		t.Fatalf("短视频偏好分数应累加到目标 prefs=%+v", prefs)
+	}
+	tags, _ := svc.GetAllTags()
+	if len(tags) != 1 || len(tags[0].Aliases) != 2 {
+		t.Fatalf("来源名称应成为目标别名 tags=%+v", tags)
+	}
+
+	videoService := &VideoService{}
+	videos, err := videoService.SearchVideosByFilter(VideoSearchFilter{Keyword: "tag:4k超清"}, 0, 0, 0, 10)
+	if err != nil || len(videos) != 2 {
+		t.Fatalf("按别名搜索应命中目标标签的视频 videos=%d err=%v", len(videos), err)
+	}
+	if existing, err := svc.CreateTag("UHD", ""); !errors.Is(err, ErrTagExists) || existing.ID != hd.ID {
+		t.Fatalf("以别名创建标签应返回别名所属标签 tag=%+v err=%v", existing, err)
+	}
+
+	split, err := svc.SplitTag(hd.ID, "8K", []uint{both.ID})
+	if err != nil {
+		t.Fatalf("拆分标签失败: %v", err)
+	}
+	var tagOfBoth, tagOfDup []uint
+	database.DB.Table("video_tags").Where("video_id = ?", both.ID).Pluck("tag_id", &tagOfBoth)
+	database.DB.Table("video_tags").Where("video_id = ?", onlyDup.ID).Pluck("tag_id", &tagOfDup)
+	if len(tagOfBoth) != 1 || tagOfBoth[0] != split.ID || len(tagOfDup) != 1 || tagOfDup[0] != hd.ID {
+		t.Fatalf("只有选中视频应改为新标签 both=%v dup=%v", tagOfBoth, tagOfDup)
+	}
+	if _, err := svc.SplitTag(hd.ID, "8K", []uint{onlyDup.ID}); !errors.Is(err, ErrTagExists) {
+		t.Fatalf("拆分到已存在的名称应被拒绝，got=%v", err)
+	}
+}
+
+func TestMergeAndSplitTagsMoveTagRules(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	root := t.TempDir()
+	svc := &TagService{}
+	target := createShortFeedTag(t, "舞蹈")
+	source := createShortFeedTag(t, "跳舞")
+	manual := createShortFeedVideo(t, root, "manual.mp4", 60, false, &target, &source)
+	ruled := createShortFeedVideo(t, root, "ruled.mp4", 60, false, &source)
+	rule := models.TagRule{Name: "舞蹈目录", TagID: source.ID, Enabled: true, PathGlob: "**/*.mp4"}
+	database.DB.Create(&rule)
+	database.DB.Create(&models.TagRuleLink{RuleID: rule.ID, VideoID: manual.ID, TagID: source.ID})
+	database.DB.Create(&models.TagRuleLink{RuleID: rule.ID, VideoID: ruled.ID, TagID: source.ID})
+
+	if err := svc.MergeTags([]uint{source.ID}, target.ID); err != nil {
+		t.Fatalf("合并标签失败: %v", err)
+	}
+	database.DB.First(&rule, rule.ID)
+	if rule.TagID != target.ID {
+		t.Fatalf("规则应改为给目标打标 rule=%+v", rule)
+	}
+	var links []models.TagRuleLink
+	database.DB.Order("video_id").Find(&links)
+	if len(links) != 1 || links[0].VideoID != ruled.ID || links[0].TagID != target.ID {
+		t.Fatalf("打标记录应转到目标，原本已有目标标签的视频不再记录 links=%+v", links)
+	}
+
+	split, err := svc.SplitTag(target.ID, "街舞", []uint{ruled.ID})
+	if err != nil {
+		t.Fatalf("拆分标签失败: %v", err)
+	}
+	database.DB.Find(&links)
+	if len(links) != 1 || links[0].TagID != split.ID {
+		t.Fatalf("拆分后选中视频的打标记录应指向新标签 links=%+v", links)
+	}
+
+	if _, err := (&TagRuleService{}).RevertTagRule(rule.ID); err != nil {
+		t.Fatalf("撤销规则失败: %v", err)
+	}
+	var tagOfManual, tagOfRuled []uint
+	database.DB.Table("video_tags").Where("video_id = ?", manual.ID).Pluck("tag_id", &tagOfManual)
+	database.DB.Table("video_tags").Where("video_id = ?", ruled.ID).Pluck("tag_id", &tagOfRuled)
+	if len(tagOfManual) != 1 || tagOfManual[0] != target.ID || len(tagOfRuled) != 0 {
+		t.Fatalf("撤销规则只应删除规则打上的标签 manual=%v ruled=%v", tagOfManual, tagOfRuled)
+	}
+}
+
+func TestMergeTagsRewritesFilterTagIDs(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	target := createShortFeedTag(t, "舞蹈")
+	source := createShortFeedTag(t, "跳舞")
+	other := createShortFeedTag(t, "4K")
+	search := models.SavedSearch{Name: "舞蹈合集", Filter: fmt.Sprintf(`{"keyword":"cat","tag_ids":[%d,%d],"min_height":720}`, source.ID, other.ID)}
+	database.DB.Create(&search)
+	playlist := models.Playlist{Name: "舞蹈", Kind: PlaylistKindSmart, SmartFilter: fmt.Sprintf(`{"tag_ids":[%d,%d],"limit":50}`, source.ID, target.ID)}
+	database.DB.Create(&playlist)
+	untouched := models.SavedSearch{Name: "4K", Filter: fmt.Sprintf(`{"keyword":"","tag_ids":[%d]}`, other.ID)}
+	database.DB.Create(&untouched)
+
+	if err := (&TagService{}).MergeTags([]uint{source.ID}, target.ID); err != nil {
+		t.Fatalf("合并标签失败: %v", err)
+	}
+	database.DB.First(&search, search.ID)
+	filter, err := decodeSavedSearchFilter(search.Filter)
+	if err != nil || !reflect.DeepEqual(filter.TagIDs, []uint{target.ID, other.ID}) || filter.Keyword != "cat" || filter.MinHeight != 720 {
+		t.Fatalf("保存的搜索中的来源标签应改为目标 filter=%s err=%v", search.Filter, err)
+	}
+	database.DB.First(&playlist, playlist.ID)
+	var smart struct {
+		TagIDs []uint `json:"tag_ids"`
+		Limit  int    `json:"limit"`
+	}
+	if err := json.Unmarshal([]byte(playlist.SmartFilter), &smart); err != nil || !reflect.DeepEqual(smart.TagIDs, []uint{target.ID}) || smart.Limit != 50 {
+		t.Fatalf("智能播放列表中的来源标签应改为目标并去重 filter=%s err=%v", playlist.SmartFilter, err)
+	}
+	database.DB.First(&untouched, untouched.ID)
+	if untouched.Filter != fmt.Sprintf(`{"keyword":"","tag_ids":[%d]}`, other.ID) {
+		t.Fatalf("未引用来源标签的条件不应改写 filter=%s", untouched.Filter)
+	}
+}
+
+func TestAICandidateApprovalResolvesTagAlias(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	tag := createShortFeedTag(t, "舞蹈")
+	if _, err := (&TagService{}).AddTagAlias(tag.ID, "跳舞"); err != nil {
+		t.Fatalf("添加别名失败: %v", err)
+	}
+	video := models.Video{Name: "dance.mp4", Path: "/tmp/dance.mp4", Directory: "/tmp"}
+	database.DB.Create(&video)
+	candidate := models.AITagCandidate{VideoID: video.ID, SuggestedName: "跳舞", NormalizedName: "跳舞", Confidence: "high", Status: models.AITagCandidateStatusPending}
+	database.DB.Create(&candidate)
+
+	if _, err := newTestAITaggingService(&fakeAITaggingClient{}, nil).ApproveCandidate(candidate.ID); err != nil {
+		t.Fatalf("审批候选失败: %v", err)
+	}
+	var tagIDs []uint
+	database.DB.Table("video_tags").Where("video_id = ?", video.ID).Pluck("tag_id", &tagIDs)
+	if len(tagIDs) != 1 || tagIDs[0] != tag.ID || countRows(t, "tags") != 1 {
+		t.Fatalf("别名候选应关联到已有标签而不是新建 tagIDs=%v", tagIDs)
+	}
+}
+
+func TestAICandidateApprovalResolvesTagPathAndLeaf(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	svc := &TagService{}
+	sports, _ := svc.CreateTag("运动", "")
+	music, _ := svc.CreateTag("音乐", "")
+	sportsLive, _ := svc.CreateChildTag("现场", "", sports.ID)
+	musicLive, _ := svc.CreateChildTag("现场", "", music.ID)
+	// 顶层类别 “足球” 与运动下的叶子 “足球” 重名，应选叶子
+	football, _ := svc.CreateChildTag("足球", "", sports.ID)
+	category, _ := svc.CreateTag("足球", "")
+	svc.CreateChildTag("英超", "", category.ID)
+
+	cases := []struct {
+		suggested string
+		want      uint
+	}{
+		{"音乐/现场", musicLive.ID},
+		{"运动/现场", sportsLive.ID},
+		{"足球", football.ID},
+	}
+	aiService := newTestAITaggingService(&fakeAITaggingClient{}, nil)
+	for i, tc := range cases {
+		video := models.Video{Name: fmt.Sprintf("v%d.mp4", i), Path: fmt.Sprintf("/tmp/v%d.mp4", i), Directory: "/tmp"}
+		database.DB.Create(&video)
+		candidate := models.AITagCandidate{VideoID: video.ID, SuggestedName: tc.suggested, NormalizedName: tc.suggested, Confidence: "high", Status: models.AITagCandidateStatusPending}
+		database.DB.Create(&candidate)
+		if _, err := aiService.ApproveCandidate(candidate.ID); err != nil {
+			t.Fatalf("审批候选 %q 失败: %v", tc.suggested, err)
+		}
+		var tagIDs []uint
+		database.DB.Table("video_tags").Where("video_id = ?", video.ID).Pluck("tag_id", &tagIDs)
+		if len(tagIDs) != 1 || tagIDs[0] != tc.want {
+			t.Fatalf("候选 %q 应关联到标签 %d，got=%v", tc.suggested, tc.want, tagIDs)
+		}
+	}
+	if countRows(t, "tags") != 7 {
+		t.Fatalf("按路径或名称匹配到已有标签时不应新建标签")
+	}
+}
+
+func TestTagStatsUsageCoOccurrenceAndUntaggedDirectories(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	root := t.TempDir()
+	other := t.TempDir()
+	svc := &TagService{}
+	sports, _ := svc.CreateTag("运动", "")
+	football, _ := svc.CreateChildTag("足球", "", sports.ID)
+	hd := createShortFeedTag(t, "4K")
+	unused := createShortFeedTag(t, "未使用")
+	first := createShortFeedVideo(t, root, "a.mp4", 60, false, football, &hd)
+	second := createShortFeedVideo(t, root, "b.mp4", 120, false, football, &hd)
+	createShortFeedVideo(t, root, "c.mp4", 30, false, &hd)
+	createShortFeedVideo(t, root, "untagged.mp4", 30, false)
+	createShortFeedVideo(t, other, "other.mp4", 30, false)
+	deleted := createShortFeedVideo(t, root, "deleted.mp4", 30, false, football, &hd)
+	database.DB.Delete(&deleted)
+	database.DB.Model(&models.Video{}).Where("id = ?", first.ID).Updates(map[string]interface{}{"play_count": 3, "random_play_count": 1})
+	database.DB.Model(&models.Video{}).Where("id = ?", second.ID).Update("play_count", 2)
+	database.DB.Create(&models.ScanDirectory{Path: root, Alias: "root"})
+	database.DB.Create(&models.ScanDirectory{Path: other, Alias: "other"})
+
+	report, err := svc.GetTagStats()
+	if err != nil {
+		t.Fatalf("获取标签统计失败: %v", err)
+	}
+	if report.TotalVideos != 5 || report.TaggedVideos != 3 {
+		t.Fatalf("视频总数统计错误: %+v", report)
+	}
+	stats := map[uint]TagUsageStat{}
+	for _, stat := range report.Tags {
+		stats[stat.TagID] = stat
+	}
+	if report.Tags[0].TagID != hd.ID || stats[hd.ID].VideoCount != 3 || stats[hd.ID].TotalDuration != 210 || stats[hd.ID].TotalSize != 48 {
+		t.Fatalf("4K 统计错误: %+v", report.Tags)
+	}
+	if got := stats[football.ID]; got.VideoCount != 2 || got.PlayCount != 5 || got.RandomPlayCount != 1 || got.Path != "运动/足球" {
+		t.Fatalf("足球统计错误: %+v", got)
+	}
+	if got, ok := stats[unused.ID]; !ok || got.VideoCount != 0 {
+		t.Fatalf("未使用的标签也应列出: %+v", report.Tags)
+	}
+	if len(report.CoOccurrences) != 1 || report.CoOccurrences[0].Count != 2 ||
+		report.CoOccurrences[0].TagID != min(football.ID, hd.ID) || report.CoOccurrences[0].OtherTagID != max(football.ID, hd.ID) {
+		t.Fatalf("共现统计错误: %+v", report.CoOccurrences)
+	}
+	untagged := map[string]DirectoryUntaggedStat{}
+	for _, dir := range report.Directories {
+		untagged[dir.Alias] = dir
+	}
+	if untagged["root"].VideoCount != 4 || untagged["root"].UntaggedCount != 1 || untagged["other"].UntaggedCount != 1 {
+		t.Fatalf("目录未打标签统计错误: %+v", report.Directories)
+	}
+
+	summary := formatTagUsageSummary([]models.Tag{*football, hd, unused}, map[uint]int64{football.ID: 2, hd.ID: 3})
+	if summary != "4K(3), 足球(2)" {
+		t.Fatalf("常用标签摘要应按使用数降序且跳过未使用标签，got=%q", summary)
+	}
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"video-master/database"
+	"video-master/models"
+
+	"gorm.io/gorm"
+)
+
+// 标签使用统计：每个标签的视频数、总时长、总体积与播放次数，标签两两共现次数，
+// 以及各扫描目录下未打标签的视频数。统计只计入未删除的视频。
+
+// TagUsageStat 单个标签的使用统计；PlayCount/RandomPlayCount 为所关联视频的播放次数之和
+type TagUsageStat struct {
+	TagID           uint    `json:"tag_id"`
+	Name            string  `json:"name"`
+	Path            string  `json:"path"`
+	ParentID        uint    `json:"parent_id"`
+	Color           string  `json:"color"`
+	VideoCount      int64   `json:"video_count"`
+	TotalDuration   float64 `json:"total_duration"`
+	TotalSize       int64   `json:"total_size"`
+	PlayCount       int64   `json:"play_count"`
+	RandomPlayCount int64   `json:"random_play_count"`
+}
+
+// TagCoOccurrence 共现矩阵中的一个非零元素（TagID < OtherTagID），Count 为同时带有两个标签的视频数
+type TagCoOccurrence struct {
+	TagID      uint  `json:"tag_id"`
+	OtherTagID uint  `json:"other_tag_id"`
+	Count      int64 `json:"count"`
+}
+
+// DirectoryUntaggedStat 扫描目录下的视频总数与未打标签的视频数
+type DirectoryUntaggedStat struct {
+	DirectoryID   uint   `json:"directory_id"`
+	Path          string `json:"path"`
+	Alias         string `json:"alias"`
+	VideoCount    int64  `json:"video_count"`
+	UntaggedCount int64  `json:"untagged_count"`
+}
+
+// TagStatsReport 标签统计报告；Tags 按视频数降序，未使用的标签也会列出（VideoCount 为 0）
+type TagStatsReport struct {
+	TotalVideos   int64                   `json:"total_videos"`
+	TaggedVideos  int64                   `json:"tagged_videos"`
+	Tags          []TagUsageStat          `json:"tags"`
+	CoOccurrences []TagCoOccurrence       `json:"co_occurrences"`
+	Directories   []DirectoryUntaggedStat `json:"directories"`
+}
+
+// GetTagStats 汇总标签使用统计、共现矩阵（稀疏形式，按次数降序）与各扫描目录的未打标签数
+func (s *TagService) GetTagStats() (*TagStatsReport, error) {
+	report := &TagStatsReport{Tags: []TagUsageStat{}, CoOccurrences: []TagCoOccurrence{}, Directories: []DirectoryUntaggedStat{}}
+	if err := database.DB.Model(&models.Video{}).Count(&report.TotalVideos).Error; err != nil {
+		return nil, err
+	}
+	if err := database.DB.Model(&models.Video{}).Where("EXISTS (SELECT 1 FROM video_tags WHERE video_tags.video_id = videos.id)").
+		Count(&report.TaggedVideos).Error; err != nil {
+		return nil, err
+	}
+
+	var tags []models.Tag
+	if err := database.DB.Order("id").Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	paths := tagPathsByID(tags)
+	var usage []TagUsageStat
+	if err := database.DB.Table("video_tags").
+		Select("video_tags.tag_id AS tag_id, COUNT(*) AS video_count, COALESCE(SUM(videos.duration), 0) AS total_duration, " +
+			"COALESCE(SUM(videos.size), 0) AS total_size, COALESCE(SUM(videos.play_count), 0) AS play_count, " +
+			"COALESCE(SUM(videos.random_play_count), 0) AS random_play_count").
+		Joins("JOIN videos ON videos.id = video_tags.video_id AND videos.deleted_at IS NULL").
+		Group("video_tags.tag_id").
+		Scan(&usage).Error; err != nil {
+		return nil, err
+	}
+	usageByTag := make(map[uint]TagUsageStat, len(usage))
+	for _, item := range usage {
+		usageByTag[item.TagID] = item
+	}
+	for _, tag := range tags {
+		stat := usageByTag[tag.ID]
+		stat.TagID = tag.ID
+		stat.Name = tag.Name
+		stat.Path = paths[tag.ID]
+		stat.ParentID = tag.ParentID
+		stat.Color = tag.Color
+		report.Tags = append(report.Tags, stat)
+	}
+	sort.SliceStable(report.Tags, func(i, j int) bool {
+		if report.Tags[i].VideoCount != report.Tags[j].VideoCount {
+			return report.Tags[i].VideoCount > report.Tags[j].VideoCount
+		}
+		return report.Tags[i].Path < report.Tags[j].Path
+	})
+
+	if err := database.DB.Table("video_tags AS a").
+		Select("a.tag_id AS tag_id, b.tag_id AS other_tag_id, COUNT(*) AS count").
+		Joins("JOIN video_tags AS b ON b.video_id = a.video_id AND a.tag_id < b.tag_id").
+		Joins("JOIN videos ON videos.id = a.video_id AND videos.deleted_at IS NULL").
+		Where("a.tag_id IN (?) AND b.tag_id IN (?)", activeTagIDsQuery(), activeTagIDsQuery()).
+		Group("a.tag_id, b.tag_id").
+		Order("count DESC, a.tag_id, b.tag_id").
+		Scan(&report.CoOccurrences).Error; err != nil {
+		return nil, err
+	}
+
+	var dirs []models.ScanDirectory
+	if err := database.DB.Order("path").Find(&dirs).Error; err != nil {
+		return nil, err
+	}
+	for _, dir := range dirs {
+		root := filepath.Clean(strings.TrimSpace(dir.Path))
+		childPrefix := escapeSQLLike(root+string(os.PathSeparator)) + "%"
+		stat := DirectoryUntaggedStat{DirectoryID: dir.ID, Path: dir.Path, Alias: dir.Alias}
+		underRoot := func() *gorm.DB {
+			return database.DB.Model(&models.Video{}).Where("path LIKE ? ESCAPE '\\'", childPrefix)
+		}
+		if err := underRoot().Count(&stat.VideoCount).Error; err != nil {
+			return nil, err
+		}
+		if err := underRoot().Where("NOT EXISTS (SELECT 1 FROM video_tags WHERE video_tags.video_id = videos.id)").
+			Count(&stat.UntaggedCount).Error; err != nil {
+			return nil, err
+		}
+		report.Directories = append(report.Directories, stat)
+	}
+	return report, nil
+}
+
+// tagVideoCounts 返回每个标签关联的未删除视频数（不含未使用的标签）
+func tagVideoCounts() (map[uint]int64, error) {
+	var rows []struct {
+		TagID uint
+		Count int64
+	}
+	if err := database.DB.Table("video_tags").
+		Select("video_tags.tag_id AS tag_id, COUNT(*) AS count").
+		Joins("JOIN videos ON videos.id = video_tags.video_id AND videos.deleted_at IS NULL").
+		Group("video_tags.tag_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	counts := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		counts[row.TagID] = row.Count
+	}
+	return counts, nil
+}
+
+func activeTagIDsQuery() *gorm.DB {
+	return database.DB.Model(&models.Tag{}).Select("id")
+}
+
+// tagPathsByID 返回每个标签的 “类别/子标签” 路径
+func tagPathsByID(tags []models.Tag) map[uint]string {
+	paths := make(map[uint]string, len(tags))
+	var walk func(nodes []TagTreeNode)
+	walk = func(nodes []TagTreeNode) {
+		for _, node := range nodes {
+			paths[node.Tag.ID] = node.Path
+			walk(node.Children)
+		}
+	}
+	walk(buildTagTree(tags))
+	return paths
+}
@@ -0,0 +1,639 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"video-master/database"
+	"video-master/models"
+
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+const (
+	ThumbnailKindPoster       = "poster"
+	ThumbnailKindContactSheet = "contact_sheet"
+	ThumbnailKindSprite       = "sprite"       // 拖动预览用的雪碧图
+	ThumbnailKindSpriteTrack  = "sprite_track" // 与雪碧图配套的 WebVTT 缩略图轨道
+
+	defaultThumbnailWorkers       = 2
+	defaultThumbnailPosterWidth   = 480
+	defaultContactSheetColumns    = 4
+	defaultContactSheetRows       = 4
+	defaultContactSheetTileWidth  = 320
+	defaultSpriteInterval         = 10
+	defaultSpriteColumns          = 10
+	defaultSpriteTileWidth        = 160
+	defaultSpriteMaxTiles         = 100
+	thumbnailJPEGQuality          = 4
+	thumbnailPosterPositionFactor = 0.1
+)
+
+// ErrThumbnailPending 缩略图尚未生成，已加入后台队列
+var ErrThumbnailPending = errors.New("thumbnail generation pending")
+
+type ThumbnailConfig struct {
+	Workers        int // ffmpeg 并发数，<=0 时使用默认值
+	PosterWidth    int // 封面最大宽度
+	SheetColumns   int // 联系表列数
+	SheetRows      int // 联系表行数
+	SheetTileWidth int // 联系表单格宽度
+
+	SpriteInterval  float64 // 雪碧图取帧间隔（秒），视频过长时按 SpriteMaxTiles 自动放大
+	SpriteColumns   int     // 雪碧图列数
+	SpriteTileWidth int     // 雪碧图单格宽度，高度按 16:9 计算
+	SpriteMaxTiles  int     // 雪碧图最多格数
+}
+
+// ThumbnailAsset 已生成的缓存图片
+type ThumbnailAsset struct {
+	Path    string
+	MIME    string
+	ModTime time.Time
+}
+
+type ThumbnailQueueStatus struct {
+	Running   bool   `json:"running"`
+	Pending   int    `json:"pending"`
+	Active    int    `json:"active"`
+	Generated int    `json:"generated"`
+	Failed    int    `json:"failed"`
+	LastError string `json:"last_error"`
+}
+
+// ThumbnailReadyEvent 通过 "thumbnail-ready" 事件推送给前端，用于刷新对应行的图片
+type ThumbnailReadyEvent struct {
+	VideoID      uint `json:"video_id"`
+	Poster       bool `json:"poster"`
+	ContactSheet bool `json:"contact_sheet"`
+	Sprite       bool `json:"sprite"`
+}
+
+// ThumbnailService 用 ffmpeg 生成封面、N×M 联系表与拖动预览雪碧图（附 WebVTT 轨道），缓存在数据目录的 thumbnails/<视频ID>/ 下，
+// 文件名带源文件 mtime，源文件变化后旧缓存自动失效。生成在后台队列中执行。
+type ThumbnailService struct {
+	cacheDir string
+	config   ThumbnailConfig
+
+	mu           sync.Mutex
+	ctx          context.Context
+	queue        []uint
+	queued       map[uint]struct{}
+	active       map[uint]struct{}
+	wake         chan struct{}
+	workerCancel context.CancelFunc
+	workerWG     sync.WaitGroup
+	generated    int
+	failed       int
+	lastError    string
+}
+
+func NewThumbnailService(dataDir string, config ThumbnailConfig) *ThumbnailService {
+	if config.Workers <= 0 {
+		config.Workers = defaultThumbnailWorkers
+	}
+	if config.PosterWidth <= 0 {
+		config.PosterWidth = defaultThumbnailPosterWidth
+	}
+	if config.SheetColumns <= 0 {
+		config.SheetColumns = defaultContactSheetColumns
+	}
+	if config.SheetRows <= 0 {
+		config.SheetRows = defaultContactSheetRows
+	}
+	if config.SheetTileWidth <= 0 {
+		config.SheetTileWidth = defaultContactSheetTileWidth
+	}
+	if config.SpriteInterval <= 0 {
+		config.SpriteInterval = defaultSpriteInterval
+	}
+	if config.SpriteColumns <= 0 {
+		config.SpriteColumns = defaultSpriteColumns
+	}
+	if config.SpriteTileWidth <= 0 {
+		config.SpriteTileWidth = defaultSpriteTileWidth
+	}
+	if config.SpriteMaxTiles <= 0 {
+		config.SpriteMaxTiles = defaultSpriteMaxTiles
+	}
+	return &ThumbnailService{
+		cacheDir: filepath.Join(dataDir, "thumbnails"),
+		config:   config,
+		queued:   make(map[uint]struct{}),
+		active:   make(map[uint]struct{}),
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// SetContext 注入 Wails 上下文，用于推送 "thumbnail-ready" 事件
+func (s *ThumbnailService) SetContext(ctx context.Context) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ctx = ctx
+}
+
+// Start 启动后台 worker
+func (s *ThumbnailService) Start(ctx context.Context) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.workerCancel != nil {
+		return
+	}
+	workerCtx, cancel := context.WithCancel(ctx)
+	s.workerCancel = cancel
+	for i := 0; i < s.config.Workers; i++ {
+		s.workerWG.Add(1)
+		go s.workerLoop(workerCtx)
+	}
+}
+
+// Stop 停止 worker 并等待进行中的 ffmpeg 退出；未处理的队列保留，重新 Start 后继续
+func (s *ThumbnailService) Stop() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	cancel := s.workerCancel
+	s.workerCancel = nil
+	s.mu.Unlock()
+	if cancel != nil {
+		cancel()
+		s.workerWG.Wait()
+	}
+}
+
+// Enqueue 将视频加入生成队列，已在队列或生成中的视频会被忽略；返回新加入的数量
+func (s *ThumbnailService) Enqueue(videoIDs ...uint) int {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	added := 0
+	for _, id := range videoIDs {
+		if id == 0 {
+			continue
+		}
+		if _, ok := s.queued[id]; ok {
+			continue
+		}
+		if _, ok := s.active[id]; ok {
+			continue
+		}
+		s.queued[id] = struct{}{}
+		s.queue = append(s.queue, id)
+		added++
+	}
+	s.mu.Unlock()
+	if added > 0 {
+		s.signal()
+	}
+	return added
+}
+
+// Invalidate 删除视频的全部缓存图片
+func (s *ThumbnailService) Invalidate(videoID uint) {
+	if s == nil || videoID == 0 {
+		return
+	}
+	if err := os.RemoveAll(s.videoCacheDir(videoID)); err != nil {
+		log.Printf("[Thumbnail] invalidate failed video=%d err=%v", videoID, err)
+	}
+}
+
+// Regenerate 清除缓存并重新排队生成（迁移、改名或手动刷新时调用）
+func (s *ThumbnailService) Regenerate(videoIDs ...uint) int {
+	if s == nil {
+		return 0
+	}
+	for _, id := range videoIDs {
+		s.Invalidate(id)
+	}
+	return s.Enqueue(videoIDs...)
+}
+
+func (s *ThumbnailService) Status() ThumbnailQueueStatus {
+	if s == nil {
+		return ThumbnailQueueStatus{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return ThumbnailQueueStatus{
+		Running:   s.workerCancel != nil,
+		Pending:   len(s.queue),
+		Active:    len(s.active),
+		Generated: s.generated,
+		Failed:    s.failed,
+		LastError: s.lastError,
+	}
+}
+
+// ResolveThumbnail 返回与源文件当前 mtime 对应的缓存图片；尚未生成时加入队列并返回 ErrThumbnailPending。
+// 源文件不可访问（如卷离线）时退回最近一次生成的缓存。
+func (s *ThumbnailService) ResolveThumbnail(videoID uint, kind string) (*ThumbnailAsset, error) {
+	if s == nil {
+		return nil, os.ErrNotExist
+	}
+	switch kind {
+	case ThumbnailKindPoster, ThumbnailKindContactSheet, ThumbnailKindSprite, ThumbnailKindSpriteTrack:
+	default:
+		return nil, fmt.Errorf("unknown thumbnail kind: %s", kind)
+	}
+	var video models.Video
+	if err := database.DB.First(&video, videoID).Error; err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(video.Path)
+	if err != nil || info.IsDir() {
+		if cached := s.latestCachedFile(videoID, kind); cached != nil {
+			return cached, nil
+		}
+		return nil, os.ErrNotExist
+	}
+	if asset := statThumbnailAsset(s.cachePath(videoID, info.ModTime(), kind)); asset != nil {
+		return asset, nil
+	}
+	s.Enqueue(videoID)
+	return nil, ErrThumbnailPending
+}
+
+func (s *ThumbnailService) videoCacheDir(videoID uint) string {
+	return filepath.Join(s.cacheDir, strconv.FormatUint(uint64(videoID), 10))
+}
+
+func (s *ThumbnailService) cachePath(videoID uint, modTime time.Time, kind string) string {
+	name := fmt.Sprintf("%d-%s.jpg", modTime.UnixNano(), kind)
+	switch kind {
+	case ThumbnailKindContactSheet:
+		name = fmt.Sprintf("%d-%s-%dx%d.jpg", modTime.UnixNano(), kind, s.config.SheetColumns, s.config.SheetRows)
+	case ThumbnailKindSpriteTrack:
+		// 轨道与雪碧图同名不同扩展名，便于成对清理
+		name = fmt.Sprintf("%d-%s.vtt", modTime.UnixNano(), ThumbnailKindSprite)
+	}
+	return filepath.Join(s.videoCacheDir(videoID), name)
+}
+
+func (s *ThumbnailService) latestCachedFile(videoID uint, kind string) *ThumbnailAsset {
+	pattern := "*-" + kind + "*.jpg"
+	if kind == ThumbnailKindSpriteTrack {
+		pattern = "*-" + ThumbnailKindSprite + ".vtt"
+	}
+	matches, _ := filepath.Glob(filepath.Join(s.videoCacheDir(videoID), pattern))
+	var latest *ThumbnailAsset
+	for _, match := range matches {
+		if isThumbnailTempFile(match) {
+			continue
+		}
+		if asset := statThumbnailAsset(match); asset != nil && (latest == nil || asset.ModTime.After(latest.ModTime)) {
+			latest = asset
+		}
+	}
+	return latest
+}
+
+func statThumbnailAsset(path string) *ThumbnailAsset {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() || info.Size() == 0 {
+		return nil
+	}
+	mimeType := "image/jpeg"
+	if filepath.Ext(path) == ".vtt" {
+		mimeType = "text/vtt; charset=utf-8"
+	}
+	return &ThumbnailAsset{Path: path, MIME: mimeType, ModTime: info.ModTime()}
+}
+
+func isThumbnailTempFile(path string) bool {
+	return strings.Contains(filepath.Base(path), ".tmp.")
+}
+
+func (s *ThumbnailService) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *ThumbnailService) workerLoop(ctx context.Context) {
+	defer s.workerWG.Done()
+	for {
+		videoID, ok := s.next()
+		if !ok {
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.wake:
+				continue
+			}
+		}
+		err := s.generate(ctx, videoID)
+		s.finish(videoID, err)
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+func (s *ThumbnailService) next() (uint, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.queue) == 0 {
+		return 0, false
+	}
+	videoID := s.queue[0]
+	s.queue = s.queue[1:]
+	delete(s.queued, videoID)
+	s.active[videoID] = struct{}{}
+	if len(s.queue) > 0 {
+		// 还有任务时继续唤醒其他空闲 worker
+		s.signal()
+	}
+	return videoID, true
+}
+
+func (s *ThumbnailService) finish(videoID uint, err error) {
+	s.mu.Lock()
+	delete(s.active, videoID)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			// 停止时中断的任务放回队首，下次 Start 后继续
+			if _, ok := s.queued[videoID]; !ok {
+				s.queued[videoID] = struct{}{}
+				s.queue = append([]uint{videoID}, s.queue...)
+			}
+		} else {
+			s.failed++
+			s.lastError = err.Error()
+		}
+	} else {
+		s.generated++
+	}
+	s.mu.Unlock()
+	if err != nil && !errors.Is(err, context.Canceled) {
+		log.Printf("[Thumbnail] generate failed video=%d err=%v", videoID, err)
+	}
+}
+
+// generate 生成缺失的封面与联系表，并清理旧 mtime 的缓存
+func (s *ThumbnailService) generate(ctx context.Context, videoID uint) error {
+	var video models.Video
+	if err := database.DB.First(&video, videoID).Error; err != nil {
+		return err
+	}
+	info, err := os.Stat(video.Path)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("视频路径是目录: %s", video.Path)
+	}
+	ffmpegBin := findMediaBinary("ffmpeg")
+	if ffmpegBin == "" {
+		return errors.New("ffmpeg unavailable for thumbnail generation")
+	}
+	dir := s.videoCacheDir(videoID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	posterPath := s.cachePath(videoID, info.ModTime(), ThumbnailKindPoster)
+	sheetPath := s.cachePath(videoID, info.ModTime(), ThumbnailKindContactSheet)
+	spritePath := s.cachePath(videoID, info.ModTime(), ThumbnailKindSprite)
+	trackPath := s.cachePath(videoID, info.ModTime(), ThumbnailKindSpriteTrack)
+	removeStaleThumbnails(dir, posterPath, sheetPath, spritePath, trackPath)
+
+	event := ThumbnailReadyEvent{VideoID: videoID}
+	if statThumbnailAsset(posterPath) == nil {
+		position := video.Duration * thumbnailPosterPositionFactor
+		if err := s.extractFrame(ctx, ffmpegBin, video.Path, position, s.config.PosterWidth, posterPath); err != nil {
+			return fmt.Errorf("生成封面失败: %w", err)
+		}
+	}
+	event.Poster = true
+
+	if video.Duration <= 0 {
+		// 时长未知时无法均匀取帧，只生成封面
+		log.Printf("[Thumbnail] skip contact sheet without duration video=%d path=%s", videoID, video.Path)
+		s.emitReady(event)
+		return nil
+	}
+	if statThumbnailAsset(sheetPath) == nil {
+		if err := s.buildContactSheet(ctx, ffmpegBin, video, sheetPath); err != nil {
+			return fmt.Errorf("生成联系表失败: %w", err)
+		}
+	}
+	event.ContactSheet = true
+
+	if statThumbnailAsset(spritePath) == nil || statThumbnailAsset(trackPath) == nil {
+		if err := s.buildSprite(ctx, ffmpegBin, video, spritePath, trackPath); err != nil {
+			return fmt.Errorf("生成雪碧图失败: %w", err)
+		}
+	}
+	event.Sprite = true
+
+	s.emitReady(event)
+	return nil
+}
+
+func (s *ThumbnailService) extractFrame(ctx context.Context, ffmpegBin string, videoPath string, position float64, width int, outPath string) error {
+	tmpPath := outPath + ".tmp.jpg"
+	defer os.Remove(tmpPath)
+	cmd := exec.CommandContext(ctx, ffmpegBin,
+		"-y",
+		"-ss", strconv.FormatFloat(position, 'f', 2, 64),
+		"-i", videoPath,
+		"-frames:v", "1",
+		"-vf", fmt.Sprintf("scale='min(%d,iw)':-2", width),
+		"-q:v", strconv.Itoa(thumbnailJPEGQuality),
+		tmpPath,
+	)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("%v %s", err, truncateLogSnippet(string(output), 200))
+	}
+	if statThumbnailAsset(tmpPath) == nil {
+		return errors.New("ffmpeg produced no image")
+	}
+	return os.Rename(tmpPath, outPath)
+}
+
+// buildContactSheet 按时长均匀取 列×行 帧，再用 tile 滤镜拼成一张图；个别帧失败时用剩余帧拼接
+func (s *ThumbnailService) buildContactSheet(ctx context.Context, ffmpegBin string, video models.Video, outPath string) error {
+	tmpDir, err := os.MkdirTemp("", "video-master-sheet-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	count := s.config.SheetColumns * s.config.SheetRows
+	extracted := 0
+	for i := 0; i < count; i++ {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		position := video.Duration * float64(i+1) / float64(count+1)
+		framePath := filepath.Join(tmpDir, fmt.Sprintf("tile-%03d.jpg", extracted+1))
+		if err := s.extractFrame(ctx, ffmpegBin, video.Path, position, s.config.SheetTileWidth, framePath); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log.Printf("[Thumbnail] contact sheet frame %d failed video=%d err=%v", i+1, video.ID, err)
+			continue
+		}
+		extracted++
+	}
+	if extracted == 0 {
+		return errors.New("no frames extracted")
+	}
+
+	tmpPath := outPath + ".tmp.jpg"
+	defer os.Remove(tmpPath)
+	cmd := exec.CommandContext(ctx, ffmpegBin,
+		"-y",
+		"-framerate", "1",
+		"-i", filepath.Join(tmpDir, "tile-%03d.jpg"),
+		"-vf", fmt.Sprintf("tile=%dx%d", s.config.SheetColumns, s.config.SheetRows),
+		"-frames:v", "1",
+		"-q:v", strconv.Itoa(thumbnailJPEGQuality),
+		tmpPath,
+	)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("%v %s", err, truncateLogSnippet(string(output), 200))
+	}
+	if statThumbnailAsset(tmpPath) == nil {
+		return errors.New("ffmpeg produced no contact sheet")
+	}
+	return os.Rename(tmpPath, outPath)
+}
+
+// spriteLayout 计算雪碧图的取帧间隔、格数与行数；超长视频放大间隔，保证总格数不超过上限
+func (s *ThumbnailService) spriteLayout(duration float64) (interval float64, tiles int, rows int) {
+	interval = s.config.SpriteInterval
+	if duration/interval > float64(s.config.SpriteMaxTiles) {
+		interval = duration / float64(s.config.SpriteMaxTiles)
+	}
+	tiles = int(math.Ceil(duration / interval))
+	if tiles < 1 {
+		tiles = 1
+	}
+	rows = (tiles + s.config.SpriteColumns - 1) / s.config.SpriteColumns
+	return interval, tiles, rows
+}
+
+func (s *ThumbnailService) spriteTileSize() (int, int) {
+	width := s.config.SpriteTileWidth
+	height := width * 9 / 16
+	return width, height - height%2
+}
+
+// buildSprite 只解码关键帧，按固定间隔取帧并等比缩放补边到统一格子，拼成一张雪碧图，再写出对应的 WebVTT 轨道
+func (s *ThumbnailService) buildSprite(ctx context.Context, ffmpegBin string, video models.Video, spritePath string, trackPath string) error {
+	interval, tiles, rows := s.spriteLayout(video.Duration)
+	tileWidth, tileHeight := s.spriteTileSize()
+
+	tmpPath := spritePath + ".tmp.jpg"
+	defer os.Remove(tmpPath)
+	filter := fmt.Sprintf(
+		"fps=1/%s,scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2,tile=%dx%d",
+		strconv.FormatFloat(interval, 'f', 3, 64), tileWidth, tileHeight, tileWidth, tileHeight, s.config.SpriteColumns, rows,
+	)
+	cmd := exec.CommandContext(ctx, ffmpegBin,
+		"-y",
+		"-skip_frame", "nokey",
+		"-i", video.Path,
+		"-an",
+		"-vf", filter,
+		"-frames:v", "1",
+		"-q:v", strconv.Itoa(thumbnailJPEGQuality),
+		tmpPath,
+	)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("%v %s", err, truncateLogSnippet(string(output), 200))
+	}
+	if statThumbnailAsset(tmpPath) == nil {
+		return errors.New("ffmpeg produced no sprite")
+	}
+
+	// 轨道中的图片地址相对于 .vtt 自身，桌面端与短视频服务可共用同一份缓存
+	imageName := strconv.FormatUint(uint64(video.ID), 10) + ".jpg"
+	track := buildSpriteTrack(imageName, video.Duration, interval, tiles, s.config.SpriteColumns, tileWidth, tileHeight)
+	tmpTrackPath := trackPath + ".tmp.vtt"
+	defer os.Remove(tmpTrackPath)
+	if err := os.WriteFile(tmpTrackPath, []byte(track), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, spritePath); err != nil {
+		return err
+	}
+	return os.Rename(tmpTrackPath, trackPath)
+}
+
+func buildSpriteTrack(imageName string, duration float64, interval float64, tiles int, columns int, tileWidth int, tileHeight int) string {
+	var builder strings.Builder
+	builder.WriteString("WEBVTT\n")
+	for i := 0; i < tiles; i++ {
+		start := float64(i) * interval
+		end := math.Min(start+interval, duration)
+		if end <= start {
+			break
+		}
+		x := (i % columns) * tileWidth
+		y := (i / columns) * tileHeight
+		fmt.Fprintf(&builder, "\n%s --> %s\n%s#xywh=%d,%d,%d,%d\n",
+			formatVTTTimestamp(start), formatVTTTimestamp(end), imageName, x, y, tileWidth, tileHeight)
+	}
+	return builder.String()
+}
+
+func formatVTTTimestamp(seconds float64) string {
+	ms := int64(math.Round(seconds * 1000))
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, (ms%3600000)/60000, (ms%60000)/1000, ms%1000)
+}
+
+func removeStaleThumbnails(dir string, keep ...string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	keepSet := make(map[string]struct{}, len(keep))
+	for _, path := range keep {
+		keepSet[filepath.Base(path)] = struct{}{}
+	}
+	for _, entry := range entries {
+		if _, ok := keepSet[entry.Name()]; !ok && !isThumbnailTempFile(entry.Name()) {
+			_ = os.Remove(filepath.Join(dir, entry.Name()))
+		}
+	}
+}
+
+func (s *ThumbnailService) emitReady(event ThumbnailReadyEvent) {
+	s.mu.Lock()
+	ctx := s.ctx
+	s.mu.Unlock()
+	if ctx == nil {
+		return
+	}
+	wailsRuntime.EventsEmit(ctx, "thumbnail-ready", event)
+}
@@ -0,0 +1,263 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+	"video-master/database"
+	"video-master/models"
+)
+
+func mockFFmpeg(t *testing.T, root string) string {
+	t.Helper()
+	ffmpegDir := filepath.Join(root, "bin")
+	if err := os.MkdirAll(ffmpegDir, 0755); err != nil {
+		t.Fatalf("创建 ffmpeg 目录失败: %v", err)
+	}
+	logPath := filepath.Join(root, "ffmpeg.log")
+	script := `#!/bin/bash
+out="${@: -1}"
+echo "$*" >> "` + logPath + `"
+printf 'jpeg' > "$out"
+`
+	if err := os.WriteFile(filepath.Join(ffmpegDir, "ffmpeg"), []byte(script), 0755); err != nil {
+		t.Fatalf("写入 ffmpeg stub 失败: %v", err)
+	}
+	t.Setenv("PATH", ffmpegDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return logPath
+}
+
+func waitForThumbnail(t *testing.T, thumbs *ThumbnailService, videoID uint, kind string) *ThumbnailAsset {
+	t.Helper()
+	var asset *ThumbnailAsset
+	waitForCondition(t, 10*time.Second, "缩略图应生成", func() bool {
+		resolved, err := thumbs.ResolveThumbnail(videoID, kind)
+		asset = resolved
+		return err == nil
+	})
+	return asset
+}
+
+func TestThumbnailServiceGeneratesPosterAndContactSheetKeyedByModTime(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	ffmpegLog := mockFFmpeg(t, t.TempDir())
+	dataDir := t.TempDir()
+	root := t.TempDir()
+	videoPath := filepath.Join(root, "movie.mp4")
+	mustCreateFile(t, videoPath)
+	video := models.Video{Name: "movie.mp4", Path: videoPath, Directory: root, Size: 1, Duration: 100}
+	if err := database.DB.Create(&video).Error; err != nil {
+		t.Fatalf("创建视频失败: %v", err)
+	}
+
+	thumbs := NewThumbnailService(dataDir, ThumbnailConfig{Workers: 2, SheetColumns: 3, SheetRows: 2})
+	if _, err := thumbs.ResolveThumbnail(video.ID, ThumbnailKindPoster); !errors.Is(err, ErrThumbnailPending) {
+		t.Fatalf("首次请求应进入队列，err=%v", err)
+	}
+	if status := thumbs.Status(); status.Pending != 1 || status.Running {
+		t.Fatalf("未启动 worker 时应只排队: %+v", status)
+	}
+	thumbs.Start(context.Background())
+	t.Cleanup(thumbs.Stop)
+
+	// 雪碧图最后生成，等到雪碧图就绪即封面与联系表都已完成
+	waitForThumbnail(t, thumbs, video.ID, ThumbnailKindSprite)
+	poster, err := thumbs.ResolveThumbnail(video.ID, ThumbnailKindPoster)
+	if err != nil {
+		t.Fatalf("封面应先于雪碧图生成: %v", err)
+	}
+	sheet, err := thumbs.ResolveThumbnail(video.ID, ThumbnailKindContactSheet)
+	if err != nil {
+		t.Fatalf("联系表应先于雪碧图生成: %v", err)
+	}
+	cacheDir := filepath.Join(dataDir, "thumbnails", strconvUint(video.ID))
+	if filepath.Dir(poster.Path) != cacheDir || filepath.Dir(sheet.Path) != cacheDir || !strings.Contains(sheet.Path, "3x2") {
+		t.Fatalf("缓存路径错误 poster=%s sheet=%s", poster.Path, sheet.Path)
+	}
+	calls, err := os.ReadFile(ffmpegLog)
+	if err != nil {
+		t.Fatalf("读取 ffmpeg 调用记录失败: %v", err)
+	}
+	// 1 次封面 + 6 格取帧 + 1 次拼接 + 1 次雪碧图
+	if got := strings.Count(string(calls), "\n"); got != 9 || !strings.Contains(string(calls), "tile=3x2") {
+		t.Fatalf("ffmpeg 调用次数错误 got=%d calls=%s", got, calls)
+	}
+
+	// 源文件修改后 mtime 变化，旧缓存失效并重新生成
+	mustSetFileModTime(t, videoPath, time.Now().Add(time.Hour))
+	if _, err := thumbs.ResolveThumbnail(video.ID, ThumbnailKindPoster); !errors.Is(err, ErrThumbnailPending) {
+		t.Fatalf("mtime 变化后应重新生成，err=%v", err)
+	}
+	regenerated := waitForThumbnail(t, thumbs, video.ID, ThumbnailKindPoster)
+	if regenerated.Path == poster.Path {
+		t.Fatalf("重新生成的缓存应使用新的 mtime 键: %s", regenerated.Path)
+	}
+	if _, err := os.Stat(poster.Path); !os.IsNotExist(err) {
+		t.Fatalf("旧 mtime 的缓存应被清理，err=%v", err)
+	}
+
+	// 源文件不可访问时退回已有缓存
+	if err := os.Remove(videoPath); err != nil {
+		t.Fatalf("删除视频失败: %v", err)
+	}
+	if cached, err := thumbs.ResolveThumbnail(video.ID, ThumbnailKindPoster); err != nil || cached.Path != regenerated.Path {
+		t.Fatalf("源文件缺失时应返回最近缓存 cached=%+v err=%v", cached, err)
+	}
+}
+
+func TestRenameVideoInvalidatesThumbnailCache(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	mockFFmpeg(t, t.TempDir())
+	dataDir := t.TempDir()
+	root := t.TempDir()
+	videoPath := filepath.Join(root, "old.mp4")
+	mustCreateFile(t, videoPath)
+	video := models.Video{Name: "old.mp4", Path: videoPath, Directory: root, Size: 1, Duration: 10}
+	if err := database.DB.Create(&video).Error; err != nil {
+		t.Fatalf("创建视频失败: %v", err)
+	}
+
+	thumbs := NewThumbnailService(dataDir, ThumbnailConfig{})
+	svc := &VideoService{}
+	svc.SetThumbnailService(thumbs)
+	thumbs.Enqueue(video.ID)
+	thumbs.Start(context.Background())
+	t.Cleanup(thumbs.Stop)
+	poster := waitForThumbnail(t, thumbs, video.ID, ThumbnailKindPoster)
+
+	thumbs.Stop()
+	if err := svc.RenameVideo(video.ID, "new"); err != nil {
+		t.Fatalf("重命名失败: %v", err)
+	}
+	if _, err := os.Stat(poster.Path); !os.IsNotExist(err) {
+		t.Fatalf("改名后缓存应被清除，err=%v", err)
+	}
+	if status := thumbs.Status(); status.Pending != 1 {
+		t.Fatalf("改名后应重新排队生成: %+v", status)
+	}
+}
+
+func TestShortFeedHTTPServesPosterThumbnail(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	mockFFmpeg(t, t.TempDir())
+	video := createShortFeedVideo(t, t.TempDir(), "poster.mp4", 30, false)
+	thumbs := NewThumbnailService(t.TempDir(), ThumbnailConfig{})
+	videoService := &VideoService{}
+	videoService.SetThumbnailService(thumbs)
+	feed := NewShortFeedService(videoService)
+	handler := NewShortFeedHTTPServer(feed, fstest.MapFS{}, ShortFeedHTTPServerConfig{}).Handler()
+
+	dto, err := feed.NextVideo(nil)
+	if err != nil {
+		t.Fatalf("获取短视频失败: %v", err)
+	}
+	if dto.PosterURL != "/short-thumbnail/"+strconvUint(video.ID) {
+		t.Fatalf("短视频应附带封面地址: %+v", dto)
+	}
+
+	request := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, dto.PosterURL, nil)
+		req.RemoteAddr = "127.0.0.1:1234"
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	if pending := request(); pending.Code != http.StatusNotFound || !strings.Contains(pending.Body.String(), "thumbnail_pending") {
+		t.Fatalf("未生成时应返回 pending，got=%d body=%s", pending.Code, pending.Body.String())
+	}
+	thumbs.Start(context.Background())
+	t.Cleanup(thumbs.Stop)
+	waitForThumbnail(t, thumbs, video.ID, ThumbnailKindPoster)
+	served := request()
+	if served.Code != http.StatusOK || served.Header().Get("Content-Type") != "image/jpeg" || served.Body.String() != "jpeg" {
+		t.Fatalf("封面响应错误 got=%d type=%s body=%q", served.Code, served.Header().Get("Content-Type"), served.Body.String())
+	}
+
+	if dto.ThumbnailTrack != "/short-sprite/"+strconvUint(video.ID)+".vtt" {
+		t.Fatalf("短视频应附带缩略图轨道地址: %+v", dto)
+	}
+	waitForThumbnail(t, thumbs, video.ID, ThumbnailKindSprite)
+	for _, path := range []string{dto.ThumbnailTrack, strings.TrimSuffix(dto.ThumbnailTrack, ".vtt") + ".jpg"} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = "127.0.0.1:1234"
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("雪碧图资源响应错误 path=%s got=%d body=%s", path, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestThumbnailServiceBuildsSpriteWithWebVTTTrack(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	ffmpegLog := mockFFmpeg(t, t.TempDir())
+	root := t.TempDir()
+	videoPath := filepath.Join(root, "long.mp4")
+	mustCreateFile(t, videoPath)
+	video := models.Video{Name: "long.mp4", Path: videoPath, Directory: root, Size: 1, Duration: 25}
+	if err := database.DB.Create(&video).Error; err != nil {
+		t.Fatalf("创建视频失败: %v", err)
+	}
+
+	thumbs := NewThumbnailService(t.TempDir(), ThumbnailConfig{SpriteInterval: 10, SpriteColumns: 2, SpriteTileWidth: 160})
+	videoService := &VideoService{}
+	videoService.SetThumbnailService(thumbs)
+	thumbs.Enqueue(video.ID)
+	thumbs.Start(context.Background())
+	t.Cleanup(thumbs.Stop)
+
+	sprite := waitForThumbnail(t, thumbs, video.ID, ThumbnailKindSprite)
+	track, err := thumbs.ResolveThumbnail(video.ID, ThumbnailKindSpriteTrack)
+	if err != nil {
+		t.Fatalf("雪碧图生成后轨道应可用: %v", err)
+	}
+	if sprite.MIME != "image/jpeg" || track.MIME != "text/vtt; charset=utf-8" || filepath.Dir(track.Path) != filepath.Dir(sprite.Path) {
+		t.Fatalf("雪碧图或轨道缓存错误 sprite=%+v track=%+v", sprite, track)
+	}
+	content, err := os.ReadFile(track.Path)
+	if err != nil {
+		t.Fatalf("读取轨道失败: %v", err)
+	}
+	id := strconvUint(video.ID)
+	want := "WEBVTT\n" +
+		"\n00:00:00.000 --> 00:00:10.000\n" + id + ".jpg#xywh=0,0,160,90\n" +
+		"\n00:00:10.000 --> 00:00:20.000\n" + id + ".jpg#xywh=160,0,160,90\n" +
+		"\n00:00:20.000 --> 00:00:25.000\n" + id + ".jpg#xywh=0,90,160,90\n"
+	if string(content) != want {
+		t.Fatalf("WebVTT 轨道内容错误:\n%s", content)
+	}
+	calls, err := os.ReadFile(ffmpegLog)
+	if err != nil {
+		t.Fatalf("读取 ffmpeg 调用记录失败: %v", err)
+	}
+	if !strings.Contains(string(calls), "-skip_frame nokey") || !strings.Contains(string(calls), "tile=2x2") {
+		t.Fatalf("雪碧图应只解码关键帧并按 2x2 拼接: %s", calls)
+	}
+
+	session, err := videoService.GetPreviewSession(video.ID)
+	if err != nil {
+		t.Fatalf("获取预览会话失败: %v", err)
+	}
+	if session.InlineSource == nil || session.InlineSource.ThumbnailTrack != "/preview/sprite/"+id+".vtt" {
+		t.Fatalf("内嵌预览应附带缩略图轨道: %+v", session.InlineSource)
+	}
+}
+
+func TestThumbnailServiceSpriteLayoutCapsTileCount(t *testing.T) {
+	thumbs := NewThumbnailService(t.TempDir(), ThumbnailConfig{SpriteInterval: 10, SpriteColumns: 10, SpriteMaxTiles: 100})
+	interval, tiles, rows := thumbs.spriteLayout(7200)
+	if interval != 72 || tiles != 100 || rows != 10 {
+		t.Fatalf("超长视频应放大取帧间隔 interval=%v tiles=%d rows=%d", interval, tiles, rows)
+	}
+	interval, tiles, rows = thumbs.spriteLayout(3)
+	if interval != 10 || tiles != 1 || rows != 1 {
+		t.Fatalf("短视频至少一格 interval=%v tiles=%d rows=%d", interval, tiles, rows)
+	}
+}
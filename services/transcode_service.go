@@ -0,0 +1,566 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"video-master/models"
+)
+
+const (
+	TranscodeModeRemux     = "remux"     // 编码浏览器可播，仅换封装
+	TranscodeModeTranscode = "transcode" // 重新编码为 H.264/AAC
+
+	TranscodeStreamResource = "stream.mp4"
+	TranscodeHLSPlaylist    = "index.m3u8"
+	transcodeHLSPrefix      = "hls/"
+
+	defaultTranscodeMaxSessions = 2
+	defaultTranscodeIdleTimeout = 90 * time.Second
+	transcodeHLSEvictAfter      = 10 * time.Second
+	transcodeHLSSegmentSeconds  = 4
+	transcodeHLSWaitTimeout     = 20 * time.Second
+	transcodeHLSPollInterval    = 100 * time.Millisecond
+	transcodeMaxOutputWidth     = 1920
+)
+
+var (
+	// ErrTranscodeBusy 并发转码会话已达上限
+	ErrTranscodeBusy = errors.New("too many transcode sessions")
+	// ErrTranscodeUnavailable 未找到 ffmpeg 或转码服务未启用
+	ErrTranscodeUnavailable = errors.New("ffmpeg unavailable for transcoding")
+	// ErrInvalidTranscodeResource 请求的转码资源名不合法
+	ErrInvalidTranscodeResource = errors.New("invalid transcode resource")
+
+	hlsSegmentNamePattern = regexp.MustCompile(`^seg-\d{5}\.ts$`)
+
+	browserVideoCodecs = map[string]bool{"h264": true}
+	browserAudioCodecs = map[string]bool{"aac": true, "mp3": true}
+)
+
+type TranscodeConfig struct {
+	MaxSessions int           // 同时运行的 ffmpeg 会话上限，<=0 时使用默认值
+	IdleTimeout time.Duration // HLS 会话无请求多久后回收并删除分片
+	TempDir     string        // HLS 分片临时目录，默认系统临时目录下的 video-master-transcode
+}
+
+// TranscodePlan 描述一个视频需要的处理方式
+type TranscodePlan struct {
+	Mode      string `json:"mode"`
+	CopyVideo bool   `json:"copy_video"`
+	CopyAudio bool   `json:"copy_audio"`
+}
+
+type TranscodeSessionInfo struct {
+	VideoID   uint      `json:"video_id"`
+	Kind      string    `json:"kind"`
+	Mode      string    `json:"mode"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+type TranscodeStatus struct {
+	MaxSessions int                    `json:"max_sessions"`
+	Sessions    []TranscodeSessionInfo `json:"sessions"`
+}
+
+type transcodeSession struct {
+	key        string
+	videoID    uint
+	kind       string
+	mode       string
+	dir        string
+	startedAt  time.Time
+	lastAccess time.Time
+	cancel     context.CancelFunc
+	done       chan struct{}
+	err        error
+}
+
+// TranscodeService 为不能直接内嵌播放的格式提供实时转封装/转码：
+// 分片 MP4 直接经管道写入 HTTP 响应（不落盘），HLS 则写入临时目录并在会话空闲后清理。
+type TranscodeService struct {
+	config TranscodeConfig
+
+	mu            sync.Mutex
+	sessions      map[string]*transcodeSession
+	nextStreamID  uint64
+	janitorCancel context.CancelFunc
+	wg            sync.WaitGroup
+}
+
+func NewTranscodeService(config TranscodeConfig) *TranscodeService {
+	if config.MaxSessions <= 0 {
+		config.MaxSessions = defaultTranscodeMaxSessions
+	}
+	if config.IdleTimeout <= 0 {
+		config.IdleTimeout = defaultTranscodeIdleTimeout
+	}
+	if config.TempDir == "" {
+		config.TempDir = filepath.Join(os.TempDir(), "video-master-transcode")
+	}
+	return &TranscodeService{
+		config:   config,
+		sessions: make(map[string]*transcodeSession),
+	}
+}
+
+// Start 清理上次异常退出遗留的分片并启动空闲会话回收
+func (s *TranscodeService) Start(ctx context.Context) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.janitorCancel != nil {
+		return
+	}
+	if err := os.RemoveAll(s.config.TempDir); err != nil {
+		log.Printf("[Transcode] cleanup temp dir failed dir=%s err=%v", s.config.TempDir, err)
+	}
+	janitorCtx, cancel := context.WithCancel(ctx)
+	s.janitorCancel = cancel
+	s.wg.Add(1)
+	go s.janitorLoop(janitorCtx)
+}
+
+// Stop 终止全部会话并删除临时分片目录
+func (s *TranscodeService) Stop() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.janitorCancel != nil {
+		s.janitorCancel()
+		s.janitorCancel = nil
+	}
+	sessions := make([]*transcodeSession, 0, len(s.sessions))
+	for _, session := range s.sessions {
+		sessions = append(sessions, session)
+	}
+	s.mu.Unlock()
+
+	for _, session := range sessions {
+		s.closeSession(session)
+	}
+	s.wg.Wait()
+	_ = os.RemoveAll(s.config.TempDir)
+}
+
+// Available 是否可以提供转码播放
+func (s *TranscodeService) Available() bool {
+	return s != nil && findMediaBinary("ffmpeg") != ""
+}
+
+func (s *TranscodeService) Status() TranscodeStatus {
+	if s == nil {
+		return TranscodeStatus{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := TranscodeStatus{MaxSessions: s.config.MaxSessions, Sessions: make([]TranscodeSessionInfo, 0, len(s.sessions))}
+	for _, session := range s.sessions {
+		status.Sessions = append(status.Sessions, TranscodeSessionInfo{
+			VideoID:   session.videoID,
+			Kind:      session.kind,
+			Mode:      session.mode,
+			StartedAt: session.startedAt,
+		})
+	}
+	return status
+}
+
+// PlanTranscode 按已解析的编码决定转封装还是转码；编码未知时按转码处理
+func PlanTranscode(video models.Video) TranscodePlan {
+	plan := TranscodePlan{
+		CopyVideo: browserVideoCodecs[strings.ToLower(video.MediaInfo.VideoCodec)],
+		CopyAudio: true,
+	}
+	if video.MediaInfo.AudioCodecs != "" {
+		// 只输出第一条音轨，与 -map 0:a:0 对应
+		firstAudio := strings.ToLower(strings.Split(video.MediaInfo.AudioCodecs, ",")[0])
+		plan.CopyAudio = browserAudioCodecs[firstAudio]
+	} else if video.MediaInfo.MediaProbedAt == nil {
+		plan.CopyAudio = false
+	}
+	plan.Mode = TranscodeModeTranscode
+	if plan.CopyVideo && plan.CopyAudio {
+		plan.Mode = TranscodeModeRemux
+	}
+	return plan
+}
+
+// ParseTranscodeAssetPath 解析 "<视频ID>/stream.mp4" 或 "<视频ID>/hls/<文件>" 形式的资源路径
+func ParseTranscodeAssetPath(rest string) (uint, string, bool) {
+	idText, resource, found := strings.Cut(rest, "/")
+	if !found || resource == "" {
+		return 0, "", false
+	}
+	id, err := strconv.ParseUint(idText, 10, 64)
+	if err != nil || id == 0 {
+		return 0, "", false
+	}
+	return uint(id), resource, true
+}
+
+// ServeAsset 输出转码资源；返回错误时尚未写入响应，由调用方按各自格式返回错误
+func (s *TranscodeService) ServeAsset(w http.ResponseWriter, r *http.Request, video models.Video, resource string) error {
+	if !s.Available() {
+		return ErrTranscodeUnavailable
+	}
+	switch {
+	case resource == TranscodeStreamResource:
+		start, _ := strconv.ParseFloat(r.URL.Query().Get("start"), 64)
+		return s.streamFragmentedMP4(w, r, video, start)
+	case strings.HasPrefix(resource, transcodeHLSPrefix):
+		name := strings.TrimPrefix(resource, transcodeHLSPrefix)
+		path, mimeType, err := s.resolveHLSFile(r.Context(), video, name)
+		if err != nil {
+			return err
+		}
+		w.Header().Set("Content-Type", mimeType)
+		w.Header().Set("Cache-Control", "no-store")
+		http.ServeFile(w, r, path)
+		return nil
+	default:
+		return ErrInvalidTranscodeResource
+	}
+}
+
+// TranscodeHTTPStatus 把转码错误映射为 HTTP 状态码
+func TranscodeHTTPStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrTranscodeBusy), errors.Is(err, ErrTranscodeUnavailable):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, ErrInvalidTranscodeResource):
+		return http.StatusBadRequest
+	case errors.Is(err, os.ErrNotExist):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func transcodeInputArgs(video models.Video, start float64) []string {
+	args := []string{"-hide_banner", "-loglevel", "error"}
+	if start > 0 {
+		args = append(args, "-ss", strconv.FormatFloat(start, 'f', 3, 64))
+	}
+	return append(args, "-i", video.Path, "-map", "0:v:0", "-map", "0:a:0?", "-sn", "-dn")
+}
+
+func transcodeCodecArgs(plan TranscodePlan) []string {
+	var args []string
+	if plan.CopyVideo {
+		args = append(args, "-c:v", "copy")
+	} else {
+		args = append(args,
+			"-c:v", "libx264", "-preset", "veryfast", "-crf", "23", "-pix_fmt", "yuv420p",
+			"-vf", fmt.Sprintf("scale='min(%d,iw)':-2", transcodeMaxOutputWidth),
+		)
+	}
+	if plan.CopyAudio {
+		args = append(args, "-c:a", "copy")
+	} else {
+		args = append(args, "-c:a", "aac", "-b:a", "160k", "-ac", "2")
+	}
+	return args
+}
+
+// streamFragmentedMP4 经管道把 ffmpeg 输出的分片 MP4 写入响应；客户端断开时 ffmpeg 随请求上下文终止
+func (s *TranscodeService) streamFragmentedMP4(w http.ResponseWriter, r *http.Request, video models.Video, start float64) error {
+	plan := PlanTranscode(video)
+	if r.Method == http.MethodHead {
+		w.Header().Set("Content-Type", "video/mp4")
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	session, err := s.reserveStreamSession(video.ID, plan.Mode, cancel)
+	if err != nil {
+		return err
+	}
+	defer s.removeSession(session)
+
+	args := transcodeInputArgs(video, start)
+	args = append(args, transcodeCodecArgs(plan)...)
+	args = append(args, "-movflags", "frag_keyframe+empty_moov+default_base_moof", "-f", "mp4", "pipe:1")
+	cmd := exec.CommandContext(ctx, findMediaBinary("ffmpeg"), args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "video/mp4")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Transcode-Mode", plan.Mode)
+	w.WriteHeader(http.StatusOK)
+	_, copyErr := io.Copy(flushWriter{w}, bufio.NewReaderSize(stdout, 64*1024))
+	waitErr := cmd.Wait()
+	if ctx.Err() == nil && (copyErr != nil || waitErr != nil) {
+		log.Printf("[Transcode] stream failed video=%d mode=%s copyErr=%v waitErr=%v stderr=%s",
+			video.ID, plan.Mode, copyErr, waitErr, truncateLogSnippet(stderr.String(), 200))
+	}
+	return nil
+}
+
+type flushWriter struct {
+	w http.ResponseWriter
+}
+
+func (f flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	if flusher, ok := f.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return n, err
+}
+
+// resolveHLSFile 返回 HLS 播放列表或已写完的分片；同一视频复用一个 ffmpeg 会话
+func (s *TranscodeService) resolveHLSFile(ctx context.Context, video models.Video, name string) (string, string, error) {
+	mimeType := "video/mp2t"
+	if name == TranscodeHLSPlaylist {
+		mimeType = "application/vnd.apple.mpegurl"
+	} else if !hlsSegmentNamePattern.MatchString(name) {
+		return "", "", ErrInvalidTranscodeResource
+	}
+
+	session, err := s.hlsSession(video)
+	if err != nil {
+		return "", "", err
+	}
+	path := filepath.Join(session.dir, name)
+	playlist := filepath.Join(session.dir, TranscodeHLSPlaylist)
+
+	// ffmpeg 在分片写完后才把它追加进播放列表，以此判断分片是否完整
+	ready := func() bool {
+		content, err := os.ReadFile(playlist)
+		return err == nil && (name == TranscodeHLSPlaylist || strings.Contains(string(content), name))
+	}
+	deadline := time.NewTimer(transcodeHLSWaitTimeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(transcodeHLSPollInterval)
+	defer ticker.Stop()
+	for {
+		if ready() {
+			s.touchSession(session)
+			return path, mimeType, nil
+		}
+		select {
+		case <-session.done:
+			// 进程已退出，不会再有新分片
+			if ready() {
+				s.touchSession(session)
+				return path, mimeType, nil
+			}
+			if session.err != nil {
+				return "", "", session.err
+			}
+			return "", "", os.ErrNotExist
+		case <-ctx.Done():
+			return "", "", ctx.Err()
+		case <-deadline.C:
+			return "", "", fmt.Errorf("等待 HLS 分片超时: %s", name)
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *TranscodeService) hlsSession(video models.Video) (*transcodeSession, error) {
+	key := fmt.Sprintf("hls-%d", video.ID)
+	plan := PlanTranscode(video)
+
+	s.mu.Lock()
+	if session, ok := s.sessions[key]; ok {
+		session.lastAccess = time.Now()
+		s.mu.Unlock()
+		return session, nil
+	}
+	var evicted *transcodeSession
+	if len(s.sessions) >= s.config.MaxSessions {
+		evicted = s.evictableHLSSessionLocked()
+		if evicted == nil {
+			s.mu.Unlock()
+			return nil, ErrTranscodeBusy
+		}
+		delete(s.sessions, evicted.key)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	session := &transcodeSession{
+		key:        key,
+		videoID:    video.ID,
+		kind:       "hls",
+		mode:       plan.Mode,
+		dir:        filepath.Join(s.config.TempDir, fmt.Sprintf("%d-%d", video.ID, time.Now().UnixNano())),
+		startedAt:  time.Now(),
+		lastAccess: time.Now(),
+		cancel:     cancel,
+		done:       make(chan struct{}),
+	}
+	s.sessions[key] = session
+	s.mu.Unlock()
+
+	if evicted != nil {
+		s.closeSession(evicted)
+	}
+	if err := os.MkdirAll(session.dir, 0755); err != nil {
+		s.removeSession(session)
+		cancel()
+		return nil, err
+	}
+
+	args := transcodeInputArgs(video, 0)
+	args = append(args, transcodeCodecArgs(plan)...)
+	args = append(args,
+		"-f", "hls",
+		"-hls_time", strconv.Itoa(transcodeHLSSegmentSeconds),
+		"-hls_list_size", "0",
+		"-hls_playlist_type", "event",
+		"-hls_segment_filename", filepath.Join(session.dir, "seg-%05d.ts"),
+		filepath.Join(session.dir, TranscodeHLSPlaylist),
+	)
+	cmd := exec.CommandContext(ctx, findMediaBinary("ffmpeg"), args...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		s.removeSession(session)
+		cancel()
+		_ = os.RemoveAll(session.dir)
+		return nil, err
+	}
+	log.Printf("[Transcode] hls session started video=%d mode=%s dir=%s", video.ID, plan.Mode, session.dir)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		err := cmd.Wait()
+		if err != nil && ctx.Err() == nil {
+			session.err = fmt.Errorf("%v %s", err, truncateLogSnippet(stderr.String(), 200))
+			log.Printf("[Transcode] hls session failed video=%d err=%v", video.ID, session.err)
+		}
+		close(session.done)
+	}()
+	return session, nil
+}
+
+// evictableHLSSessionLocked 会话已满时挑出最久未访问、且已空闲一段时间的 HLS 会话让位
+func (s *TranscodeService) evictableHLSSessionLocked() *transcodeSession {
+	var candidate *transcodeSession
+	for _, session := range s.sessions {
+		if session.kind != "hls" || time.Since(session.lastAccess) < transcodeHLSEvictAfter {
+			continue
+		}
+		if candidate == nil || session.lastAccess.Before(candidate.lastAccess) {
+			candidate = session
+		}
+	}
+	return candidate
+}
+
+func (s *TranscodeService) reserveStreamSession(videoID uint, mode string, cancel context.CancelFunc) (*transcodeSession, error) {
+	s.mu.Lock()
+	var evicted *transcodeSession
+	if len(s.sessions) >= s.config.MaxSessions {
+		evicted = s.evictableHLSSessionLocked()
+		if evicted == nil {
+			s.mu.Unlock()
+			return nil, ErrTranscodeBusy
+		}
+		delete(s.sessions, evicted.key)
+	}
+	s.nextStreamID++
+	session := &transcodeSession{
+		key:        fmt.Sprintf("stream-%d", s.nextStreamID),
+		videoID:    videoID,
+		kind:       "stream",
+		mode:       mode,
+		startedAt:  time.Now(),
+		lastAccess: time.Now(),
+		cancel:     cancel,
+	}
+	s.sessions[session.key] = session
+	s.mu.Unlock()
+
+	if evicted != nil {
+		s.closeSession(evicted)
+	}
+	return session, nil
+}
+
+func (s *TranscodeService) touchSession(session *transcodeSession) {
+	s.mu.Lock()
+	session.lastAccess = time.Now()
+	s.mu.Unlock()
+}
+
+func (s *TranscodeService) removeSession(session *transcodeSession) {
+	s.mu.Lock()
+	if current, ok := s.sessions[session.key]; ok && current == session {
+		delete(s.sessions, session.key)
+	}
+	s.mu.Unlock()
+}
+
+// closeSession 终止会话的 ffmpeg 并删除其临时分片
+func (s *TranscodeService) closeSession(session *transcodeSession) {
+	s.removeSession(session)
+	session.cancel()
+	if session.done != nil {
+		<-session.done
+	}
+	if session.dir != "" {
+		if err := os.RemoveAll(session.dir); err != nil {
+			log.Printf("[Transcode] remove segments failed dir=%s err=%v", session.dir, err)
+		}
+	}
+}
+
+func (s *TranscodeService) janitorLoop(ctx context.Context) {
+	defer s.wg.Done()
+	interval := s.config.IdleTimeout / 2
+	if interval < transcodeHLSPollInterval {
+		interval = transcodeHLSPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.reapIdleSessions()
+		}
+	}
+}
+
+func (s *TranscodeService) reapIdleSessions() {
+	s.mu.Lock()
+	var idle []*transcodeSession
+	for _, session := range s.sessions {
+		if session.kind == "hls" && time.Since(session.lastAccess) >= s.config.IdleTimeout {
+			idle = append(idle, session)
+		}
+	}
+	s.mu.Unlock()
+	for _, session := range idle {
+		log.Printf("[Transcode] reap idle hls session video=%d", session.videoID)
+		s.closeSession(session)
+	}
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+	"video-master/database"
+	"video-master/models"
+)
+
+func mockTranscodeFFmpeg(t *testing.T, root string) string {
+	t.Helper()
+	ffmpegDir := filepath.Join(root, "bin")
+	if err := os.MkdirAll(ffmpegDir, 0755); err != nil {
+		t.Fatalf("创建 ffmpeg 目录失败: %v", err)
+	}
+	logPath := filepath.Join(root, "ffmpeg.log")
+	script := `#!/bin/bash
+out="${@: -1}"
+echo "$*" >> "` + logPath + `"
+if [ "$out" = "pipe:1" ]; then
+  printf 'fmp4-stream'
+  exit 0
+fi
+dir=$(dirname "$out")
+printf 'ts' > "$dir/seg-00000.ts"
+printf '#EXTM3U\n#EXTINF:4.0,\nseg-00000.ts\n#EXT-X-ENDLIST\n' > "$out"
+`
+	if err := os.WriteFile(filepath.Join(ffmpegDir, "ffmpeg"), []byte(script), 0755); err != nil {
+		t.Fatalf("写入 ffmpeg stub 失败: %v", err)
+	}
+	t.Setenv("PATH", ffmpegDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return logPath
+}
+
+func TestPlanTranscodeRemuxesBrowserCompatibleCodecs(t *testing.T) {
+	probedAt := time.Now()
+	cases := []struct {
+		name  string
+		media models.VideoMediaInfo
+		want  TranscodePlan
+	}{
+		{"H.264 + AAC 只换封装", models.VideoMediaInfo{VideoCodec: "h264", AudioCodecs: "aac,ac3", MediaProbedAt: &probedAt},
+			TranscodePlan{Mode: TranscodeModeRemux, CopyVideo: true, CopyAudio: true}},
+		{"HEVC 需要转码视频", models.VideoMediaInfo{VideoCodec: "hevc", AudioCodecs: "aac", MediaProbedAt: &probedAt},
+			TranscodePlan{Mode: TranscodeModeTranscode, CopyVideo: false, CopyAudio: true}},
+		{"首条音轨不兼容时转码音频", models.VideoMediaInfo{VideoCodec: "h264", AudioCodecs: "ac3,aac", MediaProbedAt: &probedAt},
+			TranscodePlan{Mode: TranscodeModeTranscode, CopyVideo: true, CopyAudio: false}},
+		{"无音轨", models.VideoMediaInfo{VideoCodec: "h264", MediaProbedAt: &probedAt},
+			TranscodePlan{Mode: TranscodeModeRemux, CopyVideo: true, CopyAudio: true}},
+		{"未解析时全部转码", models.VideoMediaInfo{},
+			TranscodePlan{Mode: TranscodeModeTranscode}},
+	}
+	for _, tc := range cases {
+		if got := PlanTranscode(models.Video{MediaInfo: tc.media}); got != tc.want {
+			t.Fatalf("%s: got=%+v want=%+v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestShortFeedStreamsNonInlineFormatAsFragmentedMP4(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	ffmpegLog := mockTranscodeFFmpeg(t, t.TempDir())
+	video := createShortFeedVideo(t, t.TempDir(), "clip.mkv", 30, false)
+	probedAt := time.Now()
+	if err := database.DB.Model(&video).Updates(map[string]interface{}{
+		"video_codec": "h264", "audio_codecs": "aac", "media_probed_at": probedAt,
+	}).Error; err != nil {
+		t.Fatalf("写入媒体信息失败: %v", err)
+	}
+
+	videoService := &VideoService{}
+	videoService.SetTranscodeService(NewTranscodeService(TranscodeConfig{TempDir: t.TempDir()}))
+	feed := NewShortFeedService(videoService)
+	handler := NewShortFeedHTTPServer(feed, fstest.MapFS{}, ShortFeedHTTPServerConfig{}).Handler()
+
+	dto, err := feed.NextVideo(nil)
+	if err != nil {
+		t.Fatalf("mkv 可转码时应进入 Feed: %v", err)
+	}
+	id := strconvUint(video.ID)
+	if dto.ReasonCode != "" || dto.MediaURL != "/short-media/"+id+"/stream.mp4" || dto.MediaMIME != "video/mp4" ||
+		dto.HLSURL != "/short-media/"+id+"/hls/index.m3u8" || dto.TranscodeMode != TranscodeModeRemux {
+		t.Fatalf("转码 DTO 错误: %+v", dto)
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, dto.MediaURL+"?start=5", nil)
+	req.RemoteAddr = "127.0.0.1:1234"
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "video/mp4" || rec.Body.String() != "fmp4-stream" {
+		t.Fatalf("分片 MP4 响应错误 got=%d type=%s body=%q", rec.Code, rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+	calls, err := os.ReadFile(ffmpegLog)
+	if err != nil {
+		t.Fatalf("读取 ffmpeg 调用记录失败: %v", err)
+	}
+	for _, want := range []string{"-ss 5.000", "-c:v copy", "-c:a copy", "frag_keyframe+empty_moov", "pipe:1"} {
+		if !strings.Contains(string(calls), want) {
+			t.Fatalf("ffmpeg 参数缺少 %q: %s", want, calls)
+		}
+	}
+	if sessions := videoService.transcoder.Status().Sessions; len(sessions) != 0 {
+		t.Fatalf("流结束后会话应释放: %+v", sessions)
+	}
+}
+
+func TestTranscodeHLSSessionCapAndIdleCleanup(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	ffmpegLog := mockTranscodeFFmpeg(t, t.TempDir())
+	root := t.TempDir()
+	var videos []models.Video
+	for _, name := range []string{"a.avi", "b.wmv"} {
+		path := filepath.Join(root, name)
+		mustCreateFile(t, path)
+		video := models.Video{Name: name, Path: path, Directory: root, Size: 1, Duration: 60}
+		if err := database.DB.Create(&video).Error; err != nil {
+			t.Fatalf("创建视频失败: %v", err)
+		}
+		videos = append(videos, video)
+	}
+
+	tempDir := filepath.Join(t.TempDir(), "transcode")
+	transcoder := NewTranscodeService(TranscodeConfig{MaxSessions: 1, IdleTimeout: 300 * time.Millisecond, TempDir: tempDir})
+	videoService := &VideoService{}
+	videoService.SetTranscodeService(transcoder)
+	transcoder.Start(context.Background())
+	t.Cleanup(transcoder.Stop)
+
+	session, err := videoService.GetPreviewSession(videos[0].ID)
+	if err != nil {
+		t.Fatalf("获取预览会话失败: %v", err)
+	}
+	source := session.InlineSource
+	if session.Mode != "inline" || source == nil || source.LocatorStrategy != LocatorStrategyTranscode ||
+		source.TranscodeMode != TranscodeModeTranscode || source.Duration != 60 ||
+		source.HLSPlaylist != "/preview/media/"+strconvUint(videos[0].ID)+"/hls/index.m3u8" {
+		t.Fatalf("avi 应走转码内嵌预览: %+v %+v", session, source)
+	}
+
+	serve := func(videoID uint, resource string) (*httptest.ResponseRecorder, error) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/preview/media/"+strconvUint(videoID)+"/"+resource, nil)
+		return rec, videoService.ServeTranscode(rec, req, videoID, resource)
+	}
+	playlist, err := serve(videos[0].ID, "hls/index.m3u8")
+	if err != nil || !strings.Contains(playlist.Body.String(), "seg-00000.ts") {
+		t.Fatalf("播放列表响应错误 err=%v body=%q", err, playlist.Body.String())
+	}
+	segment, err := serve(videos[0].ID, "hls/seg-00000.ts")
+	if err != nil || segment.Body.String() != "ts" || segment.Header().Get("Content-Type") != "video/mp2t" {
+		t.Fatalf("分片响应错误 err=%v body=%q", err, segment.Body.String())
+	}
+	if _, err := serve(videos[0].ID, "hls/../../etc/passwd"); !errors.Is(err, ErrInvalidTranscodeResource) {
+		t.Fatalf("非法分片名应被拒绝，err=%v", err)
+	}
+	calls, _ := os.ReadFile(ffmpegLog)
+	if !strings.Contains(string(calls), "-c:v libx264") || !strings.Contains(string(calls), "-c:a aac") {
+		t.Fatalf("未解析编码的视频应转码: %s", calls)
+	}
+
+	// 会话数已满，且现有会话刚被访问，不能被挤占
+	if _, err := serve(videos[1].ID, "hls/index.m3u8"); !errors.Is(err, ErrTranscodeBusy) {
+		t.Fatalf("超过并发上限应返回 busy，err=%v", err)
+	}
+
+	// 空闲超时后会话被回收，临时分片被删除
+	waitForCondition(t, 5*time.Second, "空闲 HLS 会话应被回收", func() bool {
+		return len(transcoder.Status().Sessions) == 0
+	})
+	entries, err := os.ReadDir(tempDir)
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("回收后临时分片应删除 entries=%v err=%v", entries, err)
+	}
+	if _, err := serve(videos[1].ID, "hls/index.m3u8"); err != nil {
+		t.Fatalf("回收后应可启动新会话: %v", err)
+	}
+
+	transcoder.Stop()
+	if _, err := os.Stat(tempDir); !os.IsNotExist(err) {
+		t.Fatalf("停止后临时目录应删除，err=%v", err)
+	}
+}
@@ -2,17 +2,18 @@ package services
 
 import (
 	"bytes"
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
 	"log"
-	"math/rand"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 	"video-master/database"
 	"video-master/models"
@@ -20,10 +21,48 @@ import (
 	"gorm.io/gorm"
 )
 
-type VideoService struct{}
+type VideoService struct {
+	thumbnails *ThumbnailService
+	transcoder *TranscodeService
+}
+
+// SetThumbnailService 注入缩略图服务，迁移、改名与删除时同步失效缓存
+func (s *VideoService) SetThumbnailService(thumbnails *ThumbnailService) {
+	s.thumbnails = thumbnails
+}
+
+// SetTranscodeService 注入转码服务，不能内嵌播放的格式改为实时转封装/转码预览
+func (s *VideoService) SetTranscodeService(transcoder *TranscodeService) {
+	s.transcoder = transcoder
+}
 
 const recentActiveFileThreshold = 5 * time.Minute
 
+// scanSyncSem 串行化增量同步；用单槽信号量而非互斥锁，等待期间可随 ctx 取消
+var scanSyncSem = make(chan struct{}, 1)
+
+// acquireScanSync 获取同步槽位，需要等待时先调用 onWait；ctx 先被取消时返回 false
+func acquireScanSync(ctx context.Context, onWait func()) bool {
+	select {
+	case scanSyncSem <- struct{}{}:
+		return true
+	default:
+	}
+	if onWait != nil {
+		onWait()
+	}
+	select {
+	case scanSyncSem <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func releaseScanSync() {
+	<-scanSyncSem
+}
+
 var tempVideoStemSuffixes = []string{
 	".temp", "_temp", "-temp",
 	".tmp", "_tmp", "-tmp",
@@ -48,15 +87,53 @@ type ScanSyncError struct {
 	Error     string `json:"error"`
 }
 
+// ScanSyncAmbiguousMatch 描述无法唯一配对的迁移候选：相关记录保留待人工确认，候选文件暂不入库
+type ScanSyncAmbiguousMatch struct {
+	Strategy       string   `json:"strategy"`
+	VideoIDs       []uint   `json:"video_ids"`
+	VideoPaths     []string `json:"video_paths"`
+	CandidatePaths []string `json:"candidate_paths"`
+}
+
 type ScanSyncResult struct {
-	Directories       int             `json:"directories"`
-	Scanned           int             `json:"scanned"`
-	Added             int             `json:"added"`
-	Deleted           int             `json:"deleted"`
-	Relocated         int             `json:"relocated"`
-	MetadataRefreshed int             `json:"metadata_refreshed"`
-	Skipped           int             `json:"skipped"`
-	Errors            []ScanSyncError `json:"errors"`
+	Directories       int                      `json:"directories"`
+	Scanned           int                      `json:"scanned"`
+	Added             int                      `json:"added"`
+	Deleted           int                      `json:"deleted"`
+	Relocated         int                      `json:"relocated"`
+	MetadataRefreshed int                      `json:"metadata_refreshed"`
+	HashesComputed    int                      `json:"hashes_computed"`
+	RuleTagged        int                      `json:"rule_tagged"` // 自动打标规则给新增视频建立的关联数
+	Skipped           int                      `json:"skipped"`
+	Cancelled         bool                     `json:"cancelled"`
+	Offline           []string                 `json:"offline"` // 离线（未挂载）而跳过的扫描根目录
+	Ambiguous         []ScanSyncAmbiguousMatch `json:"ambiguous"`
+	Errors            []ScanSyncError          `json:"errors"`
+}
+
+func (r *ScanSyncResult) recordAmbiguous(strategy string, videos []models.Video, files []ScannedFile) {
+	match := ScanSyncAmbiguousMatch{
+		Strategy:       strategy,
+		VideoIDs:       make([]uint, 0, len(videos)),
+		VideoPaths:     make([]string, 0, len(videos)),
+		CandidatePaths: make([]string, 0, len(files)),
+	}
+	for _, video := range videos {
+		match.VideoIDs = append(match.VideoIDs, video.ID)
+		match.VideoPaths = append(match.VideoPaths, video.Path)
+	}
+	for _, file := range files {
+		match.CandidatePaths = append(match.CandidatePaths, file.Path)
+	}
+	r.Ambiguous = append(r.Ambiguous, match)
+}
+
+// cancel 标记同步被取消；已完成的变更保留，未执行的阶段（含删除）全部跳过
+func (r *ScanSyncResult) cancel() *ScanSyncResult {
+	r.Cancelled = true
+	log.Printf("增量扫描同步已取消 dirs=%d scanned=%d added=%d relocated=%d refreshed=%d hashed=%d errors=%d",
+		r.Directories, r.Scanned, r.Added, r.Relocated, r.MetadataRefreshed, r.HashesComputed, len(r.Errors))
+	return r
 }
 
 func (r *ScanSyncResult) recordError(operation, directory, path string, err error) {
@@ -131,61 +208,23 @@ func (s *VideoService) GetVideosPaginated(cursorScore float64, cursorSize int64,
 	return videos, err
 }
 
-// SearchVideos 搜索视频（按名称）- 支持分页（按概率优先排序）
+// SearchVideos 搜索视频（名称或路径，支持搜索语法）- 支持分页（按概率优先排序）
 func (s *VideoService) SearchVideos(keyword string, cursorScore float64, cursorSize int64, cursorID uint, limit int) ([]models.Video, error) {
-	return s.SearchVideosWithFilters(keyword, nil, 0, 0, 0, 0, cursorScore, cursorSize, cursorID, limit)
+	return s.SearchVideosWithFilters(keyword, nil, 0, 0, 0, 0, VideoMediaFilter{}, cursorScore, cursorSize, cursorID, limit)
 }
 
 // SearchVideosByTags 按标签搜索（多选 AND）- 支持分页（按概率优先排序）
 func (s *VideoService) SearchVideosByTags(tagIDs []uint, cursorScore float64, cursorSize int64, cursorID uint, limit int) ([]models.Video, error) {
-	return s.SearchVideosWithFilters("", tagIDs, 0, 0, 0, 0, cursorScore, cursorSize, cursorID, limit)
-}
-
-type ffprobeStream struct {
-	Width    int    `json:"width"`
-	Height   int    `json:"height"`
-	Duration string `json:"duration"`
-}
-
-type ffprobePayload struct {
-	Streams []ffprobeStream `json:"streams"`
-	Format  struct {
-		Duration string `json:"duration"`
-	} `json:"format"`
+	return s.SearchVideosWithFilters("", tagIDs, 0, 0, 0, 0, VideoMediaFilter{}, cursorScore, cursorSize, cursorID, limit)
 }
 
+// parseFFProbeOutput 只取时长与分辨率，完整字段见 parseFFProbeDetails
 func parseFFProbeOutput(output []byte) (duration float64, resolution string, width, height int, err error) {
-	trimmed := bytes.TrimSpace(output)
-	if len(trimmed) == 0 {
-		return 0, "", 0, 0, errors.New("empty ffprobe output")
-	}
-
-	var data ffprobePayload
-	if err := json.Unmarshal(trimmed, &data); err != nil {
+	meta, err := parseFFProbeDetails(output)
+	if err != nil {
 		return 0, "", 0, 0, err
 	}
-	if len(data.Streams) == 0 {
-		return 0, "", 0, 0, errors.New("ffprobe returned no video stream")
-	}
-
-	stream := data.Streams[0]
-	width = stream.Width
-	height = stream.Height
-	if width > 0 && height > 0 {
-		resolution = fmt.Sprintf("%dx%d", width, height)
-	}
-
-	durationText := strings.TrimSpace(stream.Duration)
-	if durationText == "" {
-		durationText = strings.TrimSpace(data.Format.Duration)
-	}
-	if durationText != "" {
-		if _, scanErr := fmt.Sscanf(durationText, "%f", &duration); scanErr != nil {
-			return 0, "", 0, 0, fmt.Errorf("invalid duration %q: %w", durationText, scanErr)
-		}
-	}
-
-	return duration, resolution, width, height, nil
+	return meta.Duration, meta.Resolution, meta.Width, meta.Height, nil
 }
 
 func truncateLogSnippet(text string, limit int) string {
@@ -196,8 +235,37 @@ func truncateLogSnippet(text string, limit int) string {
 	return trimmed[:limit] + "...(truncated)"
 }
 
-// SearchVideosWithFilters 组合搜索（关键词 + 标签 + 体积 + 分辨率 AND）- 支持分页（按概率优先排序）
-func (s *VideoService) SearchVideosWithFilters(keyword string, tagIDs []uint, minSize, maxSize int64, minHeight, maxHeight int, cursorScore float64, cursorSize int64, cursorID uint, limit int) ([]models.Video, error) {
+// SearchVideosWithFilters 组合搜索（关键词 + 标签 + 体积 + 分辨率 + 媒体信息 AND）- 支持分页（按概率优先排序）
+func (s *VideoService) SearchVideosWithFilters(keyword string, tagIDs []uint, minSize, maxSize int64, minHeight, maxHeight int, media VideoMediaFilter, cursorScore float64, cursorSize int64, cursorID uint, limit int) ([]models.Video, error) {
+	filter := VideoSearchFilter{
+		Keyword:   keyword,
+		TagIDs:    tagIDs,
+		MinSize:   minSize,
+		MaxSize:   maxSize,
+		MinHeight: minHeight,
+		MaxHeight: maxHeight,
+		Media:     media,
+	}
+	return s.SearchVideosByFilter(filter, cursorScore, cursorSize, cursorID, limit)
+}
+
+// VideoSearchFilter 组合搜索条件，零值字段不限；保存的搜索以 JSON 形式存储该结构
+type VideoSearchFilter struct {
+	Keyword     string           `json:"keyword"`      // 搜索语法，见 search_query.go
+	TagIDs      []uint           `json:"tag_ids"`      // 需同时包含全部标签
+	MinSize     int64            `json:"min_size"`     // 体积下限（含）
+	MaxSize     int64            `json:"max_size"`     // 体积上限（不含）
+	MinHeight   int              `json:"min_height"`   // 高度下限（含）
+	MaxHeight   int              `json:"max_height"`   // 高度上限（含）
+	MinDuration float64          `json:"min_duration"` // 时长下限（秒，含）
+	MaxDuration float64          `json:"max_duration"` // 时长上限（秒，不含）
+	Media       VideoMediaFilter `json:"media"`
+
+	IncludeTagDescendants bool `json:"include_tag_descendants"` // 标签也匹配其子孙标签
+}
+
+// SearchVideosByFilter 按组合条件游标分页搜索（按概率优先排序）
+func (s *VideoService) SearchVideosByFilter(filter VideoSearchFilter, cursorScore float64, cursorSize int64, cursorID uint, limit int) ([]models.Video, error) {
 	var videos []models.Video
 	playWeight, err := s.getPlayWeight()
 	if err != nil {
@@ -210,32 +278,9 @@ func (s *VideoService) SearchVideosWithFilters(keyword string, tagIDs []uint, mi
 		Order("videos.size desc").
 		Order("videos.id desc")
 
-	if strings.TrimSpace(keyword) != "" {
-		kw := "%" + strings.TrimSpace(keyword) + "%"
-		query = query.Where("(videos.name LIKE ? OR videos.path LIKE ?)", kw, kw)
-	}
-
-	// 体积过滤 (左闭右开 [min, max) )
-	if minSize > 0 {
-		query = query.Where("videos.size >= ?", minSize)
-	}
-	if maxSize > 0 {
-		query = query.Where("videos.size < ?", maxSize)
-	}
-
-	// 分辨率过滤 (按高度判断)
-	if minHeight > 0 {
-		query = query.Where("videos.height >= ?", minHeight)
-	}
-	if maxHeight > 0 {
-		query = query.Where("videos.height <= ?", maxHeight)
-	}
-
-	if len(tagIDs) > 0 {
-		query = query.Joins("JOIN video_tags ON video_tags.video_id = videos.id").
-			Where("video_tags.tag_id IN ?", tagIDs)
-		query = query.Group("videos.id").
-			Having("COUNT(DISTINCT video_tags.tag_id) = ?", len(tagIDs))
+	query, err = applyVideoSearchFilter(query, filter)
+	if err != nil {
+		return nil, err
 	}
 
 	query = applyCursorCondition(query, scoreSql, cursorScore, cursorSize, cursorID, "videos.")
@@ -244,52 +289,75 @@ func (s *VideoService) SearchVideosWithFilters(keyword string, tagIDs []uint, mi
 	return videos, err
 }
 
-// getVideoMetadata 使用 ffprobe 获取视频时长、分辨率、宽、高
-func (s *VideoService) getVideoMetadata(path string) (duration float64, resolution string, width, height int) {
-	ffprobeBin, err := exec.LookPath("ffprobe")
+// CountVideosByFilter 统计满足组合条件的视频数
+func (s *VideoService) CountVideosByFilter(filter VideoSearchFilter) (int64, error) {
+	query, err := applyVideoSearchFilter(database.DB.Model(&models.Video{}), filter)
 	if err != nil {
-		// 尝试常见安装路径 (Homebrew)
-		if runtime.GOOS == "darwin" {
-			paths := []string{"/opt/homebrew/bin/ffprobe", "/usr/local/bin/ffprobe"}
-			for _, p := range paths {
-				if _, err := os.Stat(p); err == nil {
-					ffprobeBin = p
-					break
-				}
-			}
-		}
+		return 0, err
 	}
+	var count int64
+	err = query.Count(&count).Error
+	return count, err
+}
 
-	if ffprobeBin == "" {
-		log.Printf("[VideoService] ffprobe not found, skipping metadata extraction")
-		return 0, "", 0, 0
+// applyVideoSearchFilter 把组合条件追加到 videos 查询；只使用 WHERE，可与游标分页和 Count 组合
+func applyVideoSearchFilter(query *gorm.DB, filter VideoSearchFilter) (*gorm.DB, error) {
+	// 关键词按搜索语法解析，见 search_query.go
+	query, err := applySearchQuery(query, filter.Keyword)
+	if err != nil {
+		return nil, err
 	}
 
-	// 获取时长和分辨率 (JSON 格式)
-	cmd := exec.Command(ffprobeBin, "-v", "error", "-select_streams", "v:0",
-		"-show_entries", "stream=width,height,duration:format=duration", "-of", "json", path)
-	var stdout bytes.Buffer
-	var stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	// 体积过滤 (左闭右开 [min, max) )
+	if filter.MinSize > 0 {
+		query = query.Where("videos.size >= ?", filter.MinSize)
+	}
+	if filter.MaxSize > 0 {
+		query = query.Where("videos.size < ?", filter.MaxSize)
+	}
 
-	if err := cmd.Run(); err != nil {
-		log.Printf("[VideoService] ffprobe failed for %s: %v stderr=%s", path, err, truncateLogSnippet(stderr.String(), 400))
-		return 0, "", 0, 0
+	// 分辨率过滤 (按高度判断)
+	if filter.MinHeight > 0 {
+		query = query.Where("videos.height >= ?", filter.MinHeight)
+	}
+	if filter.MaxHeight > 0 {
+		query = query.Where("videos.height <= ?", filter.MaxHeight)
 	}
 
-	duration, resolution, width, height, err = parseFFProbeOutput(stdout.Bytes())
+	if filter.MinDuration > 0 {
+		query = query.Where("videos.duration >= ?", filter.MinDuration)
+	}
+	if filter.MaxDuration > 0 {
+		query = query.Where("videos.duration < ?", filter.MaxDuration)
+	}
+
+	query, err = applyVideoMediaFilter(query, filter.Media)
 	if err != nil {
-		log.Printf("[VideoService] failed to parse ffprobe output for %s: %v stdout=%s stderr=%s",
-			path,
-			err,
-			truncateLogSnippet(stdout.String(), 400),
-			truncateLogSnippet(stderr.String(), 400),
-		)
-		return 0, "", 0, 0
+		return nil, err
 	}
 
-	return duration, resolution, width, height
+	if len(filter.TagIDs) > 0 && filter.IncludeTagDescendants {
+		// 每个选中标签展开为子树，视频需命中每棵子树中的至少一个标签
+		for _, tagID := range filter.TagIDs {
+			subtree, err := tagSubtreeIDs([]uint{tagID})
+			if err != nil {
+				return nil, err
+			}
+			if len(subtree) == 0 {
+				subtree = []uint{tagID}
+			}
+			query = query.Where("videos.id IN (?)", database.DB.Table("video_tags").
+				Select("video_id").
+				Where("tag_id IN ?", subtree))
+		}
+	} else if len(filter.TagIDs) > 0 {
+		query = query.Where("videos.id IN (?)", database.DB.Table("video_tags").
+			Select("video_id").
+			Where("tag_id IN ?", filter.TagIDs).
+			Group("video_id").
+			Having("COUNT(DISTINCT tag_id) = ?", len(filter.TagIDs)))
+	}
+	return query, nil
 }
 
 // AddVideo 添加视频
@@ -312,30 +380,49 @@ func (s *VideoService) AddVideo(path string) (*models.Video, error) {
 		return &existingVideo, ErrVideoExists
 	}
 
-	duration, resolution, width, height := s.getVideoMetadata(path)
+	video, err := insertVideoRecord(s.buildVideoRecord(context.Background(), path, info))
+	if err == nil {
+		applyTagRulesToNewVideos([]models.Video{*video})
+	}
+	return video, err
+}
 
-	video := &models.Video{
-		Name:       filepath.Base(path),
-		Path:       path,
-		Directory:  filepath.Dir(path),
-		Size:       info.Size(),
-		Duration:   duration,
-		Resolution: resolution,
-		Width:      width,
-		Height:     height,
+// buildVideoRecord 读取 ffprobe 元数据与采样哈希组装新记录；不访问数据库，可在扫描 worker 中并发调用
+func (s *VideoService) buildVideoRecord(ctx context.Context, path string, info os.FileInfo) *models.Video {
+	meta := s.probeVideoMetadata(ctx, path)
+	contentHash, err := getPartialHash(path)
+	if err != nil {
+		log.Printf("[VideoService] content hash failed for %s: %v", path, err)
 	}
 
-	err = database.DB.Create(video).Error
+	return &models.Video{
+		Name:        filepath.Base(path),
+		Path:        path,
+		Directory:   filepath.Dir(path),
+		Size:        info.Size(),
+		Duration:    meta.Duration,
+		Resolution:  meta.Resolution,
+		Width:       meta.Width,
+		Height:      meta.Height,
+		MediaInfo:   meta.Media,
+		ContentHash: contentHash,
+	}
+}
+
+// insertVideoRecord 写入新记录；路径已被占用时返回已有记录与 ErrVideoExists
+func insertVideoRecord(video *models.Video) (*models.Video, error) {
+	var existingVideo models.Video
+	err := database.DB.Create(video).Error
 	if err != nil {
 		errMsg := strings.ToLower(err.Error())
 		if strings.Contains(errMsg, "unique") || strings.Contains(errMsg, "constraint") {
-			if findErr := database.DB.Where("path = ?", path).First(&existingVideo).Error; findErr == nil {
+			if findErr := database.DB.Where("path = ?", video.Path).First(&existingVideo).Error; findErr == nil {
 				return &existingVideo, ErrVideoExists
 			}
 		}
 		return nil, err
 	}
-	log.Printf("新增视频 path=%s", path)
+	log.Printf("新增视频 path=%s", video.Path)
 	return video, nil
 }
 
@@ -370,6 +457,7 @@ func (s *VideoService) DeleteVideo(id uint, deleteFile bool) error {
 	if err := deleteSubtitleIndex(video.ID); err != nil {
 		log.Printf("删除字幕索引失败 videoID=%d err=%v", video.ID, err)
 	}
+	s.thumbnails.Invalidate(video.ID)
 	return database.DB.Delete(&video).Error
 }
 
@@ -474,12 +562,22 @@ type ScannedFile struct {
 }
 
 // ScanDirectoryWithInfo 扫描目录获取视频文件（附带文件大小）
+// 已登记的扫描目录按其扫描规则过滤，未登记的目录使用全局视频格式
 func (s *VideoService) ScanDirectoryWithInfo(dir string) ([]ScannedFile, error) {
-	var videoFiles []ScannedFile
 	dir = filepath.Clean(strings.TrimSpace(dir))
 	if dir == "" || dir == "." {
 		return nil, fmt.Errorf("扫描根目录为空")
 	}
+	rules, err := loadScanDirectoryRules(dir)
+	if err != nil {
+		return nil, err
+	}
+	return s.scanDirectoryWithRules(context.Background(), rules, nil)
+}
+
+// scanDirectoryWithRules 按规则遍历扫描根目录；onFile 在每发现一个视频文件时回调（可为 nil），ctx 取消时中止遍历
+func (s *VideoService) scanDirectoryWithRules(ctx context.Context, rules scanDirectoryRules, onFile func(ScannedFile)) ([]ScannedFile, error) {
+	dir := rules.root
 	rootInfo, err := os.Stat(dir)
 	if err != nil {
 		return nil, fmt.Errorf("扫描根目录不可用: %w", err)
@@ -488,103 +586,264 @@ func (s *VideoService) ScanDirectoryWithInfo(dir string) ([]ScannedFile, error)
 		return nil, fmt.Errorf("扫描根路径不是目录: %s", dir)
 	}
 
-	// 从设置中获取支持的视频格式
-	var settings models.Settings
-	if err := database.DB.First(&settings).Error; err != nil {
-		return nil, fmt.Errorf("获取设置失败: %w", err)
+	walker := &scanWalker{ctx: ctx, rules: rules, onFile: onFile, visited: make(map[string]struct{})}
+	if realRoot, err := filepath.EvalSymlinks(dir); err == nil {
+		walker.visited[realRoot] = struct{}{}
+	}
+	walker.walk(dir, "", 0)
+	if err := ctx.Err(); err != nil {
+		log.Printf("扫描目录已取消 dir=%s files=%d", dir, len(walker.files))
+		return nil, err
 	}
+	log.Printf("扫描目录完成 dir=%s files=%d", dir, len(walker.files))
 
-	// 解析视频格式
-	videoExts := strings.Split(settings.VideoExtensions, ",")
-	if len(videoExts) == 1 && strings.TrimSpace(videoExts[0]) == "" {
-		videoExts = strings.Split(".mp4,.avi,.mkv,.mov,.wmv,.flv,.webm,.m4v,.ts,.3gp,.mpg,.mpeg,.rm,.rmvb,.vob,.divx,.f4v,.asf,.qt", ",")
+	return walker.files, nil
+}
+
+// scanWalker 按扫描规则递归遍历目录；跟随符号链接时以真实路径去重防止循环
+type scanWalker struct {
+	ctx     context.Context
+	rules   scanDirectoryRules
+	onFile  func(ScannedFile)
+	visited map[string]struct{}
+	files   []ScannedFile
+}
+
+func (w *scanWalker) walk(dir string, rel string, depth int) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return // 跳过无法读取的目录
 	}
-	for i := range videoExts {
-		videoExts[i] = strings.TrimSpace(videoExts[i])
-		if videoExts[i] == "" {
-			continue
+	for _, entry := range entries {
+		if w.ctx.Err() != nil {
+			return
 		}
-		if !strings.HasPrefix(videoExts[i], ".") {
-			videoExts[i] = "." + videoExts[i]
+		path := filepath.Join(dir, entry.Name())
+		childRel := entry.Name()
+		if rel != "" {
+			childRel = rel + "/" + entry.Name()
 		}
-	}
 
-	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		info, err := entry.Info()
 		if err != nil {
-			return nil // 跳过错误的文件
+			continue
+		}
+		// 指向文件的符号链接总是收录；不跟随符号链接时只是不进入链接到的目录
+		if info.Mode()&os.ModeSymlink != 0 {
+			info, err = os.Stat(path)
+			if err != nil {
+				continue // 失效的符号链接
+			}
+			if info.IsDir() && !w.rules.followSymlinks {
+				continue
+			}
 		}
 
 		if shouldSkipHiddenPath(info) {
-			if info.IsDir() {
-				return filepath.SkipDir
-			}
-			return nil
+			continue
 		}
 
 		if info.IsDir() {
-			if isTrashDirName(info.Name()) {
-				return filepath.SkipDir
+			if isTrashDirName(info.Name()) || !w.rules.allowsDir(childRel, depth+1) {
+				continue
 			}
-			return nil
+			realPath, err := filepath.EvalSymlinks(path)
+			if err != nil {
+				continue
+			}
+			if _, seen := w.visited[realPath]; seen {
+				continue
+			}
+			w.visited[realPath] = struct{}{}
+			w.walk(path, childRel, depth+1)
+			continue
 		}
 
 		if isTrashPath(path) || hasTempVideoSuffix(path) || isRecentlyActiveFile(info) || isKnownNonVideoSourcePath(path) {
-			return nil
+			continue
 		}
 
-		ext := strings.ToLower(filepath.Ext(path))
-		for _, videoExt := range videoExts {
-			if ext == strings.ToLower(videoExt) {
-				videoFiles = append(videoFiles, ScannedFile{Path: path, Size: info.Size()})
-				break
+		if w.rules.allowsFile(childRel) {
+			file := ScannedFile{Path: path, Size: info.Size()}
+			w.files = append(w.files, file)
+			if w.onFile != nil {
+				w.onFile(file)
 			}
 		}
+	}
+}
 
-		return nil
-	})
-	log.Printf("扫描目录完成 dir=%s files=%d", dir, len(videoFiles))
+// loadScanVideoExtensions 从设置中读取支持的视频格式（统一为小写且带点前缀）
+func loadScanVideoExtensions() ([]string, error) {
+	var settings models.Settings
+	if err := database.DB.First(&settings).Error; err != nil {
+		return nil, fmt.Errorf("获取设置失败: %w", err)
+	}
 
-	return videoFiles, err
+	videoExts := normalizeVideoExtensions(settings.VideoExtensions)
+	if len(videoExts) == 0 {
+		videoExts = normalizeVideoExtensions(".mp4,.avi,.mkv,.mov,.wmv,.flv,.webm,.m4v,.ts,.3gp,.mpg,.mpeg,.rm,.rmvb,.vob,.divx,.f4v,.asf,.qt")
+	}
+	return videoExts, nil
+}
+
+// normalizeVideoExtensions 解析逗号分隔的格式列表，统一为小写且带点前缀
+func normalizeVideoExtensions(text string) []string {
+	normalized := make([]string, 0)
+	for _, ext := range strings.Split(text, ",") {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		normalized = append(normalized, ext)
+	}
+	return normalized
 }
 
-type scanFileFingerprint struct {
-	Name string
-	Size int64
+func hasVideoExtension(path string, videoExts []string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, videoExt := range videoExts {
+		if ext == videoExt {
+			return true
+		}
+	}
+	return false
 }
 
-func fingerprintScannedFile(file ScannedFile) scanFileFingerprint {
-	return scanFileFingerprint{Name: filepath.Base(file.Path), Size: file.Size}
+const (
+	relocateStrategyContentHash = "content_hash"
+	relocateStrategyNameSize    = "name_size"
+)
+
+func fingerprintScannedFile(file ScannedFile) string {
+	return fmt.Sprintf("%d:%s", file.Size, filepath.Base(file.Path))
 }
 
-func fingerprintVideo(video models.Video) scanFileFingerprint {
-	return scanFileFingerprint{Name: video.Name, Size: video.Size}
+// fingerprintVideo 仅用于尚未计算内容哈希的旧记录，已有哈希的记录必须按内容匹配
+func fingerprintVideo(video models.Video) string {
+	if video.ContentHash != "" {
+		return ""
+	}
+	return fmt.Sprintf("%d:%s", video.Size, video.Name)
+}
+
+func contentHashKeyForVideo(video models.Video) string {
+	if video.ContentHash == "" {
+		return ""
+	}
+	return buildDuplicateBucketKey(video.Size, video.ContentHash)
 }
 
 // SyncScanDirectories performs an incremental database sync for configured scan directories.
 func (s *VideoService) SyncScanDirectories(dirs []models.ScanDirectory) *ScanSyncResult {
+	return s.syncScanDirectories(context.Background(), dirs, scanSyncOptions{})
+}
+
+// scanSyncOptions 控制增量同步的并发度与进度回调
+type scanSyncOptions struct {
+	workers  int
+	progress func(stage string, current int, total int, path string, message string)
+}
+
+func (o scanSyncOptions) emit(stage string, current int, total int, path string, message string) {
+	if o.progress != nil {
+		o.progress(stage, current, total, path, message)
+	}
+}
+
+const (
+	defaultScanSyncWorkers   = 4
+	scanProgressFileInterval = 500
+)
+
+// scanMetadataTask 是已在原路径存在、但缺少元数据或内容哈希的记录
+type scanMetadataTask struct {
+	video        models.Video
+	needMetadata bool
+	needHash     bool
+}
+
+type scanMetadataOutcome struct {
+	metadata    videoMetadata
+	contentHash string
+	hashErr     error
+}
+
+// syncScanDirectories 分阶段执行增量同步：并发遍历根目录、worker 池补齐元数据与探测新文件，
+// 数据库写入统一在调用方 goroutine 串行完成。ctx 取消后不再开始新的阶段，且不会删除任何记录。
+func (s *VideoService) syncScanDirectories(ctx context.Context, dirs []models.ScanDirectory, opts scanSyncOptions) *ScanSyncResult {
 	result := &ScanSyncResult{Errors: make([]ScanSyncError, 0)}
+	// 手动同步与目录监听可能同时触发，串行执行避免重复入库或误删；
+	// 等待其他同步期间被取消时直接返回，不阻塞取消请求
+	if !acquireScanSync(ctx, func() { opts.emit("load", 0, 0, "", "正在等待其他扫描完成…") }) {
+		return result.cancel()
+	}
+	defer releaseScanSync()
+
+	if opts.workers <= 0 {
+		opts.workers = defaultScanSyncWorkers
+	}
 	scannedByPath := make(map[string]ScannedFile)
 	existingByPath := make(map[string]models.Video)
-	roots := make([]string, 0, len(dirs))
+	roots := make([]scanDirectoryRules, 0, len(dirs))
 	allExisting := make([]models.Video, 0)
 	duplicateVideos := make([]models.Video, 0)
 
-	for _, dir := range dirs {
-		root := filepath.Clean(strings.TrimSpace(dir.Path))
-		if root == "" || root == "." {
+	globalExts, err := loadScanVideoExtensions()
+	if err != nil {
+		result.recordError("scan", "", "", err)
+		return result
+	}
+
+	candidates := make([]scanDirectoryRules, 0, len(dirs))
+	offlineRoots := make([]string, 0)
+	for _, dir := range refreshScanDirectoryStates(dirs) {
+		rules := newScanDirectoryRules(dir, globalExts)
+		if rules.root == "" || rules.root == "." {
 			result.recordError("scan", dir.Path, "", fmt.Errorf("扫描目录为空"))
 			continue
 		}
 		result.Directories++
+		if dir.Offline {
+			// 卷未挂载时不扫描，其下记录保持原状，避免被当作缺失删除或标记失效
+			log.Printf("增量扫描跳过离线目录 root=%s reason=%s", rules.root, dir.OfflineReason)
+			result.Offline = append(result.Offline, rules.root)
+			offlineRoots = append(offlineRoots, rules.root)
+			continue
+		}
+		candidates = append(candidates, rules)
+	}
 
-		scannedFiles, err := s.ScanDirectoryWithInfo(root)
-		if err != nil {
-			result.recordError("scan", root, "", err)
+	type rootScan struct {
+		files []ScannedFile
+		err   error
+	}
+	var found atomic.Int64
+	onFile := func(file ScannedFile) {
+		if count := found.Add(1); count%scanProgressFileInterval == 0 {
+			opts.emit("scan", int(count), 0, file.Path, fmt.Sprintf("已发现 %d 个视频文件…", count))
+		}
+	}
+	opts.emit("scan", 0, 0, "", fmt.Sprintf("正在遍历 %d 个扫描目录…", len(candidates)))
+	scans := make([]rootScan, len(candidates))
+	runScanPool(ctx, opts.workers, len(candidates), func(idx int) {
+		files, err := s.scanDirectoryWithRules(ctx, candidates[idx], onFile)
+		scans[idx] = rootScan{files: files, err: err}
+	}, nil)
+	if ctx.Err() != nil {
+		return result.cancel()
+	}
+	for idx, rules := range candidates {
+		if scans[idx].err != nil {
+			result.recordError("scan", rules.root, "", scans[idx].err)
 			continue
 		}
-		roots = append(roots, root)
-		result.Scanned += len(scannedFiles)
-		for _, file := range scannedFiles {
+		roots = append(roots, rules)
+		result.Scanned += len(scans[idx].files)
+		for _, file := range scans[idx].files {
 			scannedByPath[file.Path] = file
 		}
 	}
@@ -594,7 +853,7 @@ func (s *VideoService) SyncScanDirectories(dirs []models.ScanDirectory) *ScanSyn
 		result.recordError("load_existing", "", "", err)
 	} else {
 		for _, video := range loadedExisting {
-			if !videoBelongsToRoots(video, roots) {
+			if !videoBelongsToRoots(video, roots) || isPathUnderAnyRoot(video.Path, offlineRoots) {
 				continue
 			}
 			if kept, exists := existingByPath[video.Path]; exists {
@@ -609,18 +868,43 @@ func (s *VideoService) SyncScanDirectories(dirs []models.ScanDirectory) *ScanSyn
 	}
 
 	missingVideos := make([]models.Video, 0)
+	metadataTasks := make([]scanMetadataTask, 0)
 	for _, video := range allExisting {
 		if _, exists := scannedByPath[video.Path]; !exists {
 			missingVideos = append(missingVideos, video)
 			continue
 		}
-		if video.Duration == 0 || video.Resolution == "" || video.Height == 0 {
-			if err := s.RefreshVideoMetadata(video.ID); err != nil {
-				result.recordError("refresh_metadata", video.Directory, video.Path, err)
-			} else {
-				result.MetadataRefreshed++
-			}
+		// 旧记录缺少编码、码率等媒体信息（media_probed_at 为空）时一并补齐
+		task := scanMetadataTask{
+			video:        video,
+			needMetadata: video.Duration == 0 || video.Resolution == "" || video.Height == 0 || video.MediaInfo.MediaProbedAt == nil,
+			// 旧记录补算内容哈希，后续移动或改名时才能按内容识别
+			needHash: video.ContentHash == "",
+		}
+		if task.needMetadata || task.needHash {
+			metadataTasks = append(metadataTasks, task)
+		}
+	}
+
+	opts.emit("metadata", 0, len(metadataTasks), "", fmt.Sprintf("正在补齐 %d 条记录的元数据…", len(metadataTasks)))
+	outcomes := make([]scanMetadataOutcome, len(metadataTasks))
+	runScanPool(ctx, opts.workers, len(metadataTasks), func(idx int) {
+		task := metadataTasks[idx]
+		outcome := &outcomes[idx]
+		if task.needMetadata {
+			outcome.metadata = s.probeVideoMetadata(ctx, task.video.Path)
+		}
+		if task.needHash {
+			outcome.contentHash, outcome.hashErr = getPartialHash(task.video.Path)
+		}
+	}, func(done int, idx int) {
+		s.applyScanMetadataOutcome(metadataTasks[idx], outcomes[idx], result)
+		if shouldEmitCleanupProgress(done, len(metadataTasks), 50) {
+			opts.emit("metadata", done, len(metadataTasks), metadataTasks[idx].video.Path, "正在补齐已有记录的元数据…")
 		}
+	})
+	if ctx.Err() != nil {
+		return result.cancel()
 	}
 
 	newFiles := make([]ScannedFile, 0)
@@ -633,53 +917,96 @@ func (s *VideoService) SyncScanDirectories(dirs []models.ScanDirectory) *ScanSyn
 	sortScannedFiles(newFiles)
 	relocatedVideoIDs := make(map[uint]struct{})
 	consumedNewPaths := make(map[string]struct{})
-	missingByFingerprint := make(map[scanFileFingerprint][]models.Video)
-	newFileCounts := make(map[scanFileFingerprint]int)
+	heldVideoIDs := make(map[uint]struct{})
+
+	missingSizes := make(map[int64]struct{})
 	for _, video := range missingVideos {
-		missingByFingerprint[fingerprintVideo(video)] = append(missingByFingerprint[fingerprintVideo(video)], video)
+		if video.ContentHash != "" {
+			missingSizes[video.Size] = struct{}{}
+		}
 	}
-	for _, file := range newFiles {
-		newFileCounts[fingerprintScannedFile(file)]++
+	hashKeyForFile := func(file ScannedFile) string {
+		if _, ok := missingSizes[file.Size]; !ok {
+			return ""
+		}
+		hash, err := getPartialHash(file.Path)
+		if err != nil {
+			result.recordError("content_hash", filepath.Dir(file.Path), file.Path, err)
+			return ""
+		}
+		return buildDuplicateBucketKey(file.Size, hash)
 	}
 
+	opts.emit("relocate", 0, len(missingVideos), "", fmt.Sprintf("正在为 %d 条缺失记录匹配迁移文件…", len(missingVideos)))
+	s.relocateMatchedFiles(relocateStrategyContentHash, missingVideos, newFiles, contentHashKeyForVideo, hashKeyForFile,
+		result, relocatedVideoIDs, consumedNewPaths, heldVideoIDs)
+	s.relocateMatchedFiles(relocateStrategyNameSize, missingVideos, newFiles, fingerprintVideo, fingerprintScannedFile,
+		result, relocatedVideoIDs, consumedNewPaths, heldVideoIDs)
+	if ctx.Err() != nil {
+		return result.cancel()
+	}
+
+	pendingFiles := make([]ScannedFile, 0, len(newFiles))
 	for _, file := range newFiles {
-		key := fingerprintScannedFile(file)
-		candidates := missingByFingerprint[key]
-		if len(candidates) != 1 || newFileCounts[key] != 1 {
-			continue
+		if _, consumed := consumedNewPaths[file.Path]; !consumed {
+			pendingFiles = append(pendingFiles, file)
 		}
-		video := candidates[0]
-		if _, used := relocatedVideoIDs[video.ID]; used {
-			continue
+	}
+	opts.emit("add", 0, len(pendingFiles), "", fmt.Sprintf("正在读取 %d 个新文件的元数据…", len(pendingFiles)))
+	probed := make([]*models.Video, len(pendingFiles))
+	added := make([]models.Video, 0, len(pendingFiles))
+	probeErrs := make([]error, len(pendingFiles))
+	runScanPool(ctx, opts.workers, len(pendingFiles), func(idx int) {
+		info, err := os.Stat(pendingFiles[idx].Path)
+		if err != nil {
+			probeErrs[idx] = fmt.Errorf("文件不存在: %w", err)
+			return
 		}
-		if err := s.RelocateVideo(video.ID, file.Path); err != nil {
-			result.recordError("relocate", video.Directory, file.Path, err)
-			continue
+		video := s.buildVideoRecord(ctx, pendingFiles[idx].Path, info)
+		// ffprobe 被取消的上下文中断时元数据不完整，不入库，留待下次同步
+		if ctx.Err() != nil {
+			return
 		}
-		result.Relocated++
-		relocatedVideoIDs[video.ID] = struct{}{}
-		consumedNewPaths[file.Path] = struct{}{}
-	}
-
-	for _, file := range newFiles {
-		if _, consumed := consumedNewPaths[file.Path]; consumed {
-			continue
+		probed[idx] = video
+	}, func(done int, idx int) {
+		file := pendingFiles[idx]
+		if probed[idx] == nil && probeErrs[idx] == nil {
+			return
 		}
-		if _, err := s.AddVideo(file.Path); err != nil {
+		if probeErrs[idx] != nil {
+			result.recordError("add", filepath.Dir(file.Path), file.Path, probeErrs[idx])
+		} else if video, err := insertVideoRecord(probed[idx]); err != nil {
 			if errors.Is(err, ErrVideoExists) {
 				result.Skipped++
-				continue
+			} else {
+				result.recordError("add", filepath.Dir(file.Path), file.Path, err)
 			}
-			result.recordError("add", filepath.Dir(file.Path), file.Path, err)
-			continue
+		} else {
+			result.Added++
+			added = append(added, *video)
+		}
+		if shouldEmitCleanupProgress(done, len(pendingFiles), 50) {
+			opts.emit("add", done, len(pendingFiles), file.Path, "正在入库新文件…")
 		}
-		result.Added++
+	})
+	// 取消前已入库的新视频也执行规则，保证与 AddVideo 行为一致
+	result.RuleTagged = applyTagRulesToNewVideos(added)
+	if ctx.Err() != nil {
+		return result.cancel()
 	}
 
-	for _, video := range append(duplicateVideos, missingVideos...) {
+	toRemove := append(duplicateVideos, missingVideos...)
+	opts.emit("delete", 0, len(toRemove), "", "正在清理已不存在的记录…")
+	for _, video := range toRemove {
 		if _, relocated := relocatedVideoIDs[video.ID]; relocated {
 			continue
 		}
+		if _, held := heldVideoIDs[video.ID]; held {
+			if err := database.DB.Model(&models.Video{}).Where("id = ?", video.ID).Update("is_stale", true).Error; err != nil {
+				result.recordError("mark_stale", video.Directory, video.Path, err)
+			}
+			continue
+		}
 		if err := s.DeleteVideo(video.ID, false); err != nil {
 			result.recordError("delete", video.Directory, video.Path, err)
 			continue
@@ -687,12 +1014,154 @@ func (s *VideoService) SyncScanDirectories(dirs []models.ScanDirectory) *ScanSyn
 		result.Deleted++
 	}
 
-	log.Printf("增量扫描同步完成 dirs=%d scanned=%d added=%d relocated=%d deleted=%d refreshed=%d skipped=%d errors=%d",
-		result.Directories, result.Scanned, result.Added, result.Relocated, result.Deleted, result.MetadataRefreshed, result.Skipped, len(result.Errors))
+	log.Printf("增量扫描同步完成 dirs=%d scanned=%d added=%d relocated=%d deleted=%d refreshed=%d hashed=%d skipped=%d rule_tagged=%d ambiguous=%d errors=%d",
+		result.Directories, result.Scanned, result.Added, result.Relocated, result.Deleted, result.MetadataRefreshed, result.HashesComputed, result.Skipped, result.RuleTagged, len(result.Ambiguous), len(result.Errors))
 	return result
 }
 
-func (s *VideoService) getActiveVideosUnderRoots(roots []string) ([]models.Video, error) {
+func (s *VideoService) applyScanMetadataOutcome(task scanMetadataTask, outcome scanMetadataOutcome, result *ScanSyncResult) {
+	video := task.video
+	if task.needMetadata {
+		if !outcome.metadata.valid() {
+			result.recordError("refresh_metadata", video.Directory, video.Path, fmt.Errorf("未能从文件中提取有效元数据: %s", video.Path))
+		} else if err := database.DB.Model(&models.Video{}).Where("id = ?", video.ID).Updates(outcome.metadata.columns()).Error; err != nil {
+			result.recordError("refresh_metadata", video.Directory, video.Path, err)
+		} else {
+			result.MetadataRefreshed++
+		}
+	}
+	if task.needHash {
+		if outcome.hashErr != nil {
+			result.recordError("content_hash", video.Directory, video.Path, outcome.hashErr)
+		} else if err := database.DB.Model(&models.Video{}).Where("id = ?", video.ID).Update("content_hash", outcome.contentHash).Error; err != nil {
+			result.recordError("content_hash", video.Directory, video.Path, err)
+		} else {
+			result.HashesComputed++
+		}
+	}
+}
+
+// runScanPool 以 workers 个 goroutine 并发执行 work(idx)；collect 在调用方 goroutine 中按完成顺序串行回调，
+// 用于集中写库。ctx 取消后不再派发新任务，已派发的任务仍会完成并回调。
+func runScanPool(ctx context.Context, workers int, total int, work func(idx int), collect func(done int, idx int)) {
+	if total == 0 {
+		return
+	}
+	if workers > total {
+		workers = total
+	}
+	jobs := make(chan int)
+	finished := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				work(idx)
+				finished <- idx
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for idx := 0; idx < total; idx++ {
+			select {
+			case <-ctx.Done():
+				return
+			case jobs <- idx:
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+
+	done := 0
+	for idx := range finished {
+		done++
+		if collect != nil {
+			collect(done, idx)
+		}
+	}
+}
+
+// relocateMatchedFiles 按给定匹配键把缺失记录迁移到新文件；仅一对一匹配时迁移，
+// 一对多或多对一时记为歧义，相关记录保留（标记失效）且候选文件暂不入库，避免丢失元数据。
+func (s *VideoService) relocateMatchedFiles(
+	strategy string,
+	missingVideos []models.Video,
+	newFiles []ScannedFile,
+	videoKey func(models.Video) string,
+	fileKey func(ScannedFile) string,
+	result *ScanSyncResult,
+	relocatedVideoIDs map[uint]struct{},
+	consumedNewPaths map[string]struct{},
+	heldVideoIDs map[uint]struct{},
+) {
+	missingByKey := make(map[string][]models.Video)
+	for _, video := range missingVideos {
+		if _, done := relocatedVideoIDs[video.ID]; done {
+			continue
+		}
+		if _, held := heldVideoIDs[video.ID]; held {
+			continue
+		}
+		if key := videoKey(video); key != "" {
+			missingByKey[key] = append(missingByKey[key], video)
+		}
+	}
+	if len(missingByKey) == 0 {
+		return
+	}
+
+	filesByKey := make(map[string][]ScannedFile)
+	keys := make([]string, 0)
+	for _, file := range newFiles {
+		if _, consumed := consumedNewPaths[file.Path]; consumed {
+			continue
+		}
+		key := fileKey(file)
+		if key == "" {
+			continue
+		}
+		if _, ok := missingByKey[key]; !ok {
+			continue
+		}
+		if _, seen := filesByKey[key]; !seen {
+			keys = append(keys, key)
+		}
+		filesByKey[key] = append(filesByKey[key], file)
+	}
+
+	for _, key := range keys {
+		candidates := missingByKey[key]
+		files := filesByKey[key]
+		if len(candidates) != 1 || len(files) != 1 {
+			result.recordAmbiguous(strategy, candidates, files)
+			for _, video := range candidates {
+				heldVideoIDs[video.ID] = struct{}{}
+			}
+			for _, file := range files {
+				consumedNewPaths[file.Path] = struct{}{}
+			}
+			continue
+		}
+		video := candidates[0]
+		file := files[0]
+		if err := s.RelocateVideo(video.ID, file.Path); err != nil {
+			result.recordError("relocate", video.Directory, file.Path, err)
+			continue
+		}
+		log.Printf("增量扫描识别迁移 strategy=%s id=%d old=%s new=%s", strategy, video.ID, video.Path, file.Path)
+		result.Relocated++
+		relocatedVideoIDs[video.ID] = struct{}{}
+		consumedNewPaths[file.Path] = struct{}{}
+	}
+}
+
+func (s *VideoService) getActiveVideosUnderRoots(roots []scanDirectoryRules) ([]models.Video, error) {
 	if len(roots) == 0 {
 		return []models.Video{}, nil
 	}
@@ -709,10 +1178,20 @@ func (s *VideoService) getActiveVideosUnderRoots(roots []string) ([]models.Video
 	return filtered, nil
 }
 
-func videoBelongsToRoots(video models.Video, roots []string) bool {
+// videoBelongsToRoots 判断记录是否落在某个扫描根目录的规则范围内；
+// 被排除模式、深度或格式过滤掉的记录不参与增量同步，不会被当作缺失删除
+func videoBelongsToRoots(video models.Video, roots []scanDirectoryRules) bool {
 	for _, root := range roots {
-		prefix := root + string(os.PathSeparator)
-		if video.Directory == root || strings.HasPrefix(video.Directory, prefix) || strings.HasPrefix(video.Path, prefix) {
+		if root.matchesPath(video.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+func isPathUnderAnyRoot(path string, roots []string) bool {
+	for _, root := range roots {
+		if isPathUnderRoot(path, root) {
 			return true
 		}
 	}
@@ -810,21 +1289,23 @@ func (s *VideoService) RelocateVideo(id uint, newPath string) error {
 	}
 
 	// 迁移时也尝试重新提取元数据（可能之前的元数据是空的）
-	duration, resolution, width, height := s.getVideoMetadata(newPath)
-
-	result := database.DB.Model(&models.Video{}).Where("id = ?", id).Updates(map[string]interface{}{
-		"path":       newPath,
-		"directory":  filepath.Dir(newPath),
-		"name":       filepath.Base(newPath),
-		"duration":   duration,
-		"resolution": resolution,
-		"width":      width,
-		"height":     height,
-	})
+	meta := s.probeVideoMetadata(context.Background(), newPath)
+
+	updates := meta.columns()
+	updates["path"] = newPath
+	updates["directory"] = filepath.Dir(newPath)
+	updates["name"] = filepath.Base(newPath)
+	if contentHash, err := getPartialHash(newPath); err == nil {
+		updates["content_hash"] = contentHash
+	} else {
+		log.Printf("[VideoService] content hash failed for %s: %v", newPath, err)
+	}
+	result := database.DB.Model(&models.Video{}).Where("id = ?", id).Updates(updates)
 	if result.Error != nil {
 		return result.Error
 	}
-	log.Printf("视频迁移并更新元数据 id=%d newPath=%s duration=%.1f res=%s", id, newPath, duration, resolution)
+	s.thumbnails.Regenerate(id)
+	log.Printf("视频迁移并更新元数据 id=%d newPath=%s duration=%.1f res=%s codec=%s", id, newPath, meta.Duration, meta.Resolution, meta.Media.VideoCodec)
 	return nil
 }
 
@@ -835,17 +1316,12 @@ func (s *VideoService) RefreshVideoMetadata(id uint) error {
 		return err
 	}
 
-	duration, resolution, width, height := s.getVideoMetadata(video.Path)
-	if duration == 0 && resolution == "" {
+	meta := s.probeVideoMetadata(context.Background(), video.Path)
+	if !meta.valid() {
 		return fmt.Errorf("未能从文件中提取有效元数据: %s", video.Path)
 	}
 
-	return database.DB.Model(&video).Updates(map[string]interface{}{
-		"duration":   duration,
-		"resolution": resolution,
-		"width":      width,
-		"height":     height,
-	}).Error
+	return database.DB.Model(&video).Updates(meta.columns()).Error
 }
 
 // RenameVideo 重命名视频文件及数据库记录
@@ -898,6 +1374,7 @@ func (s *VideoService) RenameVideo(id uint, newName string) error {
 		return fmt.Errorf("更新数据库失败: %w", err)
 	}
 
+	s.thumbnails.Regenerate(id)
 	log.Printf("视频重命名 id=%d oldName=%s newName=%s", id, video.Name, newName)
 	return nil
 }
@@ -943,77 +1420,43 @@ func (s *VideoService) PlayVideo(videoID uint) (*PlaybackAttemptResult, error) {
 	return s.dispatchFormalPlayback(&video, false)
 }
 
-// PlayRandomVideo 智能加权随机发起播放
+// PlayRandomVideo 智能加权随机发起播放（全库，少播优先）
 func (s *VideoService) PlayRandomVideo() (*PlaybackAttemptResult, error) {
-	// 获取播放权重配置
-	var settings models.Settings
-	if err := database.DB.First(&settings).Error; err != nil {
-		return nil, fmt.Errorf("获取设置失败: %w", err)
-	}
-	playWeight := settings.PlayWeight
-	if playWeight < 0.1 {
-		playWeight = 0.1
-	}
+	return s.PlayRandomVideoWithOptions(RandomPlayOptions{})
+}
 
-	// 仅查询计算权重所需的最少字段，避免全量加载
-	type videoScoreRow struct {
-		ID              uint
-		PlayCount       int
-		RandomPlayCount int
-	}
-	var rows []videoScoreRow
-	if err := database.DB.Model(&models.Video{}).
-		Select("id, play_count, random_play_count").
-		Find(&rows).Error; err != nil {
+// PlayRandomVideoWithOptions 按指定策略在范围内随机选择并发起播放
+func (s *VideoService) PlayRandomVideoWithOptions(options RandomPlayOptions) (*PlaybackAttemptResult, error) {
+	videoID, err := pickRandomVideoID(options)
+	if err != nil {
 		return nil, err
 	}
-
-	if len(rows) == 0 {
+	if videoID == 0 {
+		message := "随机播放失败：当前没有可播放的视频记录。"
+		if !isEmptyRandomPlayScope(options.Scope) {
+			message = "随机播放失败：当前范围内没有可播放的视频。"
+		}
 		return &PlaybackAttemptResult{
 			DispatchSucceeded: false,
 			ReasonCode:        "no_videos",
-			UserMessage:       "随机播放失败：当前没有可播放的视频记录。",
+			UserMessage:       message,
 		}, nil
 	}
 
-	// 计算每个视频的播放分数和最大分数
-	scores := make([]float64, len(rows))
-	maxScore := 0.0
-	for i, r := range rows {
-		scores[i] = float64(r.PlayCount)*playWeight + float64(r.RandomPlayCount)
-		if scores[i] > maxScore {
-			maxScore = scores[i]
-		}
-	}
-
-	// 计算选择权重并做加权随机选择
-	totalWeight := 0.0
-	weights := make([]float64, len(rows))
-	for i, score := range scores {
-		weights[i] = maxScore - score + 1.0
-		totalWeight += weights[i]
-	}
-
-	// 使用加权随机选择（Go 1.20+ 全局 rand 已自动 seed，无需手动调用）
-	randomValue := rand.Float64() * totalWeight
-	selectedIdx := len(rows) - 1 // 默认最后一个（防御浮点精度）
-	cumulative := 0.0
-	for i, w := range weights {
-		cumulative += w
-		if randomValue <= cumulative {
-			selectedIdx = i
-			break
-		}
-	}
-
 	// 仅对选中的视频查询完整记录（含 Tags）
 	var selectedVideo models.Video
-	if err := database.DB.Preload("Tags").First(&selectedVideo, rows[selectedIdx].ID).Error; err != nil {
+	if err := database.DB.Preload("Tags").First(&selectedVideo, videoID).Error; err != nil {
 		return nil, fmt.Errorf("查询选中视频失败: %w", err)
 	}
 
 	// 使用数据库原子操作更新随机播放次数和最后播放时间
-	return s.dispatchFormalPlayback(&selectedVideo, true)
+	result, err := s.dispatchFormalPlayback(&selectedVideo, true)
+	if err == nil && result != nil && result.DispatchSucceeded {
+		if err := recordRandomPick(videoID, RandomPickSourceDesktop, time.Now()); err != nil {
+			log.Printf("记录随机选片失败: %v", err)
+		}
+	}
+	return result, err
 }
 
 var openWithDefaultFn = openPath
@@ -1041,9 +1484,44 @@ func openPath(path string, isDir bool) error {
 	return cmd.Start()
 }
 
+// playbackLauncher 把已确认可访问的视频交给播放端
+type playbackLauncher func(video *models.Video) error
+
+// launchWithPlayerProfile 用外部播放器从续播位置打开视频
+func launchWithPlayerProfile(video *models.Video) error {
+	return launchInPlayer(video.Path, resumePositionForVideo(video.ID))
+}
+
+// dispatchFormalPlayback 发起正式播放，并把结果（成功或失败原因码）写入播放历史
 func (s *VideoService) dispatchFormalPlayback(video *models.Video, random bool) (*PlaybackAttemptResult, error) {
+	return s.dispatchFormalPlaybackVia(video, random, launchWithPlayerProfile)
+}
+
+// dispatchFormalPlaybackVia 与 dispatchFormalPlayback 相同，但由调用方决定如何打开视频（如播放列表在内嵌预览中逐项播放）
+func (s *VideoService) dispatchFormalPlaybackVia(video *models.Video, random bool, launch playbackLauncher) (*PlaybackAttemptResult, error) {
+	source := PlaybackEventSourceFormal
+	if random {
+		source = PlaybackEventSourceRandom
+	}
+	result, err := s.attemptFormalPlayback(video, random, launch)
+	if result != nil {
+		playedAt := time.Now()
+		if result.DispatchSucceeded && video.LastPlayedAt != nil {
+			playedAt = *video.LastPlayedAt
+		}
+		recordPlaybackEvent(database.DB, video.ID, source, result.ReasonCode, playedAt)
+	}
+	return result, err
+}
+
+func (s *VideoService) attemptFormalPlayback(video *models.Video, random bool, launch playbackLauncher) (*PlaybackAttemptResult, error) {
 	info, err := os.Stat(video.Path)
 	if err != nil {
+		// 卷未挂载时不标记失效、不触发全库纠偏扫描
+		if offlineDir, checkErr := findOfflineRootForVideo(video); checkErr == nil && offlineDir != nil {
+			return s.buildPlaybackFailureResult(video, "volume_offline",
+				fmt.Sprintf("所在扫描目录当前离线（%s）：%s", offlineDir.OfflineReason, offlineDir.Path), false), nil
+		}
 		if os.IsNotExist(err) {
 			return s.buildPlaybackFailureResult(video, "file_missing", "源文件不存在或已被移动。", true), nil
 		}
@@ -1053,7 +1531,7 @@ func (s *VideoService) dispatchFormalPlayback(video *models.Video, random bool)
 		return s.buildPlaybackFailureResult(video, "path_is_directory", "当前路径不是可播放文件。", true), nil
 	}
 
-	if err := openWithDefaultFn(video.Path, false); err != nil {
+	if err := launch(video); err != nil {
 		return s.buildPlaybackFailureResult(video, "dispatch_failed", err.Error(), false), nil
 	}
 
@@ -1155,33 +1633,50 @@ func (s *VideoService) findRelocatedVideoCandidate(video *models.Video) (string,
 		return "", false, nil
 	}
 
-	primary := make([]string, 0, len(directories))
-	secondary := make([]string, 0, len(directories))
-	for _, dir := range directories {
-		cleanPath := filepath.Clean(dir.Path)
-		if cleanPath == "" {
-			continue
+	onlineDirs := make([]models.ScanDirectory, 0, len(directories))
+	for _, dir := range refreshScanDirectoryStates(directories) {
+		if !dir.Offline {
+			onlineDirs = append(onlineDirs, dir)
 		}
-		prefix := cleanPath + string(os.PathSeparator)
-		if video.Directory == cleanPath || strings.HasPrefix(video.Directory, prefix) {
-			primary = append(primary, cleanPath)
+	}
+	rulesList, err := loadScanDirectoryRulesList(onlineDirs)
+	if err != nil {
+		return "", false, err
+	}
+	primary := make([]scanDirectoryRules, 0, len(rulesList))
+	secondary := make([]scanDirectoryRules, 0, len(rulesList))
+	for _, rules := range rulesList {
+		prefix := rules.root + string(os.PathSeparator)
+		if video.Directory == rules.root || strings.HasPrefix(video.Directory, prefix) {
+			primary = append(primary, rules)
 		} else {
-			secondary = append(secondary, cleanPath)
+			secondary = append(secondary, rules)
 		}
 	}
 
 	roots := append(primary, secondary...)
 	seenCandidates := map[string]struct{}{}
 	for _, root := range roots {
-		scannedFiles, err := s.ScanDirectoryWithInfo(root)
+		scannedFiles, err := s.scanDirectoryWithRules(context.Background(), root, nil)
 		if err != nil {
 			return "", false, err
 		}
 		for _, candidate := range scannedFiles {
-			if filepath.Base(candidate.Path) != video.Name {
+			if candidate.Size != video.Size || candidate.Path == video.Path {
 				continue
 			}
-			if candidate.Size != video.Size {
+			if video.ContentHash != "" {
+				// 有内容哈希时按内容匹配，移动并改名的文件也能找回
+				hash, err := getPartialHash(candidate.Path)
+				if err != nil || hash != video.ContentHash {
+					continue
+				}
+				if owned, err := isPathOwnedByOtherVideo(candidate.Path, video.ID); err != nil {
+					return "", false, err
+				} else if owned {
+					continue
+				}
+			} else if filepath.Base(candidate.Path) != video.Name {
 				continue
 			}
 			seenCandidates[candidate.Path] = struct{}{}
@@ -1197,3 +1692,11 @@ func (s *VideoService) findRelocatedVideoCandidate(video *models.Video) (string,
 
 	return "", false, nil
 }
+
+func isPathOwnedByOtherVideo(path string, videoID uint) (bool, error) {
+	var count int64
+	if err := database.DB.Model(&models.Video{}).Where("path = ? AND id != ?", path, videoID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
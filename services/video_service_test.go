@@ -206,6 +206,124 @@ func TestSyncScanDirectoriesAddsAndRelocatesPreservingTags(t *testing.T) {
 	}
 }
 
+func TestSyncScanDirectoriesRelocatesRenamedFileByContentHash(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	svc := &VideoService{}
+	root := t.TempDir()
+	oldTime := time.Now().Add(-10 * time.Minute)
+
+	oldPath := filepath.Join(root, "old", "movie.mp4")
+	newPath := filepath.Join(root, "new", "renamed-movie.mp4")
+	content := []byte("renamed movie content")
+	mustWriteSizedFile(t, newPath, content)
+	mustSetFileModTime(t, newPath, oldTime)
+	hash, err := getPartialHash(newPath)
+	if err != nil {
+		t.Fatalf("计算哈希失败: %v", err)
+	}
+
+	tag := models.Tag{Name: "keep", Color: "#fff"}
+	if err := database.DB.Create(&tag).Error; err != nil {
+		t.Fatalf("创建标签失败: %v", err)
+	}
+	video := models.Video{
+		Name:        "movie.mp4",
+		Path:        oldPath,
+		Directory:   filepath.Dir(oldPath),
+		Size:        int64(len(content)),
+		ContentHash: hash,
+		PlayCount:   3,
+	}
+	if err := database.DB.Create(&video).Error; err != nil {
+		t.Fatalf("创建视频失败: %v", err)
+	}
+	if err := database.DB.Model(&video).Association("Tags").Append(&tag); err != nil {
+		t.Fatalf("绑定标签失败: %v", err)
+	}
+
+	result := svc.SyncScanDirectories([]models.ScanDirectory{{Path: root}})
+	if result.Relocated != 1 || result.Added != 0 || result.Deleted != 0 || len(result.Ambiguous) != 0 {
+		t.Fatalf("同步结果错误: %#v", result)
+	}
+
+	var loaded models.Video
+	if err := database.DB.Preload("Tags").First(&loaded, video.ID).Error; err != nil {
+		t.Fatalf("读取迁移后视频失败: %v", err)
+	}
+	if loaded.Path != newPath || loaded.Name != "renamed-movie.mp4" || loaded.PlayCount != 3 {
+		t.Fatalf("按内容哈希迁移后记录错误: %#v", loaded)
+	}
+	if len(loaded.Tags) != 1 || loaded.Tags[0].ID != tag.ID {
+		t.Fatalf("迁移后应保留标签，实际 %#v", loaded.Tags)
+	}
+}
+
+func TestSyncScanDirectoriesReportsAmbiguousContentHashMatches(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	svc := &VideoService{}
+	root := t.TempDir()
+	oldTime := time.Now().Add(-10 * time.Minute)
+
+	content := []byte("duplicated content")
+	copyA := filepath.Join(root, "a", "copy-a.mp4")
+	copyB := filepath.Join(root, "b", "copy-b.mp4")
+	for _, path := range []string{copyA, copyB} {
+		mustWriteSizedFile(t, path, content)
+		mustSetFileModTime(t, path, oldTime)
+	}
+	hash, err := getPartialHash(copyA)
+	if err != nil {
+		t.Fatalf("计算哈希失败: %v", err)
+	}
+	missingPath := filepath.Join(root, "gone", "original.mp4")
+	video := models.Video{
+		Name:        "original.mp4",
+		Path:        missingPath,
+		Directory:   filepath.Dir(missingPath),
+		Size:        int64(len(content)),
+		ContentHash: hash,
+	}
+	if err := database.DB.Create(&video).Error; err != nil {
+		t.Fatalf("创建视频失败: %v", err)
+	}
+
+	result := svc.SyncScanDirectories([]models.ScanDirectory{{Path: root}})
+	if result.Relocated != 0 || result.Added != 0 || result.Deleted != 0 {
+		t.Fatalf("歧义匹配不应迁移、新增或删除: %#v", result)
+	}
+	if len(result.Ambiguous) != 1 {
+		t.Fatalf("期望报告1组歧义匹配，实际 %#v", result.Ambiguous)
+	}
+	ambiguous := result.Ambiguous[0]
+	if ambiguous.Strategy != relocateStrategyContentHash || len(ambiguous.VideoIDs) != 1 || ambiguous.VideoIDs[0] != video.ID || len(ambiguous.CandidatePaths) != 2 {
+		t.Fatalf("歧义匹配内容错误: %#v", ambiguous)
+	}
+
+	loaded := previewStatsSnapshot(t, video.ID)
+	if !loaded.IsStale || loaded.Path != missingPath {
+		t.Fatalf("歧义记录应保留并标记失效: %#v", loaded)
+	}
+}
+
+func TestAddVideoStoresContentHash(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	svc := &VideoService{}
+	videoPath := filepath.Join(t.TempDir(), "hashed.mp4")
+	mustWriteSizedFile(t, videoPath, []byte("hashed content"))
+
+	video, err := svc.AddVideo(videoPath)
+	if err != nil {
+		t.Fatalf("添加视频失败: %v", err)
+	}
+	want, err := getPartialHash(videoPath)
+	if err != nil {
+		t.Fatalf("计算哈希失败: %v", err)
+	}
+	if video.ContentHash == "" || video.ContentHash != want {
+		t.Fatalf("期望保存内容哈希 %s，实际 %q", want, video.ContentHash)
+	}
+}
+
 func TestDeleteVideoMovesFileToTrashWhenDeleteFileEnabled(t *testing.T) {
 	setupVideoServiceTestDB(t)
 	svc := &VideoService{}
@@ -311,7 +429,7 @@ func TestSearchVideosWithFiltersCombinesKeywordAndTags(t *testing.T) {
 		t.Fatalf("绑定标签失败: %v", err)
 	}
 
-	videos, err := svc.SearchVideosWithFilters("cat", []uint{tag.ID}, 0, 0, 0, 100, 0, 0, 0, 100)
+	videos, err := svc.SearchVideosWithFilters("cat", []uint{tag.ID}, 0, 0, 0, 100, VideoMediaFilter{}, 0, 0, 0, 100)
 	if err != nil {
 		t.Fatalf("组合搜索失败: %v", err)
 	}
@@ -696,6 +814,40 @@ func TestPlayVideoMissingFileReturnsReconcileResultAndMarksStale(t *testing.T) {
 	}
 }
 
+func TestPlayVideoMissingFileRelocatesRenamedFileByContentHash(t *testing.T) {
+	setupVideoServiceTestDB(t)
+	svc := &VideoService{}
+	root := t.TempDir()
+	content := []byte("moved and renamed")
+	renamedPath := filepath.Join(root, "sorted", "better-name.mp4")
+	mustWriteSizedFile(t, renamedPath, content)
+	mustSetFileModTime(t, renamedPath, time.Now().Add(-10*time.Minute))
+	hash, err := getPartialHash(renamedPath)
+	if err != nil {
+		t.Fatalf("计算哈希失败: %v", err)
+	}
+	if err := database.DB.Create(&models.ScanDirectory{Path: root}).Error; err != nil {
+		t.Fatalf("创建扫描目录失败: %v", err)
+	}
+	originalPath := filepath.Join(root, "original.mp4")
+	video := models.Video{Name: "original.mp4", Path: originalPath, Directory: root, Size: int64(len(content)), ContentHash: hash}
+	if err := database.DB.Create(&video).Error; err != nil {
+		t.Fatalf("创建视频失败: %v", err)
+	}
+
+	result, err := svc.PlayVideo(video.ID)
+	if err != nil {
+		t.Fatalf("期望领域失败走返回值而非 error: %v", err)
+	}
+	if result.ReconcileResult == nil || !result.ReconcileResult.DidRelocate {
+		t.Fatalf("期望按内容哈希自动纠偏: %#v", result.ReconcileResult)
+	}
+	after := previewStatsSnapshot(t, video.ID)
+	if after.Path != renamedPath || after.IsStale {
+		t.Fatalf("纠偏后路径错误: %#v", after)
+	}
+}
+
 func TestPlayRandomVideoSuccessWritesStatsOnlyOnDispatchSuccess(t *testing.T) {
 	setupVideoServiceTestDB(t)
 	svc := &VideoService{}